@@ -0,0 +1,181 @@
+package admin
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/bomber-team/rest-bomber/handlers"
+	"github.com/sirupsen/logrus"
+)
+
+// Disabled - value of address or token which turns off the admin API
+const Disabled = "off"
+
+const cancelSuffix = "/cancel"
+
+// Bomber - operations of the bomber available for operators
+type Bomber interface {
+	Tasks() handlers.TasksSnapshot
+	LastResult() *handlers.LastResult
+	CancelAttack(taskId string) bool
+	Pause()
+	Resume()
+	Reload() *handlers.ReloadAnswer
+}
+
+type server struct {
+	bomber Bomber
+	token  string
+}
+
+/*
+Serve - exposes admin API of the bomber, every request must have header Authorization: Bearer <token>.
+The API is not served without token. Blocks until listener fails
+*/
+func Serve(addr string, token string, bomber Bomber) {
+	if addr == Disabled || addr == "" {
+		return
+	}
+	if token == Disabled || token == "" {
+		logrus.Error("Can not serve admin API without token, set ADMIN_TOKEN")
+		return
+	}
+	srv := &server{bomber: bomber, token: token}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/tasks", srv.tasks)
+	mux.HandleFunc("/tasks/", srv.cancel)
+	mux.HandleFunc("/results/last", srv.lastResult)
+	mux.HandleFunc("/log-level", srv.logLevel)
+	mux.HandleFunc("/pause", srv.pause)
+	mux.HandleFunc("/resume", srv.resume)
+	mux.HandleFunc("/reload", srv.reload)
+	logrus.Info("Serving admin API on ", addr)
+	if err := http.ListenAndServe(addr, srv.authorized(mux)); err != nil {
+		logrus.Error("Can not serve admin API: ", err)
+	}
+}
+
+func (srv *server) authorized(next http.Handler) http.Handler {
+	expected := []byte("Bearer " + srv.token)
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(request.Header.Get("Authorization")), expected) != 1 {
+			http.Error(writer, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+func allowed(writer http.ResponseWriter, request *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if request.Method == method {
+			return true
+		}
+	}
+	writer.Header().Set("Allow", strings.Join(methods, ", "))
+	http.Error(writer, "method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(writer http.ResponseWriter, status int, value interface{}) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(value); err != nil {
+		logrus.Error("Can not write answer of admin API: ", err)
+	}
+}
+
+func (srv *server) tasks(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(writer, request, http.MethodGet) {
+		return
+	}
+	writeJSON(writer, http.StatusOK, srv.bomber.Tasks())
+}
+
+// cancel - POST /tasks/<task id>/cancel, task is cancelled as by cancel topic
+func (srv *server) cancel(writer http.ResponseWriter, request *http.Request) {
+	taskId := strings.TrimPrefix(request.URL.Path, "/tasks/")
+	if !strings.HasSuffix(taskId, cancelSuffix) {
+		http.NotFound(writer, request)
+		return
+	}
+	if !allowed(writer, request, http.MethodPost) {
+		return
+	}
+	taskId = strings.TrimSuffix(taskId, cancelSuffix)
+	cancelled := srv.bomber.CancelAttack(taskId)
+	status := http.StatusOK
+	if !cancelled {
+		status = http.StatusNotFound
+	}
+	writeJSON(writer, status, map[string]interface{}{"task_id": taskId, "cancelled": cancelled})
+}
+
+func (srv *server) lastResult(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(writer, request, http.MethodGet) {
+		return
+	}
+	result := srv.bomber.LastResult()
+	if result == nil {
+		http.Error(writer, "bomber did not finish any attack yet", http.StatusNotFound)
+		return
+	}
+	writeJSON(writer, http.StatusOK, result)
+}
+
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+// logLevel - GET answers current level, PUT with {"level": "debug"} changes it until restart
+func (srv *server) logLevel(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(writer, request, http.MethodGet, http.MethodPut) {
+		return
+	}
+	if request.Method == http.MethodPut {
+		var body logLevel
+		if err := json.NewDecoder(request.Body).Decode(&body); err != nil {
+			http.Error(writer, "invalid body: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		level, err := logrus.ParseLevel(body.Level)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logrus.SetLevel(level)
+		logrus.Info("Log level is changed by admin API to ", level)
+	}
+	writeJSON(writer, http.StatusOK, logLevel{Level: logrus.GetLevel().String()})
+}
+
+func (srv *server) pause(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(writer, request, http.MethodPost) {
+		return
+	}
+	srv.bomber.Pause()
+	writeJSON(writer, http.StatusOK, srv.bomber.Tasks())
+}
+
+func (srv *server) resume(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(writer, request, http.MethodPost) {
+		return
+	}
+	srv.bomber.Resume()
+	writeJSON(writer, http.StatusOK, srv.bomber.Tasks())
+}
+
+// reload - POST reads configuration again, bad request if it is invalid now
+func (srv *server) reload(writer http.ResponseWriter, request *http.Request) {
+	if !allowed(writer, request, http.MethodPost) {
+		return
+	}
+	answer := srv.bomber.Reload()
+	status := http.StatusOK
+	if answer.Error != "" {
+		status = http.StatusBadRequest
+	}
+	writeJSON(writer, status, answer)
+}
@@ -0,0 +1,46 @@
+package broker
+
+import (
+	"errors"
+	"time"
+)
+
+// Kinds of brokers, NATS is the default one
+const (
+	KindNats     = "nats"
+	KindKafka    = "kafka"
+	KindRabbitMQ = "rabbitmq"
+	KindRedis    = "redis"
+	// broker of one process, bombers do not talk to a server
+	KindLocal = "local"
+)
+
+var ErrUnknownKind = errors.New("unknown kind of broker, expected nats, kafka, rabbitmq, redis or local")
+
+// Message - message of any broker, reply is empty if sender does not wait for answer
+type Message struct {
+	Subject string
+	Data    []byte
+	Reply   string
+}
+
+type Handler func(message *Message)
+
+type Subscription interface {
+	Unsubscribe() error
+}
+
+/*
+Broker - transport of tasks and results of the bomber. Subscribers of the same group share messages
+of the subject, each message is delivered to only one of them, subscriber without group gets all messages
+*/
+type Broker interface {
+	Publish(subject string, data []byte) error
+	Subscribe(subject string, group string, handler Handler) (Subscription, error)
+	Connected() bool
+	// Flush - waits until published messages are received by the broker
+	Flush(timeout time.Duration) error
+	// MaxPayload - limit of size of one message
+	MaxPayload() int
+	Close() error
+}
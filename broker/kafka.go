@@ -0,0 +1,161 @@
+package broker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	kafkaProduceTimeout  = 10 * time.Second
+	kafkaPublishAttempts = 3
+)
+
+// KafkaOptions - brokers are addresses host:port used to discover the cluster
+type KafkaOptions struct {
+	Brokers    []string
+	ClientID   string
+	MaxPayload int
+}
+
+/*
+Kafka - subjects of the bomber are topics of Kafka, messages are produced into partitions in turn.
+Reply subject of a request is taken from header reply of the record. Subscribers of a group are members of consumer group
+with the same name, which share partitions of the topic and commit offsets of handled messages
+*/
+type Kafka struct {
+	options   KafkaOptions
+	producer  *kafkaClient
+	mutex     sync.Mutex
+	next      map[string]int
+	consumers []*kafkaConsumer
+	connected int32
+}
+
+// NewKafka - error if none of brokers is available
+func NewKafka(options KafkaOptions) (*Kafka, error) {
+	kafka := &Kafka{
+		options:  options,
+		producer: newKafkaClient(options.Brokers, options.ClientID),
+		next:     map[string]int{},
+	}
+	if err := kafka.producer.refresh(); err != nil {
+		return nil, err
+	}
+	kafka.setConnected(true)
+	logrus.Info("Connected to kafka: ", options.Brokers)
+	return kafka, nil
+}
+
+func (kafka *Kafka) setConnected(connected bool) {
+	var value int32
+	if connected {
+		value = 1
+	}
+	atomic.StoreInt32(&kafka.connected, value)
+}
+
+func (kafka *Kafka) Connected() bool {
+	return atomic.LoadInt32(&kafka.connected) == 1
+}
+
+// Publish - waits until record is written by all in-sync replicas of the partition
+func (kafka *Kafka) Publish(subject string, data []byte) error {
+	batch := encodeRecordBatch(data, nil, time.Now())
+	var err error
+	for attempt := 0; attempt < kafkaPublishAttempts; attempt++ {
+		if attempt > 0 {
+			kafka.producer.forget(subject)
+			time.Sleep(kafkaRetryBackoff)
+		}
+		if err = kafka.produce(subject, batch); err == nil {
+			kafka.setConnected(true)
+			return nil
+		}
+		if _, rejected := err.(KafkaError); !rejected {
+			kafka.setConnected(false)
+		}
+	}
+	return err
+}
+
+func (kafka *Kafka) produce(topic string, batch []byte) error {
+	partitions, err := kafka.producer.topicPartitions(topic)
+	if err != nil {
+		return err
+	}
+	kafka.mutex.Lock()
+	partition := partitions[kafka.next[topic]%len(partitions)]
+	kafka.next[topic]++
+	kafka.mutex.Unlock()
+	leader, err := kafka.producer.leader(topic, partition)
+	if err != nil {
+		return err
+	}
+	var body kafkaEncoder
+	body.nullString()
+	body.int16(-1)
+	body.int32(int32(kafkaProduceTimeout / time.Millisecond))
+	body.int32(1)
+	body.string(topic)
+	body.int32(1)
+	body.int32(partition)
+	body.bytes(batch)
+	response, err := kafka.producer.request(leader, kafkaProduce, 3, body.Bytes(), kafkaProduceTimeout)
+	if err != nil {
+		return err
+	}
+	errCode := kafkaNone
+	for topics := response.array(); topics > 0; topics-- {
+		response.string()
+		for partitions := response.array(); partitions > 0; partitions-- {
+			response.int32()
+			if code := response.int16(); code != kafkaNone {
+				errCode = code
+			}
+			response.int64()
+			response.int64()
+		}
+	}
+	if response.err != nil {
+		return response.err
+	}
+	return kafkaErr(errCode)
+}
+
+func (kafka *Kafka) Subscribe(subject string, group string, handler Handler) (Subscription, error) {
+	consumer := newKafkaConsumer(kafka, subject, group, handler)
+	if _, err := consumer.fetcher.topicPartitions(subject); err != nil {
+		return nil, err
+	}
+	kafka.mutex.Lock()
+	kafka.consumers = append(kafka.consumers, consumer)
+	kafka.mutex.Unlock()
+	go consumer.run()
+	logrus.Info("Completed subscription to kafka topic: ", subject, " group: ", group)
+	return consumer, nil
+}
+
+// Flush - records are written by Publish, nothing is left to flush
+func (kafka *Kafka) Flush(timeout time.Duration) error {
+	return nil
+}
+
+func (kafka *Kafka) MaxPayload() int {
+	return kafka.options.MaxPayload
+}
+
+func (kafka *Kafka) Close() error {
+	kafka.mutex.Lock()
+	consumers := kafka.consumers
+	kafka.consumers = nil
+	kafka.mutex.Unlock()
+	for _, consumer := range consumers {
+		consumer.Unsubscribe()
+	}
+	kafka.producer.close()
+	kafka.setConnected(false)
+	return nil
+}
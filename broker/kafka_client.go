@@ -0,0 +1,360 @@
+package broker
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	kafkaDialTimeout  = 10 * time.Second
+	kafkaRetryBackoff = time.Second
+)
+
+var errKafkaNoBrokers = errors.New("no broker of kafka is available")
+
+// kafkaConn - connection to one broker, requests of it are sent one by one
+type kafkaConn struct {
+	mutex       sync.Mutex
+	conn        net.Conn
+	reader      *bufio.Reader
+	clientID    string
+	correlation int32
+}
+
+func dialKafka(addr string, clientID string) (*kafkaConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, kafkaDialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaConn{conn: conn, reader: bufio.NewReader(conn), clientID: clientID}, nil
+}
+
+// request - body of the response after its correlation id, timeout is the time the broker may hold the request
+func (conn *kafkaConn) request(key int16, version int16, body []byte, timeout time.Duration) (*kafkaDecoder, error) {
+	conn.mutex.Lock()
+	defer conn.mutex.Unlock()
+	conn.correlation++
+	var request kafkaEncoder
+	request.int32(0)
+	request.int16(key)
+	request.int16(version)
+	request.int32(conn.correlation)
+	request.string(conn.clientID)
+	request.Write(body)
+	data := request.Bytes()
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+	conn.conn.SetDeadline(time.Now().Add(timeout + kafkaDialTimeout))
+	if _, err := conn.conn.Write(data); err != nil {
+		return nil, err
+	}
+	var size [4]byte
+	if _, err := io.ReadFull(conn.reader, size[:]); err != nil {
+		return nil, err
+	}
+	response := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(conn.reader, response); err != nil {
+		return nil, err
+	}
+	decoder := &kafkaDecoder{data: response}
+	if correlation := decoder.int32(); correlation != conn.correlation {
+		return nil, fmt.Errorf("unexpected correlation id %d of kafka response, expected %d", correlation, conn.correlation)
+	}
+	return decoder, nil
+}
+
+func (conn *kafkaConn) close() {
+	conn.conn.Close()
+}
+
+func joinHostPort(host string, port int32) string {
+	return net.JoinHostPort(host, strconv.Itoa(int(port)))
+}
+
+type kafkaPartition struct {
+	topic     string
+	partition int32
+}
+
+/*
+kafkaClient - connections to brokers of the cluster and leaders of partitions. Connections are not
+shared between clients, so long fetches of a consumer do not hold requests of others
+*/
+type kafkaClient struct {
+	mutex     sync.Mutex
+	bootstrap []string
+	clientID  string
+	brokers   map[int32]string
+	conns     map[int32]*kafkaConn
+	leaders   map[kafkaPartition]int32
+	// partitions of topics, in order of their indexes
+	partitions map[string][]int32
+}
+
+func newKafkaClient(bootstrap []string, clientID string) *kafkaClient {
+	return &kafkaClient{
+		bootstrap:  bootstrap,
+		clientID:   clientID,
+		brokers:    map[int32]string{},
+		conns:      map[int32]*kafkaConn{},
+		leaders:    map[kafkaPartition]int32{},
+		partitions: map[string][]int32{},
+	}
+}
+
+// refresh - metadata of topics is requested from the first available broker, brokers of the cluster are tried first
+func (client *kafkaClient) refresh(topics ...string) error {
+	var body kafkaEncoder
+	body.int32(int32(len(topics)))
+	for _, topic := range topics {
+		body.string(topic)
+	}
+	client.mutex.Lock()
+	addrs := make([]string, 0, len(client.brokers)+len(client.bootstrap))
+	for _, addr := range client.brokers {
+		addrs = append(addrs, addr)
+	}
+	client.mutex.Unlock()
+	addrs = append(addrs, client.bootstrap...)
+	err := errKafkaNoBrokers
+	for _, addr := range addrs {
+		var conn *kafkaConn
+		conn, err = dialKafka(addr, client.clientID)
+		if err != nil {
+			continue
+		}
+		var response *kafkaDecoder
+		response, err = conn.request(kafkaMetadata, 1, body.Bytes(), 0)
+		conn.close()
+		if err != nil {
+			continue
+		}
+		return client.applyMetadata(response)
+	}
+	return err
+}
+
+func (client *kafkaClient) applyMetadata(response *kafkaDecoder) error {
+	brokers := map[int32]string{}
+	for amount := response.array(); amount > 0; amount-- {
+		node := response.int32()
+		host := response.string()
+		port := response.int32()
+		response.string()
+		brokers[node] = joinHostPort(host, port)
+	}
+	response.int32()
+	type topicMetadata struct {
+		name       string
+		err        error
+		partitions []int32
+		leaders    []int32
+	}
+	var topics []topicMetadata
+	for amount := response.array(); amount > 0; amount-- {
+		topic := topicMetadata{err: kafkaErr(response.int16()), name: response.string()}
+		response.int8()
+		for partitions := response.array(); partitions > 0; partitions-- {
+			partitionErr := response.int16()
+			partition := response.int32()
+			leader := response.int32()
+			for replicas := response.array(); replicas > 0; replicas-- {
+				response.int32()
+			}
+			for isr := response.array(); isr > 0; isr-- {
+				response.int32()
+			}
+			if partitionErr == kafkaLeaderNotAvailable {
+				leader = -1
+			}
+			topic.partitions = append(topic.partitions, partition)
+			topic.leaders = append(topic.leaders, leader)
+		}
+		topics = append(topics, topic)
+	}
+	if response.err != nil {
+		return response.err
+	}
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	for node, addr := range brokers {
+		if known, ok := client.brokers[node]; ok && known != addr {
+			client.dropLocked(node)
+		}
+		client.brokers[node] = addr
+	}
+	var err error
+	for _, topic := range topics {
+		if topic.err != nil {
+			err = fmt.Errorf("can not get metadata of kafka topic %s: %w", topic.name, topic.err)
+			continue
+		}
+		sorted := make([]int32, len(topic.partitions))
+		for index, partition := range topic.partitions {
+			client.leaders[kafkaPartition{topic: topic.name, partition: partition}] = topic.leaders[index]
+			sorted[index] = partition
+		}
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		client.partitions[topic.name] = sorted
+	}
+	return err
+}
+
+// topicPartitions - partitions of the topic, metadata of it is requested if it is not known
+func (client *kafkaClient) topicPartitions(topic string) ([]int32, error) {
+	client.mutex.Lock()
+	partitions, ok := client.partitions[topic]
+	client.mutex.Unlock()
+	if ok && len(partitions) > 0 {
+		return partitions, nil
+	}
+	if err := client.refresh(topic); err != nil {
+		return nil, err
+	}
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if len(client.partitions[topic]) == 0 {
+		return nil, fmt.Errorf("kafka topic %s has no partitions", topic)
+	}
+	return client.partitions[topic], nil
+}
+
+func (client *kafkaClient) leader(topic string, partition int32) (int32, error) {
+	key := kafkaPartition{topic: topic, partition: partition}
+	client.mutex.Lock()
+	leader, ok := client.leaders[key]
+	client.mutex.Unlock()
+	if !ok || leader < 0 {
+		if err := client.refresh(topic); err != nil {
+			return 0, err
+		}
+		client.mutex.Lock()
+		leader, ok = client.leaders[key]
+		client.mutex.Unlock()
+	}
+	if !ok || leader < 0 {
+		return 0, fmt.Errorf("partition %d of kafka topic %s has no leader", partition, topic)
+	}
+	return leader, nil
+}
+
+// conn - connection to the broker of the node, it is dialed on first use
+func (client *kafkaClient) conn(node int32) (*kafkaConn, error) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	if conn, ok := client.conns[node]; ok {
+		return conn, nil
+	}
+	addr, ok := client.brokers[node]
+	if !ok {
+		return nil, fmt.Errorf("unknown broker %d of kafka", node)
+	}
+	conn, err := dialKafka(addr, client.clientID)
+	if err != nil {
+		return nil, err
+	}
+	client.conns[node] = conn
+	return conn, nil
+}
+
+func (client *kafkaClient) connAddr(node int32, addr string) (*kafkaConn, error) {
+	client.mutex.Lock()
+	if known, ok := client.brokers[node]; !ok || known != addr {
+		client.dropLocked(node)
+		client.brokers[node] = addr
+	}
+	client.mutex.Unlock()
+	return client.conn(node)
+}
+
+// drop - connection is closed after failed request, it is dialed again by next one
+func (client *kafkaClient) drop(node int32) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	client.dropLocked(node)
+}
+
+func (client *kafkaClient) dropLocked(node int32) {
+	if conn, ok := client.conns[node]; ok {
+		conn.close()
+		delete(client.conns, node)
+	}
+}
+
+// forget - leaders of the topic are requested again by next request
+func (client *kafkaClient) forget(topic string) {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	delete(client.partitions, topic)
+	for key := range client.leaders {
+		if key.topic == topic {
+			delete(client.leaders, key)
+		}
+	}
+}
+
+func (client *kafkaClient) request(node int32, key int16, version int16, body []byte, timeout time.Duration) (*kafkaDecoder, error) {
+	conn, err := client.conn(node)
+	if err != nil {
+		return nil, err
+	}
+	response, err := conn.request(key, version, body, timeout)
+	if err != nil {
+		client.drop(node)
+		return nil, err
+	}
+	return response, nil
+}
+
+func (client *kafkaClient) close() {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	for node := range client.conns {
+		client.dropLocked(node)
+	}
+}
+
+// listOffset - offset of the next message of the partition
+func (client *kafkaClient) listOffset(topic string, partition int32) (int64, error) {
+	leader, err := client.leader(topic, partition)
+	if err != nil {
+		return 0, err
+	}
+	var body kafkaEncoder
+	body.int32(-1)
+	body.int32(1)
+	body.string(topic)
+	body.int32(1)
+	body.int32(partition)
+	body.int64(kafkaLatestOffset)
+	response, err := client.request(leader, kafkaListOffsets, 1, body.Bytes(), 0)
+	if err != nil {
+		return 0, err
+	}
+	var offset int64
+	errCode := kafkaNone
+	for topics := response.array(); topics > 0; topics-- {
+		response.string()
+		for partitions := response.array(); partitions > 0; partitions-- {
+			response.int32()
+			errCode = response.int16()
+			response.int64()
+			offset = response.int64()
+		}
+	}
+	if response.err != nil {
+		return 0, response.err
+	}
+	if errCode != kafkaNone {
+		client.forget(topic)
+		return 0, kafkaErr(errCode)
+	}
+	return offset, nil
+}
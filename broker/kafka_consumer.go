@@ -0,0 +1,586 @@
+package broker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	kafkaFetchWait         = 500 * time.Millisecond
+	kafkaFetchMaxBytes     = 16 << 20
+	kafkaPartitionMaxBytes = 1 << 20
+	kafkaSessionTimeout    = 10 * time.Second
+	kafkaRebalanceTimeout  = 30 * time.Second
+	kafkaHeartbeatInterval = 3 * time.Second
+	kafkaProtocolType      = "consumer"
+	kafkaAssignor          = "roundrobin"
+)
+
+/*
+kafkaConsumer - subscription to a topic. Consumer without group reads all partitions from their end.
+Consumer of a group gets partitions assigned by the leader of the group and starts from committed offsets,
+offsets are committed after messages are handled, so a message can be delivered again after rebalance
+*/
+type kafkaConsumer struct {
+	kafka   *Kafka
+	topic   string
+	group   string
+	handler Handler
+	fetcher *kafkaClient
+	// coordinator of the group, heartbeats are sent while fetcher waits for messages
+	coordinator     *kafkaClient
+	coordinatorNode int32
+	memberID        string
+	generation      int32
+	offsets         map[int32]int64
+	committed       map[int32]int64
+	stop            chan struct{}
+	done            chan struct{}
+	once            sync.Once
+}
+
+func newKafkaConsumer(kafka *Kafka, topic string, group string, handler Handler) *kafkaConsumer {
+	return &kafkaConsumer{
+		kafka:       kafka,
+		topic:       topic,
+		group:       group,
+		handler:     handler,
+		fetcher:     newKafkaClient(kafka.options.Brokers, kafka.options.ClientID),
+		coordinator: newKafkaClient(kafka.options.Brokers, kafka.options.ClientID),
+		// coordinator is found and the group is joined by the first assignment
+		coordinatorNode: -1,
+		generation:      -1,
+		stop:            make(chan struct{}),
+		done:            make(chan struct{}),
+	}
+}
+
+// Unsubscribe - offsets of handled messages are committed and the consumer leaves its group
+func (consumer *kafkaConsumer) Unsubscribe() error {
+	consumer.once.Do(func() {
+		close(consumer.stop)
+		<-consumer.done
+	})
+	return nil
+}
+
+func (consumer *kafkaConsumer) stopped() bool {
+	select {
+	case <-consumer.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// pause - false if consumer was stopped during the pause
+func (consumer *kafkaConsumer) pause() bool {
+	select {
+	case <-consumer.stop:
+		return false
+	case <-time.After(kafkaRetryBackoff):
+		return true
+	}
+}
+
+func (consumer *kafkaConsumer) run() {
+	defer close(consumer.done)
+	defer consumer.fetcher.close()
+	defer consumer.coordinator.close()
+	for !consumer.stopped() {
+		partitions, err := consumer.assign()
+		if err != nil {
+			logrus.Error("Can not get partitions of kafka topic ", consumer.topic, ": ", err)
+			consumer.kafka.setConnected(false)
+			consumer.fetcher.forget(consumer.topic)
+			if !consumer.pause() {
+				return
+			}
+			continue
+		}
+		consumer.consume(partitions)
+	}
+	if consumer.group != "" {
+		consumer.commit()
+		consumer.leave()
+	}
+}
+
+// consume - fetches messages of partitions until the consumer is stopped or its group is rebalanced
+func (consumer *kafkaConsumer) consume(partitions []int32) {
+	rebalance := make(chan struct{})
+	stopHeartbeat := make(chan struct{})
+	defer close(stopHeartbeat)
+	if consumer.group != "" {
+		go consumer.heartbeat(consumer.coordinatorNode, rebalance, stopHeartbeat)
+	}
+	for !consumer.stopped() {
+		select {
+		case <-rebalance:
+			consumer.commit()
+			return
+		default:
+		}
+		if len(partitions) == 0 {
+			// member without partitions waits for rebalance, when other members leave the group
+			select {
+			case <-rebalance:
+				return
+			case <-consumer.stop:
+				return
+			}
+		}
+		if err := consumer.fetch(partitions); err != nil {
+			logrus.Error("Can not fetch messages of kafka topic ", consumer.topic, ": ", err)
+			consumer.kafka.setConnected(false)
+			consumer.fetcher.forget(consumer.topic)
+			if !consumer.pause() {
+				return
+			}
+			continue
+		}
+		consumer.kafka.setConnected(true)
+		if consumer.group != "" {
+			consumer.commit()
+		}
+	}
+}
+
+// assign - partitions of the topic and offsets to start from, group is joined again for consumer of group
+func (consumer *kafkaConsumer) assign() ([]int32, error) {
+	consumer.offsets = map[int32]int64{}
+	consumer.committed = map[int32]int64{}
+	var partitions []int32
+	var err error
+	if consumer.group == "" {
+		partitions, err = consumer.fetcher.topicPartitions(consumer.topic)
+	} else {
+		partitions, err = consumer.join()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if consumer.group != "" {
+		if err := consumer.fetchCommitted(partitions); err != nil {
+			return nil, err
+		}
+	}
+	for _, partition := range partitions {
+		if _, ok := consumer.offsets[partition]; ok {
+			continue
+		}
+		offset, err := consumer.fetcher.listOffset(consumer.topic, partition)
+		if err != nil {
+			return nil, err
+		}
+		consumer.offsets[partition] = offset
+	}
+	return partitions, nil
+}
+
+// fetch - one fetch request for partitions of each leader, messages are handled in order of partitions
+func (consumer *kafkaConsumer) fetch(partitions []int32) error {
+	byLeader := map[int32][]int32{}
+	var leaders []int32
+	for _, partition := range partitions {
+		leader, err := consumer.fetcher.leader(consumer.topic, partition)
+		if err != nil {
+			return err
+		}
+		if _, ok := byLeader[leader]; !ok {
+			leaders = append(leaders, leader)
+		}
+		byLeader[leader] = append(byLeader[leader], partition)
+	}
+	sort.Slice(leaders, func(i, j int) bool { return leaders[i] < leaders[j] })
+	for _, leader := range leaders {
+		if err := consumer.fetchLeader(leader, byLeader[leader]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (consumer *kafkaConsumer) fetchLeader(leader int32, partitions []int32) error {
+	var body kafkaEncoder
+	body.int32(-1)
+	body.int32(int32(kafkaFetchWait / time.Millisecond))
+	body.int32(1)
+	body.int32(kafkaFetchMaxBytes)
+	body.int8(0)
+	body.int32(1)
+	body.string(consumer.topic)
+	body.int32(int32(len(partitions)))
+	for _, partition := range partitions {
+		body.int32(partition)
+		body.int64(consumer.offsets[partition])
+		body.int32(kafkaPartitionMaxBytes)
+	}
+	response, err := consumer.fetcher.request(leader, kafkaFetch, 4, body.Bytes(), kafkaFetchWait)
+	if err != nil {
+		return err
+	}
+	response.int32()
+	type fetched struct {
+		partition int32
+		errCode   int16
+		records   []byte
+	}
+	var results []fetched
+	for topics := response.array(); topics > 0; topics-- {
+		response.string()
+		for amount := response.array(); amount > 0; amount-- {
+			result := fetched{partition: response.int32(), errCode: response.int16()}
+			response.int64()
+			response.int64()
+			for aborted := response.array(); aborted > 0; aborted-- {
+				response.int64()
+				response.int64()
+			}
+			result.records = response.bytes()
+			results = append(results, result)
+		}
+	}
+	if response.err != nil {
+		return response.err
+	}
+	for _, result := range results {
+		switch result.errCode {
+		case kafkaNone:
+		case kafkaOffsetOutOfRange:
+			offset, err := consumer.fetcher.listOffset(consumer.topic, result.partition)
+			if err != nil {
+				return err
+			}
+			logrus.Error("Offset of partition ", result.partition, " of kafka topic ", consumer.topic, " is out of range, consuming from ", offset)
+			consumer.offsets[result.partition] = offset
+			continue
+		default:
+			return kafkaErr(result.errCode)
+		}
+		consumer.deliver(result.partition, result.records)
+	}
+	return nil
+}
+
+func (consumer *kafkaConsumer) deliver(partition int32, data []byte) {
+	records, next, err := decodeRecordBatches(data, consumer.offsets[partition])
+	for _, record := range records {
+		if consumer.stopped() {
+			return
+		}
+		consumer.handler(&Message{Subject: consumer.topic, Data: record.value, Reply: record.headers[kafkaReplyHeader]})
+		consumer.offsets[partition] = record.offset + 1
+	}
+	if err != nil {
+		logrus.Error("Can not decode records of partition ", partition, " of kafka topic ", consumer.topic, ", they are skipped: ", err)
+	}
+	if next > consumer.offsets[partition] {
+		consumer.offsets[partition] = next
+	}
+}
+
+// coordinatorRequest - coordinator of the group is found again after its failure
+func (consumer *kafkaConsumer) coordinatorRequest(key int16, version int16, body []byte, timeout time.Duration) (*kafkaDecoder, error) {
+	if consumer.coordinatorNode < 0 {
+		if err := consumer.findCoordinator(); err != nil {
+			return nil, err
+		}
+	}
+	response, err := consumer.coordinator.request(consumer.coordinatorNode, key, version, body, timeout)
+	if err != nil {
+		consumer.coordinatorNode = -1
+	}
+	return response, err
+}
+
+func (consumer *kafkaConsumer) findCoordinator() error {
+	var body kafkaEncoder
+	body.string(consumer.group)
+	if err := consumer.coordinator.refresh(); err != nil {
+		return err
+	}
+	consumer.coordinator.mutex.Lock()
+	var node int32 = -1
+	for known := range consumer.coordinator.brokers {
+		node = known
+		break
+	}
+	consumer.coordinator.mutex.Unlock()
+	response, err := consumer.coordinator.request(node, kafkaFindCoordinator, 0, body.Bytes(), 0)
+	if err != nil {
+		return err
+	}
+	errCode := response.int16()
+	coordinator := response.int32()
+	host := response.string()
+	port := response.int32()
+	if response.err != nil {
+		return response.err
+	}
+	if errCode != kafkaNone {
+		return kafkaErr(errCode)
+	}
+	if _, err := consumer.coordinator.connAddr(coordinator, joinHostPort(host, port)); err != nil {
+		return err
+	}
+	consumer.coordinatorNode = coordinator
+	return nil
+}
+
+/*
+join - joins the group and gets partitions of the member. Leader of the group assigns partitions of
+the topic to members in turn, members are ordered by their ids
+*/
+func (consumer *kafkaConsumer) join() ([]int32, error) {
+	var metadata kafkaEncoder
+	metadata.int16(0)
+	metadata.int32(1)
+	metadata.string(consumer.topic)
+	metadata.bytes(nil)
+	var body kafkaEncoder
+	body.string(consumer.group)
+	body.int32(int32(kafkaSessionTimeout / time.Millisecond))
+	body.int32(int32(kafkaRebalanceTimeout / time.Millisecond))
+	body.string(consumer.memberID)
+	body.string(kafkaProtocolType)
+	body.int32(1)
+	body.string(kafkaAssignor)
+	body.bytes(metadata.Bytes())
+	response, err := consumer.coordinatorRequest(kafkaJoinGroup, 2, body.Bytes(), kafkaRebalanceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	response.int32()
+	errCode := response.int16()
+	generation := response.int32()
+	response.string()
+	leader := response.string()
+	memberID := response.string()
+	var members []string
+	for amount := response.array(); amount > 0; amount-- {
+		members = append(members, response.string())
+		response.bytes()
+	}
+	if response.err != nil {
+		return nil, response.err
+	}
+	if err := consumer.groupError(errCode); err != nil {
+		return nil, err
+	}
+	consumer.generation = generation
+	consumer.memberID = memberID
+	var assignments map[string][]int32
+	if leader == memberID {
+		partitions, err := consumer.fetcher.topicPartitions(consumer.topic)
+		if err != nil {
+			return nil, err
+		}
+		assignments = assignPartitions(members, partitions)
+	}
+	return consumer.sync(assignments)
+}
+
+func assignPartitions(members []string, partitions []int32) map[string][]int32 {
+	sort.Strings(members)
+	assignments := map[string][]int32{}
+	for _, member := range members {
+		assignments[member] = []int32{}
+	}
+	for index, partition := range partitions {
+		member := members[index%len(members)]
+		assignments[member] = append(assignments[member], partition)
+	}
+	return assignments
+}
+
+func (consumer *kafkaConsumer) sync(assignments map[string][]int32) ([]int32, error) {
+	var body kafkaEncoder
+	body.string(consumer.group)
+	body.int32(consumer.generation)
+	body.string(consumer.memberID)
+	body.int32(int32(len(assignments)))
+	for member, partitions := range assignments {
+		var assignment kafkaEncoder
+		assignment.int16(0)
+		assignment.int32(1)
+		assignment.string(consumer.topic)
+		assignment.int32(int32(len(partitions)))
+		for _, partition := range partitions {
+			assignment.int32(partition)
+		}
+		assignment.bytes(nil)
+		body.string(member)
+		body.bytes(assignment.Bytes())
+	}
+	response, err := consumer.coordinatorRequest(kafkaSyncGroup, 0, body.Bytes(), kafkaRebalanceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	errCode := response.int16()
+	assignment := kafkaDecoder{data: response.bytes()}
+	if response.err != nil {
+		return nil, response.err
+	}
+	if err := consumer.groupError(errCode); err != nil {
+		return nil, err
+	}
+	var partitions []int32
+	if len(assignment.data) == 0 {
+		return partitions, nil
+	}
+	assignment.int16()
+	for topics := assignment.array(); topics > 0; topics-- {
+		topic := assignment.string()
+		for amount := assignment.array(); amount > 0; amount-- {
+			partition := assignment.int32()
+			if topic == consumer.topic {
+				partitions = append(partitions, partition)
+			}
+		}
+	}
+	if assignment.err != nil {
+		return nil, assignment.err
+	}
+	logrus.Info("Partitions ", partitions, " of kafka topic ", consumer.topic, " are assigned to member ", consumer.memberID, " of group ", consumer.group)
+	return partitions, nil
+}
+
+// groupError - membership is reset by errors of generation, coordinator is found again by errors of coordinator
+func (consumer *kafkaConsumer) groupError(errCode int16) error {
+	switch errCode {
+	case kafkaNone:
+		return nil
+	case kafkaUnknownMember, kafkaIllegalGeneration:
+		consumer.memberID = ""
+		consumer.generation = -1
+	case kafkaNotCoordinator, kafkaCoordinatorNotAvailable, kafkaCoordinatorLoading:
+		consumer.coordinatorNode = -1
+	}
+	return kafkaErr(errCode)
+}
+
+func (consumer *kafkaConsumer) fetchCommitted(partitions []int32) error {
+	var body kafkaEncoder
+	body.string(consumer.group)
+	body.int32(1)
+	body.string(consumer.topic)
+	body.int32(int32(len(partitions)))
+	for _, partition := range partitions {
+		body.int32(partition)
+	}
+	response, err := consumer.coordinatorRequest(kafkaOffsetFetch, 1, body.Bytes(), 0)
+	if err != nil {
+		return err
+	}
+	for topics := response.array(); topics > 0; topics-- {
+		response.string()
+		for amount := response.array(); amount > 0; amount-- {
+			partition := response.int32()
+			offset := response.int64()
+			response.string()
+			errCode := response.int16()
+			if errCode != kafkaNone {
+				return consumer.groupError(errCode)
+			}
+			// partition without committed offset is consumed from its end, as NATS delivers only new messages
+			if offset >= 0 {
+				consumer.offsets[partition] = offset
+				consumer.committed[partition] = offset
+			}
+		}
+	}
+	return response.err
+}
+
+// commit - offsets which changed since the last commit, failure is logged and offsets are committed by next one
+func (consumer *kafkaConsumer) commit() {
+	changed := map[int32]int64{}
+	for partition, offset := range consumer.offsets {
+		if committed, ok := consumer.committed[partition]; !ok || committed != offset {
+			changed[partition] = offset
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	var body kafkaEncoder
+	body.string(consumer.group)
+	body.int32(consumer.generation)
+	body.string(consumer.memberID)
+	body.int64(-1)
+	body.int32(1)
+	body.string(consumer.topic)
+	body.int32(int32(len(changed)))
+	for partition, offset := range changed {
+		body.int32(partition)
+		body.int64(offset)
+		body.nullString()
+	}
+	response, err := consumer.coordinatorRequest(kafkaOffsetCommit, 2, body.Bytes(), 0)
+	if err != nil {
+		logrus.Error("Can not commit offsets of kafka topic ", consumer.topic, ": ", err)
+		return
+	}
+	for topics := response.array(); topics > 0; topics-- {
+		response.string()
+		for amount := response.array(); amount > 0; amount-- {
+			partition := response.int32()
+			if errCode := response.int16(); errCode != kafkaNone {
+				logrus.Error("Can not commit offset of partition ", partition, " of kafka topic ", consumer.topic, ": ", consumer.groupError(errCode))
+				continue
+			}
+			consumer.committed[partition] = changed[partition]
+		}
+	}
+}
+
+/*
+heartbeat - keeps membership of the consumer in its group, rebalance is signaled when the group is
+rebalanced or the member is not known by coordinator anymore
+*/
+func (consumer *kafkaConsumer) heartbeat(coordinator int32, rebalance chan struct{}, stop chan struct{}) {
+	var body kafkaEncoder
+	body.string(consumer.group)
+	body.int32(consumer.generation)
+	body.string(consumer.memberID)
+	ticker := time.NewTicker(kafkaHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		response, err := consumer.coordinator.request(coordinator, kafkaHeartbeat, 0, body.Bytes(), 0)
+		if err != nil {
+			logrus.Error("Can not send heartbeat to coordinator of kafka group ", consumer.group, ": ", err)
+			close(rebalance)
+			return
+		}
+		errCode := response.int16()
+		if response.err == nil && errCode == kafkaNone {
+			continue
+		}
+		if errCode != kafkaRebalanceInProgress {
+			logrus.Error("Membership in kafka group ", consumer.group, " is lost: ", kafkaErr(errCode))
+		}
+		close(rebalance)
+		return
+	}
+}
+
+func (consumer *kafkaConsumer) leave() {
+	if consumer.memberID == "" {
+		return
+	}
+	var body kafkaEncoder
+	body.string(consumer.group)
+	body.string(consumer.memberID)
+	if _, err := consumer.coordinatorRequest(kafkaLeaveGroup, 0, body.Bytes(), 0); err != nil {
+		logrus.Error("Can not leave kafka group ", consumer.group, ": ", err)
+	}
+}
@@ -0,0 +1,369 @@
+package broker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io/ioutil"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// keys and versions of requests of Kafka protocol, versions are the oldest ones supported by Kafka 4
+const (
+	kafkaProduce         int16 = 0
+	kafkaFetch           int16 = 1
+	kafkaListOffsets     int16 = 2
+	kafkaMetadata        int16 = 3
+	kafkaOffsetCommit    int16 = 8
+	kafkaOffsetFetch     int16 = 9
+	kafkaFindCoordinator int16 = 10
+	kafkaJoinGroup       int16 = 11
+	kafkaHeartbeat       int16 = 12
+	kafkaLeaveGroup      int16 = 13
+	kafkaSyncGroup       int16 = 14
+)
+
+// error codes of Kafka which change behaviour of the client
+const (
+	kafkaNone                    int16 = 0
+	kafkaOffsetOutOfRange        int16 = 1
+	kafkaLeaderNotAvailable      int16 = 5
+	kafkaCoordinatorLoading      int16 = 14
+	kafkaCoordinatorNotAvailable int16 = 15
+	kafkaNotCoordinator          int16 = 16
+	kafkaIllegalGeneration       int16 = 22
+	kafkaUnknownMember           int16 = 25
+	kafkaRebalanceInProgress     int16 = 27
+)
+
+const (
+	kafkaLatestOffset        int64 = -1
+	kafkaReplyHeader               = "reply"
+	kafkaCompression               = 0x07
+	kafkaControlBatch              = 0x20
+	kafkaRecordBatchV2             = 2
+	kafkaBatchHeaderSize           = 61
+	kafkaBatchLengthPosition       = 8
+	kafkaBatchCrcPosition          = 17
+)
+
+const (
+	kafkaCodecNone = iota
+	kafkaCodecGzip
+	kafkaCodecSnappy
+	kafkaCodecLz4
+	kafkaCodecZstd
+)
+
+var (
+	errKafkaShortResponse = errors.New("response of kafka is truncated")
+	errKafkaCorrupted     = errors.New("record batch of kafka is corrupted")
+	castagnoli            = crc32.MakeTable(crc32.Castagnoli)
+	zstdDecoder, _        = zstd.NewReader(nil)
+)
+
+// KafkaError - error code of kafka answer
+type KafkaError int16
+
+func (code KafkaError) Error() string {
+	return fmt.Sprint("kafka error code ", int16(code))
+}
+
+// kafkaErr - nil for code of success
+func kafkaErr(code int16) error {
+	if code == kafkaNone {
+		return nil
+	}
+	return KafkaError(code)
+}
+
+type kafkaEncoder struct {
+	bytes.Buffer
+}
+
+func (encoder *kafkaEncoder) int8(value int8) {
+	encoder.WriteByte(byte(value))
+}
+
+func (encoder *kafkaEncoder) int16(value int16) {
+	var data [2]byte
+	binary.BigEndian.PutUint16(data[:], uint16(value))
+	encoder.Write(data[:])
+}
+
+func (encoder *kafkaEncoder) int32(value int32) {
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], uint32(value))
+	encoder.Write(data[:])
+}
+
+func (encoder *kafkaEncoder) int64(value int64) {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], uint64(value))
+	encoder.Write(data[:])
+}
+
+func (encoder *kafkaEncoder) string(value string) {
+	encoder.int16(int16(len(value)))
+	encoder.WriteString(value)
+}
+
+func (encoder *kafkaEncoder) nullString() {
+	encoder.int16(-1)
+}
+
+func (encoder *kafkaEncoder) bytes(value []byte) {
+	if value == nil {
+		encoder.int32(-1)
+		return
+	}
+	encoder.int32(int32(len(value)))
+	encoder.Write(value)
+}
+
+func (encoder *kafkaEncoder) varint(value int64) {
+	var data [binary.MaxVarintLen64]byte
+	encoder.Write(data[:binary.PutVarint(data[:], value)])
+}
+
+func (encoder *kafkaEncoder) varbytes(value []byte) {
+	if value == nil {
+		encoder.varint(-1)
+		return
+	}
+	encoder.varint(int64(len(value)))
+	encoder.Write(value)
+}
+
+// kafkaDecoder - errors are sticky, values after the first error are zero
+type kafkaDecoder struct {
+	data []byte
+	err  error
+}
+
+func (decoder *kafkaDecoder) take(size int) []byte {
+	if decoder.err != nil {
+		return nil
+	}
+	if size < 0 || size > len(decoder.data) {
+		decoder.err = errKafkaShortResponse
+		return nil
+	}
+	taken := decoder.data[:size]
+	decoder.data = decoder.data[size:]
+	return taken
+}
+
+func (decoder *kafkaDecoder) int8() int8 {
+	data := decoder.take(1)
+	if data == nil {
+		return 0
+	}
+	return int8(data[0])
+}
+
+func (decoder *kafkaDecoder) int16() int16 {
+	data := decoder.take(2)
+	if data == nil {
+		return 0
+	}
+	return int16(binary.BigEndian.Uint16(data))
+}
+
+func (decoder *kafkaDecoder) int32() int32 {
+	data := decoder.take(4)
+	if data == nil {
+		return 0
+	}
+	return int32(binary.BigEndian.Uint32(data))
+}
+
+func (decoder *kafkaDecoder) int64() int64 {
+	data := decoder.take(8)
+	if data == nil {
+		return 0
+	}
+	return int64(binary.BigEndian.Uint64(data))
+}
+
+func (decoder *kafkaDecoder) string() string {
+	size := decoder.int16()
+	if size < 0 {
+		return ""
+	}
+	return string(decoder.take(int(size)))
+}
+
+func (decoder *kafkaDecoder) bytes() []byte {
+	size := decoder.int32()
+	if size < 0 {
+		return nil
+	}
+	return decoder.take(int(size))
+}
+
+// array - length of array, null array is empty
+func (decoder *kafkaDecoder) array() int {
+	size := decoder.int32()
+	if size < 0 || decoder.err != nil {
+		return 0
+	}
+	// each element takes at least one byte, so bigger length is surely corrupted
+	if int(size) > len(decoder.data) {
+		decoder.err = errKafkaShortResponse
+		return 0
+	}
+	return int(size)
+}
+
+func (decoder *kafkaDecoder) varint() int64 {
+	if decoder.err != nil {
+		return 0
+	}
+	value, size := binary.Varint(decoder.data)
+	if size <= 0 {
+		decoder.err = errKafkaShortResponse
+		return 0
+	}
+	decoder.data = decoder.data[size:]
+	return value
+}
+
+func (decoder *kafkaDecoder) varbytes() []byte {
+	size := decoder.varint()
+	if size < 0 {
+		return nil
+	}
+	return decoder.take(int(size))
+}
+
+// kafkaRecord - record of a batch, offset is absolute
+type kafkaRecord struct {
+	offset  int64
+	value   []byte
+	headers map[string]string
+}
+
+// encodeRecordBatch - uncompressed batch of record format v2 with one record
+func encodeRecordBatch(value []byte, headers map[string]string, moment time.Time) []byte {
+	var record kafkaEncoder
+	record.int8(0)
+	record.varint(0)
+	record.varint(0)
+	record.varbytes(nil)
+	record.varbytes(value)
+	record.varint(int64(len(headers)))
+	for key, header := range headers {
+		record.varbytes([]byte(key))
+		record.varbytes([]byte(header))
+	}
+	var batch kafkaEncoder
+	timestamp := moment.UnixNano() / int64(time.Millisecond)
+	batch.int64(0)
+	batch.int32(0)
+	batch.int32(-1)
+	batch.int8(kafkaRecordBatchV2)
+	batch.int32(0)
+	batch.int16(0)
+	batch.int32(0)
+	batch.int64(timestamp)
+	batch.int64(timestamp)
+	batch.int64(-1)
+	batch.int16(-1)
+	batch.int32(-1)
+	batch.int32(1)
+	batch.varint(int64(record.Len()))
+	batch.Write(record.Bytes())
+	data := batch.Bytes()
+	binary.BigEndian.PutUint32(data[kafkaBatchLengthPosition:], uint32(len(data)-kafkaBatchLengthPosition-4))
+	binary.BigEndian.PutUint32(data[kafkaBatchCrcPosition:], crc32.Checksum(data[kafkaBatchCrcPosition+4:], castagnoli))
+	return data
+}
+
+/*
+decodeRecordBatches - records of fetched batches from the offset. Last batch can be truncated by size limit
+of the fetch, it is fetched again then. Batches of legacy formats and control batches are skipped
+*/
+func decodeRecordBatches(data []byte, from int64) ([]kafkaRecord, int64, error) {
+	var records []kafkaRecord
+	next := from
+	for len(data) >= kafkaBatchHeaderSize {
+		baseOffset := int64(binary.BigEndian.Uint64(data))
+		length := int(binary.BigEndian.Uint32(data[kafkaBatchLengthPosition:]))
+		if length+12 > len(data) {
+			break
+		}
+		batch := data[:length+12]
+		data = data[length+12:]
+		if batch[16] != kafkaRecordBatchV2 {
+			continue
+		}
+		if crc32.Checksum(batch[kafkaBatchCrcPosition+4:], castagnoli) != binary.BigEndian.Uint32(batch[kafkaBatchCrcPosition:]) {
+			return records, next, errKafkaCorrupted
+		}
+		header := kafkaDecoder{data: batch[kafkaBatchCrcPosition+4:]}
+		attributes := header.int16()
+		lastOffsetDelta := header.int32()
+		header.take(8 + 8 + 8 + 2 + 4)
+		count := header.int32()
+		if header.err != nil {
+			return records, next, header.err
+		}
+		if end := baseOffset + int64(lastOffsetDelta) + 1; end > next {
+			next = end
+		}
+		if attributes&kafkaControlBatch != 0 {
+			continue
+		}
+		body, err := decompress(attributes&kafkaCompression, header.data)
+		if err != nil {
+			return records, next, err
+		}
+		decoder := kafkaDecoder{data: body}
+		for index := int32(0); index < count && decoder.err == nil; index++ {
+			record := kafkaDecoder{data: decoder.varbytes()}
+			record.int8()
+			record.varint()
+			offset := baseOffset + record.varint()
+			record.varbytes()
+			value := record.varbytes()
+			headers := map[string]string{}
+			for amount := record.varint(); amount > 0 && record.err == nil; amount-- {
+				key := record.varbytes()
+				headers[string(key)] = string(record.varbytes())
+			}
+			if record.err != nil {
+				return records, next, record.err
+			}
+			if offset >= from {
+				records = append(records, kafkaRecord{offset: offset, value: value, headers: headers})
+			}
+		}
+		if decoder.err != nil {
+			return records, next, decoder.err
+		}
+	}
+	return records, next, nil
+}
+
+func decompress(codec int16, data []byte) ([]byte, error) {
+	switch codec {
+	case kafkaCodecNone:
+		return data, nil
+	case kafkaCodecGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return ioutil.ReadAll(reader)
+	case kafkaCodecZstd:
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	return nil, fmt.Errorf("compression codec %d of kafka is not supported, expected none, gzip or zstd", codec)
+}
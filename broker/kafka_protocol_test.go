@@ -0,0 +1,209 @@
+package broker
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+/*
+Frames below are written by the Kafka protocol guide byte by byte (record batch v2, metadata v1),
+not by the encoder under test, so both sides of the wire are checked against the spec
+*/
+
+// record batch of one record "hi" with header reply: r, timestamp 1700000000000, producers send leader epoch -1
+const kafkaFrameEncoded = "000000000000000000000042ffffffff023a89d2270000000000000000018bcfe568000000018bcfe568" +
+	"00ffffffffffffffffffffffffffff000000012000000001046869020a7265706c790272"
+
+var kafkaFrameFetch = []string{
+	// base offset 10, records "a" and "b" with header k: v
+	"000000000000000a0000004500000000028dba0b3a0000000000010000018bcfe568000000018bcfe56800ffffffff" +
+		"ffffffffffffffffffff000000020e000000010261001600000201026202026b0276",
+	// base offset 12, gzip compressed records "c" and "d"
+	"000000000000000c000000550000000002aed70adc0001000000010000018bcfe568000000018bcfe56800ffffffff" +
+		"ffffffffffffffffffff000000021f8b0800000000000203e363606060644a66e063606062644a610000d9abc3e010000000",
+	// base offset 14, control batch of transaction marker
+	"000000000000000e0000003b0000000002a17012ce0020000000000000018bcfe568000000018bcfe56800ffffffff" +
+		"ffffffffffffffffffff0000000112000000010663746c00",
+	// base offset 15, message set of legacy format
+	"000000000000000f0000000e0000000001000000000000000000",
+	// base offset 16, batch truncated by size limit of fetch
+	"0000000000000010000000390000000002d8d0f56d0000000000000000018bcfe568000000018bcfe56800ffffffff" +
+		"ffffffffffffffffffff000000010e00000001",
+}
+
+// metadata v1 response of brokers 1 and 2, topic tasks with partition 0 without leader, unknown topic missing
+const kafkaFrameMetadata = "000000020000000100076b61666b612d3100002384ffff0000000200076b61666b612d320000238500047261636b" +
+	"0000000100000002000000057461736b7300000000020000000000010000000200000001000000010000000100000001" +
+	"0005000000000000000100000001000000010000000100000001000300076d697373696e670000000000"
+
+func decodeHex(t *testing.T, frames ...string) []byte {
+	t.Helper()
+	var data []byte
+	for _, frame := range frames {
+		decoded, err := hex.DecodeString(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data = append(data, decoded...)
+	}
+	return data
+}
+
+func TestEncodeRecordBatch(t *testing.T) {
+	batch := encodeRecordBatch([]byte("hi"), map[string]string{kafkaReplyHeader: "r"}, time.Unix(1700000000, 0))
+	if expected := decodeHex(t, kafkaFrameEncoded); !bytes.Equal(batch, expected) {
+		t.Fatalf("batch\n%x\nexpected\n%x", batch, expected)
+	}
+	records, next, err := decodeRecordBatches(batch, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != 1 || len(records) != 1 || string(records[0].value) != "hi" || records[0].headers[kafkaReplyHeader] != "r" {
+		t.Fatalf("decoded records %+v, next %d", records, next)
+	}
+}
+
+func TestDecodeRecordBatches(t *testing.T) {
+	data := decodeHex(t, kafkaFrameFetch...)
+	records, next, err := decodeRecordBatches(data, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []kafkaRecord{
+		{offset: 11, value: []byte("b"), headers: map[string]string{"k": "v"}},
+		{offset: 12, value: []byte("c"), headers: map[string]string{}},
+		{offset: 13, value: []byte("d"), headers: map[string]string{}},
+	}
+	if !reflect.DeepEqual(records, expected) {
+		t.Fatalf("records %+v, expected %+v", records, expected)
+	}
+	// control batch moves the offset, truncated batch is fetched again
+	if next != 15 {
+		t.Fatalf("next offset %d, expected 15", next)
+	}
+}
+
+func TestDecodeRecordBatchesCorrupted(t *testing.T) {
+	data := decodeHex(t, kafkaFrameFetch[0])
+	data[len(data)-1] ^= 0xff
+	if _, _, err := decodeRecordBatches(data, 0); err != errKafkaCorrupted {
+		t.Fatalf("error %v, expected errKafkaCorrupted", err)
+	}
+	if records, next, err := decodeRecordBatches(data[:40], 7); err != nil || len(records) != 0 || next != 7 {
+		t.Fatalf("short data gives %v, %d, %v", records, next, err)
+	}
+}
+
+func TestKafkaDecoderSticky(t *testing.T) {
+	decoder := kafkaDecoder{data: []byte{0, 1, 0}}
+	if decoder.int16() != 1 {
+		t.Fatal("int16 is not big endian")
+	}
+	if decoder.int32() != 0 || decoder.err != errKafkaShortResponse {
+		t.Fatalf("short int32 gives error %v", decoder.err)
+	}
+	if decoder.int8() != 0 || decoder.string() != "" || decoder.bytes() != nil {
+		t.Fatal("values after error are not zero")
+	}
+	// length of array beyond the data is corrupted
+	huge := kafkaDecoder{data: []byte{0, 0, 1, 0}}
+	if huge.array() != 0 || huge.err != errKafkaShortResponse {
+		t.Fatalf("array beyond the data gives error %v", huge.err)
+	}
+	null := kafkaDecoder{data: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}
+	if null.array() != 0 || null.string() != "" || null.err != nil {
+		t.Fatalf("null array and string give error %v", null.err)
+	}
+}
+
+func TestKafkaVarint(t *testing.T) {
+	for _, value := range []int64{0, -1, 1, 63, -64, 64, 300, -300, 1 << 40} {
+		var encoder kafkaEncoder
+		encoder.varint(value)
+		decoder := kafkaDecoder{data: encoder.Bytes()}
+		if decoded := decoder.varint(); decoded != value || decoder.err != nil || len(decoder.data) != 0 {
+			t.Errorf("varint %d decoded as %d, %v", value, decoded, decoder.err)
+		}
+	}
+	// zigzag encoding of the spec
+	var encoder kafkaEncoder
+	encoder.varint(-1)
+	encoder.varint(150)
+	if encoded := hex.EncodeToString(encoder.Bytes()); encoded != "01ac02" {
+		t.Fatalf("varints -1 and 150 are %s, expected 01ac02", encoded)
+	}
+}
+
+func TestKafkaConnRequest(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	conn := &kafkaConn{conn: client, reader: bufio.NewReader(client), clientID: "bomber"}
+	received := make(chan []byte, 1)
+	go func() {
+		defer server.Close()
+		for _, correlation := range []string{"00000001", "00000007"} {
+			request := make([]byte, 24)
+			if _, err := io.ReadFull(server, request); err != nil {
+				return
+			}
+			received <- request
+			server.Write(decodeHex(t, "00000008", correlation, "cafebabe"))
+		}
+	}()
+	response, err := conn.request(kafkaMetadata, 1, []byte{0, 0, 0, 0}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// size, api key, api version, correlation id, client id and body of request header v1
+	if request, expected := <-received, decodeHex(t, "00000014", "0003", "0001", "00000001", "0006626f6d626572", "00000000"); !bytes.Equal(request, expected) {
+		t.Fatalf("request %x, expected %x", request, expected)
+	}
+	if !bytes.Equal(response.data, []byte{0xca, 0xfe, 0xba, 0xbe}) {
+		t.Fatalf("body of response %x", response.data)
+	}
+	if _, err := conn.request(kafkaMetadata, 1, []byte{0, 0, 0, 0}, 0); err == nil {
+		t.Fatal("response of other correlation id is accepted")
+	}
+}
+
+func TestApplyMetadata(t *testing.T) {
+	client := newKafkaClient(nil, "bomber")
+	err := client.applyMetadata(&kafkaDecoder{data: decodeHex(t, kafkaFrameMetadata)})
+	var code KafkaError
+	if !errors.As(err, &code) || code != 3 {
+		t.Fatalf("error %v, expected kafka error code 3 of topic missing", err)
+	}
+	if !reflect.DeepEqual(client.brokers, map[int32]string{1: "kafka-1:9092", 2: "kafka-2:9093"}) {
+		t.Fatalf("brokers %v", client.brokers)
+	}
+	if !reflect.DeepEqual(client.partitions["tasks"], []int32{0, 1}) {
+		t.Fatalf("partitions %v, expected sorted", client.partitions["tasks"])
+	}
+	if leader := client.leaders[kafkaPartition{topic: "tasks", partition: 1}]; leader != 2 {
+		t.Fatalf("leader of partition 1 is %d", leader)
+	}
+	if leader := client.leaders[kafkaPartition{topic: "tasks", partition: 0}]; leader != -1 {
+		t.Fatalf("partition without leader has leader %d", leader)
+	}
+	if _, ok := client.partitions["missing"]; ok {
+		t.Fatal("unknown topic has partitions")
+	}
+}
+
+func TestAssignPartitions(t *testing.T) {
+	assignments := assignPartitions([]string{"b", "a", "c"}, []int32{0, 1, 2, 3, 4})
+	expected := map[string][]int32{"a": {0, 3}, "b": {1, 4}, "c": {2}}
+	if !reflect.DeepEqual(assignments, expected) {
+		t.Fatalf("assignments %v, expected %v", assignments, expected)
+	}
+	if assignments := assignPartitions([]string{"a", "b"}, nil); len(assignments["a"]) != 0 || assignments["b"] == nil {
+		t.Fatalf("members without partitions %v", assignments)
+	}
+}
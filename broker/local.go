@@ -0,0 +1,94 @@
+package broker
+
+import (
+	"sync"
+	"time"
+)
+
+const localMaxPayload = 64 << 20
+
+/*
+Local - broker of one process without any server, used by standalone mode. Messages are delivered to subscribers
+of this process only, one subscriber of each group gets the message in turn. Messages without subscribers are dropped
+*/
+type Local struct {
+	mutex         sync.Mutex
+	subscriptions map[string][]*localSubscription
+	next          map[string]int
+}
+
+func NewLocal() *Local {
+	return &Local{subscriptions: map[string][]*localSubscription{}, next: map[string]int{}}
+}
+
+func (local *Local) Publish(subject string, data []byte) error {
+	message := &Message{Subject: subject, Data: append([]byte{}, data...)}
+	local.mutex.Lock()
+	var receivers []*localSubscription
+	groups := map[string][]*localSubscription{}
+	for _, subscription := range local.subscriptions[subject] {
+		if subscription.group == "" {
+			receivers = append(receivers, subscription)
+			continue
+		}
+		groups[subscription.group] = append(groups[subscription.group], subscription)
+	}
+	for group, members := range groups {
+		key := subject + " " + group
+		receivers = append(receivers, members[local.next[key]%len(members)])
+		local.next[key]++
+	}
+	local.mutex.Unlock()
+	for _, receiver := range receivers {
+		go receiver.handler(message)
+	}
+	return nil
+}
+
+func (local *Local) Subscribe(subject string, group string, handler Handler) (Subscription, error) {
+	subscription := &localSubscription{local: local, subject: subject, group: group, handler: handler}
+	local.mutex.Lock()
+	local.subscriptions[subject] = append(local.subscriptions[subject], subscription)
+	local.mutex.Unlock()
+	return subscription, nil
+}
+
+func (local *Local) Connected() bool {
+	return true
+}
+
+func (local *Local) Flush(timeout time.Duration) error {
+	return nil
+}
+
+func (local *Local) MaxPayload() int {
+	return localMaxPayload
+}
+
+func (local *Local) Close() error {
+	local.mutex.Lock()
+	local.subscriptions = map[string][]*localSubscription{}
+	local.mutex.Unlock()
+	return nil
+}
+
+type localSubscription struct {
+	local   *Local
+	subject string
+	group   string
+	handler Handler
+}
+
+func (subscription *localSubscription) Unsubscribe() error {
+	local := subscription.local
+	local.mutex.Lock()
+	defer local.mutex.Unlock()
+	subscriptions := local.subscriptions[subscription.subject]
+	for index, known := range subscriptions {
+		if known == subscription {
+			local.subscriptions[subscription.subject] = append(subscriptions[:index], subscriptions[index+1:]...)
+			break
+		}
+	}
+	return nil
+}
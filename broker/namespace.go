@@ -0,0 +1,53 @@
+package broker
+
+import "strings"
+
+// replies of NATS requests, they are answered as they came
+const inboxPrefix = "_INBOX."
+
+/*
+Namespaced - subjects of the broker are prefixed by the namespace, so bombers of different namespaces
+share the broker without seeing messages of each other. Handlers get subjects without the namespace
+*/
+type Namespaced struct {
+	Broker
+	prefix string
+}
+
+func NewNamespaced(bus Broker, namespace string) *Namespaced {
+	return &Namespaced{Broker: bus, prefix: namespace + "."}
+}
+
+// Subject - subject of the namespace, reply subjects are not namespaced
+func (namespaced *Namespaced) Subject(subject string) string {
+	if strings.HasPrefix(subject, inboxPrefix) {
+		return subject
+	}
+	return namespaced.prefix + subject
+}
+
+func (namespaced *Namespaced) Publish(subject string, data []byte) error {
+	return namespaced.Broker.Publish(namespaced.Subject(subject), data)
+}
+
+func (namespaced *Namespaced) Subscribe(subject string, group string, handler Handler) (Subscription, error) {
+	return namespaced.Broker.Subscribe(namespaced.Subject(subject), group, func(message *Message) {
+		// message can be shared by subscribers of the local broker
+		received := *message
+		received.Subject = strings.TrimPrefix(message.Subject, namespaced.prefix)
+		handler(&received)
+	})
+}
+
+// Unwrap - broker of all namespaces
+func (namespaced *Namespaced) Unwrap() Broker {
+	return namespaced.Broker
+}
+
+// SubjectOf - subject as it is published into the broker, namespaced if the broker is namespaced
+func SubjectOf(bus Broker, subject string) string {
+	if namespaced, ok := bus.(*Namespaced); ok {
+		return namespaced.Subject(subject)
+	}
+	return subject
+}
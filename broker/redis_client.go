@@ -0,0 +1,248 @@
+package broker
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	redisDialTimeout = 10 * time.Second
+	// answers bigger than it are surely corrupted
+	redisMaxBulk = 512 << 20
+)
+
+var errRedisMalformed = errors.New("answer of redis is malformed")
+
+// redisError - error answered by redis, connection stays usable after it
+type redisError string
+
+func (err redisError) Error() string {
+	return "redis: " + string(err)
+}
+
+type redisURL struct {
+	addr     string
+	tls      bool
+	host     string
+	user     string
+	password string
+	database int
+}
+
+func parseRedisURL(raw string) (redisURL, error) {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return redisURL{}, err
+	}
+	result := redisURL{host: parsed.Hostname()}
+	switch parsed.Scheme {
+	case "redis":
+	case "rediss":
+		result.tls = true
+	default:
+		return redisURL{}, fmt.Errorf("unknown scheme %s of redis url, expected redis or rediss", parsed.Scheme)
+	}
+	port := "6379"
+	if parsed.Port() != "" {
+		port = parsed.Port()
+	}
+	result.addr = net.JoinHostPort(parsed.Hostname(), port)
+	if parsed.User != nil {
+		result.user = parsed.User.Username()
+		result.password, _ = parsed.User.Password()
+	}
+	if database := strings.TrimPrefix(parsed.Path, "/"); database != "" {
+		if result.database, err = strconv.Atoi(database); err != nil {
+			return redisURL{}, fmt.Errorf("database %s of redis url is not a number", database)
+		}
+	}
+	return result, nil
+}
+
+// redisConn - connection of RESP protocol, commands of it are not sent concurrently
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// dialRedis - authenticated connection with selected database
+func dialRedis(address redisURL, name string) (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: redisDialTimeout}
+	var conn net.Conn
+	var err error
+	if address.tls {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address.addr, &tls.Config{ServerName: address.host})
+	} else {
+		conn, err = dialer.Dial("tcp", address.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	redis := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+	var commands [][]string
+	if address.password != "" {
+		if address.user != "" {
+			commands = append(commands, []string{"AUTH", address.user, address.password})
+		} else {
+			commands = append(commands, []string{"AUTH", address.password})
+		}
+	}
+	if address.database != 0 {
+		commands = append(commands, []string{"SELECT", strconv.Itoa(address.database)})
+	}
+	if name != "" {
+		commands = append(commands, []string{"CLIENT", "SETNAME", strings.ReplaceAll(name, " ", "-")})
+	}
+	for _, command := range commands {
+		if _, err := redis.do(0, command...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return redis, nil
+}
+
+/*
+do - answer of the command: string, int64, []byte, []interface{} or nil. Timeout is the time
+redis may hold blocking command, answer of redis error is redisError
+*/
+func (conn *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	var command strings.Builder
+	fmt.Fprintf(&command, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&command, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	conn.conn.SetDeadline(time.Now().Add(timeout + redisDialTimeout))
+	if _, err := io.WriteString(conn.conn, command.String()); err != nil {
+		return nil, err
+	}
+	reply, err := conn.read()
+	if err != nil {
+		return nil, err
+	}
+	if answer, ok := reply.(redisError); ok {
+		return nil, answer
+	}
+	return reply, nil
+}
+
+func (conn *redisConn) read() (interface{}, error) {
+	line, err := conn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, errRedisMalformed
+	}
+	kind, value := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return redisError(value), nil
+	case ':':
+		number, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errRedisMalformed
+		}
+		return number, nil
+	case '$':
+		size, err := strconv.Atoi(value)
+		if err != nil || size > redisMaxBulk {
+			return nil, errRedisMalformed
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(conn.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		size, err := strconv.Atoi(value)
+		if err != nil || size > redisMaxBulk {
+			return nil, errRedisMalformed
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, size)
+		for index := range items {
+			if items[index], err = conn.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, errRedisMalformed
+}
+
+func (conn *redisConn) close() {
+	conn.conn.Close()
+}
+
+// redisEntry - entry of a stream, fields are nil for entry deleted before it was acknowledged
+type redisEntry struct {
+	id     string
+	fields map[string][]byte
+}
+
+// parseRedisEntries - entries of answer [id, [field, value...]]...
+func parseRedisEntries(reply interface{}) ([]redisEntry, error) {
+	items, ok := reply.([]interface{})
+	if !ok && reply != nil {
+		return nil, errRedisMalformed
+	}
+	entries := make([]redisEntry, 0, len(items))
+	for _, item := range items {
+		pair, ok := item.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, errRedisMalformed
+		}
+		id, ok := pair[0].([]byte)
+		if !ok {
+			return nil, errRedisMalformed
+		}
+		entry := redisEntry{id: string(id)}
+		if values, ok := pair[1].([]interface{}); ok {
+			entry.fields = map[string][]byte{}
+			for index := 0; index+1 < len(values); index += 2 {
+				key, _ := values[index].([]byte)
+				value, _ := values[index+1].([]byte)
+				entry.fields[string(key)] = value
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// parseRedisStreams - entries of answer of XREAD and XREADGROUP, nil answer of timeout is empty
+func parseRedisStreams(reply interface{}) ([]redisEntry, error) {
+	streams, ok := reply.([]interface{})
+	if !ok && reply != nil {
+		return nil, errRedisMalformed
+	}
+	var entries []redisEntry
+	for _, stream := range streams {
+		pair, ok := stream.([]interface{})
+		if !ok || len(pair) != 2 {
+			return nil, errRedisMalformed
+		}
+		parsed, err := parseRedisEntries(pair[1])
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, parsed...)
+	}
+	return entries, nil
+}
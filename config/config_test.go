@@ -0,0 +1,162 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolateEnv - unsets environment of all settings and sets the given one, restores it when the test ends
+func isolateEnv(t *testing.T, values map[string]string) {
+	saved := map[string]string{}
+	for _, item := range (&Configuration{}).settings() {
+		if raw, ok := os.LookupEnv(item.env); ok {
+			saved[item.env] = raw
+		}
+		os.Unsetenv(item.env)
+	}
+	for name, raw := range values {
+		os.Setenv(name, raw)
+	}
+	t.Cleanup(func() {
+		for _, item := range (&Configuration{}).settings() {
+			os.Unsetenv(item.env)
+		}
+		for name, raw := range saved {
+			os.Setenv(name, raw)
+		}
+	})
+}
+
+// writeFile - path of yaml file with the content inside temporary directory of the test
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "bomber.yaml")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func parseFlags(t *testing.T, args ...string) *Flags {
+	set := flag.NewFlagSet("bomber", flag.ContinueOnError)
+	flags := RegisterFlags(set)
+	if err := set.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return flags
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	file := "nats:\n  name: file\n  max_wait: 2\n  tls:\n    enabled: true\n"
+	cases := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		client  string
+		maxWait int
+		tls     bool
+	}{
+		{"defaults", "", nil, nil, "bomber", 1, false},
+		{"file over defaults", file, nil, nil, "file", 2, true},
+		{"env over file", file, map[string]string{"NATS_NAME": "env", "NATS_MAX_WAIT": "3", "NATS_TLS": "false"}, nil, "env", 3, false},
+		{"empty env is ignored", file, map[string]string{"NATS_NAME": "", "NATS_MAX_WAIT": ""}, nil, "file", 2, true},
+		{"flags over env", file, map[string]string{"NATS_NAME": "env", "NATS_MAX_WAIT": "3"}, []string{"-nats.name", "flag", "-nats.max_wait=4", "-nats.tls.enabled=false"}, "flag", 4, false},
+		{"flags over defaults", "", nil, []string{"-nats.name=flag", "-nats.tls.enabled"}, "flag", 1, true},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			env := map[string]string{}
+			for name, raw := range testCase.env {
+				env[name] = raw
+			}
+			if testCase.file != "" {
+				env["BOMBER_CONFIG"] = writeFile(t, testCase.file)
+			}
+			isolateEnv(t, env)
+			config, err := Load(parseFlags(t, testCase.args...))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.NameClient != testCase.client || config.MaxWait != testCase.maxWait || config.TLS != testCase.tls {
+				t.Fatalf("got name %q, max wait %d, tls %v, expected %q, %d, %v",
+					config.NameClient, config.MaxWait, config.TLS, testCase.client, testCase.maxWait, testCase.tls)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFileByFlag(t *testing.T) {
+	isolateEnv(t, map[string]string{"BOMBER_CONFIG": writeFile(t, "nats:\n  name: env-file\n")})
+	path := writeFile(t, "nats:\n  name: flag-file\n")
+	config, err := Load(parseFlags(t, "-config", path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.ConfigFile != path || config.NameClient != "flag-file" {
+		t.Fatalf("got file %q and name %q, expected file of the flag", config.ConfigFile, config.NameClient)
+	}
+}
+
+func TestLoadWithoutFlags(t *testing.T) {
+	isolateEnv(t, map[string]string{"NATS_NAME": "env"})
+	config, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.NameClient != "env" || config.URL != "nats://localhost:4222" {
+		t.Fatalf("got name %q and url %q", config.NameClient, config.URL)
+	}
+}
+
+func TestLoadProblems(t *testing.T) {
+	cases := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		expected string
+	}{
+		{"integer of env", "", map[string]string{"NATS_MAX_WAIT": "many"}, nil,
+			`invalid configuration of bomber: NATS_MAX_WAIT: expected integer, got "many"`},
+		{"boolean of env", "", map[string]string{"NATS_TLS": "maybe"}, nil,
+			`invalid configuration of bomber: NATS_TLS: expected true or false, got "maybe"`},
+		{"integer of flag", "", nil, []string{"-nats.max_wait=many"},
+			`invalid configuration of bomber: -nats.max_wait: expected integer, got "many"`},
+		{"boolean of flag", "", nil, []string{"-nats.tls.enabled=maybe"},
+			`invalid configuration of bomber: -nats.tls.enabled: expected true or false, got "maybe"`},
+		{"all sources at once", "nats:\n  max_wait: many\n", map[string]string{"NATS_TLS": "maybe"}, []string{"-nats.reconnect_delay=soon"},
+			`: nats.max_wait (NATS_MAX_WAIT): expected integer, got "many"; -nats.reconnect_delay: expected integer, got "soon"; NATS_TLS: expected true or false, got "maybe"`},
+		{"validation after sources", "", map[string]string{"BROKER": "zeromq"}, nil,
+			`invalid configuration of bomber: broker.kind (BROKER): "zeromq" is not one of nats, kafka, rabbitmq, redis, local`},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			env := map[string]string{}
+			for name, raw := range testCase.env {
+				env[name] = raw
+			}
+			if testCase.file != "" {
+				env["BOMBER_CONFIG"] = writeFile(t, testCase.file)
+			}
+			isolateEnv(t, env)
+			_, err := Load(parseFlags(t, testCase.args...))
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.HasSuffix(err.Error(), testCase.expected) {
+				t.Fatalf("got %q, expected %q", err, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	isolateEnv(t, map[string]string{"BOMBER_CONFIG": filepath.Join(t.TempDir(), "missing.yaml")})
+	if _, err := Load(nil); err == nil || !strings.Contains(err.Error(), "no such file") {
+		t.Fatalf("got %v, expected error of missing file", err)
+	}
+}
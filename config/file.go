@@ -0,0 +1,138 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+/*FileDisabled - value of config file which turns off reading of it*/
+const FileDisabled = "off"
+
+// Error - all problems of the configuration, each one names setting of the file and its environment variable
+type Error struct {
+	Source   string
+	Problems []string
+}
+
+func (err *Error) Error() string {
+	return "invalid configuration of " + err.Source + ": " + strings.Join(err.Problems, "; ")
+}
+
+func (err *Error) add(format string, args ...interface{}) {
+	err.Problems = append(err.Problems, fmt.Sprintf(format, args...))
+}
+
+func (err *Error) orNil() error {
+	if len(err.Problems) == 0 {
+		return nil
+	}
+	return err
+}
+
+/*
+LoadFile - settings of yaml file, nested sections are joined by dots into keys (nats: {url: ...} is nats.url).
+Unknown settings and values of wrong types are errors
+*/
+func (config *Configuration) LoadFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return &Error{Source: path, Problems: []string{err.Error()}}
+	}
+	values := map[string]interface{}{}
+	problems := &Error{Source: path}
+	flattenSettings("", document, values, problems)
+	byKey := map[string]setting{}
+	for _, item := range config.settings() {
+		if item.key != "" {
+			byKey[item.key] = item
+		}
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		item, known := byKey[key]
+		if !known {
+			problems.add("unknown setting %s", key)
+			continue
+		}
+		if err := assignSetting(item.value, values[key]); err != nil {
+			problems.add("%s: %v", item, err)
+		}
+	}
+	return problems.orNil()
+}
+
+func flattenSettings(prefix string, node interface{}, values map[string]interface{}, problems *Error) {
+	section, ok := node.(map[interface{}]interface{})
+	if !ok {
+		if prefix == "" {
+			if node != nil {
+				problems.add("config file has to be a mapping of sections")
+			}
+			return
+		}
+		values[prefix] = node
+		return
+	}
+	for name, child := range section {
+		key := fmt.Sprint(name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenSettings(key, child, values, problems)
+	}
+}
+
+// assignSetting - lists are joined by comma for string settings, as environment variables are
+func assignSetting(field reflect.Value, value interface{}) error {
+	switch field.Kind() {
+	case reflect.String:
+		switch typed := value.(type) {
+		case string, int, float64, bool:
+			field.SetString(fmt.Sprint(typed))
+		case []interface{}:
+			items := make([]string, len(typed))
+			for index, item := range typed {
+				items[index] = fmt.Sprint(item)
+			}
+			field.SetString(strings.Join(items, ","))
+		default:
+			return fmt.Errorf("expected string, got %v", value)
+		}
+	case reflect.Int, reflect.Int64:
+		switch typed := value.(type) {
+		case int:
+			field.SetInt(int64(typed))
+		case string:
+			number, err := strconv.ParseInt(typed, 10, 64)
+			if err != nil {
+				return fmt.Errorf("expected integer, got %q", typed)
+			}
+			field.SetInt(number)
+		default:
+			return fmt.Errorf("expected integer, got %v", value)
+		}
+	case reflect.Bool:
+		flag, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("expected true or false, got %v", value)
+		}
+		field.SetBool(flag)
+	default:
+		return fmt.Errorf("setting of type %s is not supported", field.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		check   func(config *Configuration) bool
+	}{
+		{"empty file", "", func(config *Configuration) bool { return config.NameClient == "" }},
+		{"nested sections", "nats:\n  url: nats://nats:4222\n  tls:\n    enabled: true\n",
+			func(config *Configuration) bool { return config.URL == "nats://nats:4222" && config.TLS }},
+		{"dotted keys", "nats.url: nats://nats:4222\nnats:\n  max_wait: 3\n",
+			func(config *Configuration) bool { return config.URL == "nats://nats:4222" && config.MaxWait == 3 }},
+		{"list of strings", "broker:\n  kafka:\n    brokers: [kafka-1:9092, kafka-2:9092]\n",
+			func(config *Configuration) bool { return config.KafkaBrokers == "kafka-1:9092,kafka-2:9092" }},
+		{"number of string", "bomber:\n  id: 42\n", func(config *Configuration) bool { return config.CurrentServiceID == "42" }},
+		{"quoted integer", "nats:\n  max_wait: \"5\"\n", func(config *Configuration) bool { return config.MaxWait == 5 }},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := &Configuration{}
+			if err := config.LoadFile(writeFile(t, testCase.content)); err != nil {
+				t.Fatal(err)
+			}
+			if !testCase.check(config) {
+				t.Fatalf("unexpected settings %+v", config)
+			}
+		})
+	}
+}
+
+func TestLoadFileProblems(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		problems []string
+	}{
+		{"unknown setting", "nats:\n  uri: nats://nats:4222\n", []string{"unknown setting nats.uri"}},
+		{"unknown section", "natz:\n  url: nats://nats:4222\n", []string{"unknown setting natz.url"}},
+		{"section instead of setting", "nats:\n  url:\n    host: nats\n", []string{"unknown setting nats.url.host"}},
+		{"setting instead of section", "nats:\n  tls: true\n", []string{"unknown setting nats.tls"}},
+		{"integer of text", "nats:\n  max_wait: many\n", []string{`nats.max_wait (NATS_MAX_WAIT): expected integer, got "many"`}},
+		{"integer of list", "nats:\n  max_wait: [1, 2]\n", []string{"nats.max_wait (NATS_MAX_WAIT): expected integer, got [1 2]"}},
+		{"integer of float", "nats:\n  max_wait: 1.5\n", []string{"nats.max_wait (NATS_MAX_WAIT): expected integer, got 1.5"}},
+		{"boolean of text", "nats:\n  tls:\n    enabled: maybe\n", []string{"nats.tls.enabled (NATS_TLS): expected true or false, got maybe"}},
+		{"boolean of number", "nats:\n  tls:\n    enabled: 1\n", []string{"nats.tls.enabled (NATS_TLS): expected true or false, got 1"}},
+		{"file setting inside file", "config: other.yaml\n", []string{"unknown setting config"}},
+		{"sorted problems", "nats:\n  uri: x\n  max_wait: many\n",
+			[]string{`nats.max_wait (NATS_MAX_WAIT): expected integer, got "many"`, "unknown setting nats.uri"}},
+		{"list instead of mapping", "- nats\n- kafka\n", []string{"config file has to be a mapping of sections"}},
+		{"scalar instead of mapping", "nats\n", []string{"config file has to be a mapping of sections"}},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := writeFile(t, testCase.content)
+			err := (&Configuration{}).LoadFile(path)
+			expected := "invalid configuration of " + path + ": " + strings.Join(testCase.problems, "; ")
+			if err == nil || err.Error() != expected {
+				t.Fatalf("got %v, expected %s", err, expected)
+			}
+		})
+	}
+}
+
+func TestLoadFileSyntax(t *testing.T) {
+	path := writeFile(t, "nats:\n  url: [nats\n")
+	err := (&Configuration{}).LoadFile(path)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid configuration of "+path+": yaml: ") {
+		t.Fatalf("got %v, expected error of yaml syntax", err)
+	}
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// reloadable - settings applied without restart, they do not change running attack
+var reloadable = map[string]bool{
+	"LogLevel":            true,
+	"HeartbeatInterval":   true,
+	"ReportDir":           true,
+	"InfluxURL":           true,
+	"InfluxToken":         true,
+	"S3Endpoint":          true,
+	"S3Region":            true,
+	"S3Bucket":            true,
+	"S3AccessKey":         true,
+	"S3SecretKey":         true,
+	"S3Prefix":            true,
+	"GrafanaURL":          true,
+	"GrafanaToken":        true,
+	"GrafanaDashboardUID": true,
+}
+
+// Reload - changed settings by their keys, applied ones are changed already, others after restart of the bomber
+type Reload struct {
+	Applied []string `json:"applied"`
+	Restart []string `json:"restart"`
+	fields  map[string]bool
+}
+
+// Changed - true if any of settings, by names of fields, was applied by the reload
+func (reload *Reload) Changed(fields ...string) bool {
+	for _, field := range fields {
+		if reload.fields[field] {
+			return true
+		}
+	}
+	return false
+}
+
+// Listener - applies reloaded settings, configuration has them and settings of the start otherwise
+type Listener func(config *Configuration, reload *Reload)
+
+/*
+Reloader - reads all sources of configuration again, by request or on change of the file,
+and passes changed reloadable settings to listeners
+*/
+type Reloader struct {
+	flags     *Flags
+	mutex     sync.Mutex
+	current   *Configuration
+	listeners []Listener
+}
+
+// NewReloader - flags are the flags configuration was loaded with, they take precedence on reload too
+func NewReloader(flags *Flags, current *Configuration) *Reloader {
+	return &Reloader{flags: flags, current: current}
+}
+
+func (reloader *Reloader) OnReload(listener Listener) {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	reloader.listeners = append(reloader.listeners, listener)
+}
+
+// Reload - error if configuration is invalid now, nothing is applied then
+func (reloader *Reloader) Reload() (*Reload, error) {
+	reloader.mutex.Lock()
+	defer reloader.mutex.Unlock()
+	loaded, err := Load(reloader.flags)
+	if err != nil {
+		return nil, err
+	}
+	next := *reloader.current
+	reload := &Reload{Applied: []string{}, Restart: []string{}, fields: map[string]bool{}}
+	current := reloader.current.settings()
+	settings := next.settings()
+	sources := loaded.settings()
+	for _, name := range sortedNames(settings) {
+		// id is generated at start, it is never the one of sources
+		if name == "CurrentServiceID" {
+			continue
+		}
+		value := sources[name].value
+		if reflect.DeepEqual(current[name].value.Interface(), value.Interface()) {
+			continue
+		}
+		if !reloadable[name] {
+			reload.Restart = append(reload.Restart, settings[name].flag)
+			continue
+		}
+		settings[name].value.Set(value)
+		reload.Applied = append(reload.Applied, settings[name].flag)
+		reload.fields[name] = true
+	}
+	if len(reload.Restart) > 0 {
+		logrus.Info("Settings are changed, but applied only after restart: ", strings.Join(reload.Restart, ", "))
+	}
+	if len(reload.Applied) == 0 {
+		return reload, nil
+	}
+	logrus.Info("Reloaded settings: ", strings.Join(reload.Applied, ", "))
+	reloader.current = &next
+	for _, listener := range reloader.listeners {
+		listener(reloader.current, reload)
+	}
+	return reload, nil
+}
+
+// Watch - reloads configuration when modification time of the file is changed, zero interval or no file disables it
+func (reloader *Reloader) Watch(interval time.Duration) {
+	reloader.mutex.Lock()
+	path := reloader.current.ConfigFile
+	reloader.mutex.Unlock()
+	if interval <= 0 || path == FileDisabled {
+		return
+	}
+	modified := modifiedAt(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		latest := modifiedAt(path)
+		if latest.IsZero() || latest.Equal(modified) {
+			continue
+		}
+		modified = latest
+		logrus.Info("Configuration file ", path, " is changed, reloading")
+		if _, err := reloader.Reload(); err != nil {
+			logrus.Error("Can not reload configuration: ", err)
+		}
+	}
+}
+
+// modifiedAt - zero time while the file is not available, editors may replace it by new one
+func modifiedAt(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
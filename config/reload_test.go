@@ -0,0 +1,117 @@
+package config
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+const reloadedFile = "bomber:\n  log_level: error\n  heartbeat_interval: 5\nnats:\n  name: bomber\n"
+
+// startReloader - reloader of configuration loaded from the file, with listener counting calls
+func startReloader(t *testing.T) (*Reloader, string, *[]*Reload) {
+	path := writeFile(t, reloadedFile)
+	isolateEnv(t, map[string]string{"BOMBER_CONFIG": path})
+	current, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	current.CorrectedGeneratingHandlerName()
+	reloader := NewReloader(nil, current)
+	calls := &[]*Reload{}
+	reloader.OnReload(func(config *Configuration, reload *Reload) {
+		*calls = append(*calls, reload)
+	})
+	return reloader, path, calls
+}
+
+func rewrite(t *testing.T, path string, content string) {
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		applied []string
+		restart []string
+	}{
+		{"nothing changed", reloadedFile, []string{}, []string{}},
+		{"reloadable settings", "bomber:\n  log_level: debug\n  heartbeat_interval: 1\nnats:\n  name: bomber\n",
+			[]string{"bomber.heartbeat_interval", "bomber.log_level"}, []string{}},
+		{"restart only settings", "bomber:\n  log_level: error\n  heartbeat_interval: 5\nnats:\n  name: other\n  max_wait: 3\n",
+			[]string{}, []string{"nats.max_wait", "nats.name"}},
+		{"both kinds", "bomber:\n  log_level: info\n  heartbeat_interval: 5\nnats:\n  name: other\n",
+			[]string{"bomber.log_level"}, []string{"nats.name"}},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reloader, path, calls := startReloader(t)
+			started := *reloader.current
+			rewrite(t, path, testCase.content)
+			reload, err := reloader.Reload()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(reload.Applied, testCase.applied) || !reflect.DeepEqual(reload.Restart, testCase.restart) {
+				t.Fatalf("got applied %v and restart %v, expected %v and %v", reload.Applied, reload.Restart, testCase.applied, testCase.restart)
+			}
+			if expected := len(testCase.applied) > 0; (len(*calls) == 1) != expected {
+				t.Fatalf("listener is called %d times", len(*calls))
+			}
+			// settings of restart stay the ones of the start
+			if reloader.current.NameClient != started.NameClient || reloader.current.MaxWait != started.MaxWait {
+				t.Fatalf("restart only settings are applied: %+v", reloader.current)
+			}
+			if reloader.current.CurrentServiceID != started.CurrentServiceID {
+				t.Fatalf("id of the bomber is changed to %s", reloader.current.CurrentServiceID)
+			}
+		})
+	}
+}
+
+func TestReloadApplies(t *testing.T) {
+	reloader, path, calls := startReloader(t)
+	started := reloader.current
+	rewrite(t, path, "bomber:\n  log_level: debug\n  heartbeat_interval: 5\nnats:\n  name: bomber\n")
+	reload, err := reloader.Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloader.current.LogLevel != "debug" || started.LogLevel != "error" {
+		t.Fatalf("got log level %s, the one of start is %s", reloader.current.LogLevel, started.LogLevel)
+	}
+	if !reload.Changed("HeartbeatInterval", "LogLevel") || reload.Changed("HeartbeatInterval", "NameClient") {
+		t.Fatalf("unexpected changed fields %v", reload.fields)
+	}
+	if len(*calls) != 1 || (*calls)[0] != reload {
+		t.Fatalf("listener got %v", *calls)
+	}
+}
+
+func TestReloadInvalidFile(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+	}{
+		{"wrong type", "bomber:\n  log_level: debug\nnats:\n  max_wait: many\n"},
+		{"unknown setting", "bomber:\n  log_level: debug\n  loglevel: debug\n"},
+		{"invalid value", "bomber:\n  log_level: loud\n"},
+		{"syntax", "bomber:\n  log_level: [debug\n"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reloader, path, calls := startReloader(t)
+			started := *reloader.current
+			rewrite(t, path, testCase.content)
+			if _, err := reloader.Reload(); err == nil {
+				t.Fatal("expected error")
+			}
+			if !reflect.DeepEqual(*reloader.current, started) || len(*calls) != 0 {
+				t.Fatalf("running configuration is changed to %+v", reloader.current)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/sirupsen/logrus"
+)
+
+// Validate - checks values of settings together, whatever source they came from
+func (config *Configuration) Validate() error {
+	settings := config.settings()
+	problems := &Error{Source: "bomber"}
+	if config.ConfigFile != FileDisabled {
+		problems.Source = config.ConfigFile
+	}
+	oneOf := func(field string, allowed ...string) {
+		value := settings[field].value.String()
+		for _, known := range allowed {
+			if value == known {
+				return
+			}
+		}
+		problems.add("%s: %q is not one of %s", settings[field], value, strings.Join(allowed, ", "))
+	}
+	atLeast := func(minimum int64, fields ...string) {
+		for _, field := range fields {
+			if value := settings[field].value.Int(); value < minimum {
+				problems.add("%s: %d is less than %d", settings[field], value, minimum)
+			}
+		}
+	}
+	address := func(fields ...string) {
+		for _, field := range fields {
+			value := settings[field].value.String()
+			if value == "off" {
+				continue
+			}
+			if _, _, err := net.SplitHostPort(value); err != nil {
+				problems.add("%s: %q is not host:port", settings[field], value)
+			}
+		}
+	}
+	// assumed is scheme of addresses without it, empty if scheme is required
+	urls := func(field string, assumed string, schemes ...string) {
+		for _, raw := range strings.Split(settings[field].value.String(), ",") {
+			raw = strings.TrimSpace(raw)
+			if assumed != "" && !strings.Contains(raw, "://") {
+				raw = assumed + "://" + raw
+			}
+			parsed, err := url.Parse(raw)
+			if err != nil || parsed.Host == "" {
+				problems.add("%s: %q is not url", settings[field], raw)
+				continue
+			}
+			oneOfScheme := false
+			for _, scheme := range schemes {
+				oneOfScheme = oneOfScheme || parsed.Scheme == scheme
+			}
+			if !oneOfScheme {
+				problems.add("%s: scheme of %q is not one of %s", settings[field], raw, strings.Join(schemes, ", "))
+			}
+		}
+	}
+	oneOf("Broker", broker.KindNats, broker.KindKafka, broker.KindRabbitMQ, broker.KindRedis, broker.KindLocal)
+	// encodings of nats_listener, it can not be imported by configuration
+	oneOf("ResultEncoding", "none", "gzip", "zstd")
+	oneOf("Dashboard", "off", "on", "auto")
+	// signings of nats_listener
+	oneOf("MessageSigning", "off", "hmac", "nkey")
+	if config.MessageSigning == "hmac" && config.MessageHMACKey == "off" {
+		problems.add("%s is required by hmac signing of %s", settings["MessageHMACKey"], settings["MessageSigning"])
+	}
+	if config.MessageSigning == "nkey" && config.MessageSigners == "off" {
+		problems.add("%s is required by nkey signing of %s", settings["MessageSigners"], settings["MessageSigning"])
+	}
+	// nonces of signed messages are kept for max age, replays are not known without it
+	if (config.MessageSigning == "hmac" || config.MessageSigning == "nkey") && config.MessageMaxAge < 1 {
+		problems.add("%s: %d is less than 1 with signing of %s", settings["MessageMaxAge"], config.MessageMaxAge, settings["MessageSigning"])
+	}
+	// tenant is the first token of subjects of the bomber
+	if strings.ContainsAny(config.Tenant, ".*> \t") || config.Tenant == "" {
+		problems.add("%s: %q is not one token of subject, it has to be without dots, wildcards and spaces", settings["Tenant"], config.Tenant)
+	}
+	if _, err := logrus.ParseLevel(config.LogLevel); err != nil {
+		problems.add("%s: %v", settings["LogLevel"], err)
+	}
+	atLeast(0, "ReconnectDelay", "ReconnectBuffer", "TaskQueueDepth", "TaskDedupWindow", "ShutdownDrain", "MaxTestedRps",
+		"HeartbeatInterval", "ProgressIntervalMs", "ConfigWatchInterval", "MessageMaxAge")
+	atLeast(1, "MaxWait", "KafkaMaxMessageBytes", "RabbitMQMaxMessageBytes", "RedisStreamMaxLen", "RedisMaxMessageBytes",
+		"OTLPIntervalMs", "JetStreamAckWait", "CoordinatorLease", "CoordinatorWindow", "MaxAttacks")
+	address("MetricsAddr", "HealthAddr", "ControlGRPCAddr", "AdminAddr", "StatsDAddr")
+	if (config.TLSCert == "off") != (config.TLSKey == "off") {
+		problems.add("%s and %s have to be set together", settings["TLSCert"], settings["TLSKey"])
+	}
+	switch config.Broker {
+	case broker.KindNats, "":
+		urls("URL", "nats", "nats", "tls")
+	case broker.KindRabbitMQ:
+		urls("RabbitMQURL", "", "amqp", "amqps")
+	case broker.KindRedis:
+		urls("RedisURL", "", "redis", "rediss")
+	}
+	return problems.orNil()
+}
@@ -0,0 +1,111 @@
+package config
+
+import "testing"
+
+// defaults - configuration with default value of each setting
+func defaults(t *testing.T) *Configuration {
+	config := &Configuration{}
+	for _, item := range config.settings() {
+		if err := assignRaw(item.value, item.defaultRaw); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return config
+}
+
+func TestValidateDefaults(t *testing.T) {
+	if err := defaults(t).Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cases := []struct {
+		name     string
+		change   func(config *Configuration)
+		expected string
+	}{
+		{"broker", func(config *Configuration) { config.Broker = "zeromq" },
+			`broker.kind (BROKER): "zeromq" is not one of nats, kafka, rabbitmq, redis, local`},
+		{"result encoding", func(config *Configuration) { config.ResultEncoding = "brotli" },
+			`sinks.result_encoding (RESULT_ENCODING): "brotli" is not one of none, gzip, zstd`},
+		{"dashboard", func(config *Configuration) { config.Dashboard = "yes" },
+			`bomber.dashboard (DASHBOARD): "yes" is not one of off, on, auto`},
+		{"message signing", func(config *Configuration) { config.MessageSigning = "rsa" },
+			`messages.signing (MESSAGE_SIGNING): "rsa" is not one of off, hmac, nkey`},
+		{"hmac signing without key", func(config *Configuration) { config.MessageSigning = "hmac" },
+			"messages.hmac_key (MESSAGE_HMAC_KEY) is required by hmac signing of messages.signing (MESSAGE_SIGNING)"},
+		{"nkey signing without signers", func(config *Configuration) { config.MessageSigning = "nkey" },
+			"messages.signers (MESSAGE_SIGNERS) is required by nkey signing of messages.signing (MESSAGE_SIGNING)"},
+		{"signing without max age", func(config *Configuration) {
+			config.MessageSigning = "hmac"
+			config.MessageHMACKey = "secret"
+			config.MessageMaxAge = 0
+		}, "messages.max_age (MESSAGE_MAX_AGE): 0 is less than 1 with signing of messages.signing (MESSAGE_SIGNING)"},
+		{"tenant with dot", func(config *Configuration) { config.Tenant = "team.a" },
+			`bomber.tenant (TENANT): "team.a" is not one token of subject, it has to be without dots, wildcards and spaces`},
+		{"tenant with wildcard", func(config *Configuration) { config.Tenant = "team*" },
+			`bomber.tenant (TENANT): "team*" is not one token of subject, it has to be without dots, wildcards and spaces`},
+		{"empty tenant", func(config *Configuration) { config.Tenant = "" },
+			`bomber.tenant (TENANT): "" is not one token of subject, it has to be without dots, wildcards and spaces`},
+		{"log level", func(config *Configuration) { config.LogLevel = "loud" },
+			`bomber.log_level (LOG_LEVEL): not a valid logrus Level: "loud"`},
+		{"negative interval", func(config *Configuration) { config.HeartbeatInterval = -1 },
+			"bomber.heartbeat_interval (HEARTBEAT_INTERVAL): -1 is less than 0"},
+		{"zero attacks", func(config *Configuration) { config.MaxAttacks = 0 },
+			"tasks.max_attacks (MAX_ATTACKS): 0 is less than 1"},
+		{"address without port", func(config *Configuration) { config.MetricsAddr = "localhost" },
+			`servers.metrics_addr (METRICS_ADDR): "localhost" is not host:port`},
+		{"certificate without key", func(config *Configuration) { config.TLSCert = "cert.pem" },
+			"nats.tls.cert (NATS_TLS_CERT) and nats.tls.key (NATS_TLS_KEY) have to be set together"},
+		{"key without certificate", func(config *Configuration) { config.TLSKey = "key.pem" },
+			"nats.tls.cert (NATS_TLS_CERT) and nats.tls.key (NATS_TLS_KEY) have to be set together"},
+		{"nats url without host", func(config *Configuration) { config.URL = "nats://" },
+			`nats.url (NATS_URL): "nats://" is not url`},
+		{"nats url of other scheme", func(config *Configuration) { config.URL = "nats://a:4222, http://b:4222" },
+			`nats.url (NATS_URL): scheme of "http://b:4222" is not one of nats, tls`},
+		{"rabbitmq url without scheme", func(config *Configuration) {
+			config.Broker = "rabbitmq"
+			config.RabbitMQURL = "localhost:5672"
+		}, `broker.rabbitmq.url (RABBITMQ_URL): "localhost:5672" is not url`},
+		{"redis url of other scheme", func(config *Configuration) {
+			config.Broker = "redis"
+			config.RedisURL = "http://localhost:6379"
+		}, `broker.redis.url (REDIS_URL): scheme of "http://localhost:6379" is not one of redis, rediss`},
+		{"several problems", func(config *Configuration) {
+			config.Dashboard = "yes"
+			config.MaxAttacks = 0
+		}, `bomber.dashboard (DASHBOARD): "yes" is not one of off, on, auto; tasks.max_attacks (MAX_ATTACKS): 0 is less than 1`},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			config := defaults(t)
+			testCase.change(config)
+			err := config.Validate()
+			expected := "invalid configuration of bomber: " + testCase.expected
+			if err == nil || err.Error() != expected {
+				t.Fatalf("got %v, expected %s", err, expected)
+			}
+		})
+	}
+}
+
+func TestValidateSourceOfFile(t *testing.T) {
+	config := defaults(t)
+	config.ConfigFile = "bomber.yaml"
+	config.MaxAttacks = 0
+	expected := "invalid configuration of bomber.yaml: tasks.max_attacks (MAX_ATTACKS): 0 is less than 1"
+	if err := config.Validate(); err == nil || err.Error() != expected {
+		t.Fatalf("got %v, expected %s", err, expected)
+	}
+}
+
+func TestValidateSkipsOtherBrokers(t *testing.T) {
+	config := defaults(t)
+	config.Broker = "kafka"
+	config.URL = "http://nats"
+	config.RedisURL = "http://redis"
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
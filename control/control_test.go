@@ -0,0 +1,89 @@
+package control
+
+import (
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+var (
+	protoMessage = regexp.MustCompile(`^message (\w+) \{(\})?$`)
+	protoField   = regexp.MustCompile(`^(repeated )?(\w+) (\w+) = (\d+);$`)
+	protoMethod  = regexp.MustCompile(`^rpc (\w+)\((\w+)\) returns \((stream )?(\w+)\);$`)
+)
+
+// schemaOf - messages, fields and methods of control.proto, one line per declaration
+func schemaOf(t *testing.T) []string {
+	data, err := ioutil.ReadFile("control.proto")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema []string
+	var message string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if match := protoMessage.FindStringSubmatch(line); match != nil {
+			message = match[1]
+			schema = append(schema, "message "+message)
+		} else if match := protoField.FindStringSubmatch(line); match != nil {
+			schema = append(schema, fmt.Sprintf("%s.%s %s%s = %s", message, match[3], match[1], match[2], match[4]))
+		} else if match := protoMethod.FindStringSubmatch(line); match != nil {
+			schema = append(schema, fmt.Sprintf("rpc %s(%s) returns (%s%s)", match[1], match[2], match[3], match[4]))
+		}
+	}
+	return schema
+}
+
+func descriptorSchema(file protoreflect.FileDescriptor) []string {
+	var schema []string
+	for index := 0; index < file.Messages().Len(); index++ {
+		message := file.Messages().Get(index)
+		schema = append(schema, "message "+string(message.Name()))
+		for position := 0; position < message.Fields().Len(); position++ {
+			field := message.Fields().Get(position)
+			kind := field.Kind().String()
+			if field.Message() != nil {
+				kind = string(field.Message().Name())
+			}
+			repeated := ""
+			if field.Cardinality() == protoreflect.Repeated {
+				repeated = "repeated "
+			}
+			schema = append(schema, fmt.Sprintf("%s.%s %s%s = %d", message.Name(), field.Name(), repeated, kind, field.Number()))
+		}
+	}
+	methods := file.Services().ByName("BomberControl").Methods()
+	for index := 0; index < methods.Len(); index++ {
+		method := methods.Get(index)
+		stream := ""
+		if method.IsStreamingServer() {
+			stream = "stream "
+		}
+		schema = append(schema, fmt.Sprintf("rpc %s(%s) returns (%s%s)", method.Name(), method.Input().Name(), stream, method.Output().Name()))
+	}
+	return schema
+}
+
+// TestGeneratedSchema - control.pb.go has to be generated again after control.proto is changed
+func TestGeneratedSchema(t *testing.T) {
+	file := File_control_control_proto
+	if string(file.Package()) != "bomber.control.v1" || string(file.Services().Get(0).FullName()) != serviceName {
+		t.Fatalf("service %s of package %s", file.Services().Get(0).FullName(), file.Package())
+	}
+	expected, generated := schemaOf(t), descriptorSchema(file)
+	// service is declared before messages in control.proto
+	sort.Strings(expected)
+	sort.Strings(generated)
+	if strings.Join(expected, "\n") != strings.Join(generated, "\n") {
+		t.Fatalf("control.pb.go differs from control.proto, run go generate ./control\nproto:\n%s\ngenerated:\n%s",
+			strings.Join(expected, "\n"), strings.Join(generated, "\n"))
+	}
+	if len(expected) < 10 {
+		t.Fatalf("control.proto is not parsed: %v", expected)
+	}
+}
@@ -0,0 +1,212 @@
+package control
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative control/control.proto
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/handlers"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// Disabled - value of address or token which turns off the control API
+const Disabled = "off"
+
+const (
+	serviceName            = "bomber.control.v1.BomberControl"
+	defaultMetricsInterval = time.Second
+	authorizationKey       = "authorization"
+)
+
+var errUnauthorized = status.Error(codes.Unauthenticated, "unauthorized")
+
+// Controller - drives tasks of the bomber as they would come from NATS
+type Controller interface {
+	StartAttack(task []byte) *handlers.TaskAck
+	CancelAttack(taskId string) bool
+}
+
+type server struct {
+	controller Controller
+	bomber     *core.Core
+	token      string
+}
+
+/*
+Serve - serves gRPC service bomber.control.v1.BomberControl with server reflection, blocks until
+listener fails. Every call must have metadata authorization: Bearer <token> as requests of admin API,
+the API is not served without token. Messages of the service are generated from control.proto
+*/
+func Serve(addr string, token string, controller Controller, bomber *core.Core) {
+	if addr == Disabled || addr == "" {
+		return
+	}
+	if token == Disabled || token == "" {
+		logrus.Error("Can not serve control API without token, set ADMIN_TOKEN")
+		return
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logrus.Error("Can not listen control API: ", err)
+		return
+	}
+	srv := &server{controller: controller, bomber: bomber, token: token}
+	logrus.Info("Serving control API on ", addr)
+	if err := srv.newGRPCServer().Serve(listener); err != nil {
+		logrus.Error("Can not serve control API: ", err)
+	}
+}
+
+// newGRPCServer - calls of reflection are authorized too, so the schema is not disclosed to strangers
+func (srv *server) newGRPCServer() *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(srv.authorizeUnary), grpc.StreamInterceptor(srv.authorizeStream))
+	grpcServer.RegisterService(serviceDesc(), srv)
+	reflection.Register(grpcServer)
+	return grpcServer
+}
+
+func (srv *server) authorized(ctx context.Context) error {
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	values := incoming.Get(authorizationKey)
+	if len(values) != 1 || subtle.ConstantTimeCompare([]byte(values[0]), []byte("Bearer "+srv.token)) != 1 {
+		return errUnauthorized
+	}
+	return nil
+}
+
+func (srv *server) authorizeUnary(ctx context.Context, request interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := srv.authorized(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, request)
+}
+
+func (srv *server) authorizeStream(service interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := srv.authorized(stream.Context()); err != nil {
+		return err
+	}
+	return handler(service, stream)
+}
+
+// fromJSON - message of the service from json of the value, fields of value unknown to message are dropped
+func fromJSON(value interface{}, message proto.Message) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, message)
+}
+
+func (srv *server) startAttack(request *StartAttackRequest) (*TaskAck, error) {
+	ack := &TaskAck{}
+	if err := fromJSON(srv.controller.StartAttack(request.GetTask()), ack); err != nil {
+		return nil, err
+	}
+	return ack, nil
+}
+
+func (srv *server) cancelAttack(request *CancelAttackRequest) (*CancelAttackResponse, error) {
+	return &CancelAttackResponse{Cancelled: srv.controller.CancelAttack(request.GetTaskId())}, nil
+}
+
+func (srv *server) status(sampler *core.HeartbeatSampler) (*Status, error) {
+	status := &Status{}
+	if err := fromJSON(srv.bomber.FormHeartbeat(sampler), status); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+func (srv *server) streamMetrics(request *StreamMetricsRequest, stream grpc.ServerStream) error {
+	interval := time.Duration(request.GetIntervalMs()) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultMetricsInterval
+	}
+	sampler := core.NewHeartbeatSampler()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := srv.status(sampler)
+		if err != nil {
+			return err
+		}
+		if err := stream.SendMsg(status); err != nil {
+			return err
+		}
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+var errUnknownServer = errors.New("unknown server of control API")
+
+// unary - handler of unary method, request is decoded into new message of the input type
+func unary(method string, input func() proto.Message, call func(srv *server, request proto.Message) (proto.Message, error)) func(interface{}, context.Context, func(interface{}) error, grpc.UnaryServerInterceptor) (interface{}, error) {
+	return func(service interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+		srv, ok := service.(*server)
+		if !ok {
+			return nil, errUnknownServer
+		}
+		request := input()
+		if err := dec(request); err != nil {
+			return nil, err
+		}
+		handler := func(_ context.Context, request interface{}) (interface{}, error) {
+			return call(srv, request.(proto.Message))
+		}
+		if interceptor == nil {
+			return handler(ctx, request)
+		}
+		return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}, handler)
+	}
+}
+
+func serviceDesc() *grpc.ServiceDesc {
+	return &grpc.ServiceDesc{
+		ServiceName: serviceName,
+		HandlerType: (*interface{})(nil),
+		Methods: []grpc.MethodDesc{
+			{MethodName: "StartAttack", Handler: unary("StartAttack", func() proto.Message { return &StartAttackRequest{} }, func(srv *server, request proto.Message) (proto.Message, error) {
+				return srv.startAttack(request.(*StartAttackRequest))
+			})},
+			{MethodName: "CancelAttack", Handler: unary("CancelAttack", func() proto.Message { return &CancelAttackRequest{} }, func(srv *server, request proto.Message) (proto.Message, error) {
+				return srv.cancelAttack(request.(*CancelAttackRequest))
+			})},
+			{MethodName: "GetStatus", Handler: unary("GetStatus", func() proto.Message { return &GetStatusRequest{} }, func(srv *server, _ proto.Message) (proto.Message, error) {
+				return srv.status(core.NewHeartbeatSampler())
+			})},
+		},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "StreamMetrics",
+			ServerStreams: true,
+			Handler: func(service interface{}, stream grpc.ServerStream) error {
+				srv, ok := service.(*server)
+				if !ok {
+					return errUnknownServer
+				}
+				request := &StreamMetricsRequest{}
+				if err := stream.RecvMsg(request); err != nil {
+					return err
+				}
+				return srv.streamMetrics(request, stream)
+			},
+		}},
+		Metadata: File_control_control_proto.Path(),
+	}
+}
@@ -0,0 +1,115 @@
+package control
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/bomber-team/rest-bomber/handlers"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type fakeController struct {
+	cancelled []string
+}
+
+func (controller *fakeController) StartAttack(task []byte) *handlers.TaskAck {
+	return &handlers.TaskAck{TaskID: string(task), Accepted: true}
+}
+
+func (controller *fakeController) CancelAttack(taskId string) bool {
+	controller.cancelled = append(controller.cancelled, taskId)
+	return true
+}
+
+func dialTestServer(t *testing.T, controller Controller) (*grpc.ClientConn, func()) {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := (&server{controller: controller, token: "secret"}).newGRPCServer()
+	go grpcServer.Serve(listener)
+	conn, err := grpc.Dial("bufnet", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return listener.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+func withAuthorization(value string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), authorizationKey, value)
+}
+
+func TestControlAuthorization(t *testing.T) {
+	controller := &fakeController{}
+	conn, stop := dialTestServer(t, controller)
+	defer stop()
+	cases := []struct {
+		name string
+		ctx  context.Context
+		code codes.Code
+	}{
+		{"without token", context.Background(), codes.Unauthenticated},
+		{"wrong token", withAuthorization("Bearer guess"), codes.Unauthenticated},
+		{"token without scheme", withAuthorization("secret"), codes.Unauthenticated},
+		{"token", withAuthorization("Bearer secret"), codes.OK},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := &CancelAttackResponse{}
+			err := conn.Invoke(tc.ctx, "/"+serviceName+"/CancelAttack", &CancelAttackRequest{TaskId: "form-1"}, response)
+			if code := status.Code(err); code != tc.code {
+				t.Fatalf("code %v, expected %v: %v", code, tc.code, err)
+			}
+			if tc.code == codes.OK && !response.GetCancelled() {
+				t.Fatal("task is not cancelled")
+			}
+		})
+	}
+	if len(controller.cancelled) != 1 {
+		t.Fatalf("controller is called %d times, expected only by authorized call", len(controller.cancelled))
+	}
+}
+
+func TestControlStartAttack(t *testing.T) {
+	conn, stop := dialTestServer(t, &fakeController{})
+	defer stop()
+	ack := &TaskAck{}
+	if err := conn.Invoke(withAuthorization("Bearer secret"), "/"+serviceName+"/StartAttack", &StartAttackRequest{Task: []byte("form-1")}, ack); err != nil {
+		t.Fatal(err)
+	}
+	if ack.GetTaskId() != "form-1" || !ack.GetAccepted() {
+		t.Fatalf("ack %v", ack)
+	}
+}
+
+func TestControlStreamAuthorization(t *testing.T) {
+	conn, stop := dialTestServer(t, &fakeController{})
+	defer stop()
+	streams := []string{
+		"/" + serviceName + "/StreamMetrics",
+		"/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo",
+	}
+	for _, method := range streams {
+		stream, err := conn.NewStream(context.Background(), &grpc.StreamDesc{ServerStreams: true, ClientStreams: true}, method)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.RecvMsg(&Status{}); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("stream %s without token gives %v", method, err)
+		}
+	}
+}
+
+func TestServeWithoutToken(t *testing.T) {
+	// returns at once instead of serving without authorization
+	Serve("127.0.0.1:0", Disabled, &fakeController{}, nil)
+	Serve("127.0.0.1:0", "", &fakeController{}, nil)
+}
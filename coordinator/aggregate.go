@@ -0,0 +1,219 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// reports of bombers are published by nats sink
+	topicReport    = "bombers.server.task_report"
+	topicAggregate = "bombers.server.task_aggregate"
+	// tasks without reports for this time are forgotten, later reports start new aggregate
+	retention = 10 * time.Minute
+)
+
+// FleetReport - reports of all bombers of the task merged by the coordinator
+type FleetReport struct {
+	FormId          string   `json:"form_id"`
+	CoordinatorId   string   `json:"coordinator_id"`
+	Tenant          string   `json:"tenant,omitempty"`
+	ContractVersion string   `json:"contract_version"`
+	Bombers         []string `json:"bombers"`
+	// total of shards of sharded task, complete if all of them are reported
+	Shards          int64                        `json:"shards,omitempty"`
+	Complete        bool                         `json:"complete"`
+	Cancelled       bool                         `json:"cancelled,omitempty"`
+	Rps             int64                        `json:"rps,omitempty"` // sum of rps of reported shards
+	Errors          map[string]int64             `json:"errors"`
+	StatusClasses   *core.StatusClassesReport    `json:"status_classes,omitempty"`
+	Latency         core.LatencyReport           `json:"latency"`
+	LatencyByStatus map[int32]core.LatencyReport `json:"latency_by_status"`
+	Traffic         core.TrafficReport           `json:"traffic"`
+	// verdict of thresholds of all bombers, failed if any of them failed
+	ThresholdsPassed *bool `json:"thresholds_passed,omitempty"`
+}
+
+type task struct {
+	reports map[string]*core.AttackReport // by bomber id
+	last    time.Time
+	// reports came after the aggregate was published
+	dirty bool
+}
+
+type aggregator struct {
+	self      string
+	window    time.Duration
+	election  *election
+	publisher *nats_listener.Publisher
+	mutex     sync.Mutex
+	tasks     map[string]*task // by form id
+}
+
+/*
+Start - bomber is a candidate for coordinator, which merges reports of all bombers of each task
+and publishes fleet report of it. All candidates collect reports, so the new coordinator has them
+if the previous one is gone. Disabled by configuration by default
+*/
+func Start(bus broker.Broker, config *config.Configuration) {
+	if !config.Coordinator {
+		return
+	}
+	election := newElection(bus, config.CurrentServiceID, time.Duration(config.CoordinatorLease)*time.Second)
+	aggregator := &aggregator{
+		self:      config.CurrentServiceID,
+		window:    time.Duration(config.CoordinatorWindow) * time.Second,
+		election:  election,
+		publisher: nats_listener.NewPublisher(bus),
+		tasks:     map[string]*task{},
+	}
+	if err := nats_listener.NewSubscriber(bus, topicReport).Subscribe(aggregator.handle); err != nil {
+		logrus.Error("Can not subscribe coordinator to reports: ", err)
+		return
+	}
+	if err := election.start(bus); err != nil {
+		logrus.Error("Can not start election of coordinator: ", err)
+		return
+	}
+	go aggregator.run()
+}
+
+func (aggregator *aggregator) handle(message *broker.Message) {
+	var report core.AttackReport
+	if err := json.Unmarshal(message.Data, &report); err != nil {
+		logrus.Error("Can not unmarshal report for coordinator: ", err)
+		return
+	}
+	if report.FormId == "" || report.BomberId == "" {
+		return
+	}
+	aggregator.mutex.Lock()
+	defer aggregator.mutex.Unlock()
+	entry, ok := aggregator.tasks[report.FormId]
+	if !ok {
+		entry = &task{reports: map[string]*core.AttackReport{}}
+		aggregator.tasks[report.FormId] = entry
+	}
+	entry.reports[report.BomberId] = &report
+	entry.last = time.Now()
+	entry.dirty = true
+}
+
+/*
+run - aggregate is published as soon as all shards of sharded task are reported,
+reports of other tasks are merged when no more of them came for the window
+*/
+func (aggregator *aggregator) run() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		var ready []*FleetReport
+		aggregator.mutex.Lock()
+		for formId, entry := range aggregator.tasks {
+			if time.Since(entry.last) > retention {
+				delete(aggregator.tasks, formId)
+				continue
+			}
+			if !entry.dirty {
+				continue
+			}
+			if !entry.complete() && time.Since(entry.last) < aggregator.window {
+				continue
+			}
+			entry.dirty = false
+			ready = append(ready, aggregator.merge(formId, entry))
+		}
+		aggregator.mutex.Unlock()
+		if !aggregator.election.isLeader() {
+			continue
+		}
+		for _, fleet := range ready {
+			aggregator.publish(fleet)
+		}
+	}
+}
+
+// complete - all shards of sharded task are reported, bombers of the task are not known otherwise
+func (entry *task) complete() bool {
+	shards := map[int64]bool{}
+	var total int64
+	for _, report := range entry.reports {
+		if report.Shard != nil {
+			total = report.Shard.Total
+			shards[report.Shard.Index] = true
+		}
+	}
+	return total > 0 && int64(len(shards)) == total
+}
+
+func (aggregator *aggregator) merge(formId string, entry *task) *FleetReport {
+	fleet := &FleetReport{
+		FormId:          formId,
+		CoordinatorId:   aggregator.self,
+		ContractVersion: core.ContractVersion,
+		Errors:          map[string]int64{},
+		LatencyByStatus: map[int32]core.LatencyReport{},
+	}
+	var latencies []core.LatencyReport
+	byStatus := map[int32][]core.LatencyReport{}
+	var classes []*core.StatusClassesReport
+	for bomberId, report := range entry.reports {
+		fleet.Bombers = append(fleet.Bombers, bomberId)
+		fleet.Tenant = report.Tenant
+		fleet.Cancelled = fleet.Cancelled || report.Cancelled
+		if report.Shard != nil {
+			fleet.Shards = report.Shard.Total
+			fleet.Rps += report.Shard.Rps
+		}
+		for category, amount := range report.Errors {
+			fleet.Errors[category] += amount
+		}
+		latencies = append(latencies, report.Latency)
+		for status, latency := range report.LatencyByStatus {
+			byStatus[status] = append(byStatus[status], latency)
+		}
+		classes = append(classes, report.StatusClasses)
+		fleet.Traffic.BytesIn += report.Traffic.BytesIn
+		fleet.Traffic.BytesOut += report.Traffic.BytesOut
+		fleet.Traffic.InPerSecond += report.Traffic.InPerSecond
+		fleet.Traffic.OutPerSecond += report.Traffic.OutPerSecond
+		if report.ThresholdsPassed != nil {
+			passed := *report.ThresholdsPassed && (fleet.ThresholdsPassed == nil || *fleet.ThresholdsPassed)
+			fleet.ThresholdsPassed = &passed
+		}
+	}
+	sort.Strings(fleet.Bombers)
+	fleet.Complete = entry.complete()
+	fleet.StatusClasses = core.MergeStatusClasses(classes)
+	var err error
+	if fleet.Latency, err = core.MergeLatencyReports(latencies); err != nil {
+		logrus.Error("Can not merge latency of task ", formId, ": ", err)
+	}
+	for status, reports := range byStatus {
+		if fleet.LatencyByStatus[status], err = core.MergeLatencyReports(reports); err != nil {
+			logrus.Error("Can not merge latency of task ", formId, " by status: ", err)
+		}
+	}
+	return fleet
+}
+
+func (aggregator *aggregator) publish(fleet *FleetReport) {
+	data, err := json.Marshal(fleet)
+	if err != nil {
+		logrus.Error("Can not marshal fleet report: ", err)
+		return
+	}
+	if err := aggregator.publisher.PublishNewMessage(topicAggregate, data); err != nil {
+		logrus.Error("Can not publish fleet report: ", err)
+		return
+	}
+	logrus.Info("Fleet report of task ", fleet.FormId, " was published, bombers: ", len(fleet.Bombers))
+}
@@ -0,0 +1,112 @@
+package coordinator
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const topicLease = "bombers.coordinator.lease"
+
+// Lease - candidate is alive until ttl since the lease came, leases are renewed three times per ttl
+type Lease struct {
+	BomberId string `json:"bomber_id"`
+	TtlMs    int64  `json:"ttl_ms"`
+}
+
+/*
+election - coordinator is the candidate with the least id of all candidates with alive leases,
+so all candidates elect the same one without any store. Expiry is counted by time of the receiver,
+clocks of bombers do not matter
+*/
+type election struct {
+	self       string
+	ttl        time.Duration
+	publisher  *nats_listener.Publisher
+	mutex      sync.Mutex
+	candidates map[string]time.Time // expiry of the lease by bomber id
+	leader     string
+}
+
+func newElection(bus broker.Broker, self string, ttl time.Duration) *election {
+	return &election{
+		self:       self,
+		ttl:        ttl,
+		publisher:  nats_listener.NewPublisher(bus),
+		candidates: map[string]time.Time{},
+	}
+}
+
+func (election *election) start(bus broker.Broker) error {
+	if err := nats_listener.NewSubscriber(bus, topicLease).Subscribe(election.handle); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(election.ttl / 3)
+		defer ticker.Stop()
+		for ; true; <-ticker.C {
+			election.renew()
+		}
+	}()
+	return nil
+}
+
+func (election *election) renew() {
+	data, err := json.Marshal(Lease{BomberId: election.self, TtlMs: election.ttl.Milliseconds()})
+	if err != nil {
+		logrus.Error("Can not marshal lease of coordinator: ", err)
+		return
+	}
+	if err := election.publisher.PublishNewMessage(topicLease, data); err != nil {
+		logrus.Error("Can not publish lease of coordinator: ", err)
+	}
+	election.elect()
+}
+
+func (election *election) handle(message *broker.Message) {
+	var lease Lease
+	if err := json.Unmarshal(message.Data, &lease); err != nil || lease.BomberId == "" {
+		logrus.Error("Can not unmarshal lease of coordinator: ", err)
+		return
+	}
+	election.mutex.Lock()
+	election.candidates[lease.BomberId] = time.Now().Add(time.Duration(lease.TtlMs) * time.Millisecond)
+	election.mutex.Unlock()
+	election.elect()
+}
+
+// elect - bomber itself is a candidate all the time, expired candidates are forgotten
+func (election *election) elect() {
+	election.mutex.Lock()
+	defer election.mutex.Unlock()
+	now := time.Now()
+	leader := election.self
+	for id, expiry := range election.candidates {
+		if now.After(expiry) {
+			delete(election.candidates, id)
+			continue
+		}
+		if id < leader {
+			leader = id
+		}
+	}
+	if leader == election.leader {
+		return
+	}
+	election.leader = leader
+	if leader == election.self {
+		logrus.Info("Bomber is elected as coordinator of results")
+	} else {
+		logrus.Info("Bomber ", leader, " is coordinator of results")
+	}
+}
+
+func (election *election) isLeader() bool {
+	election.mutex.Lock()
+	defer election.mutex.Unlock()
+	return election.leader == election.self
+}
@@ -0,0 +1,79 @@
+package core
+
+import "time"
+
+const defaultApdexSatisfied = 500 * time.Millisecond
+
+type ApdexOptions struct {
+	// requests faster than this are satisfied, 500 if empty
+	SatisfiedMs int64 `json:"satisfied_ms,omitempty"`
+	// requests faster than this are tolerating, four times of satisfied if empty
+	ToleratingMs int64 `json:"tolerating_ms,omitempty"`
+}
+
+type ApdexReport struct {
+	Score        float64 `json:"score"`
+	SatisfiedMs  int64   `json:"satisfied_ms"`
+	ToleratingMs int64   `json:"tolerating_ms"`
+	Satisfied    int64   `json:"satisfied"`
+	Tolerating   int64   `json:"tolerating"`
+	// slow requests, failed requests and responses with errors
+	Frustrated int64 `json:"frustrated"`
+}
+
+type apdexStats struct {
+	satisfied  time.Duration
+	tolerating time.Duration
+	report     ApdexReport
+}
+
+func newApdexStats(options ApdexOptions) apdexStats {
+	satisfied := time.Duration(options.SatisfiedMs) * time.Millisecond
+	if satisfied <= 0 {
+		satisfied = defaultApdexSatisfied
+	}
+	tolerating := time.Duration(options.ToleratingMs) * time.Millisecond
+	if tolerating <= satisfied {
+		tolerating = 4 * satisfied
+	}
+	return apdexStats{
+		satisfied:  satisfied,
+		tolerating: tolerating,
+		report: ApdexReport{
+			SatisfiedMs:  satisfied.Milliseconds(),
+			ToleratingMs: tolerating.Milliseconds(),
+		},
+	}
+}
+
+func (stats *apdexStats) add(latency int64, failed bool) {
+	switch {
+	case failed:
+		stats.report.Frustrated++
+	case time.Duration(latency) <= stats.satisfied:
+		stats.report.Satisfied++
+	case time.Duration(latency) <= stats.tolerating:
+		stats.report.Tolerating++
+	default:
+		stats.report.Frustrated++
+	}
+}
+
+func (stats *apdexStats) addFailed() {
+	stats.report.Frustrated++
+}
+
+func (stats *apdexStats) score() float64 {
+	report := stats.report
+	total := report.Satisfied + report.Tolerating + report.Frustrated
+	if total == 0 {
+		return 0
+	}
+	return (float64(report.Satisfied) + float64(report.Tolerating)/2) / float64(total)
+}
+
+func (stats *apdexStats) finish() ApdexReport {
+	report := stats.report
+	report.Score = stats.score()
+	return report
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"github.com/valyala/fasthttp"
+)
+
+// kinds of failed assertions
+const (
+	AssertionStatus       = "status"
+	AssertionBodyContains = "body_contains"
+	AssertionJSON         = "json"
+	AssertionHeader       = "header"
+)
+
+/*
+AssertionOptions - checks of responses, which passed transport. Response failed the first of them
+is counted as assertion failure, not as error of transport. Assertions are disabled if empty
+*/
+type AssertionOptions struct {
+	// allowed statuses of response
+	Statuses     []int  `json:"statuses,omitempty"`
+	BodyContains string `json:"body_contains,omitempty"`
+	// expected values by json paths, values are compared as they are extracted
+	JSON map[string]string `json:"json,omitempty"`
+	// headers which have to be present in response
+	Headers []string `json:"headers,omitempty"`
+	// regexes which values of headers have to match, absent header fails them
+	HeaderValues map[string]string `json:"header_values,omitempty"`
+}
+
+func (options AssertionOptions) enabled() bool {
+	return len(options.Statuses) > 0 || options.BodyContains != "" || len(options.JSON) > 0 || len(options.Headers) > 0 ||
+		len(options.HeaderValues) > 0
+}
+
+// assertions - compiled assertions, nil checks nothing
+type assertions struct {
+	statuses map[int]bool
+	contains []byte
+	paths    map[string]jsonPath
+	expected map[string]string // by json path
+	headers  []string
+	values   map[string]*regexp.Regexp // by header
+}
+
+func compileAssertions(options AssertionOptions) (*assertions, error) {
+	if !options.enabled() {
+		return nil, nil
+	}
+	compiled := &assertions{
+		statuses: map[int]bool{},
+		contains: []byte(options.BodyContains),
+		paths:    map[string]jsonPath{},
+		expected: options.JSON,
+		headers:  options.Headers,
+		values:   map[string]*regexp.Regexp{},
+	}
+	for _, status := range options.Statuses {
+		compiled.statuses[status] = true
+	}
+	for path := range options.JSON {
+		parsed, err := compileJSONPath(path)
+		if err != nil {
+			return nil, err
+		}
+		compiled.paths[path] = parsed
+	}
+	for header, pattern := range options.HeaderValues {
+		parsed, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled.values[header] = parsed
+	}
+	return compiled, nil
+}
+
+// checksStatus - statuses of assertions decide whether the response failed instead of its status class
+func (assertions *assertions) checksStatus() bool {
+	return assertions != nil && len(assertions.statuses) > 0
+}
+
+// check - kind of the first failed assertion, empty if the response passed all of them
+func (assertions *assertions) check(response *fasthttp.Response) string {
+	if assertions == nil {
+		return ""
+	}
+	if len(assertions.statuses) > 0 && !assertions.statuses[response.StatusCode()] {
+		return AssertionStatus
+	}
+	for _, header := range assertions.headers {
+		if len(response.Header.Peek(header)) == 0 {
+			return AssertionHeader
+		}
+	}
+	for header, pattern := range assertions.values {
+		if value := response.Header.Peek(header); len(value) == 0 || !pattern.Match(value) {
+			return AssertionHeader
+		}
+	}
+	if len(assertions.contains) == 0 && len(assertions.paths) == 0 {
+		return ""
+	}
+	body, err := decodeBody(response)
+	if err != nil {
+		return AssertionBodyContains
+	}
+	if len(assertions.contains) > 0 && !bytes.Contains(body, assertions.contains) {
+		return AssertionBodyContains
+	}
+	if len(assertions.paths) == 0 {
+		return ""
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return AssertionJSON
+	}
+	for path, parsed := range assertions.paths {
+		value, ok := parsed.lookup(document)
+		if !ok || extractValue(value) != assertions.expected[path] {
+			return AssertionJSON
+		}
+	}
+	return ""
+}
+
+type assertionStats struct {
+	checked  int64
+	failed   int64
+	failures map[string]int64 // by kind of the failed assertion
+}
+
+// AssertionsReport - responses checked by assertions, failed ones are counted by kind of the first failed assertion
+type AssertionsReport struct {
+	Checked  int64            `json:"checked"`
+	Failed   int64            `json:"failed"`
+	Failures map[string]int64 `json:"failures"`
+}
+
+// recordAssertion - must be called under results lock of the attack
+func (attack *Attack) recordAssertion(result SliceResult) {
+	if !result.Asserted {
+		return
+	}
+	if attack.resultAssertions.failures == nil {
+		attack.resultAssertions.failures = map[string]int64{}
+	}
+	attack.resultAssertions.checked++
+	if result.AssertionFailed != "" {
+		attack.resultAssertions.failed++
+		attack.resultAssertions.failures[result.AssertionFailed]++
+	}
+}
+
+// assertionsReport - nil if no response was checked
+func (attack *Attack) assertionsReport() *AssertionsReport {
+	if attack.resultAssertions.checked == 0 {
+		return nil
+	}
+	return &AssertionsReport{
+		Checked:  attack.resultAssertions.checked,
+		Failed:   attack.resultAssertions.failed,
+		Failures: attack.resultAssertions.failures,
+	}
+}
+
+func validateAssertions(field string, options AssertionOptions, add func(field string, reason string)) {
+	for _, status := range options.Statuses {
+		if status < 100 || status > 599 {
+			add(field+".statuses", "must be http statuses from 100 to 599")
+			break
+		}
+	}
+	for path := range options.JSON {
+		if _, err := compileJSONPath(path); err != nil {
+			add(field+".json."+path, err.Error())
+		}
+	}
+	for header, pattern := range options.HeaderValues {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add(field+".header_values."+header, err.Error())
+		}
+	}
+}
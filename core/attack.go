@@ -0,0 +1,174 @@
+package core
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/transport"
+	"github.com/jamiealquiza/tachymeter"
+	"github.com/valyala/fasthttp"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+/*
+Attack - task of the bomber from its preparing until its results, all requests and results of it
+belong to the attack only. Bomber of several attacks at once runs each of them by its own Attack
+*/
+type Attack struct {
+	core     *Core
+	prepared time.Time
+	// task as it was verified and prepared, the attack is started by it
+	task rest_contracts.Task
+	// results are saved by workers and read by reports under the lock
+	results                sync.Mutex
+	state                  attackState
+	progress               progressState
+	dataAttack             []*fasthttp.Request
+	resultsAttack          map[int32]int64 // amount statuses per status
+	resultTimeouts         int64           // amount time out requests
+	resultTimesForRequests []int64         // amount ms for one request
+	resultLatency          *hdrhistogram.Histogram
+	resultLatencyByStatus  map[int32]*hdrhistogram.Histogram
+	resultErrors           map[string]int64 // by category
+	resultTimeline         *timeline
+	attackReady            bool // ready for attack?
+	formId                 string
+	tahometr               *tachymeter.Tachymeter
+	resultRedirects        int64 // amount followed redirect hops
+	resultRedirectsLimit   int64 // amount requests stopped by limit of redirects
+	resultBodyRawBytes     int64 // size of request bodies before compression
+	resultBodyEncodedBytes int64 // size of request bodies after compression
+	resultWireBytes        int64 // size of response bodies on the wire
+	resultDecodedBytes     int64 // size of response bodies after decompression
+	resultDecodeErrors     int64 // amount responses, which can not be decompressed
+	resultRetries          retriesStats
+	resultContinue         continueStats
+	resultTracing          tracingStats
+	resultEndpoints        map[string]*endpointStats // by method and path
+	resultApdex            apdexStats
+	resultSummary          summaryStats
+	resultMessages         *errorMessages
+	resultFailures         *failureSamples // nil if task does not ask for them
+	resultBodies           *bodySamples    // nil if task does not ask for them
+	resultSamples          *samplesWriter  // nil if task does not ask for samples
+	attackTargetRps        int64
+	baseline               *AttackReport // report of previous run to compare with
+	attackElapsed          time.Duration
+	resultSkipped          int64 // amount requests not sent because of open circuit breaker
+	resultPrewarm          prewarmStats
+	resultPhases           *phaseMeters
+	resultWebsocket        *websocketStats
+	resultSSE              *sseStats
+	resultGRPC             *grpcStats
+	grpcMethod             protoreflect.MethodDescriptor
+	resultRaw              *rawStats
+	resultRawNetwork       string
+	resultScenario         *scenarioStats // nil if task has no scenario
+	scenarioSteps          []scenarioStep
+	resultAssertions       assertionStats
+	assertions             *assertions   // nil if task has no assertions
+	resultHeaders          []headerStats // by capture_headers of the task
+	resultLogin            loginStats
+	resultCSRF             csrfStats
+	resultSetup            []HookReport
+	resultTeardown         []HookReport
+	hookVariables          map[string]string   // extracted by setup
+	teardownSteps          []scenarioStep      // nil after teardown
+	virtualUsers           int64               // amount of created virtual users, the last id of them
+	inFlight               int64               // amount requests of the attack waiting for response now
+	setupTask              rest_contracts.Task // sharded task of requests before the attack
+	loginShared            *loginSession       // session of login per bomber
+	oauth2                 *oauth2Token        // nil if task has no oauth2
+	jwt                    *jwtSigner          // nil if task has no jwt
+	sigv4                  *sigv4Signer        // nil if task has no sigv4
+	hmacSigning            *hmacSigner         // nil if task has no hmac signing
+	breaker                *circuitBreaker
+	options                *TaskOptions
+	dialer                 *transport.Dialer
+}
+
+// attacks - attacks of the bomber by form id of their tasks, from preparing until the end of their progress
+type attacks struct {
+	mutex    sync.Mutex
+	byFormId map[string]*Attack
+}
+
+/*
+PreparingData - new attack of the task, which is known to the bomber until EndProgress of it.
+Cancel of the task can come while it is prepared
+*/
+func (core *Core) PreparingData(task rest_contracts.Task) (*Attack, error) {
+	attack := &Attack{core: core, formId: task.FormId, prepared: time.Now(), task: task}
+	core.attacks.mutex.Lock()
+	core.attacks.byFormId[task.FormId] = attack
+	core.attacks.mutex.Unlock()
+	if err := attack.prepare(task); err != nil {
+		return nil, err
+	}
+	return attack, nil
+}
+
+/*
+UnavailableError - preparing failed by a service the task depends on (oauth2 server, baseline, instance metadata),
+the same task may be prepared later
+*/
+type UnavailableError struct {
+	Err error
+}
+
+func (err *UnavailableError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *UnavailableError) Unwrap() error {
+	return err.Err
+}
+
+// Unavailable - true if preparing failed by UnavailableError
+func Unavailable(err error) bool {
+	var unavailable *UnavailableError
+	return errors.As(err, &unavailable)
+}
+
+// Attack - nil if the bomber does not prepare or attack by the task
+func (core *Core) Attack(formId string) *Attack {
+	core.attacks.mutex.Lock()
+	defer core.attacks.mutex.Unlock()
+	return core.attacks.byFormId[formId]
+}
+
+// Attacks - attacks of the bomber in order of their preparing
+func (core *Core) Attacks() []*Attack {
+	core.attacks.mutex.Lock()
+	all := make([]*Attack, 0, len(core.attacks.byFormId))
+	for _, attack := range core.attacks.byFormId {
+		all = append(all, attack)
+	}
+	core.attacks.mutex.Unlock()
+	sort.Slice(all, func(first int, second int) bool {
+		return all[first].prepared.Before(all[second].prepared)
+	})
+	return all
+}
+
+func (core *Core) forget(attack *Attack) {
+	core.attacks.mutex.Lock()
+	defer core.attacks.mutex.Unlock()
+	if core.attacks.byFormId[attack.formId] == attack {
+		delete(core.attacks.byFormId, attack.formId)
+	}
+}
+
+// FormId - form id of the task of the attack
+func (attack *Attack) FormId() string {
+	return attack.formId
+}
+
+// Task - task the attack was prepared by
+func (attack *Attack) Task() rest_contracts.Task {
+	return attack.task
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"encoding/base64"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+type BasicAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type Credentials struct {
+	Basic  *BasicAuth `json:"basic,omitempty"`
+	Bearer string     `json:"bearer,omitempty"`
+}
+
+type EndpointAuth struct {
+	// host or host with path prefix, like api.example.com/admin
+	Match string `json:"match"`
+	Credentials
+	// do not send credentials to this endpoint
+	Anonymous bool `json:"anonymous,omitempty"`
+}
+
+type AuthOptions struct {
+	Credentials
+	// overrides of credentials, the longest match wins
+	Endpoints []EndpointAuth `json:"endpoints,omitempty"`
+	// bearer token of client credentials grant, it replaces credentials in http mode
+	OAuth2 *OAuth2Options `json:"oauth2,omitempty"`
+}
+
+func (credentials Credentials) authorization() string {
+	if credentials.Basic != nil {
+		pair := credentials.Basic.Username + ":" + credentials.Basic.Password
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(pair))
+	}
+	if credentials.Bearer != "" {
+		return "Bearer " + credentials.Bearer
+	}
+	return ""
+}
+
+const redactedSecret = "REDACTED"
+
+func (credentials Credentials) redacted() Credentials {
+	if credentials.Basic != nil {
+		credentials.Basic = &BasicAuth{Username: credentials.Basic.Username, Password: redactedSecret}
+	}
+	if credentials.Bearer != "" {
+		credentials.Bearer = redactedSecret
+	}
+	return credentials
+}
+
+// Redacted - copy of options without passwords and tokens, safe to store
+func (options AuthOptions) Redacted() AuthOptions {
+	options.Credentials = options.Credentials.redacted()
+	options.OAuth2 = options.OAuth2.redacted()
+	endpoints := make([]EndpointAuth, len(options.Endpoints))
+	for index, endpoint := range options.Endpoints {
+		endpoint.Credentials = endpoint.Credentials.redacted()
+		endpoints[index] = endpoint
+	}
+	if options.Endpoints != nil {
+		options.Endpoints = endpoints
+	}
+	return options
+}
+
+func (options AuthOptions) configured() bool {
+	return options.Basic != nil || options.Bearer != "" || len(options.Endpoints) > 0
+}
+
+/*
+authorization - value of Authorization header for host and path,
+empty if credentials should not be sent
+*/
+func (options AuthOptions) authorization(host string, path string) string {
+	hostname := host
+	if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+		hostname = withoutPort
+	}
+	credentials := options.Credentials
+	matched := -1
+	for _, endpoint := range options.Endpoints {
+		matchHost := endpoint.Match
+		matchPath := ""
+		if slash := strings.Index(endpoint.Match, "/"); slash >= 0 {
+			matchHost = endpoint.Match[:slash]
+			matchPath = endpoint.Match[slash:]
+		}
+		hostMatched := strings.EqualFold(host, matchHost) || strings.EqualFold(hostname, matchHost)
+		if !hostMatched || !strings.HasPrefix(path, matchPath) {
+			continue
+		}
+		if len(endpoint.Match) > matched {
+			matched = len(endpoint.Match)
+			credentials = endpoint.Credentials
+			if endpoint.Anonymous {
+				credentials = Credentials{}
+			}
+		}
+	}
+	return credentials.authorization()
+}
+
+// apply - sets Authorization header of request by its endpoint, header of schema is replaced
+func (options AuthOptions) apply(request *fasthttp.Request) {
+	if !options.configured() {
+		return
+	}
+	uri := request.URI()
+	value := options.authorization(string(uri.Host()), string(uri.Path()))
+	if value == "" {
+		request.Header.Del(fasthttp.HeaderAuthorization)
+		return
+	}
+	request.Header.Set(fasthttp.HeaderAuthorization, value)
+}
+
+// addressAuthorization - value of Authorization header for address of modes without fasthttp requests
+func (options AuthOptions) addressAuthorization(address string) string {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return options.Credentials.authorization()
+	}
+	return options.authorization(parsed.Host, parsed.Path)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultBaselineTolerance      = 10 // percent
+	defaultBaselineErrorTolerance = 1  // percentage points
+	baselineFetchTimeout          = 10 * time.Second
+)
+
+var ErrBaselineFetch = errors.New("can not fetch report of baseline")
+
+// metrics compared with baseline, latency is regressed when it grows, rps when it falls
+var baselineMetrics = []string{"mean", "p50", "p90", "p95", "p99", "error_rate", "rps"}
+
+type BaselineOptions struct {
+	// report of the previous run as it was published
+	Report *AttackReport `json:"report,omitempty"`
+	// url of report of the previous run or of its report file, used if report is empty
+	URL string `json:"url,omitempty"`
+	// allowed growth of latency and fall of rps in percent, 10 if empty
+	TolerancePercent float64 `json:"tolerance_percent,omitempty"`
+	// allowed growth of error rate in percentage points, 1 if empty
+	ErrorTolerance float64 `json:"error_tolerance,omitempty"`
+}
+
+type BaselineDelta struct {
+	Metric   string  `json:"metric"`
+	Baseline float64 `json:"baseline"`
+	Actual   float64 `json:"actual"`
+	// change in percent of baseline, in percentage points for error_rate
+	Delta     float64 `json:"delta"`
+	Regressed bool    `json:"regressed"`
+}
+
+type BaselineReport struct {
+	FormId    string          `json:"form_id"`
+	Metrics   []BaselineDelta `json:"metrics"`
+	Regressed bool            `json:"regressed"`
+}
+
+func (options BaselineOptions) enabled() bool {
+	return options.Report != nil || options.URL != ""
+}
+
+// loadBaseline - report of baseline embedded into task or fetched by url
+func loadBaseline(options BaselineOptions) (*AttackReport, error) {
+	if options.Report != nil {
+		return options.Report, nil
+	}
+	client := &http.Client{Timeout: baselineFetchTimeout}
+	response, err := client.Get(options.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, ErrBaselineFetch
+	}
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	// report files keep the report next to the task and the result
+	var record struct {
+		Report *AttackReport `json:"report"`
+	}
+	if err := json.Unmarshal(data, &record); err == nil && record.Report != nil {
+		return record.Report, nil
+	}
+	report := &AttackReport{}
+	if err := json.Unmarshal(data, report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// baselineSummary - numbers of previous run, its duration is taken from the timeline
+func baselineSummary(report *AttackReport) attackSummary {
+	summary := attackSummary{
+		report:  report,
+		elapsed: time.Duration(len(report.Timeline.Buckets)) * time.Duration(report.Timeline.IntervalMs) * time.Millisecond,
+	}
+	for _, bucket := range report.Timeline.Buckets {
+		summary.requests += bucket.Requests
+		summary.failed += bucket.Errors + bucket.Timeouts
+	}
+	return summary
+}
+
+func compareBaseline(options BaselineOptions, baseline *AttackReport, current attackSummary) *BaselineReport {
+	tolerance := options.TolerancePercent
+	if tolerance <= 0 {
+		tolerance = defaultBaselineTolerance
+	}
+	errorTolerance := options.ErrorTolerance
+	if errorTolerance <= 0 {
+		errorTolerance = defaultBaselineErrorTolerance
+	}
+	previous := baselineSummary(baseline)
+	report := &BaselineReport{
+		FormId:  baseline.FormId,
+		Metrics: make([]BaselineDelta, 0, len(baselineMetrics)),
+	}
+	for _, metric := range baselineMetrics {
+		before, _ := thresholdValue(previous, metric)
+		actual, _ := thresholdValue(current, metric)
+		delta := BaselineDelta{Metric: metric, Baseline: before, Actual: actual}
+		switch metric {
+		case "error_rate":
+			delta.Delta = actual - before
+			delta.Regressed = delta.Delta > errorTolerance
+		case "rps":
+			delta.Delta = percentChange(before, actual)
+			delta.Regressed = delta.Delta < -tolerance
+		default:
+			delta.Delta = percentChange(before, actual)
+			delta.Regressed = delta.Delta > tolerance
+		}
+		report.Regressed = report.Regressed || delta.Regressed
+		report.Metrics = append(report.Metrics, delta)
+	}
+	return report
+}
+
+func percentChange(before float64, actual float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (actual - before) * 100 / before
+}
@@ -0,0 +1,62 @@
+package core
+
+import (
+	"math/rand"
+	"sync"
+
+	"github.com/valyala/fasthttp"
+)
+
+const defaultBodySamplesKept = 20
+
+type BodySamplesOptions struct {
+	// fraction of successful responses captured from 0 to 1, disabled if empty
+	Rate float64 `json:"rate,omitempty"`
+	// bodies are truncated to this size, 1024 if empty
+	MaxBodyBytes int `json:"max_body_bytes,omitempty"`
+	// limit of captured responses of the attack, 20 if empty
+	Keep int `json:"keep,omitempty"`
+}
+
+// bodySamples - random successful responses of the attack, used by workers concurrently
+type bodySamples struct {
+	mutex   sync.Mutex
+	options BodySamplesOptions
+	samples []ResponseSample
+}
+
+func newBodySamples(options BodySamplesOptions) *bodySamples {
+	if options.Rate <= 0 {
+		return nil
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = defaultSampleBody
+	}
+	if options.Keep <= 0 {
+		options.Keep = defaultBodySamplesKept
+	}
+	return &bodySamples{options: options}
+}
+
+func (samples *bodySamples) add(request *fasthttp.Request, response *fasthttp.Response, latency int64) {
+	if samples == nil || response.StatusCode() >= fasthttp.StatusBadRequest || rand.Float64() >= samples.options.Rate {
+		return
+	}
+	samples.mutex.Lock()
+	defer samples.mutex.Unlock()
+	if len(samples.samples) >= samples.options.Keep {
+		return
+	}
+	sample := captureResponse(request, response, samples.options.MaxBodyBytes)
+	sample.LatencyNs = latency
+	samples.samples = append(samples.samples, sample)
+}
+
+func (samples *bodySamples) report() []ResponseSample {
+	if samples == nil {
+		return nil
+	}
+	samples.mutex.Lock()
+	defer samples.mutex.Unlock()
+	return samples.samples
+}
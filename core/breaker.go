@@ -0,0 +1,152 @@
+package core
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half_open"
+)
+
+const (
+	defaultFailureThreshold = 50
+	defaultBreakerOpen      = 5 * time.Second
+)
+
+type CircuitBreakerOptions struct {
+	Enabled bool `json:"enabled"`
+	// amount of consecutive failures, which opens the breaker
+	FailureThreshold int   `json:"failure_threshold,omitempty"`
+	OpenMs           int64 `json:"open_ms,omitempty"`
+	// amount of requests let through in half-open state
+	HalfOpenProbes int `json:"half_open_probes,omitempty"`
+}
+
+type BreakerPeriod struct {
+	State   string `json:"state"`
+	StartMs int64  `json:"start_ms"` // offset from start of the attack
+	EndMs   int64  `json:"end_ms"`
+}
+
+/*
+circuitBreaker - stops sending requests to the target after consecutive failures,
+and probes it after a pause. Shared by all workers of the attack
+*/
+type circuitBreaker struct {
+	mutex       sync.Mutex
+	options     CircuitBreakerOptions
+	state       string
+	failures    int
+	probes      int
+	stateSince  time.Time
+	attackStart time.Time
+	periods     []BreakerPeriod
+}
+
+func newCircuitBreaker(options CircuitBreakerOptions) *circuitBreaker {
+	if options.FailureThreshold <= 0 {
+		options.FailureThreshold = defaultFailureThreshold
+	}
+	if options.OpenMs <= 0 {
+		options.OpenMs = defaultBreakerOpen.Milliseconds()
+	}
+	if options.HalfOpenProbes <= 0 {
+		options.HalfOpenProbes = 1
+	}
+	now := time.Now()
+	return &circuitBreaker{
+		options:     options,
+		state:       breakerClosed,
+		stateSince:  now,
+		attackStart: now,
+	}
+}
+
+// allow - can the next request be sent to the target
+func (breaker *circuitBreaker) allow() bool {
+	if breaker == nil {
+		return true
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	switch breaker.state {
+	case breakerOpen:
+		if time.Since(breaker.stateSince) < time.Duration(breaker.options.OpenMs)*time.Millisecond {
+			return false
+		}
+		breaker.changeState(breakerHalfOpen)
+		breaker.probes = 1
+		return true
+	case breakerHalfOpen:
+		if breaker.probes >= breaker.options.HalfOpenProbes {
+			return false
+		}
+		breaker.probes++
+		return true
+	default:
+		return true
+	}
+}
+
+func (breaker *circuitBreaker) record(success bool) {
+	if breaker == nil {
+		return
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	switch breaker.state {
+	case breakerHalfOpen:
+		if success {
+			breaker.failures = 0
+			breaker.changeState(breakerClosed)
+		} else {
+			breaker.changeState(breakerOpen)
+		}
+	case breakerClosed:
+		if success {
+			breaker.failures = 0
+			return
+		}
+		breaker.failures++
+		if breaker.failures >= breaker.options.FailureThreshold {
+			breaker.changeState(breakerOpen)
+		}
+	}
+}
+
+func (breaker *circuitBreaker) changeState(state string) {
+	now := time.Now()
+	if breaker.state != breakerClosed {
+		breaker.periods = append(breaker.periods, BreakerPeriod{
+			State:   breaker.state,
+			StartMs: breaker.stateSince.Sub(breaker.attackStart).Milliseconds(),
+			EndMs:   now.Sub(breaker.attackStart).Milliseconds(),
+		})
+	}
+	logrus.Info("Circuit breaker changed state from ", breaker.state, " to ", state)
+	breaker.state = state
+	breaker.stateSince = now
+}
+
+// finish - closes the current period and returns all periods of not closed state
+func (breaker *circuitBreaker) finish() []BreakerPeriod {
+	if breaker == nil {
+		return nil
+	}
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	periods := append([]BreakerPeriod(nil), breaker.periods...)
+	if breaker.state != breakerClosed {
+		periods = append(periods, BreakerPeriod{
+			State:   breaker.state,
+			StartMs: breaker.stateSince.Sub(breaker.attackStart).Milliseconds(),
+			EndMs:   time.Since(breaker.attackStart).Milliseconds(),
+		})
+	}
+	return periods
+}
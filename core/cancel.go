@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+)
+
+// attackState - context of the running attack, it is cancelled by Cancel or at the end of the attack
+type attackState struct {
+	mutex    sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	running  bool
+	finished bool
+	// form id of the cancelled task, cancel can come while the task is prepared
+	cancelled string
+	// form id of the task which preempted the cancelled one
+	preemptedBy string
+	// plan of the running attack, progress of it is published by heartbeats
+	formId   string
+	started  time.Time
+	duration time.Duration
+	requests int64
+	// reason why the attack could not run, nil if it ran
+	failure error
+}
+
+func (attack *Attack) fail(err error) {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.failure = err
+}
+
+// Failure - reason why the attack could not run, nil if it ran
+func (attack *Attack) Failure() error {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.failure
+}
+
+func (attack *Attack) beginAttack(task rest_contracts.Task) context.Context {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.ctx, state.cancel = context.WithCancel(context.Background())
+	state.running = true
+	state.finished = false
+	state.formId = task.FormId
+	state.started = time.Now()
+	state.duration = time.Duration(task.Script.Config.Time) * time.Second
+	state.requests = task.Script.Config.Rps * task.Script.Config.Time
+	if state.cancelled != "" && state.cancelled == attack.formId {
+		state.cancel()
+	}
+	return state.ctx
+}
+
+func (attack *Attack) endAttack() {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	state.running = false
+	state.finished = true
+	state.cancel()
+}
+
+// attackContext - context of the attack, it is done when the attack is cancelled
+func (attack *Attack) attackContext() context.Context {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.ctx == nil {
+		return context.Background()
+	}
+	return state.ctx
+}
+
+/*
+Cancel - stops attack of the task, requests in flight are completed and saved as partial results.
+False if the bomber is not preparing or attacking by the task
+*/
+func (core *Core) Cancel(formId string) bool {
+	attack := core.Attack(formId)
+	return attack != nil && attack.cancelAttack("")
+}
+
+// Preempt - cancels attack of the task for the task of higher priority, partial results of it are published as after Cancel
+func (core *Core) Preempt(formId string, by string) bool {
+	attack := core.Attack(formId)
+	return attack != nil && attack.cancelAttack(by)
+}
+
+func (attack *Attack) cancelAttack(preemptedBy string) bool {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.finished {
+		return false
+	}
+	state.cancelled = attack.formId
+	state.preemptedBy = preemptedBy
+	if state.running {
+		state.cancel()
+	}
+	return true
+}
+
+// PreemptedBy - form id of the task which preempted the attack, empty if it was not preempted
+func (attack *Attack) PreemptedBy() string {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if state.cancelled == "" || state.cancelled != attack.formId {
+		return ""
+	}
+	return state.preemptedBy
+}
+
+// Cancelled - whether the attack was cancelled, results of it are partial then
+func (attack *Attack) Cancelled() bool {
+	state := &attack.state
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	return state.cancelled != "" && state.cancelled == attack.formId
+}
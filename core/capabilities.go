@@ -0,0 +1,114 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/sirupsen/logrus"
+)
+
+const topicCapabilities = "bombers.server.capabilities"
+
+// Version - version of the bomber, set at build by -ldflags "-X github.com/bomber-team/rest-bomber/core.Version=<version>"
+var Version = "dev"
+
+/*
+Capabilities - published once at start of the bomber, orchestrator schedules to it only
+tasks which it can execute
+*/
+type Capabilities struct {
+	BomberId        string   `json:"bomber_id"`
+	Tenant          string   `json:"tenant,omitempty"`
+	BomberIp        string   `json:"bomber_ip"`
+	Version         string   `json:"version"`
+	ContractVersion string   `json:"contract_version"`
+	Modes           []string `json:"modes"`
+	Protocols       []string `json:"protocols"`
+	Generators      []string `json:"generators"`
+	// 0 if rps of the bomber was not measured
+	MaxTestedRps int64  `json:"max_tested_rps"`
+	CPUs         int    `json:"cpus"`
+	MemoryBytes  uint64 `json:"memory_bytes"`
+	OS           string `json:"os"`
+	Arch         string `json:"arch"`
+}
+
+func (core *Core) FormCapabilities() *Capabilities {
+	generators := make([]string, 0, len(rest_contracts.GeneratorType_name))
+	for _, name := range rest_contracts.GeneratorType_name {
+		generators = append(generators, name)
+	}
+	sort.Strings(generators)
+	return &Capabilities{
+		BomberId:        core.config.CurrentServiceID,
+		Tenant:          core.config.TenantId(),
+		BomberIp:        core.bomberIp,
+		Version:         Version,
+		ContractVersion: ContractVersion,
+		Modes:           []string{ModeHTTP, ModeWebsocket, ModeSSE, ModeGRPC, ModeRaw},
+		Protocols:       []string{"http", "https", "ws", "wss", "tcp", "udp"},
+		Generators:      generators,
+		MaxTestedRps:    core.config.MaxTestedRps,
+		CPUs:            runtime.GOMAXPROCS(0),
+		MemoryBytes:     availableMemory(),
+		OS:              runtime.GOOS,
+		Arch:            runtime.GOARCH,
+	}
+}
+
+func (core *Core) publishCapabilities() {
+	data, err := json.Marshal(core.FormCapabilities())
+	if err != nil {
+		logrus.Error("Can not marshal capabilities: ", err)
+		return
+	}
+	if errPublish := core.publisher.PublishNewMessage(topicCapabilities, data); errPublish != nil {
+		logrus.Error("Can not publish capabilities: ", errPublish)
+	}
+}
+
+// availableMemory - total memory of the host limited by memory of the cgroup, 0 if it is unknown
+func availableMemory() uint64 {
+	total := memInfoTotal()
+	for _, path := range []string{"/sys/fs/cgroup/memory.max", "/sys/fs/cgroup/memory/memory.limit_in_bytes"} {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		limit, err := strconv.ParseUint(strings.TrimSpace(string(content)), 10, 64)
+		// "max" or huge value of v1 means no limit
+		if err == nil && limit > 0 && (total == 0 || limit < total) {
+			total = limit
+		}
+		break
+	}
+	return total
+}
+
+func memInfoTotal() uint64 {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || fields[0] != "MemTotal:" {
+			continue
+		}
+		kilobytes, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kilobytes * 1024
+	}
+	return 0
+}
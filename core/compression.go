@@ -0,0 +1,79 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+var ErrUnknownEncoding = errors.New("unknown content encoding")
+
+func encodeBody(body []byte, encoding string) ([]byte, error) {
+	switch encoding {
+	case EncodingGzip:
+		return fasthttp.AppendGzipBytes(nil, body), nil
+	case EncodingDeflate:
+		return fasthttp.AppendDeflateBytes(nil, body), nil
+	default:
+		return nil, ErrUnknownEncoding
+	}
+}
+
+func (attack *Attack) compressBody(request *fasthttp.Request, body []byte) error {
+	encoding := attack.options.BodyEncoding
+	if encoding == "" || len(body) == 0 {
+		request.SetBody(body)
+		return nil
+	}
+	encoded, err := encodeBody(body, encoding)
+	if err != nil {
+		return err
+	}
+	request.SetBody(encoded)
+	request.Header.Set(fasthttp.HeaderContentEncoding, encoding)
+	attack.resultBodyRawBytes += int64(len(body))
+	attack.resultBodyEncodedBytes += int64(len(encoded))
+	return nil
+}
+
+const EncodingBrotli = "br"
+
+/*
+decodeBody - returns body of response decompressed by its Content-Encoding
+*/
+func decodeBody(response *fasthttp.Response) ([]byte, error) {
+	switch string(response.Header.Peek(fasthttp.HeaderContentEncoding)) {
+	case EncodingGzip:
+		return response.BodyGunzip()
+	case EncodingDeflate:
+		return response.BodyInflate()
+	case EncodingBrotli:
+		return response.BodyUnbrotli()
+	default:
+		return response.Body(), nil
+	}
+}
+
+func (attack *Attack) acceptEncoding(request *fasthttp.Request) {
+	if attack.options.AcceptEncoding != "" {
+		request.Header.Set(fasthttp.HeaderAcceptEncoding, attack.options.AcceptEncoding)
+	}
+}
+
+// measureBody - size of response body on the wire and after decompression
+func (attack *Attack) measureBody(response *fasthttp.Response) (int, int, error) {
+	wire := len(response.Body())
+	if !attack.options.DecompressResponses {
+		return wire, wire, nil
+	}
+	decoded, err := decodeBody(response)
+	if err != nil {
+		return wire, 0, err
+	}
+	return wire, len(decoded), nil
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+)
+
+/*
+StepCondition - step is sent only if the condition holds, otherwise it is passed over and the next step is sent.
+All given checks have to hold, not inverts the result
+*/
+type StepCondition struct {
+	// name of a previous step, which status is checked
+	Step     string `json:"step,omitempty"`
+	Statuses []int  `json:"statuses,omitempty"`
+	// name of variable, which value is checked
+	Variable string `json:"variable,omitempty"`
+	Equals   string `json:"equals,omitempty"`
+	Matches  string `json:"matches,omitempty"`
+	Not      bool   `json:"not,omitempty"`
+}
+
+type stepCondition struct {
+	StepCondition
+	statuses map[int]bool
+	matches  *regexp.Regexp
+}
+
+// compileCondition - nil if the step has no condition
+func compileCondition(condition *StepCondition) (*stepCondition, error) {
+	if condition == nil {
+		return nil, nil
+	}
+	compiled := &stepCondition{StepCondition: *condition, statuses: map[int]bool{}}
+	for _, status := range condition.Statuses {
+		compiled.statuses[status] = true
+	}
+	if condition.Matches != "" {
+		matches, err := regexp.Compile(condition.Matches)
+		if err != nil {
+			return nil, err
+		}
+		compiled.matches = matches
+	}
+	return compiled, nil
+}
+
+/*
+holds - statuses are of steps sent before in the iteration, a step which was not sent has no status.
+Missing variable equals to nothing
+*/
+func (condition *stepCondition) holds(statuses map[string]int, variables map[string]string) bool {
+	if condition == nil {
+		return true
+	}
+	holds := true
+	if condition.Step != "" {
+		status, sent := statuses[condition.Step]
+		holds = sent && (len(condition.statuses) == 0 || condition.statuses[status])
+	}
+	if holds && condition.Variable != "" {
+		value, ok := variables[condition.Variable]
+		switch {
+		case condition.Equals != "":
+			holds = ok && value == condition.Equals
+		case condition.matches != nil:
+			holds = ok && condition.matches.MatchString(value)
+		default:
+			holds = ok && value != ""
+		}
+	}
+	return holds != condition.Not
+}
+
+// validateCondition - step of condition has to be one of previous steps
+func validateCondition(field string, condition *StepCondition, previous map[string]bool, add func(field string, reason string)) {
+	if condition == nil {
+		return
+	}
+	if condition.Step == "" && condition.Variable == "" {
+		add(field, "requires step or variable")
+	}
+	if condition.Step != "" && !previous[condition.Step] {
+		add(field+".step", "must be name of a previous step")
+	}
+	if len(condition.Statuses) > 0 && condition.Step == "" {
+		add(field+".statuses", "require step")
+	}
+	for index, status := range condition.Statuses {
+		if status < 100 || status > 599 {
+			add(fmt.Sprintf("%s.statuses[%d]", field, index), "must be http status")
+		}
+	}
+	if (condition.Equals != "" || condition.Matches != "") && condition.Variable == "" {
+		add(field+".variable", "is required")
+	}
+	if condition.Equals != "" && condition.Matches != "" {
+		add(field+".matches", "must not be set with equals")
+	}
+	if condition.Matches != "" {
+		if _, err := regexp.Compile(condition.Matches); err != nil {
+			add(field+".matches", err.Error())
+		}
+	}
+}
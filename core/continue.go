@@ -0,0 +1,124 @@
+package core
+
+import (
+	"bufio"
+	"net"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	defaultContinueMinBody = 1024 * 1024
+	defaultContinueTimeout = time.Second
+	statusLineLength       = len("HTTP/1.1 100")
+)
+
+type ExpectContinueOptions struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// bodies smaller than this size are sent without handshake, 1MB if empty
+	MinBodyBytes int `json:"min_body_bytes,omitempty"`
+	// time to wait for interim response before sending body anyway
+	TimeoutMs int64 `json:"timeout_ms,omitempty"`
+}
+
+const (
+	continueNone = iota
+	continueAccepted
+	continueRejected // final status was received before body
+	continueWaitTimeout
+)
+
+type continueStats struct {
+	requests         int64
+	accepted         int64
+	rejected         int64
+	waitTimeouts     int64
+	rejectedStatuses map[int32]int64
+}
+
+func newContinueStats() continueStats {
+	return continueStats{
+		rejectedStatuses: map[int32]int64{},
+	}
+}
+
+func (stats *continueStats) add(result SliceResult) {
+	switch result.Continue {
+	case continueNone:
+		return
+	case continueAccepted:
+		stats.accepted++
+	case continueRejected:
+		stats.rejected++
+		stats.rejectedStatuses[int32(result.Status)]++
+	case continueWaitTimeout:
+		stats.waitTimeouts++
+	}
+	stats.requests++
+}
+
+func (options ExpectContinueOptions) applies(request *fasthttp.Request) bool {
+	if !options.Enabled || request.IsBodyStream() {
+		return false
+	}
+	minBody := options.MinBodyBytes
+	if minBody <= 0 {
+		minBody = defaultContinueMinBody
+	}
+	return len(request.Body()) >= minBody
+}
+
+/*
+doExpectContinue - sends headers with Expect: 100-continue on a new connection and sends body
+only after interim response or timeout. Final response received before body is kept
+*/
+func (user *virtualUser) doExpectContinue(client *fasthttp.HostClient, request *fasthttp.Request, response *fasthttp.Response) (int, error) {
+	conn, err := user.dialer.DialTraced(client.Addr, client.IsTLS, &user.trace)
+	if err != nil {
+		return continueNone, err
+	}
+	defer conn.Close()
+	uri := request.URI()
+	request.Header.SetHostBytes(uri.Host())
+	request.Header.SetRequestURIBytes(uri.RequestURI())
+	request.Header.SetContentLength(len(request.Body()))
+	request.Header.Set("Expect", "100-continue")
+	writer := bufio.NewWriter(conn)
+	reader := bufio.NewReader(conn)
+	if err := request.Header.Write(writer); err != nil {
+		return continueNone, err
+	}
+	if err := writer.Flush(); err != nil {
+		return continueNone, err
+	}
+	timeout := time.Duration(user.expect.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultContinueTimeout
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	statusLine, errPeek := reader.Peek(statusLineLength)
+	conn.SetReadDeadline(time.Time{})
+	outcome := continueAccepted
+	netErr, isNetErr := errPeek.(net.Error)
+	switch {
+	case isNetErr && netErr.Timeout():
+		outcome = continueWaitTimeout
+	case errPeek != nil:
+		return continueNone, errPeek
+	case string(statusLine[statusLineLength-3:]) != "100":
+		return continueRejected, response.Read(reader)
+	default:
+		var interim fasthttp.ResponseHeader
+		if err := interim.Read(reader); err != nil {
+			return continueNone, err
+		}
+	}
+	if _, err := writer.Write(request.Body()); err != nil {
+		return continueNone, err
+	}
+	if err := writer.Flush(); err != nil {
+		return continueNone, err
+	}
+	return outcome, response.Read(reader)
+}
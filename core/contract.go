@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+)
+
+// ContractVersion - version of bomber-proto-contracts the bomber is built with, in sync with go.mod
+const ContractVersion = "0.2.15"
+
+// ContractField - field of errors of tasks, which are built with incompatible contracts
+const ContractField = "contract_version"
+
+/*
+CompatibleContract - tasks of the same major version are compatible, while major is 0 minor
+versions are not compatible with each other
+*/
+func CompatibleContract(version string) bool {
+	major, minor, ok := parseContractVersion(version)
+	bomberMajor, bomberMinor, _ := parseContractVersion(ContractVersion)
+	if !ok || major != bomberMajor {
+		return false
+	}
+	return major > 0 || minor == bomberMinor
+}
+
+func parseContractVersion(version string) (int, int, bool) {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, errMajor := strconv.Atoi(parts[0])
+	minor, errMinor := strconv.Atoi(parts[1])
+	return major, minor, errMajor == nil && errMinor == nil
+}
+
+/*
+validateContract - task built with newer contracts has fields or enum values unknown to the bomber,
+protobuf drops them silently, so such task is rejected instead of attack without them.
+False if the task is incompatible, other fields of it are not validated then
+*/
+func validateContract(task *rest_contracts.Task, options *TaskOptions, add func(field string, reason string)) bool {
+	if options.ContractVersion != "" && !CompatibleContract(options.ContractVersion) {
+		add(ContractField, fmt.Sprintf("task is built with contracts %s, which are incompatible with contracts %s of the bomber",
+			options.ContractVersion, ContractVersion))
+		return false
+	}
+	var unknown []string
+	collectUnknown(reflect.ValueOf(task), "task", &unknown)
+	if len(unknown) > 0 {
+		add(ContractField, fmt.Sprintf("task has fields unknown to contracts %s of the bomber: %s",
+			ContractVersion, strings.Join(unknown, ", ")))
+		return false
+	}
+	return true
+}
+
+var stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+
+// collectUnknown - paths of messages with unrecognized fields and of enums with unknown values
+func collectUnknown(value reflect.Value, path string, unknown *[]string) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			collectUnknown(value.Elem(), path, unknown)
+		}
+	case reflect.Slice:
+		for index := 0; index < value.Len(); index++ {
+			collectUnknown(value.Index(index), fmt.Sprintf("%s[%d]", path, index), unknown)
+		}
+	case reflect.Map:
+		for _, key := range value.MapKeys() {
+			collectUnknown(value.MapIndex(key), fmt.Sprintf("%s[%v]", path, key), unknown)
+		}
+	case reflect.Struct:
+		for index := 0; index < value.NumField(); index++ {
+			field := value.Type().Field(index)
+			if field.Name == "XXX_unrecognized" {
+				if value.Field(index).Len() > 0 {
+					*unknown = append(*unknown, path)
+				}
+				continue
+			}
+			if field.PkgPath != "" || strings.HasPrefix(field.Name, "XXX_") {
+				continue
+			}
+			collectUnknown(value.Field(index), path+"."+protobufName(field), unknown)
+		}
+	case reflect.Int32:
+		// unknown value of generated enum is printed as its number
+		if value.Type().Implements(stringerType) && value.Interface().(fmt.Stringer).String() == strconv.FormatInt(value.Int(), 10) {
+			*unknown = append(*unknown, path)
+		}
+	}
+}
+
+func protobufName(field reflect.StructField) string {
+	for _, part := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(part, "name=") {
+			return strings.TrimPrefix(part, "name=")
+		}
+	}
+	return field.Name
+}
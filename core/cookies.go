@@ -0,0 +1,93 @@
+package core
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+type storedCookie struct {
+	name    string
+	value   string
+	domain  string
+	path    string
+	expires time.Time
+}
+
+/*
+cookieJar - cookies of one virtual user, which are stored from Set-Cookie headers
+and sent back with the next requests to the same host
+*/
+type cookieJar struct {
+	cookies map[string]*storedCookie // by domain, path and name
+}
+
+func newCookieJar() *cookieJar {
+	return &cookieJar{
+		cookies: map[string]*storedCookie{},
+	}
+}
+
+func (jar *cookieJar) apply(request *fasthttp.Request) {
+	host := string(request.URI().Host())
+	path := string(request.URI().Path())
+	now := time.Now()
+	for key, cookie := range jar.cookies {
+		if !cookie.expires.IsZero() && now.After(cookie.expires) {
+			delete(jar.cookies, key)
+			continue
+		}
+		if domainMatch(host, cookie.domain) && strings.HasPrefix(path, cookie.path) {
+			request.Header.SetCookie(cookie.name, cookie.value)
+		}
+	}
+}
+
+func (jar *cookieJar) store(request *fasthttp.Request, response *fasthttp.Response) {
+	host := string(request.URI().Host())
+	response.Header.VisitAllCookie(func(_, value []byte) {
+		cookie := fasthttp.AcquireCookie()
+		defer fasthttp.ReleaseCookie(cookie)
+		if err := cookie.ParseBytes(value); err != nil {
+			return
+		}
+		stored := &storedCookie{
+			name:    string(cookie.Key()),
+			value:   string(cookie.Value()),
+			domain:  strings.TrimPrefix(string(cookie.Domain()), "."),
+			path:    string(cookie.Path()),
+			expires: cookie.Expire(),
+		}
+		if stored.domain == "" {
+			stored.domain = host
+			// host only cookie is matched without port, as domainMatch does
+			if withoutPort, _, err := net.SplitHostPort(host); err == nil {
+				stored.domain = withoutPort
+			}
+		}
+		if stored.path == "" {
+			stored.path = "/"
+		}
+		if cookie.MaxAge() > 0 {
+			stored.expires = time.Now().Add(time.Duration(cookie.MaxAge()) * time.Second)
+		}
+		if stored.expires.Equal(fasthttp.CookieExpireUnlimited) {
+			stored.expires = time.Time{}
+		}
+		key := stored.domain + stored.path + "#" + stored.name
+		if !stored.expires.IsZero() && time.Now().After(stored.expires) {
+			delete(jar.cookies, key)
+			return
+		}
+		jar.cookies[key] = stored
+	})
+}
+
+func domainMatch(host, domain string) bool {
+	if index := strings.LastIndexByte(host, ':'); index != -1 && !strings.Contains(host, "]") {
+		host = host[:index]
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -1,37 +1,42 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/HdrHistogram/hdrhistogram-go"
 	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
 	"github.com/bomber-team/bomber-proto-contracts/golang/system"
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
 	"github.com/bomber-team/rest-bomber/generators"
+	"github.com/bomber-team/rest-bomber/metrics"
 	"github.com/bomber-team/rest-bomber/nats_listener"
 	"github.com/bomber-team/rest-bomber/tools"
+	"github.com/bomber-team/rest-bomber/transport"
 	"github.com/jamiealquiza/tachymeter"
-	"github.com/nats-io/nats.go"
 	"github.com/sirupsen/logrus"
 	"github.com/valyala/fasthttp"
 )
 
 type Core struct {
-	connection             *nats.Conn
-	publisher              *nats_listener.Publisher
-	config                 *nats_listener.NatsConnectionConfiguration
-	currentStatusBomber    system.StatusBomber
-	dataAttack             []*fasthttp.Request
-	httpClient             *http.Transport
-	resultsAttack          map[int32]int64 // amount statuses per status
-	resultTimeouts         int64           // amount time out requests
-	resultTimesForRequests []int64         // amount ms for one request
-	attackReady            bool            // ready for attack?
-	bomberIp               string
-	formId                 string
-	tahometr               *tachymeter.Tachymeter
+	progressInterval    int64 // of progress of attacks, zero if it is disabled
+	broker              broker.Broker
+	publisher           *nats_listener.Publisher
+	config              *config.Configuration
+	currentStatusBomber system.StatusBomber
+	httpClient          *http.Transport
+	engaged             int32 // tasks from their preparing until end of their attacks
+	capacity            int32 // attacks which the bomber runs at once
+	bomberIp            string
+	heartbeats          heartbeats
+	attacks             attacks
 }
 
 type Config struct {
@@ -39,16 +44,69 @@ type Config struct {
 	AmountTimeInSeconds    int64
 }
 
-var saveResults sync.Mutex
-
 type SliceResult struct {
-	Status      int
-	TimeElapsed int64
-	Timeout     bool
+	Status                int
+	TimeElapsed           int64
+	Timeout               bool
+	Skipped               bool
+	Redirects             int
+	RedirectLimitExceeded bool
+	BytesWire             int
+	BytesDecoded          int
+	DecodeFailed          bool
+	Attempts              int
+	FirstStatus           int
+	FirstFailed           bool
+	Phases                phaseTimings
+	Continue              int
+	Error                 string   // category of transport error
+	TraceId               string   // sent in traceparent header if request was sampled
+	RequestId             string   // sent in X-Bomber-Request-Id header if task asks for it
+	Endpoint              string   // method and path of request
+	Asserted              bool     // response was checked by assertions
+	AssertionFailed       string   // kind of the first failed assertion
+	Captured              []string // values of capture_headers of the task, empty if absent
+}
+
+func (attack *Attack) CheckReady() bool {
+	return attack.attackReady
+}
+
+var ErrNotEngaged = errors.New("bomber is released without task which engaged it")
+
+// TryEngage - false if bomber is already busy by as many tasks as attacks it runs at once
+func (core *Core) TryEngage() bool {
+	for {
+		engaged := atomic.LoadInt32(&core.engaged)
+		if engaged >= core.capacity {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&core.engaged, engaged, engaged+1) {
+			return true
+		}
+	}
+}
+
+// Release - error if the bomber is not engaged, engagement does not go below zero then
+func (core *Core) Release() error {
+	for {
+		engaged := atomic.LoadInt32(&core.engaged)
+		if engaged <= 0 {
+			return ErrNotEngaged
+		}
+		if atomic.CompareAndSwapInt32(&core.engaged, engaged, engaged-1) {
+			return nil
+		}
+	}
 }
 
-func (core *Core) CheckReady() bool {
-	return core.attackReady
+func (core *Core) Idle() bool {
+	return atomic.LoadInt32(&core.engaged) == 0
+}
+
+// Spare - bomber can take one more task at once
+func (core *Core) Spare() bool {
+	return atomic.LoadInt32(&core.engaged) < core.capacity
 }
 
 const (
@@ -77,36 +135,45 @@ func createHTTPClient() *http.Client {
 	return client
 }
 
-func NewCore() *Core {
-	parsedConfigureService, errParsing := nats_listener.ParseConfiguration()
-	if errParsing != nil {
-		logrus.Error("can not parsed configuration: ", errParsing)
-		panic(errParsing)
-	}
+func NewCore(parsedConfigureService *config.Configuration) *Core {
 	parsedConfigureService.CorrectedGeneratingHandlerName()
-	connection, errConnection := nats_listener.CreateNewConnectionToNats(parsedConfigureService)
+	return newCore(parsedConfigureService)
+}
+
+// NewLocalCore - core of standalone mode, it has in-process broker instead of configured one
+func NewLocalCore(parsedConfigureService *config.Configuration) *Core {
+	parsedConfigureService.Broker = broker.KindLocal
+	return NewCore(parsedConfigureService)
+}
+
+func newCore(parsedConfigureService *config.Configuration) *Core {
+	bus, errConnection := nats_listener.OpenBroker(parsedConfigureService)
 	if errConnection != nil {
-		logrus.Error("Can not connected to nats: ", errConnection)
+		logrus.Error("Can not connected to broker: ", errConnection)
 		panic(errConnection)
 	}
 
+	capacity := parsedConfigureService.MaxAttacks
+	if capacity < 1 {
+		capacity = 1
+	}
 	return &Core{
-		connection:             connection,
-		publisher:              nats_listener.NewPublisher(connection),
-		currentStatusBomber:    system.StatusBomber_UP,
-		httpClient:             &http.Transport{},
-		bomberIp:               tools.InitIp(),
-		resultTimesForRequests: []int64{},
-		tahometr:               tachymeter.New(&tachymeter.Config{Size: 1000}),
-		config:                 parsedConfigureService,
+		broker:              bus,
+		publisher:           nats_listener.NewPublisher(bus),
+		currentStatusBomber: system.StatusBomber_UP,
+		httpClient:          &http.Transport{},
+		bomberIp:            tools.InitIp(),
+		config:              parsedConfigureService,
+		capacity:            int32(capacity),
+		attacks:             attacks{byFormId: map[string]*Attack{}},
 	}
 }
 
-func (core *Core) GetConnection() *nats.Conn {
-	return core.connection
+func (core *Core) GetBroker() broker.Broker {
+	return core.broker
 }
 
-func (core *Core) GetConfig() *nats_listener.NatsConnectionConfiguration {
+func (core *Core) GetConfig() *config.Configuration {
 	return core.config
 }
 
@@ -116,22 +183,11 @@ type RequestPayload struct {
 	Id       int
 }
 
-func (core *Core) preparingBody(bodyParams []*rest_contracts.BodyParam) ([]byte, error) {
-	var resultBody map[string]interface{} = nil
+func (attack *Attack) preparingBody(bodyParams []*rest_contracts.BodyParam) ([]byte, error) {
+	resultBody := map[string]interface{}{}
 	for _, value := range bodyParams {
-		if value.IsGenerated {
-			switch x := value.Config.Res.(type) {
-			case *rest_contracts.GeneratorConfig_WordGeneratorConfig:
-				resultBody[value.Name] = generators.GenerateWord(*x)
-			case *rest_contracts.GeneratorConfig_DigitGeneratorConfig:
-				resultBody[value.Name] = generators.GenerateDigits(*x)
-			case *rest_contracts.GeneratorConfig_RegexpConfig:
-				resultBody[value.Name] = generators.GenerateByRegexp(x)
-			default:
-				continue
-			}
-		} else {
-			resultBody[value.Name] = value
+		if generated, ok := bodyParamValue(value); ok {
+			resultBody[value.Name] = generated
 		}
 	}
 	resultMarshaled, err := json.Marshal(resultBody)
@@ -142,7 +198,66 @@ func (core *Core) preparingBody(bodyParams []*rest_contracts.BodyParam) ([]byte,
 	return resultMarshaled, nil
 }
 
-func (core *Core) prepareRequestParams(requestParams []*rest_contracts.RequestParam) string {
+// bodyParamValue - generated or plain json value of the param, false if its generator is unknown
+func bodyParamValue(param *rest_contracts.BodyParam) (interface{}, bool) {
+	if param == nil {
+		return nil, false
+	}
+	if param.IsGenerated {
+		if param.Config == nil {
+			return nil, false
+		}
+		switch x := param.Config.Res.(type) {
+		case *rest_contracts.GeneratorConfig_WordGeneratorConfig:
+			return generators.GenerateWord(*x), true
+		case *rest_contracts.GeneratorConfig_DigitGeneratorConfig:
+			return generators.GenerateDigits(*x), true
+		case *rest_contracts.GeneratorConfig_RegexpConfig:
+			return generators.GenerateByRegexp(x), true
+		}
+		return nil, false
+	}
+	switch value := param.Value.(type) {
+	case *rest_contracts.BodyParam_SimpleProperty:
+		return simpleValue(value.SimpleProperty), true
+	case *rest_contracts.BodyParam_ListProperty:
+		list := []interface{}{}
+		if value.ListProperty != nil {
+			for _, item := range value.ListProperty.Value {
+				list = append(list, simpleValue(item))
+			}
+		}
+		return list, true
+	case *rest_contracts.BodyParam_Properties:
+		object := map[string]interface{}{}
+		if value.Properties != nil {
+			for name, property := range value.Properties.Properties {
+				if propertyValue, ok := bodyParamValue(property); ok {
+					object[name] = propertyValue
+				}
+			}
+		}
+		return object, true
+	}
+	return nil, true
+}
+
+func simpleValue(value *rest_contracts.SimpleValue) interface{} {
+	if value == nil {
+		return nil
+	}
+	switch x := value.Value.(type) {
+	case *rest_contracts.SimpleValue_StringValue:
+		return x.StringValue
+	case *rest_contracts.SimpleValue_Int32Value:
+		return x.Int32Value
+	case *rest_contracts.SimpleValue_Int64Value:
+		return x.Int64Value
+	}
+	return nil
+}
+
+func (attack *Attack) prepareRequestParams(requestParams []*rest_contracts.RequestParam) string {
 	if len(requestParams) == 0 {
 		return ""
 	}
@@ -171,140 +286,470 @@ func (core *Core) prepareRequestParams(requestParams []*rest_contracts.RequestPa
 	return resultUrlQueries
 }
 
-func (core *Core) enhancedHeadersInRequest(request *fasthttp.Request, task rest_contracts.Task) *fasthttp.Request {
+func (attack *Attack) enhancedHeadersInRequest(request *fasthttp.Request, task rest_contracts.Task) *fasthttp.Request {
 	for key, value := range task.Schema.Headers {
+		if key == OptionsHeader {
+			continue
+		}
 		request.Header.Set(key, value)
 	}
 	return request
 }
 
-func (core *Core) preparingRequest(restTask *rest_contracts.Task) (*fasthttp.Request, error) {
-	body, err := core.preparingBody(restTask.Schema.Body)
+func (attack *Attack) preparingRequest(restTask *rest_contracts.Task) (*fasthttp.Request, error) {
+	body, err := attack.preparingBody(restTask.Schema.Body)
 	if err != nil {
 		return nil, err
 	}
-	urlParams := core.prepareRequestParams(restTask.Schema.Request)
+	urlParams := attack.prepareRequestParams(restTask.Schema.Request)
 	req := fasthttp.AcquireRequest()
-	req.SetBody(body)
+	if err := attack.compressBody(req, body); err != nil {
+		fasthttp.ReleaseRequest(req)
+		return nil, err
+	}
+	attack.streamBody(req)
 	req.SetRequestURI(restTask.Script.Address + urlParams)
-	return core.enhancedHeadersInRequest(req, *restTask), nil
+	attack.acceptEncoding(req)
+	return attack.enhancedHeadersInRequest(req, *restTask), nil
 }
 
-func (core *Core) cleanCurrentResults() {
-	core.dataAttack = []*fasthttp.Request{}
-	core.resultTimeouts = 0
-	core.resultTimesForRequests = []int64{}
-	core.resultsAttack = map[int32]int64{}
-	core.attackReady = false
-	core.tahometr = tachymeter.New(&tachymeter.Config{
+func (attack *Attack) cleanCurrentResults() {
+	attack.dataAttack = []*fasthttp.Request{}
+	attack.resultTimeouts = 0
+	attack.resultTimesForRequests = []int64{}
+	attack.resultLatency = newLatencyHistogram()
+	attack.resultLatencyByStatus = map[int32]*hdrhistogram.Histogram{}
+	attack.resultErrors = map[string]int64{}
+	attack.resultTimeline = newTimeline(0)
+	attack.resultsAttack = map[int32]int64{}
+	attack.resultRedirects = 0
+	attack.resultRedirectsLimit = 0
+	attack.resultBodyRawBytes = 0
+	attack.resultBodyEncodedBytes = 0
+	attack.resultWireBytes = 0
+	attack.resultDecodedBytes = 0
+	attack.resultDecodeErrors = 0
+	attack.resultRetries = newRetriesStats()
+	attack.resultContinue = newContinueStats()
+	attack.resultTracing = newTracingStats(TracingOptions{})
+	attack.resultFailures = nil
+	attack.resultApdex = newApdexStats(ApdexOptions{})
+	attack.resultSummary = summaryStats{}
+	attack.resultMessages = newErrorMessages(0)
+	attack.resultEndpoints = map[string]*endpointStats{}
+	attack.resultBodies = nil
+	attack.resultSkipped = 0
+	attack.breaker = nil
+	attack.resultPhases = newPhaseMeters(1)
+	attack.resultWebsocket = nil
+	attack.resultSSE = nil
+	attack.resultGRPC = nil
+	attack.resultRaw = nil
+	attack.resultScenario = nil
+	attack.resultAssertions = assertionStats{}
+	attack.resultHeaders = nil
+	attack.resultLogin = loginStats{}
+	attack.resultCSRF = csrfStats{}
+	attack.attackReady = false
+	attack.tahometr = tachymeter.New(&tachymeter.Config{
 		Size: 500,
 	})
 }
 
-func (core *Core) PreparingData(task rest_contracts.Task) {
-	core.cleanCurrentResults()
+func (attack *Attack) prepare(task rest_contracts.Task) (err error) {
+	attack.beginProgress(task)
+	defer func() {
+		// failed task has no results, its progress ends with its status
+		if err != nil {
+			attack.EndProgress()
+		}
+	}()
+	attack.cleanCurrentResults()
+	options, errOptions := ParseTaskOptions(task)
+	if errOptions != nil {
+		logrus.Error("Can not parse task options: ", errOptions)
+		return errOptions
+	}
+	dialer, errDialer := transport.NewDialer(transport.DialerConfig{
+		Hosts:      options.Hosts,
+		Resolver:   options.Resolver,
+		Family:     options.AddressFamily,
+		LocalAddrs: options.SourceAddrs,
+
+		MaxBytesPerSecond: options.MaxBytesPerSecond,
+		TCP: transport.TCPOptions{
+			NoDelay:    options.TCP.NoDelay,
+			ReuseAddr:  options.TCP.ReuseAddr,
+			ReusePort:  options.TCP.ReusePort,
+			SendBuffer: options.TCP.SendBuffer,
+			RecvBuffer: options.TCP.RecvBuffer,
+		},
+		TLS: transport.TLSOptions{
+			SessionResumption:  options.TLS.SessionResumption,
+			InsecureSkipVerify: options.TLS.InsecureSkipVerify,
+		},
+	})
+	if errDialer != nil {
+		logrus.Error("Can not configure dialer: ", errDialer)
+		return errDialer
+	}
+	task = options.Shard.apply(task)
+	attack.options = options
+	attack.planProgress(task)
+	attack.dialer = dialer
+	attack.resultTracing = newTracingStats(options.Tracing)
+	attack.resultFailures = newFailureSamples(options.FailureSamples)
+	attack.resultApdex = newApdexStats(options.Apdex)
+	attack.resultMessages = newErrorMessages(options.TopErrors)
+	attack.resultBodies = newBodySamples(options.BodySamples)
+	if options.Mode == ModeGRPC {
+		method, errMethod := loadGRPCMethod(options.GRPC)
+		if errMethod != nil {
+			logrus.Error("Can not load grpc method: ", errMethod)
+			return errMethod
+		}
+		attack.grpcMethod = method
+	}
+	if options.Mode == ModeRaw {
+		if _, _, errAddress := parseRawAddress(task.Script.Address); errAddress != nil {
+			logrus.Error("Can not parse raw address: ", errAddress)
+			return errAddress
+		}
+	}
+	if errThresholds := validateThresholds(options.Thresholds); errThresholds != nil {
+		logrus.Error("Can not check thresholds: ", errThresholds)
+		return errThresholds
+	}
+	attack.baseline = nil
+	if options.Baseline.enabled() {
+		baseline, errBaseline := loadBaseline(options.Baseline)
+		if errBaseline != nil {
+			logrus.Error("Can not load baseline: ", errBaseline)
+			return &UnavailableError{Err: errBaseline}
+		}
+		attack.baseline = baseline
+	}
+	if options.Samples != "" && options.Samples != SamplesCSV && options.Samples != SamplesNDJSON {
+		logrus.Error("Can not write samples: ", ErrSamplesFormat)
+		return ErrSamplesFormat
+	}
+	assertions, errAssertions := compileAssertions(options.Assertions)
+	if errAssertions != nil {
+		logrus.Error("Can not compile assertions: ", errAssertions)
+		return errAssertions
+	}
+	attack.assertions = assertions
+	if errOAuth2 := attack.prepareOAuth2(); errOAuth2 != nil {
+		logrus.Error("Can not fetch oauth2 token: ", errOAuth2)
+		return &UnavailableError{Err: errOAuth2}
+	}
+	if errJWT := attack.prepareJWT(task); errJWT != nil {
+		logrus.Error("Can not prepare jwt: ", errJWT)
+		return errJWT
+	}
+	if errSigV4 := attack.prepareSigV4(); errSigV4 != nil {
+		logrus.Error("Can not get aws credentials: ", errSigV4)
+		return errSigV4
+	}
+	if errSigning := attack.prepareHMACSigning(); errSigning != nil {
+		logrus.Error("Can not prepare hmac signing: ", errSigning)
+		return errSigning
+	}
+	attack.setupTask = task
+	if errLogin := attack.prepareLogin(); errLogin != nil {
+		logrus.Error("Can not login before the attack: ", errLogin)
+		return errLogin
+	}
+	if errSetup := attack.prepareSetup(); errSetup != nil {
+		logrus.Error("Can not set up the attack: ", errSetup)
+		return errSetup
+	}
+	if options.Scenario.enabled() {
+		steps, errScenario := compileScenario(options.Scenario)
+		if errScenario != nil {
+			logrus.Error("Can not compile scenario: ", errScenario)
+			return errScenario
+		}
+		attack.scenarioSteps = steps
+	}
+	// requests of scenario are formed by its steps during the attack
+	if options.Mode != ModeHTTP || options.Scenario.enabled() {
+		attack.attackReady = true
+		return nil
+	}
 	var index int64 = 0
 	amountRequests := task.Script.Config.Rps * task.Script.Config.Time
 	resultSliceRequests := make([]*fasthttp.Request, amountRequests)
 	for ; index < amountRequests; index++ {
-		newRequest, errFormRequest := core.preparingRequest(&task)
+		newRequest, errFormRequest := attack.preparingRequest(&task)
 		if errFormRequest != nil {
 			logrus.Error("Can not forming request: ", errFormRequest)
 			continue
 		}
 		resultSliceRequests[index] = newRequest
 	}
-	core.dataAttack = resultSliceRequests
-	core.formId = task.FormId
-	core.attackReady = true
+	attack.dataAttack = resultSliceRequests
+	attack.attackReady = true
+	return nil
 }
 
-func (core *Core) resultHandler(resultChan chan SliceResult, completed chan bool, wg *sync.WaitGroup) {
+func (attack *Attack) resultHandler(ctx context.Context, resultChan chan SliceResult, completed chan bool, workersDone chan struct{}, wg *sync.WaitGroup) {
 	var countRequests int = 0
-	logrus.Debug("All requests: ", len(core.dataAttack))
+	logrus.Debug("All requests: ", len(attack.dataAttack))
 	for {
-		newRes := <-resultChan
-		countRequests++
-		saveResults.Lock()
-		if newRes.Timeout {
-			core.resultTimeouts++
-		} else {
-			core.resultsAttack[int32(newRes.Status)]++
-			core.resultTimesForRequests = append(core.resultTimesForRequests, newRes.TimeElapsed)
-		}
-		saveResults.Unlock()
-		if countRequests == len(core.dataAttack)-1 {
-			completed <- true
+		select {
+		case newRes := <-resultChan:
+			countRequests++
+			attack.results.Lock()
+			attack.saveResult(newRes)
+			attack.results.Unlock()
+			if countRequests == len(attack.dataAttack) {
+				close(completed)
+				wg.Done()
+				return
+			}
+		case <-ctx.Done():
+			attack.drainResults(resultChan, workersDone)
+			close(completed)
 			wg.Done()
 			return
 		}
 	}
 }
 
-func (core *Core) runWorkers(config Config, task chan RequestPayload, completed chan bool, resultChan chan SliceResult) {
-	cli := fasthttp.Client{
-		MaxConnsPerHost: 10000,
+// drainResults - saves results of requests, which were in flight when the attack was cancelled
+func (attack *Attack) drainResults(resultChan chan SliceResult, workersDone chan struct{}) {
+	for {
+		select {
+		case newRes := <-resultChan:
+			attack.results.Lock()
+			attack.saveResult(newRes)
+			attack.results.Unlock()
+		case <-workersDone:
+			for len(resultChan) > 0 {
+				newRes := <-resultChan
+				attack.results.Lock()
+				attack.saveResult(newRes)
+				attack.results.Unlock()
+			}
+			return
+		}
 	}
-	timeout := (1.0 / float64(config.AmountRequestPerWorker/currentWorkers)) * 1000000000
+}
+
+func (attack *Attack) saveResult(newRes SliceResult) {
+	if newRes.Skipped {
+		attack.resultSkipped++
+		return
+	}
+	attack.resultRetries.add(newRes, attack.options.Retry)
+	attack.resultContinue.add(newRes)
+	attack.resultTracing.add(newRes)
+	attack.recordEndpoint(newRes)
+	if newRes.Timeout {
+		attack.recordTimeout(newRes.Error)
+		return
+	}
+	attack.recordAssertion(newRes)
+	attack.recordHeaders(newRes)
+	attack.resultsAttack[int32(newRes.Status)]++
+	attack.resultPhases.add(newRes.Phases)
+	attack.recordLatency(newRes.TimeElapsed, int32(newRes.Status), newRes.Status >= fasthttp.StatusBadRequest, newRes.BytesWire)
+	attack.resultRedirects += int64(newRes.Redirects)
+	if newRes.RedirectLimitExceeded {
+		attack.resultRedirectsLimit++
+	}
+	attack.resultWireBytes += int64(newRes.BytesWire)
+	attack.resultDecodedBytes += int64(newRes.BytesDecoded)
+	if newRes.DecodeFailed {
+		attack.resultDecodeErrors++
+	}
+}
+
+// workerInterval - each worker keeps its share of rps, the share is below one request per second if rps is below amount of workers
+func workerInterval(rps int64) time.Duration {
+	return time.Duration(float64(time.Second) * float64(currentWorkers) / float64(rps))
+}
+
+func (attack *Attack) runWorkers(ctx context.Context, config Config, task chan RequestPayload, completed chan bool, resultChan chan SliceResult) {
+	user := attack.newVirtualUser()
+	attack.fetchCSRF(user)
+	attack.loginUser(user)
+	timeout := workerInterval(config.AmountRequestPerWorker)
 	for {
 		select {
 		case newRequest := <-task:
-			timeStart := time.Now()
-
-			if err := cli.Do(newRequest.Request, newRequest.Response); err != nil {
-				logrus.Error("Error while request: ", err)
-				resultChan <- SliceResult{
-					Timeout: true,
-				}
+			result := attack.exchange(user, newRequest.Request, newRequest.Response)
+			attack.inspectResponse(&result, newRequest.Response, attack.assertions)
+			resultChan <- result
+			fasthttp.ReleaseResponse(newRequest.Response)
+			fasthttp.ReleaseRequest(newRequest.Request)
+			if result.Timeout {
 				continue
 			}
-			durationTime := time.Since(timeStart)
-			core.tahometr.AddTime(durationTime)
-			resultChan <- SliceResult{
-				Status:      newRequest.Response.StatusCode(),
-				TimeElapsed: durationTime.Nanoseconds(),
+			if result.Skipped {
+				time.Sleep(timeout)
+				continue
 			}
-			fasthttp.ReleaseResponse(newRequest.Response)
-			fasthttp.ReleaseRequest(newRequest.Request)
-			if durationTime.Nanoseconds() < time.Duration(timeout).Nanoseconds() {
-				time.Sleep(time.Duration(timeout) - durationTime)
+			if result.TimeElapsed < timeout.Nanoseconds() {
+				time.Sleep(timeout - time.Duration(result.TimeElapsed))
 			}
 		case <-completed:
 			logrus.Debug("Completed requests")
 			return
+		case <-ctx.Done():
+			logrus.Debug("Attack was cancelled")
+			return
+		}
+	}
+}
+
+// exchange - sends the request by the user, request is skipped while circuit breaker is open
+func (attack *Attack) exchange(user *virtualUser, request *fasthttp.Request, response *fasthttp.Response) SliceResult {
+	if !attack.breaker.allow() {
+		return SliceResult{
+			Skipped: true,
 		}
 	}
+	user.beginRequest()
+	metrics.RequestStarted()
+	atomic.AddInt64(&attack.inFlight, 1)
+	redirected, retried, err := attack.doWithRetries(user, request, response)
+	atomic.AddInt64(&attack.inFlight, -1)
+	metrics.RequestFinished()
+	attack.breaker.record(err == nil && response.StatusCode() < fasthttp.StatusInternalServerError)
+	if err != nil {
+		category := classifyError(err)
+		attack.logError(category, err)
+		attack.resultFailures.addError(request, err)
+		return SliceResult{
+			Timeout:     true,
+			Error:       category,
+			Attempts:    retried.attempts,
+			FirstFailed: retried.firstFailed,
+			FirstStatus: retried.firstStatus,
+			TraceId:     user.traceID,
+			RequestId:   user.requestID,
+			Endpoint:    endpointLabel(request),
+		}
+	}
+	durationTime := redirected.elapsed
+	attack.tahometr.AddTime(durationTime)
+	wireBytes, decodedBytes, errDecode := attack.measureBody(response)
+	if errDecode != nil {
+		logrus.Debug("Can not decompress response: ", errDecode)
+	}
+	attack.resultFailures.addResponse(request, response, durationTime.Nanoseconds())
+	attack.resultBodies.add(request, response, durationTime.Nanoseconds())
+	return SliceResult{
+		Status:                response.StatusCode(),
+		TimeElapsed:           durationTime.Nanoseconds(),
+		Redirects:             redirected.hops,
+		RedirectLimitExceeded: redirected.limitExceeded,
+		BytesWire:             wireBytes,
+		BytesDecoded:          decodedBytes,
+		DecodeFailed:          errDecode != nil,
+		Attempts:              retried.attempts,
+		FirstFailed:           retried.firstFailed,
+		FirstStatus:           retried.firstStatus,
+		Phases:                user.timings(),
+		Continue:              user.continued,
+		TraceId:               user.traceID,
+		RequestId:             user.requestID,
+		Endpoint:              endpointLabel(request),
+	}
 }
 
 // func (core *Core) dispatcherRequest(taskrequest chan RequestPayload, completed chan bool)
 
-func (core *Core) startAttack(taskRunner chan RequestPayload) error {
-	core.currentStatusBomber = system.StatusBomber_WORKING
-	for index, request := range core.dataAttack {
-		taskRunner <- RequestPayload{
+func (attack *Attack) startAttack(ctx context.Context, taskRunner chan RequestPayload) error {
+	attack.core.setStatus(system.StatusBomber_WORKING)
+	for index, request := range attack.dataAttack {
+		response := fasthttp.AcquireResponse()
+		select {
+		case taskRunner <- RequestPayload{
 			Request:  request,
-			Response: fasthttp.AcquireResponse(),
+			Response: response,
 			Id:       index,
+		}:
+		case <-ctx.Done():
+			fasthttp.ReleaseResponse(response)
+			return ctx.Err()
 		}
 	}
 	return nil
 }
 
-func (core *Core) FormResultAttack() *rest_contracts.BomberResult {
-	logrus.Info("Stats: ", core.tahometr.Calc())
+func (attack *Attack) FormResultAttack() *rest_contracts.BomberResult {
+	logrus.Info("Stats: ", attack.tahometr.Calc())
 	return &rest_contracts.BomberResult{
-		BomberIp:                core.bomberIp,
-		FormId:                  core.formId,
-		AmountTimeoutsRequests:  core.resultTimeouts,
-		AmountStatusesPerStatus: core.resultsAttack,
-		MsPerRequest:            core.resultTimesForRequests,
+		BomberIp:                attack.core.bomberIp,
+		FormId:                  attack.formId,
+		AmountTimeoutsRequests:  attack.resultTimeouts,
+		AmountStatusesPerStatus: attack.resultsAttack,
+		MsPerRequest:            attack.resultTimesForRequests,
 	}
 }
 
-func (core *Core) Start(task rest_contracts.Task, wg *sync.WaitGroup) {
-	core.tahometr = tachymeter.New(&tachymeter.Config{
+func (attack *Attack) Start(task rest_contracts.Task, wg *sync.WaitGroup) {
+	task = attack.options.Shard.apply(task)
+	attack.tahometr = tachymeter.New(&tachymeter.Config{
 		Size: int(task.Script.Config.Rps * task.Script.Config.Time),
 	})
+	attack.resultPhases = newPhaseMeters(int(task.Script.Config.Rps * task.Script.Config.Time))
+	attack.results.Lock()
+	attack.resultTimeline = newTimeline(attack.options.BucketIntervalMs)
+	attack.results.Unlock()
+	attack.attackTargetRps = task.Script.Config.Rps
+	// after elapsed time of the attack is known
+	defer attack.stage(StageFinishing)
+	defer func(started time.Time) {
+		attack.attackElapsed = time.Since(started)
+	}(time.Now())
+	defer attack.sampleTraffic()()
+	ctx := attack.beginAttack(task)
+	if attack.oauth2 != nil {
+		go attack.oauth2.refresh(ctx)
+	}
+	if attack.sigv4 != nil {
+		go attack.sigv4.credentials.Refresh(ctx)
+	}
+	attack.stage(StageAttack)
+	defer attack.endAttack()
+	metrics.AttackStarted()
+	defer metrics.AttackFinished()
+	attack.startSamples()
+	defer attack.stopSamples()
+	attack.core.setStatus(system.StatusBomber_WORKING)
+	switch attack.options.Mode {
+	case ModeWebsocket:
+		attack.startWebsocketAttack(task)
+		wg.Done()
+		logrus.Debug("Attack was completed")
+		return
+	case ModeSSE:
+		attack.startSSEAttack(task)
+		wg.Done()
+		logrus.Debug("Attack was completed")
+		return
+	case ModeGRPC:
+		attack.startGRPCAttack(task)
+		wg.Done()
+		logrus.Debug("Attack was completed")
+		return
+	case ModeRaw:
+		attack.startRawAttack(task)
+		wg.Done()
+		logrus.Debug("Attack was completed")
+		return
+	case ModeHTTP:
+	default:
+		// the task is validated before, so it is a bug of the bomber rather than of the task
+		logrus.Error("Can not attack: ", ErrUnknownMode, ": ", attack.options.Mode)
+		attack.fail(ErrUnknownMode)
+		wg.Done()
+		return
+	}
 	taskRunner := make(chan RequestPayload, currentWorkers)
 	completed := make(chan bool)
 	taskResult := make(chan SliceResult, currentWorkers)
@@ -313,39 +758,73 @@ func (core *Core) Start(task rest_contracts.Task, wg *sync.WaitGroup) {
 		AmountTimeInSeconds:    task.Script.Config.Time,
 		AmountRequestPerWorker: task.Script.Config.Rps,
 	}
+	if attack.options.CircuitBreaker.Enabled {
+		attack.breaker = newCircuitBreaker(attack.options.CircuitBreaker)
+	}
+	if attack.options.Scenario.enabled() {
+		attack.startScenarioAttack(ctx, task)
+		attack.dialer.CloseWarm()
+		wg.Done()
+		logrus.Debug("Attack was completed")
+		return
+	}
+	var workers sync.WaitGroup
 	for ; index < currentWorkers; index++ {
-		go core.runWorkers(config, taskRunner, completed, taskResult)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			attack.runWorkers(ctx, config, taskRunner, completed, taskResult)
+		}()
 	}
-	go core.resultHandler(taskResult, completed, wg)
-	core.startAttack(taskRunner)
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	go attack.resultHandler(ctx, taskResult, completed, workersDone, wg)
+	attack.startAttack(ctx, taskRunner)
 	logrus.Debug("Attack was started")
 	<-completed
+	attack.dialer.CloseWarm()
 	logrus.Debug("Attack was completed")
 
 }
 
+// InitializeService - announces status and capabilities of the started bomber
 func (core *Core) InitializeService() {
-	core.changeStatusBomber(core.currentStatusBomber)
+	core.changeStatusBomber(core.status())
+	core.publishCapabilities()
+}
+
+// setStatus - status of the bomber is changed by its attacks at once
+func (core *Core) setStatus(status system.StatusBomber) {
+	atomic.StoreInt32((*int32)(&core.currentStatusBomber), int32(status))
+}
+
+func (core *Core) status() system.StatusBomber {
+	return system.StatusBomber(atomic.LoadInt32((*int32)(&core.currentStatusBomber)))
 }
 
 func (core *Core) handlingChangeStatusBomber() {
-	currentStatus := core.currentStatusBomber
+	currentStatus := core.status()
 	for {
 		time.Sleep(time.Second * 5)
-		if currentStatus != core.currentStatusBomber {
-			logrus.Debug("Handled changing current status worker: ", core.currentStatusBomber.String())
-			core.changeStatusBomber(core.currentStatusBomber)
-			currentStatus = core.currentStatusBomber
+		if status := core.status(); currentStatus != status {
+			logrus.Debug("Handled changing current status worker: ", status.String())
+			core.changeStatusBomber(status)
+			currentStatus = status
 		}
 	}
 }
 
-func (core *Core) gracefullDownService() {
+// GracefullDownService - announces that the bomber is down, it is the last status of the bomber
+func (core *Core) GracefullDownService() {
 	logrus.Debug("Graceful down service")
 	core.changeStatusBomber(system.StatusBomber_DOWN)
 }
 
 func (core *Core) changeStatusBomber(status system.StatusBomber) {
+	metrics.Status(int32(status))
 	statusBomberInitialized := system.BomberStatusChange{
 		BomberId:     core.config.CurrentServiceID,
 		StatusBomber: status,
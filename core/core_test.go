@@ -0,0 +1,172 @@
+package core
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+)
+
+func TestEngageRelease(t *testing.T) {
+	core := &Core{capacity: 2}
+	if !core.TryEngage() || !core.TryEngage() {
+		t.Fatal("bomber with capacity 2 did not take 2 tasks")
+	}
+	if core.TryEngage() {
+		t.Fatal("bomber took a task beyond its capacity")
+	}
+	if err := core.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if err := core.Release(); err != ErrNotEngaged {
+		t.Fatalf("release without engagement = %v, expected ErrNotEngaged", err)
+	}
+	if !core.Idle() {
+		t.Fatal("engagement went below zero")
+	}
+	if !core.TryEngage() || !core.TryEngage() || core.TryEngage() {
+		t.Fatal("capacity changed after release without engagement")
+	}
+}
+
+func TestEngageReleaseConcurrently(t *testing.T) {
+	const capacity = 3
+	core := &Core{capacity: capacity}
+	var running, highest int32
+	var wg sync.WaitGroup
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attempt := 0; attempt < 1000; attempt++ {
+				if !core.TryEngage() {
+					continue
+				}
+				current := atomic.AddInt32(&running, 1)
+				for {
+					seen := atomic.LoadInt32(&highest)
+					if current <= seen || atomic.CompareAndSwapInt32(&highest, seen, current) {
+						break
+					}
+				}
+				atomic.AddInt32(&running, -1)
+				if err := core.Release(); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if highest > capacity {
+		t.Fatalf("%d attacks ran at once with capacity %d", highest, capacity)
+	}
+	if !core.Idle() {
+		t.Fatalf("bomber is engaged by %d tasks after all of them were released", atomic.LoadInt32(&core.engaged))
+	}
+	var stray int32
+	for worker := 0; worker < 16; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if core.Release() == nil {
+				atomic.AddInt32(&stray, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if stray > 0 {
+		t.Fatalf("%d releases of idle bomber succeeded", stray)
+	}
+	for index := 0; index < capacity; index++ {
+		if !core.TryEngage() {
+			t.Fatal("idle bomber did not take tasks up to its capacity")
+		}
+	}
+	if core.TryEngage() {
+		t.Fatal("stray releases raised capacity of the bomber")
+	}
+}
+
+func TestStartUnknownMode(t *testing.T) {
+	bomber := &Core{config: &config.Configuration{}, attacks: attacks{byFormId: map[string]*Attack{}}}
+	task := taskWithOptions("")
+	attack, err := bomber.PreparingData(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attack.options.Mode = "htttp"
+	var wg sync.WaitGroup
+	wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		attack.Start(task, &wg)
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("attack of unknown mode did not end")
+	}
+	if !errors.Is(attack.Failure(), ErrUnknownMode) {
+		t.Fatalf("failure %v, expected ErrUnknownMode", attack.Failure())
+	}
+}
+
+func TestStartSavesAllResults(t *testing.T) {
+	cases := []struct {
+		name string
+		rps  int64
+		time int64
+	}{
+		{"single request", 1, 1},
+		{"requests of several seconds", 2, 2},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			var received int64
+			server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				atomic.AddInt64(&received, 1)
+			}))
+			defer server.Close()
+			bomber := &Core{config: &config.Configuration{}, attacks: attacks{byFormId: map[string]*Attack{}}}
+			task := taskWithOptions("")
+			task.Script.Address = server.URL
+			task.Script.Config.Rps = testCase.rps
+			task.Script.Config.Time = testCase.time
+			attack, err := bomber.PreparingData(task)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var wg sync.WaitGroup
+			wg.Add(1)
+			done := make(chan struct{})
+			go func() {
+				attack.Start(task, &wg)
+				wg.Wait()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(10 * time.Second):
+				t.Fatal("attack did not end")
+			}
+			expected := testCase.rps * testCase.time
+			attack.results.Lock()
+			saved := attack.resultsAttack[http.StatusOK]
+			attack.results.Unlock()
+			if saved != expected || atomic.LoadInt64(&received) != expected {
+				t.Fatalf("saved %d results of %d requests, expected %d", saved, atomic.LoadInt64(&received), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package core
+
+import "time"
+
+// processCPUTime - cpu of the process is not sampled on this platform, heartbeats report zero
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package core
+
+import (
+	"syscall"
+	"time"
+)
+
+// processCPUTime - user and system cpu time consumed by the bomber process
+func processCPUTime() (time.Duration, bool) {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &usage); err != nil {
+		return 0, false
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano()), true
+}
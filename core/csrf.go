@@ -0,0 +1,184 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// sources of csrf token
+const (
+	CSRFFromCookie = "cookie"
+	CSRFFromHeader = "header"
+	CSRFFromMeta   = "meta"
+)
+
+var (
+	ErrCSRFFrom   = errors.New("csrf token is taken from cookie, header or meta")
+	ErrCSRFStatus = errors.New("page of csrf token is answered with status 400 and above")
+	ErrCSRFToken  = errors.New("csrf token is not found in response")
+)
+
+/*
+CSRFOptions - page fetched by each virtual user before its first request, its token is sent with
+mutating requests of the user. CSRF is disabled without path
+*/
+type CSRFOptions struct {
+	// appended to address of the task, absolute url replaces it
+	Path string `json:"path,omitempty"`
+	// cookie if empty
+	From string `json:"from,omitempty"`
+	// name of cookie, header or meta tag with the token, csrf-token if empty
+	Name string `json:"name,omitempty"`
+	// header of mutating requests with the token, X-CSRF-Token if empty
+	Header string `json:"header,omitempty"`
+}
+
+func (options CSRFOptions) enabled() bool {
+	return options.Path != ""
+}
+
+func (options CSRFOptions) name() string {
+	if options.Name == "" {
+		return "csrf-token"
+	}
+	return options.Name
+}
+
+// csrfSession - token of the virtual user and cookies of its page, as double submit cookie expects them back
+type csrfSession struct {
+	header  string
+	token   string
+	cookies loginSession
+}
+
+func (session *csrfSession) apply(request *fasthttp.Request) {
+	if session == nil {
+		return
+	}
+	session.cookies.apply(request)
+	switch string(request.Header.Method()) {
+	case fasthttp.MethodPost, fasthttp.MethodPut, fasthttp.MethodPatch, fasthttp.MethodDelete:
+		request.Header.Set(session.header, session.token)
+	}
+}
+
+type csrfStats struct {
+	fetched int64
+	failed  int64
+}
+
+type CSRFReport struct {
+	Fetched int64 `json:"fetched"`
+	Failed  int64 `json:"failed"`
+}
+
+// csrfReport - nil if the task has no csrf
+func (attack *Attack) csrfReport() *CSRFReport {
+	if attack.options == nil || !attack.options.CSRF.enabled() {
+		return nil
+	}
+	return &CSRFReport{Fetched: attack.resultCSRF.fetched, Failed: attack.resultCSRF.failed}
+}
+
+// fetchCSRF - user without token attacks as it is, the failure is counted in report
+func (attack *Attack) fetchCSRF(user *virtualUser) {
+	options := attack.options.CSRF
+	if !options.enabled() || attack.options.Mode != ModeHTTP {
+		return
+	}
+	session, err := attack.readCSRF(user, options)
+	attack.results.Lock()
+	attack.resultCSRF.fetched++
+	if err != nil {
+		attack.resultCSRF.failed++
+	}
+	attack.results.Unlock()
+	if err != nil {
+		logrus.Error("Can not fetch csrf token: ", err)
+		return
+	}
+	user.csrf = session
+}
+
+func (attack *Attack) readCSRF(user *virtualUser, options CSRFOptions) (*csrfSession, error) {
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	task := attack.setupTask
+	if strings.Contains(options.Path, "://") {
+		request.SetRequestURI(options.Path)
+	} else {
+		request.SetRequestURI(strings.TrimRight(task.Script.Address, "/") + "/" + strings.TrimLeft(options.Path, "/"))
+	}
+	attack.enhancedHeadersInRequest(request, task)
+	user.beginRequest()
+	if err := user.do(request, response); err != nil {
+		return nil, err
+	}
+	if response.StatusCode() >= fasthttp.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %d", ErrCSRFStatus, response.StatusCode())
+	}
+	session := &csrfSession{header: options.Header, cookies: loginSession{cookies: []storedCookie{}}}
+	if session.header == "" {
+		session.header = "X-CSRF-Token"
+	}
+	jar := newCookieJar()
+	jar.store(request, response)
+	for _, cookie := range jar.cookies {
+		session.cookies.cookies = append(session.cookies.cookies, *cookie)
+		if options.From == CSRFFromCookie || options.From == "" {
+			if cookie.name == options.name() {
+				session.token = cookie.value
+			}
+		}
+	}
+	switch options.From {
+	case CSRFFromHeader:
+		session.token = string(response.Header.Peek(options.name()))
+	case CSRFFromMeta:
+		body, err := decodeBody(response)
+		if err != nil {
+			return nil, err
+		}
+		session.token = metaContent(body, options.name())
+	}
+	if session.token == "" {
+		return nil, ErrCSRFToken
+	}
+	return session, nil
+}
+
+var (
+	metaTagPattern     = regexp.MustCompile(`(?i)<meta\s[^>]*>`)
+	metaNamePattern    = regexp.MustCompile(`(?i)\sname\s*=\s*["']([^"']*)["']`)
+	metaContentPattern = regexp.MustCompile(`(?i)\scontent\s*=\s*["']([^"']*)["']`)
+)
+
+// metaContent - content of the first meta tag with the name, attributes are in any order
+func metaContent(body []byte, name string) string {
+	for _, tag := range metaTagPattern.FindAll(body, -1) {
+		named := metaNamePattern.FindSubmatch(tag)
+		if named == nil || !strings.EqualFold(string(named[1]), name) {
+			continue
+		}
+		if content := metaContentPattern.FindSubmatch(tag); content != nil {
+			return string(content[1])
+		}
+	}
+	return ""
+}
+
+func validateCSRF(options CSRFOptions, add func(field string, reason string)) {
+	if !options.enabled() {
+		return
+	}
+	if options.From != "" && options.From != CSRFFromCookie && options.From != CSRFFromHeader && options.From != CSRFFromMeta {
+		add("schema.headers."+OptionsHeader+".csrf.from", ErrCSRFFrom.Error())
+	}
+}
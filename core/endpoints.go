@@ -0,0 +1,97 @@
+package core
+
+import (
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// paths with generated ids would create a label per request, the rest is counted together
+	maxEndpoints   = 100
+	otherEndpoints = "other"
+)
+
+type endpointStats struct {
+	requests int64
+	timeouts int64
+	statuses map[int32]int64
+	errors   map[string]int64
+	latency  *hdrhistogram.Histogram
+}
+
+type EndpointReport struct {
+	Requests int64            `json:"requests"`
+	Timeouts int64            `json:"timeouts"`
+	Statuses map[int32]int64  `json:"statuses"`
+	Errors   map[string]int64 `json:"errors"`
+	Latency  LatencyReport    `json:"latency"`
+}
+
+// endpointLabel - method and path of request without query
+func endpointLabel(request *fasthttp.Request) string {
+	return string(request.Header.Method()) + " " + string(request.URI().Path())
+}
+
+// recordEndpoint - must be called under results lock of the attack
+func (attack *Attack) recordEndpoint(result SliceResult) {
+	if result.Endpoint == "" {
+		return
+	}
+	label := result.Endpoint
+	stats, ok := attack.resultEndpoints[label]
+	if !ok {
+		if len(attack.resultEndpoints) >= maxEndpoints {
+			label = otherEndpoints
+			stats, ok = attack.resultEndpoints[label]
+		}
+		if !ok {
+			stats = newEndpointStats()
+			attack.resultEndpoints[label] = stats
+		}
+	}
+	stats.add(result)
+}
+
+func newEndpointStats() *endpointStats {
+	return &endpointStats{
+		statuses: map[int32]int64{},
+		errors:   map[string]int64{},
+		latency:  newLatencyHistogram(),
+	}
+}
+
+func (stats *endpointStats) add(result SliceResult) {
+	stats.requests++
+	if result.Timeout {
+		stats.timeouts++
+		stats.errors[result.Error]++
+		return
+	}
+	stats.statuses[int32(result.Status)]++
+	latency := result.TimeElapsed
+	if latency > latencyHighest {
+		latency = latencyHighest
+	}
+	stats.latency.RecordValue(latency)
+}
+
+func (attack *Attack) endpointsReport() map[string]EndpointReport {
+	if len(attack.resultEndpoints) == 0 {
+		return nil
+	}
+	report := make(map[string]EndpointReport, len(attack.resultEndpoints))
+	for label, stats := range attack.resultEndpoints {
+		report[label] = stats.report()
+	}
+	return report
+}
+
+func (stats *endpointStats) report() EndpointReport {
+	return EndpointReport{
+		Requests: stats.requests,
+		Timeouts: stats.timeouts,
+		Statuses: stats.statuses,
+		Errors:   stats.errors,
+		Latency:  latencyReport(stats.latency),
+	}
+}
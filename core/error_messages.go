@@ -0,0 +1,91 @@
+package core
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultTopErrors = 10
+	// limit of unique messages, messages with generated parts would grow without it
+	maxErrorMessages = 1000
+)
+
+type ErrorMessageReport struct {
+	Message  string `json:"message"`
+	Category string `json:"category"`
+	Count    int64  `json:"count"`
+}
+
+type ErrorMessagesReport struct {
+	Unique int `json:"unique"`
+	// failures with new messages after limit of unique messages
+	Dropped int64                `json:"dropped"`
+	Top     []ErrorMessageReport `json:"top"`
+}
+
+// errorMessages - counts of unique messages of transport errors, used by workers concurrently
+type errorMessages struct {
+	mutex    sync.Mutex
+	top      int
+	messages map[string]*ErrorMessageReport
+	dropped  int64
+}
+
+func newErrorMessages(top int) *errorMessages {
+	if top <= 0 {
+		top = defaultTopErrors
+	}
+	return &errorMessages{top: top, messages: map[string]*ErrorMessageReport{}}
+}
+
+// add - counts message of error, true if it is the first one with this message
+func (messages *errorMessages) add(category string, err error) bool {
+	message := err.Error()
+	messages.mutex.Lock()
+	defer messages.mutex.Unlock()
+	if counted, ok := messages.messages[message]; ok {
+		counted.Count++
+		return false
+	}
+	if len(messages.messages) >= maxErrorMessages {
+		messages.dropped++
+		return false
+	}
+	messages.messages[message] = &ErrorMessageReport{Message: message, Category: category, Count: 1}
+	return true
+}
+
+func (messages *errorMessages) report() ErrorMessagesReport {
+	messages.mutex.Lock()
+	defer messages.mutex.Unlock()
+	top := make([]ErrorMessageReport, 0, len(messages.messages))
+	for _, counted := range messages.messages {
+		top = append(top, *counted)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Count != top[j].Count {
+			return top[i].Count > top[j].Count
+		}
+		return top[i].Message < top[j].Message
+	})
+	if len(top) > messages.top {
+		top = top[:messages.top]
+	}
+	return ErrorMessagesReport{
+		Unique:  len(messages.messages),
+		Dropped: messages.dropped,
+		Top:     top,
+	}
+}
+
+// logError - only the first error with each message is logged as error, messages are counted in report
+func (attack *Attack) logError(category string, err error) {
+	if attack.resultMessages.add(category, err) {
+		logrus.Error("Error while request: ", err)
+		return
+	}
+	logrus.Debug("Error while request: ", err)
+}
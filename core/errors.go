@@ -0,0 +1,65 @@
+package core
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/bomber-team/rest-bomber/metrics"
+	"github.com/bomber-team/rest-bomber/transport"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	ErrorTimeout      = "timeout"
+	ErrorRefused      = "connection_refused"
+	ErrorReset        = "connection_reset"
+	ErrorDNS          = "dns"
+	ErrorTLS          = "tls"
+	ErrorTooManyFiles = "too_many_open_files"
+	ErrorOther        = "other"
+)
+
+// classifyError - category of transport error of request
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certificateErr x509.CertificateInvalidError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE):
+		return ErrorTooManyFiles
+	case errors.As(err, &dnsErr) || errors.Is(err, transport.ErrNoAddresses):
+		return ErrorDNS
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrorRefused
+	case errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, fasthttp.ErrConnectionClosed):
+		return ErrorReset
+	case errors.As(err, &recordErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &certificateErr) ||
+		strings.HasPrefix(err.Error(), "tls: "):
+		return ErrorTLS
+	case errors.Is(err, fasthttp.ErrTimeout) || errors.Is(err, fasthttp.ErrDialTimeout) ||
+		(errors.As(err, &netErr) && netErr.Timeout()):
+		return ErrorTimeout
+	}
+	return ErrorOther
+}
+
+// countError - counts failure of connection of modes, which are not counted as requests
+func (attack *Attack) countError(err error) {
+	category := classifyError(err)
+	metrics.RequestFailed(category)
+	attack.logError(category, err)
+	attack.results.Lock()
+	defer attack.results.Unlock()
+	attack.resultErrors[category]++
+}
@@ -0,0 +1,195 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrJSONPath        = errors.New("json path has to start with $ and consist of .name, ['name'] and [index]")
+	ErrExtractNotFound = errors.New("value of json path is not found in response")
+	ErrRegexNotFound   = errors.New("pattern of regex extraction does not match response")
+	ErrRegexGroup      = errors.New("group of regex extraction is not one of groups of its pattern")
+)
+
+// RegexExtract - value of capture group of the first match in body or header of the response
+type RegexExtract struct {
+	Pattern string `json:"pattern"`
+	// header of the response, for example Location, body if empty
+	Header string `json:"header,omitempty"`
+	// the first group if empty, the whole match if the pattern has no groups
+	Group int `json:"group,omitempty"`
+}
+
+type regexExtractor struct {
+	pattern *regexp.Regexp
+	header  string
+	group   int
+}
+
+func compileRegexExtract(extract RegexExtract) (regexExtractor, error) {
+	pattern, err := regexp.Compile(extract.Pattern)
+	if err != nil {
+		return regexExtractor{}, err
+	}
+	group := extract.Group
+	if group == 0 && pattern.NumSubexp() > 0 {
+		group = 1
+	}
+	if group < 0 || group > pattern.NumSubexp() {
+		return regexExtractor{}, ErrRegexGroup
+	}
+	return regexExtractor{pattern: pattern, header: extract.Header, group: group}, nil
+}
+
+// extractRegex - values of all extractors into variables, error if one of them does not match
+func extractRegex(response *fasthttp.Response, extractors map[string]regexExtractor, variables map[string]string) error {
+	var body []byte
+	for name, extractor := range extractors {
+		source := response.Header.Peek(extractor.header)
+		if extractor.header == "" {
+			if body == nil {
+				decoded, err := decodeBody(response)
+				if err != nil {
+					return err
+				}
+				body = decoded
+			}
+			source = body
+		}
+		match := extractor.pattern.FindSubmatch(source)
+		if match == nil {
+			return ErrRegexNotFound
+		}
+		variables[name] = string(match[extractor.group])
+	}
+	return nil
+}
+
+type pathToken struct {
+	field   string
+	index   int
+	isIndex bool
+}
+
+/*
+jsonPath - subset of JSONPath, which addresses one value: $.items[0].id, $['content-type'].
+Negative index counts from the end of array
+*/
+type jsonPath []pathToken
+
+func compileJSONPath(path string) (jsonPath, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, ErrJSONPath
+	}
+	compiled := jsonPath{}
+	rest := path[1:]
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[]")
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			if end == 0 || end+1 < len(rest) && rest[end+1] == ']' {
+				return nil, ErrJSONPath
+			}
+			compiled = append(compiled, pathToken{field: rest[1 : end+1]})
+			rest = rest[end+1:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 2 {
+				return nil, ErrJSONPath
+			}
+			inner := rest[1:end]
+			if quote := inner[0]; quote == '\'' || quote == '"' {
+				if len(inner) < 2 || inner[len(inner)-1] != quote {
+					return nil, ErrJSONPath
+				}
+				compiled = append(compiled, pathToken{field: inner[1 : len(inner)-1]})
+			} else {
+				index, err := strconv.Atoi(inner)
+				if err != nil {
+					return nil, ErrJSONPath
+				}
+				compiled = append(compiled, pathToken{index: index, isIndex: true})
+			}
+			rest = rest[end+1:]
+		default:
+			return nil, ErrJSONPath
+		}
+	}
+	return compiled, nil
+}
+
+func (path jsonPath) lookup(document interface{}) (interface{}, bool) {
+	current := document
+	for _, token := range path {
+		if token.isIndex {
+			array, ok := current.([]interface{})
+			if !ok {
+				return nil, false
+			}
+			index := token.index
+			if index < 0 {
+				index += len(array)
+			}
+			if index < 0 || index >= len(array) {
+				return nil, false
+			}
+			current = array[index]
+			continue
+		}
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[token.field]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// extractValue - strings are extracted as they are, other values as json
+func extractValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case json.Number:
+		return typed.String()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(encoded)
+}
+
+// extractJSON - values of all paths into variables, error if body is not json or one of paths is not found
+func extractJSON(response *fasthttp.Response, paths map[string]jsonPath, variables map[string]string) error {
+	body, err := decodeBody(response)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return err
+	}
+	for name, path := range paths {
+		value, ok := path.lookup(document)
+		if !ok {
+			return ErrExtractNotFound
+		}
+		variables[name] = extractValue(value)
+	}
+	return nil
+}
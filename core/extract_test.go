@@ -0,0 +1,166 @@
+package core
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+)
+
+const extractDocument = `{"a": [{"id": 7}, "second", "last"], "x.y": "dotted", "content-type": "json",
+	"nested": {"items": [1, 2.5, {"ok": true}]}, "empty": null}`
+
+func TestJSONPath(t *testing.T) {
+	cases := []struct {
+		name  string
+		path  string
+		value string
+		found bool
+	}{
+		{"root", "$", `{"a":[{"id":7},"second","last"],"content-type":"json","empty":null,` +
+			`"nested":{"items":[1,2.5,{"ok":true}]},"x.y":"dotted"}`, true},
+		{"array element", "$.a[1]", "second", true},
+		{"field of array element", "$.a[0].id", "7", true},
+		{"whole object", "$.a[0]", `{"id":7}`, true},
+		{"quoted field with dot", "$['x.y']", "dotted", true},
+		{"double quoted field", `$["content-type"]`, "json", true},
+		{"negative index", "$.a[-1]", "last", true},
+		{"negative index of nested array", "$.nested.items[-3]", "1", true},
+		{"number", "$.nested.items[1]", "2.5", true},
+		{"null", "$.empty", "null", true},
+		{"index after end", "$.a[3]", "", false},
+		{"negative index before start", "$.a[-4]", "", false},
+		{"missing field", "$.b", "", false},
+		{"field of array", "$.a.id", "", false},
+		{"index of object", "$.nested[0]", "", false},
+		{"field of string", "$.a[1].id", "", false},
+	}
+	decoder := json.NewDecoder(strings.NewReader(extractDocument))
+	decoder.UseNumber()
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		t.Fatal(err)
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path, err := compileJSONPath(testCase.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			value, found := path.lookup(document)
+			if found != testCase.found {
+				t.Fatalf("found %v, expected %v", found, testCase.found)
+			}
+			if found && extractValue(value) != testCase.value {
+				t.Fatalf("got %s, expected %s", extractValue(value), testCase.value)
+			}
+		})
+	}
+}
+
+func TestCompileJSONPathMalformed(t *testing.T) {
+	cases := []string{"", "a", "$a", "$.", "$..a", "$.a.", "$[", "$[]", "$[']", `$['a"]`, "$['a'", "$[a]", "$[1.5]", "$.a]"}
+	for _, path := range cases {
+		t.Run(path, func(t *testing.T) {
+			if _, err := compileJSONPath(path); err != ErrJSONPath {
+				t.Fatalf("got %v, expected %v", err, ErrJSONPath)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	paths := map[string]jsonPath{}
+	for name, path := range map[string]string{"id": "$.a[0].id", "type": "$['content-type']"} {
+		compiled, err := compileJSONPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths[name] = compiled
+	}
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	response.SetBodyString(extractDocument)
+	variables := map[string]string{}
+	if err := extractJSON(response, paths, variables); err != nil {
+		t.Fatal(err)
+	}
+	if variables["id"] != "7" || variables["type"] != "json" {
+		t.Fatalf("unexpected variables %v", variables)
+	}
+	missing, _ := compileJSONPath("$.missing")
+	if err := extractJSON(response, map[string]jsonPath{"missing": missing}, variables); err != ErrExtractNotFound {
+		t.Fatalf("got %v, expected %v", err, ErrExtractNotFound)
+	}
+	response.SetBodyString("not json")
+	if err := extractJSON(response, paths, map[string]string{}); err == nil {
+		t.Fatal("expected error of body which is not json")
+	}
+}
+
+func TestCompileRegexExtract(t *testing.T) {
+	cases := []struct {
+		name    string
+		extract RegexExtract
+		group   int
+		err     error
+	}{
+		{"first group by default", RegexExtract{Pattern: `id=(\d+)&(\w+)`}, 1, nil},
+		{"whole match without groups", RegexExtract{Pattern: `\d+`}, 0, nil},
+		{"second group", RegexExtract{Pattern: `id=(\d+)&(\w+)`, Group: 2}, 2, nil},
+		{"group after the last one", RegexExtract{Pattern: `id=(\d+)&(\w+)`, Group: 3}, 0, ErrRegexGroup},
+		{"group of pattern without groups", RegexExtract{Pattern: `\d+`, Group: 1}, 0, ErrRegexGroup},
+		{"negative group", RegexExtract{Pattern: `(\d+)`, Group: -1}, 0, ErrRegexGroup},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			extractor, err := compileRegexExtract(testCase.extract)
+			if err != testCase.err {
+				t.Fatalf("got %v, expected %v", err, testCase.err)
+			}
+			if err == nil && extractor.group != testCase.group {
+				t.Fatalf("got group %d, expected %d", extractor.group, testCase.group)
+			}
+		})
+	}
+	if _, err := compileRegexExtract(RegexExtract{Pattern: `(\d+`}); err == nil {
+		t.Fatal("expected error of malformed pattern")
+	}
+}
+
+func TestExtractRegex(t *testing.T) {
+	cases := []struct {
+		name    string
+		extract RegexExtract
+		value   string
+		err     error
+	}{
+		{"first group of body", RegexExtract{Pattern: `name="csrf" value="(\w+)"`}, "abc123", nil},
+		{"whole match of body", RegexExtract{Pattern: `order-\d+`}, "order-42", nil},
+		{"second group of header", RegexExtract{Pattern: `/orders/(\d+)/(\w+)`, Header: "Location", Group: 2}, "items", nil},
+		{"the first match", RegexExtract{Pattern: `item-(\d)`}, "1", nil},
+		{"no match in body", RegexExtract{Pattern: `missing-(\d+)`}, "", ErrRegexNotFound},
+		{"missing header", RegexExtract{Pattern: `(.+)`, Header: "X-Missing"}, "", ErrRegexNotFound},
+	}
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	response.SetBodyString(`<input name="csrf" value="abc123"> order-42 item-1 item-2`)
+	response.Header.Set("Location", "/orders/42/items")
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			extractor, err := compileRegexExtract(testCase.extract)
+			if err != nil {
+				t.Fatal(err)
+			}
+			variables := map[string]string{}
+			err = extractRegex(response, map[string]regexExtractor{"value": extractor}, variables)
+			if err != testCase.err {
+				t.Fatalf("got %v, expected %v", err, testCase.err)
+			}
+			if variables["value"] != testCase.value {
+				t.Fatalf("got %q, expected %q", variables["value"], testCase.value)
+			}
+		})
+	}
+}
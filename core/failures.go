@@ -0,0 +1,139 @@
+package core
+
+import (
+	"encoding/base64"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/valyala/fasthttp"
+)
+
+const defaultSampleBody = 1024
+
+type FailureSamplesOptions struct {
+	// failed responses kept for each status and errors kept for each category, disabled if empty
+	PerStatus int `json:"per_status,omitempty"`
+	// bodies of responses are truncated to this size, 1024 if empty
+	MaxBodyBytes int `json:"max_body_bytes,omitempty"`
+}
+
+type ResponseSample struct {
+	URI       string            `json:"uri"`
+	RequestId string            `json:"request_id,omitempty"`
+	Status    int               `json:"status"`
+	LatencyNs int64             `json:"latency_ns"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+	// body is not valid utf-8 and is encoded in base64
+	BodyBase64 bool `json:"body_base64,omitempty"`
+	// size of the whole body after decompression
+	BodyBytes int  `json:"body_bytes"`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+type ErrorSample struct {
+	URI       string `json:"uri"`
+	RequestId string `json:"request_id,omitempty"`
+	Message   string `json:"message"`
+}
+
+type FailureSamplesReport struct {
+	Responses map[int32][]ResponseSample `json:"responses"`
+	Errors    map[string][]ErrorSample   `json:"errors"`
+}
+
+// failureSamples - the first failed responses and errors of the attack, used by workers concurrently
+type failureSamples struct {
+	mutex     sync.Mutex
+	options   FailureSamplesOptions
+	responses map[int32][]ResponseSample
+	errors    map[string][]ErrorSample
+}
+
+func newFailureSamples(options FailureSamplesOptions) *failureSamples {
+	if options.PerStatus <= 0 {
+		return nil
+	}
+	if options.MaxBodyBytes <= 0 {
+		options.MaxBodyBytes = defaultSampleBody
+	}
+	return &failureSamples{
+		options:   options,
+		responses: map[int32][]ResponseSample{},
+		errors:    map[string][]ErrorSample{},
+	}
+}
+
+// addResponse - keeps response with status of error if there are less samples of its status than needed
+func (samples *failureSamples) addResponse(request *fasthttp.Request, response *fasthttp.Response, latency int64) {
+	if samples == nil || response.StatusCode() < fasthttp.StatusBadRequest {
+		return
+	}
+	status := int32(response.StatusCode())
+	samples.mutex.Lock()
+	defer samples.mutex.Unlock()
+	if len(samples.responses[status]) >= samples.options.PerStatus {
+		return
+	}
+	sample := captureResponse(request, response, samples.options.MaxBodyBytes)
+	sample.LatencyNs = latency
+	samples.responses[status] = append(samples.responses[status], sample)
+}
+
+func (samples *failureSamples) addError(request *fasthttp.Request, err error) {
+	if samples == nil {
+		return
+	}
+	category := classifyError(err)
+	samples.mutex.Lock()
+	defer samples.mutex.Unlock()
+	if len(samples.errors[category]) >= samples.options.PerStatus {
+		return
+	}
+	samples.errors[category] = append(samples.errors[category], ErrorSample{
+		URI:       request.URI().String(),
+		RequestId: string(request.Header.Peek(requestIdHeader)),
+		Message:   err.Error(),
+	})
+}
+
+func (samples *failureSamples) report() *FailureSamplesReport {
+	if samples == nil {
+		return nil
+	}
+	samples.mutex.Lock()
+	defer samples.mutex.Unlock()
+	return &FailureSamplesReport{
+		Responses: samples.responses,
+		Errors:    samples.errors,
+	}
+}
+
+// captureResponse - copy of response with decompressed body truncated to maxBody bytes
+func captureResponse(request *fasthttp.Request, response *fasthttp.Response, maxBody int) ResponseSample {
+	sample := ResponseSample{
+		URI:       request.URI().String(),
+		RequestId: string(request.Header.Peek(requestIdHeader)),
+		Status:    response.StatusCode(),
+		Headers:   map[string]string{},
+	}
+	response.Header.VisitAll(func(key []byte, value []byte) {
+		sample.Headers[string(key)] = string(value)
+	})
+	body, err := decodeBody(response)
+	if err != nil {
+		body = response.Body()
+	}
+	sample.BodyBytes = len(body)
+	if len(body) > maxBody {
+		body = body[:maxBody]
+		sample.Truncated = true
+	}
+	if utf8.Valid(body) {
+		sample.Body = string(body)
+	} else {
+		sample.Body = base64.StdEncoding.EncodeToString(body)
+		sample.BodyBase64 = true
+	}
+	return sample
+}
@@ -0,0 +1,234 @@
+package core
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+var (
+	ErrGRPCMethod    = errors.New("grpc method is not found in descriptor")
+	ErrGRPCStreaming = errors.New("streaming grpc methods are not supported")
+)
+
+type GRPCOptions struct {
+	// base64 of FileDescriptorSet, protoc --include_imports --descriptor_set_out
+	Descriptor string `json:"descriptor"`
+	// full name of method, like package.Service/Method
+	Method string `json:"method"`
+	// json template of request message with {{name}} placeholders of body params,
+	// generated json body is sent if empty
+	Message string `json:"message,omitempty"`
+	// amount of concurrent connections, amount of workers if empty
+	Connections int   `json:"connections,omitempty"`
+	TimeoutMs   int64 `json:"timeout_ms,omitempty"`
+}
+
+type grpcStats struct {
+	mutex         sync.Mutex
+	connectErrors int64
+	calls         int64
+	codes         map[string]int64
+}
+
+type GRPCReport struct {
+	ConnectErrors int64            `json:"connect_errors"`
+	Calls         int64            `json:"calls"`
+	Codes         map[string]int64 `json:"codes"`
+}
+
+func (stats *grpcStats) report() *GRPCReport {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	report := &GRPCReport{
+		ConnectErrors: stats.connectErrors,
+		Calls:         stats.calls,
+		Codes:         map[string]int64{},
+	}
+	for code, amount := range stats.codes {
+		report.Codes[code] = amount
+	}
+	return report
+}
+
+// grpcCodec - marshals dynamic messages, which default codec of grpc does not know
+type grpcCodec struct{}
+
+func (grpcCodec) Marshal(v interface{}) ([]byte, error) {
+	return proto.Marshal(v.(proto.Message))
+}
+
+func (grpcCodec) Unmarshal(data []byte, v interface{}) error {
+	return proto.Unmarshal(data, v.(proto.Message))
+}
+
+func (grpcCodec) Name() string {
+	return "proto"
+}
+
+/*
+loadGRPCMethod - finds unary method of options in descriptor set
+*/
+func loadGRPCMethod(options GRPCOptions) (protoreflect.MethodDescriptor, error) {
+	raw, err := base64.StdEncoding.DecodeString(options.Descriptor)
+	if err != nil {
+		return nil, err
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(raw, set); err != nil {
+		return nil, err
+	}
+	files, err := protodesc.NewFiles(set)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(options.Method, "/")
+	slash := strings.LastIndex(name, "/")
+	if slash < 0 {
+		return nil, ErrGRPCMethod
+	}
+	found, err := files.FindDescriptorByName(protoreflect.FullName(name[:slash]))
+	if err != nil {
+		return nil, ErrGRPCMethod
+	}
+	service, ok := found.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, ErrGRPCMethod
+	}
+	method := service.Methods().ByName(protoreflect.Name(name[slash+1:]))
+	if method == nil {
+		return nil, ErrGRPCMethod
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, ErrGRPCStreaming
+	}
+	return method, nil
+}
+
+/*
+startGRPCAttack - opens connections to the target and calls method with rps of the task
+divided between connections during time of the task
+*/
+func (attack *Attack) startGRPCAttack(task rest_contracts.Task) {
+	connections := attack.options.GRPC.Connections
+	if connections <= 0 {
+		connections = currentWorkers
+	}
+	attack.resultGRPC = &grpcStats{codes: map[string]int64{}}
+	interval := time.Duration(float64(time.Second) * float64(connections) / float64(task.Script.Config.Rps))
+	deadline := time.Now().Add(time.Duration(task.Script.Config.Time) * time.Second)
+	var wg sync.WaitGroup
+	for index := 0; index < connections; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attack.runGRPCUser(task, interval, deadline)
+		}()
+	}
+	wg.Wait()
+}
+
+func (attack *Attack) dialGRPC(address string) (*grpc.ClientConn, error) {
+	credentialsOption := grpc.WithInsecure()
+	if strings.HasPrefix(address, "grpcs://") {
+		credentialsOption = grpc.WithTransportCredentials(credentials.NewTLS(attack.options.TLS.tlsConfig()))
+	}
+	target := strings.TrimPrefix(strings.TrimPrefix(address, "grpcs://"), "grpc://")
+	return grpc.Dial(target,
+		credentialsOption,
+		grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+			return attack.dialer.Dial(addr)
+		}),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(grpcCodec{})),
+	)
+}
+
+func (attack *Attack) grpcMessage(task rest_contracts.Task) (proto.Message, error) {
+	var body []byte
+	if attack.options.GRPC.Message != "" {
+		body = []byte(renderTemplate(attack.options.GRPC.Message, task.Schema.Body))
+	} else {
+		var err error
+		if body, err = attack.preparingBody(task.Schema.Body); err != nil {
+			return nil, err
+		}
+	}
+	message := dynamicpb.NewMessage(attack.grpcMethod.Input())
+	if err := protojson.Unmarshal(body, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (attack *Attack) runGRPCUser(task rest_contracts.Task, interval time.Duration, deadline time.Time) {
+	stats := attack.resultGRPC
+	conn, err := attack.dialGRPC(task.Script.Address)
+	if err != nil {
+		logrus.Error("Can not connect to grpc target: ", err)
+		stats.mutex.Lock()
+		stats.connectErrors++
+		stats.mutex.Unlock()
+		return
+	}
+	defer conn.Close()
+	timeout := time.Duration(attack.options.GRPC.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultReplyTimeout
+	}
+	outgoing := metadata.MD{}
+	for key, values := range attack.schemaHeaders(task) {
+		outgoing.Set(key, values...)
+	}
+	method := "/" + string(attack.grpcMethod.Parent().FullName()) + "/" + string(attack.grpcMethod.Name())
+	attackCtx := attack.attackContext()
+	for time.Now().Before(deadline) && attackCtx.Err() == nil {
+		timeStart := time.Now()
+		request, err := attack.grpcMessage(task)
+		if err != nil {
+			logrus.Error("Can not form grpc message: ", err)
+			return
+		}
+		reply := dynamicpb.NewMessage(attack.grpcMethod.Output())
+		ctx, cancel := context.WithTimeout(metadata.NewOutgoingContext(context.Background(), outgoing), timeout)
+		err = conn.Invoke(ctx, method, request, reply)
+		cancel()
+		latency := time.Since(timeStart)
+		code := status.Code(err)
+		stats.mutex.Lock()
+		stats.calls++
+		stats.codes[code.String()]++
+		stats.mutex.Unlock()
+		attack.results.Lock()
+		attack.resultsAttack[int32(code)]++
+		if code == codes.DeadlineExceeded {
+			attack.recordTimeout(ErrorTimeout)
+		} else {
+			attack.recordLatency(latency.Nanoseconds(), int32(code), code != codes.OK, 0)
+		}
+		attack.results.Unlock()
+		if code != codes.DeadlineExceeded {
+			attack.tahometr.AddTime(latency)
+		}
+		if elapsed := time.Since(timeStart); elapsed < interval {
+			time.Sleep(interval - elapsed)
+		}
+	}
+}
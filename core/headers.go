@@ -0,0 +1,112 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+var ErrHeaderNotFound = errors.New("header to extract is not found in response")
+
+type headerStats struct {
+	seen    int64
+	missing int64
+	numeric int64 // values parsed as numbers
+	min     float64
+	max     float64
+	sum     float64
+	last    string
+}
+
+/*
+HeaderReport - values of captured header of all responses. Min, max and mean are of values which are
+numbers, for example X-RateLimit-Remaining, they are absent if no value is a number
+*/
+type HeaderReport struct {
+	Seen    int64    `json:"seen"`
+	Missing int64    `json:"missing"`
+	Numeric int64    `json:"numeric"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Mean    *float64 `json:"mean,omitempty"`
+	Last    string   `json:"last"`
+}
+
+// inspectResponse - assertions and captured headers of the response, which passed transport
+func (attack *Attack) inspectResponse(result *SliceResult, response *fasthttp.Response, assertions *assertions) {
+	if result.Skipped || result.Timeout {
+		return
+	}
+	if assertions != nil {
+		result.Asserted = true
+		result.AssertionFailed = assertions.check(response)
+	}
+	if headers := attack.options.CaptureHeaders; len(headers) > 0 {
+		result.Captured = make([]string, len(headers))
+		for index, header := range headers {
+			result.Captured[index] = string(response.Header.Peek(header))
+		}
+	}
+}
+
+// recordHeaders - must be called under results lock of the attack
+func (attack *Attack) recordHeaders(result SliceResult) {
+	if len(result.Captured) == 0 {
+		return
+	}
+	if attack.resultHeaders == nil {
+		attack.resultHeaders = make([]headerStats, len(result.Captured))
+	}
+	for index, value := range result.Captured {
+		stats := &attack.resultHeaders[index]
+		if value == "" {
+			stats.missing++
+			continue
+		}
+		stats.seen++
+		stats.last = value
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			continue
+		}
+		if stats.numeric == 0 || number < stats.min {
+			stats.min = number
+		}
+		if stats.numeric == 0 || number > stats.max {
+			stats.max = number
+		}
+		stats.numeric++
+		stats.sum += number
+	}
+}
+
+// headersReport - nil if the task captures no headers
+func (attack *Attack) headersReport() map[string]HeaderReport {
+	if attack.resultHeaders == nil {
+		return nil
+	}
+	report := make(map[string]HeaderReport, len(attack.resultHeaders))
+	for index, stats := range attack.resultHeaders {
+		header := HeaderReport{Seen: stats.seen, Missing: stats.missing, Numeric: stats.numeric, Last: stats.last}
+		if stats.numeric > 0 {
+			min, max, mean := stats.min, stats.max, stats.sum/float64(stats.numeric)
+			header.Min, header.Max, header.Mean = &min, &max, &mean
+		}
+		report[attack.options.CaptureHeaders[index]] = header
+	}
+	return report
+}
+
+// extractHeaders - values of headers of the response into variables, error if one of them is absent
+func extractHeaders(response *fasthttp.Response, headers map[string]string, variables map[string]string) error {
+	for name, header := range headers {
+		value := response.Header.Peek(header)
+		if len(value) == 0 {
+			return ErrHeaderNotFound
+		}
+		variables[name] = string(value)
+	}
+	return nil
+}
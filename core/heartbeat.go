@@ -0,0 +1,175 @@
+package core
+
+import (
+	"encoding/json"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/system"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const topicHeartbeat = "bombers.server.heartbeat"
+
+/*
+Heartbeat - published periodically whatever status of the bomber is, bomber which stopped
+sending heartbeats or sends them with stalled progress is stuck
+*/
+type Heartbeat struct {
+	BomberId        string    `json:"bomber_id"`
+	Tenant          string    `json:"tenant,omitempty"`
+	ContractVersion string    `json:"contract_version"`
+	Status          string    `json:"status"`
+	Time            time.Time `json:"time"`
+	FormId          string    `json:"form_id,omitempty"`
+	ElapsedMs       int64     `json:"elapsed_ms"`
+	Rps             float64   `json:"rps"` // of the last elapsed bucket of the timeline
+	TargetRps       int64     `json:"target_rps"`
+	Progress        float64   `json:"progress_percent"`
+	Completed       int64     `json:"completed"`
+	InFlight        int64     `json:"in_flight"`
+	// cpu of the bomber process since previous heartbeat, 100 is one core
+	CPU        float64 `json:"cpu_percent"`
+	HeapBytes  uint64  `json:"heap_bytes"`
+	SysBytes   uint64  `json:"sys_bytes"`
+	Goroutines int     `json:"goroutines"`
+	// attacks of the bomber from their preparing, metrics of the attack above are of the first running one
+	Attacks int `json:"attacks"`
+}
+
+// HeartbeatSampler - cpu of the process sampled by the previous heartbeat
+type HeartbeatSampler struct {
+	sampled time.Time
+	cpu     time.Duration
+}
+
+// heartbeats - loop of heartbeats, it is stopped when the loop with another interval starts
+type heartbeats struct {
+	mutex sync.Mutex
+	stop  chan struct{}
+}
+
+// StartHeartbeat - publishes heartbeats every interval, zero interval disables them. Heartbeats started before are stopped
+func (core *Core) StartHeartbeat(interval time.Duration) {
+	loop := &core.heartbeats
+	loop.mutex.Lock()
+	defer loop.mutex.Unlock()
+	if loop.stop != nil {
+		close(loop.stop)
+		loop.stop = nil
+	}
+	if interval <= 0 {
+		logrus.Info("Heartbeats of bomber are disabled")
+		return
+	}
+	stop := make(chan struct{})
+	loop.stop = stop
+	go func() {
+		sampler := NewHeartbeatSampler()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				core.publishHeartbeat(core.FormHeartbeat(sampler))
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// ReloadHeartbeat - listener of reloaded configuration, heartbeats are started again with changed interval
+func (core *Core) ReloadHeartbeat(config *config.Configuration, reload *config.Reload) {
+	if reload.Changed("HeartbeatInterval") {
+		core.StartHeartbeat(time.Duration(config.HeartbeatInterval) * time.Second)
+	}
+}
+
+func NewHeartbeatSampler() *HeartbeatSampler {
+	sampler := &HeartbeatSampler{}
+	sampler.cpuPercent()
+	return sampler
+}
+
+// FormHeartbeat - live status and metrics of the bomber, cpu is measured since previous heartbeat of the sampler
+func (core *Core) FormHeartbeat(sampler *HeartbeatSampler) *Heartbeat {
+	var memory runtime.MemStats
+	runtime.ReadMemStats(&memory)
+	heartbeat := &Heartbeat{
+		BomberId:        core.config.CurrentServiceID,
+		Tenant:          core.config.TenantId(),
+		ContractVersion: ContractVersion,
+		Status:          system.StatusBomber_UP.String(),
+		Time:            time.Now(),
+		InFlight:        metrics.InFlight(),
+		CPU:             sampler.cpuPercent(),
+		HeapBytes:       memory.HeapAlloc,
+		SysBytes:        memory.Sys,
+		Goroutines:      runtime.NumGoroutine(),
+	}
+	all := core.Attacks()
+	heartbeat.Attacks = len(all)
+	for _, attack := range all {
+		if attack.heartbeat(heartbeat) {
+			return heartbeat
+		}
+	}
+	if !core.Idle() {
+		heartbeat.Status = system.StatusBomber_PREPARING_DATA.String()
+	}
+	return heartbeat
+}
+
+// heartbeat - false if the attack is not running, heartbeat of the bomber has progress of its first running attack
+func (attack *Attack) heartbeat(heartbeat *Heartbeat) bool {
+	state := &attack.state
+	state.mutex.Lock()
+	running, formId, started, duration, requests := state.running, state.formId, state.started, state.duration, state.requests
+	state.mutex.Unlock()
+	if !running {
+		return false
+	}
+	heartbeat.Status = system.StatusBomber_WORKING.String()
+	heartbeat.FormId = formId
+	heartbeat.TargetRps = attack.attackTargetRps
+	heartbeat.ElapsedMs = time.Since(started).Milliseconds()
+	heartbeat.Completed = attack.completedRequests()
+	attack.results.Lock()
+	if attack.resultTimeline != nil {
+		heartbeat.Rps = attack.resultTimeline.window().Rps
+	}
+	attack.results.Unlock()
+	heartbeat.Progress = attack.attackProgress(heartbeat.Completed, time.Since(started), requests, duration)
+	return true
+}
+
+func (core *Core) publishHeartbeat(heartbeat *Heartbeat) {
+	data, err := json.Marshal(heartbeat)
+	if err != nil {
+		logrus.Error("Can not marshal heartbeat: ", err)
+		return
+	}
+	if errPublish := core.publisher.PublishNewMessage(topicHeartbeat, data); errPublish != nil {
+		logrus.Error("Can not publish heartbeat: ", errPublish)
+	}
+}
+
+func (sampler *HeartbeatSampler) cpuPercent() float64 {
+	cpu, ok := processCPUTime()
+	if !ok {
+		return 0
+	}
+	now := time.Now()
+	var percent float64
+	if !sampler.sampled.IsZero() {
+		if wall := now.Sub(sampler.sampled); wall > 0 {
+			percent = float64(cpu-sampler.cpu) / float64(wall) * 100
+		}
+	}
+	sampler.sampled, sampler.cpu = now, cpu
+	return percent
+}
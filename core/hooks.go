@@ -0,0 +1,143 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	ErrHookStatus    = errors.New("step answered with status 400 and above")
+	ErrHookAssertion = errors.New("step failed assertion")
+)
+
+// HookReport - request of setup or teardown, it is not counted in results of the attack
+type HookReport struct {
+	Name      string  `json:"name"`
+	Status    int     `json:"status"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	// step was not sent, as its condition did not hold
+	ConditionUnmet bool `json:"condition_unmet,omitempty"`
+}
+
+/*
+prepareSetup - steps of setup are sent one by one before the attack, the first failed one fails the task.
+Status 400 and above of a step, which later conditions refer to, does not fail it.
+Variables extracted by setup are kept for scenario and teardown
+*/
+func (attack *Attack) prepareSetup() error {
+	attack.resultSetup = nil
+	attack.resultTeardown = nil
+	attack.hookVariables = map[string]string{}
+	attack.teardownSteps = nil
+	if attack.options.Mode != ModeHTTP {
+		return nil
+	}
+	setup, err := compileScenario(ScenarioOptions{Steps: attack.options.Setup})
+	if err != nil {
+		return err
+	}
+	teardown, err := compileScenario(ScenarioOptions{Steps: attack.options.Teardown})
+	if err != nil {
+		return err
+	}
+	attack.teardownSteps = teardown
+	if len(setup) == 0 {
+		return nil
+	}
+	user := attack.newVirtualUser()
+	attack.fetchCSRF(user)
+	attack.loginUser(user)
+	statuses := map[string]int{}
+	for _, step := range setup {
+		report, errStep := attack.runHook(user, step, attack.hookVariables, statuses)
+		attack.resultSetup = append(attack.resultSetup, report)
+		if errStep != nil && !(step.branched && errors.Is(errStep, ErrHookStatus)) {
+			attack.teardownSteps = nil
+			return fmt.Errorf("setup step %s: %w", step.Name, errStep)
+		}
+	}
+	return nil
+}
+
+/*
+Teardown - sends steps of teardown after the attack, even a cancelled one, so it has to be called after
+its elapsed time is measured. Failed step is reported, the rest of steps are sent anyway
+*/
+func (attack *Attack) Teardown() {
+	steps := attack.teardownSteps
+	attack.teardownSteps = nil
+	if len(steps) == 0 {
+		return
+	}
+	variables := map[string]string{}
+	user := attack.newVirtualUser()
+	attack.fetchCSRF(user)
+	attack.loginUser(user)
+	statuses := map[string]int{}
+	for _, step := range steps {
+		report, err := attack.runHook(user, step, variables, statuses)
+		if err != nil {
+			logrus.Error("Can not tear down by step ", step.Name, ": ", err)
+		}
+		attack.results.Lock()
+		attack.resultTeardown = append(attack.resultTeardown, report)
+		attack.results.Unlock()
+	}
+}
+
+// runHook - step with condition, which does not hold, is reported without error
+func (attack *Attack) runHook(user *virtualUser, step scenarioStep, variables map[string]string, statuses map[string]int) (HookReport, error) {
+	report := HookReport{Name: step.Name}
+	if step.when != nil && !step.when.holds(statuses, user.variables(variables)) {
+		report.ConditionUnmet = true
+		return report, nil
+	}
+	request, err := attack.stepRequest(attack.setupTask, step, user.variables(variables))
+	if err != nil {
+		report.Error = err.Error()
+		return report, err
+	}
+	defer fasthttp.ReleaseRequest(request)
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	started := time.Now()
+	user.beginRequest()
+	err = user.do(request, response)
+	report.LatencyMs = float64(time.Since(started).Microseconds()) / 1000
+	if err == nil {
+		report.Status = response.StatusCode()
+		statuses[step.Name] = report.Status
+		err = checkHook(step, response, variables)
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return report, err
+}
+
+func checkHook(step scenarioStep, response *fasthttp.Response, variables map[string]string) error {
+	if failed := step.assertions.check(response); failed != "" {
+		return fmt.Errorf("%w: %s", ErrHookAssertion, failed)
+	}
+	if step.failedByStatus(response.StatusCode()) {
+		return fmt.Errorf("%w: %d", ErrHookStatus, response.StatusCode())
+	}
+	return step.extractVariables(response, variables)
+}
+
+func validateHooks(name string, steps []ScenarioStep, mode string, add func(field string, reason string)) {
+	if len(steps) == 0 {
+		return
+	}
+	field := "schema.headers." + OptionsHeader + "." + name
+	if mode != ModeHTTP {
+		add(field, "is supported in http mode only")
+		return
+	}
+	validateSteps(field, steps, add)
+}
@@ -0,0 +1,63 @@
+package core
+
+import "time"
+
+/*
+InterimResult - aggregated results of running attack, published periodically
+while the attack goes on
+*/
+type InterimResult struct {
+	FormId          string           `json:"form_id"`
+	BomberId        string           `json:"bomber_id"`
+	Tenant          string           `json:"tenant,omitempty"`
+	ContractVersion string           `json:"contract_version"`
+	ElapsedMs       int64            `json:"elapsed_ms"`
+	Shard           *ShardReport     `json:"shard,omitempty"`
+	Completed       int64            `json:"completed"`
+	Timeouts        int64            `json:"timeouts"`
+	Statuses        map[int32]int64  `json:"statuses"`
+	Latency         LatencyReport    `json:"latency"`
+	Errors          map[string]int64 `json:"errors"`
+	// classes of statuses from the start of the attack, empty for modes without http statuses
+	StatusClasses *StatusClassesReport `json:"status_classes,omitempty"`
+	// results of the last elapsed bucket of the timeline
+	Window BucketReport `json:"window"`
+}
+
+// InterimInterval - period of interim results of the task, zero if they are disabled
+func (attack *Attack) InterimInterval() time.Duration {
+	if attack.options == nil {
+		return 0
+	}
+	return time.Duration(attack.options.InterimIntervalMs) * time.Millisecond
+}
+
+func (attack *Attack) FormInterimResult() *InterimResult {
+	attack.results.Lock()
+	defer attack.results.Unlock()
+	result := &InterimResult{
+		FormId:          attack.formId,
+		BomberId:        attack.core.config.CurrentServiceID,
+		Tenant:          attack.core.config.TenantId(),
+		ContractVersion: ContractVersion,
+		ElapsedMs:       time.Since(attack.resultTimeline.start).Milliseconds(),
+		Shard:           attack.shardReport(),
+		Timeouts:        attack.resultTimeouts,
+		Statuses:        make(map[int32]int64, len(attack.resultsAttack)),
+		Latency:         attack.latencyReport(),
+		Errors:          make(map[string]int64, len(attack.resultErrors)),
+		Window:          attack.resultTimeline.window(),
+	}
+	if attack.options != nil {
+		result.StatusClasses = statusClassesReport(attack.options.Mode, attack.resultsAttack, attack.resultTimeouts)
+	}
+	for category, amount := range attack.resultErrors {
+		result.Errors[category] = amount
+	}
+	result.Completed = result.Timeouts
+	for status, amount := range attack.resultsAttack {
+		result.Statuses[status] = amount
+		result.Completed += amount
+	}
+	return result
+}
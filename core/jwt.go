@@ -0,0 +1,208 @@
+package core
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	JWTHS256 = "HS256"
+	JWTRS256 = "RS256"
+)
+
+var (
+	ErrJWTAlgorithm = errors.New("algorithm of jwt is HS256 or RS256")
+	ErrJWTKey       = errors.New("HS256 jwt requires JWT_HMAC_KEY, RS256 jwt requires JWT_PRIVATE_KEY of the bomber")
+	ErrJWTPEM       = errors.New("JWT_PRIVATE_KEY has to be pem file of rsa private key")
+)
+
+/*
+JWTOptions - new signed token for each request of the attack, keys are settings of the bomber,
+so tasks do not carry them. JWT is disabled without algorithm
+*/
+type JWTOptions struct {
+	Algorithm string `json:"algorithm,omitempty"`
+	// string claims are templates with {{name}} placeholders of body params and variables of the user, other values are sent as they are
+	Claims map[string]interface{} `json:"claims,omitempty"`
+	// exp of the token after iat, 60 if empty
+	ExpiresS int    `json:"expires_s,omitempty"`
+	KeyId    string `json:"key_id,omitempty"`
+	// header of requests with token, Authorization with Bearer prefix if empty
+	Header string `json:"header,omitempty"`
+}
+
+func (options JWTOptions) enabled() bool {
+	return options.Algorithm != ""
+}
+
+// jwtSigner - signs tokens of the attack, it is shared by virtual users
+type jwtSigner struct {
+	options JWTOptions
+	params  []*rest_contracts.BodyParam
+	header  string // encoded header of all tokens
+	secret  []byte
+	private *rsa.PrivateKey
+}
+
+func newJWTSigner(options JWTOptions, preference *config.Configuration, params []*rest_contracts.BodyParam) (*jwtSigner, error) {
+	signer := &jwtSigner{options: options, params: params}
+	switch options.Algorithm {
+	case JWTHS256:
+		if preference.JWTHMACKey == "" || preference.JWTHMACKey == "off" {
+			return nil, ErrJWTKey
+		}
+		signer.secret = []byte(preference.JWTHMACKey)
+	case JWTRS256:
+		if preference.JWTPrivateKey == "" || preference.JWTPrivateKey == "off" {
+			return nil, ErrJWTKey
+		}
+		private, err := loadRSAKey(preference.JWTPrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		signer.private = private
+	default:
+		return nil, ErrJWTAlgorithm
+	}
+	header := map[string]string{"alg": options.Algorithm, "typ": "JWT"}
+	if options.KeyId != "" {
+		header["kid"] = options.KeyId
+	}
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	signer.header = base64.RawURLEncoding.EncodeToString(encoded)
+	return signer, nil
+}
+
+func loadRSAKey(path string) (*rsa.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrJWTPEM
+	}
+	if private, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return private, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrJWTPEM
+	}
+	private, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrJWTPEM
+	}
+	return private, nil
+}
+
+// mint - iat, exp and unique jti are added unless claims of the task set them
+func (signer *jwtSigner) mint(variables map[string]string) (string, error) {
+	now := time.Now()
+	expires := signer.options.ExpiresS
+	if expires <= 0 {
+		expires = 60
+	}
+	claims := map[string]interface{}{
+		"iat": now.Unix(),
+		"exp": now.Add(time.Duration(expires) * time.Second).Unix(),
+		"jti": uuid.New().String(),
+	}
+	for name, value := range signer.options.Claims {
+		if template, ok := value.(string); ok {
+			value = renderVariables(template, signer.params, variables)
+		}
+		claims[name] = value
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	signed := signer.header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	signature, err := signer.sign(signed)
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// sign - signature of encoded header and payload joined by dot
+func (signer *jwtSigner) sign(signed string) ([]byte, error) {
+	if signer.private != nil {
+		digest := sha256.Sum256([]byte(signed))
+		return rsa.SignPKCS1v15(rand.Reader, signer.private, crypto.SHA256, digest[:])
+	}
+	mac := hmac.New(sha256.New, signer.secret)
+	mac.Write([]byte(signed))
+	return mac.Sum(nil), nil
+}
+
+// apply - request without token is sent as it is, if the token can not be signed
+func (signer *jwtSigner) apply(request *fasthttp.Request, token string) {
+	if signer == nil || token == "" {
+		return
+	}
+	if signer.options.Header == "" {
+		request.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+token)
+		return
+	}
+	request.Header.Set(signer.options.Header, token)
+}
+
+// token - new token of the next request of the user, retries and redirect hops of the request keep it
+func (signer *jwtSigner) token(variables map[string]string) string {
+	if signer == nil {
+		return ""
+	}
+	token, err := signer.mint(variables)
+	if err != nil {
+		logrus.Debug("Can not sign jwt: ", err)
+		return ""
+	}
+	return token
+}
+
+func (attack *Attack) prepareJWT(task rest_contracts.Task) error {
+	attack.jwt = nil
+	if !attack.options.JWT.enabled() || attack.options.Mode != ModeHTTP {
+		return nil
+	}
+	signer, err := newJWTSigner(attack.options.JWT, attack.core.config, task.Schema.Body)
+	if err != nil {
+		return err
+	}
+	attack.jwt = signer
+	return nil
+}
+
+func validateJWT(options JWTOptions, add func(field string, reason string)) {
+	if !options.enabled() {
+		return
+	}
+	field := "schema.headers." + OptionsHeader + ".jwt"
+	if options.Algorithm != JWTHS256 && options.Algorithm != JWTRS256 {
+		add(field+".algorithm", ErrJWTAlgorithm.Error())
+	}
+	if options.ExpiresS < 0 {
+		add(field+".expires_s", "must not be negative")
+	}
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+)
+
+// header and payload of the example token of jwt.io signed by its secret
+const (
+	exampleJWTSigned    = "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyJzdWIiOiIxMjM0NTY3ODkwIiwibmFtZSI6IkpvaG4gRG9lIiwiaWF0IjoxNTE2MjM5MDIyfQ"
+	exampleJWTSecret    = "your-256-bit-secret"
+	exampleJWTSignature = "SflKxwRJSMeKKF2QT4fwpMeJf36POk6yJV_adQssw5c"
+)
+
+// writeRSAKey - pem file of new rsa key in PKCS#1 or PKCS#8
+func writeRSAKey(t *testing.T, pkcs8 bool) (string, *rsa.PrivateKey) {
+	private, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(private)}
+	if pkcs8 {
+		encoded, err := x509.MarshalPKCS8PrivateKey(private)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: encoded}
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path, private
+}
+
+// decodeJWT - header and claims of the token, the signature is checked by verify
+func decodeJWT(t *testing.T, token string, verify func(signed string, signature []byte) bool) (map[string]interface{}, map[string]interface{}) {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("token %s has %d parts", token, len(parts))
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verify(parts[0]+"."+parts[1], signature) {
+		t.Fatalf("signature of %s is not valid", token)
+	}
+	decoded := make([]map[string]interface{}, 2)
+	for index := range decoded {
+		data, err := base64.RawURLEncoding.DecodeString(parts[index])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &decoded[index]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return decoded[0], decoded[1]
+}
+
+func TestJWTSignExample(t *testing.T) {
+	signer, err := newJWTSigner(JWTOptions{Algorithm: JWTHS256}, &config.Configuration{JWTHMACKey: exampleJWTSecret}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(exampleJWTSigned, signer.header+".") {
+		t.Fatalf("got header %s, expected the one of %s", signer.header, exampleJWTSigned)
+	}
+	signature, err := signer.sign(exampleJWTSigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoded := base64.RawURLEncoding.EncodeToString(signature); encoded != exampleJWTSignature {
+		t.Fatalf("got signature %s, expected %s", encoded, exampleJWTSignature)
+	}
+}
+
+func TestJWTMint(t *testing.T) {
+	hmacSecret := "secret"
+	pkcs1, pkcs1Key := writeRSAKey(t, false)
+	pkcs8, pkcs8Key := writeRSAKey(t, true)
+	verifyHMAC := func(signed string, signature []byte) bool {
+		mac := hmac.New(sha256.New, []byte(hmacSecret))
+		mac.Write([]byte(signed))
+		return hmac.Equal(mac.Sum(nil), signature)
+	}
+	verifyRSA := func(private *rsa.PrivateKey) func(string, []byte) bool {
+		return func(signed string, signature []byte) bool {
+			digest := sha256.Sum256([]byte(signed))
+			return rsa.VerifyPKCS1v15(&private.PublicKey, crypto.SHA256, digest[:], signature) == nil
+		}
+	}
+	cases := []struct {
+		name       string
+		algorithm  string
+		preference *config.Configuration
+		verify     func(signed string, signature []byte) bool
+	}{
+		{"HS256", JWTHS256, &config.Configuration{JWTHMACKey: hmacSecret}, verifyHMAC},
+		{"RS256 of PKCS#1 key", JWTRS256, &config.Configuration{JWTPrivateKey: pkcs1}, verifyRSA(pkcs1Key)},
+		{"RS256 of PKCS#8 key", JWTRS256, &config.Configuration{JWTPrivateKey: pkcs8}, verifyRSA(pkcs8Key)},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			options := JWTOptions{Algorithm: testCase.algorithm, KeyId: "bomber-1", ExpiresS: 30,
+				Claims: map[string]interface{}{"sub": "user-{{user_id}}", "admin": false}}
+			signer, err := newJWTSigner(options, testCase.preference, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			before := time.Now().Unix()
+			token, err := signer.mint(map[string]string{"user_id": "7"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, claims := decodeJWT(t, token, testCase.verify)
+			if header["alg"] != testCase.algorithm || header["typ"] != "JWT" || header["kid"] != "bomber-1" {
+				t.Fatalf("unexpected header %v", header)
+			}
+			if claims["sub"] != "user-7" || claims["admin"] != false || claims["jti"] == "" {
+				t.Fatalf("unexpected claims %v", claims)
+			}
+			issued, _ := claims["iat"].(float64)
+			if int64(issued) < before || claims["exp"] != issued+30 {
+				t.Fatalf("got iat %v and exp %v", claims["iat"], claims["exp"])
+			}
+			other, err := signer.mint(map[string]string{"user_id": "7"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, next := decodeJWT(t, other, testCase.verify); next["jti"] == claims["jti"] {
+				t.Fatal("tokens have the same jti")
+			}
+		})
+	}
+}
+
+func TestNewJWTSignerKeys(t *testing.T) {
+	broken := filepath.Join(t.TempDir(), "broken.pem")
+	if err := ioutil.WriteFile(broken, []byte("not pem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name       string
+		algorithm  string
+		preference *config.Configuration
+		err        error
+	}{
+		{"HS256 without key", JWTHS256, &config.Configuration{JWTHMACKey: "off"}, ErrJWTKey},
+		{"RS256 without key", JWTRS256, &config.Configuration{JWTPrivateKey: "off"}, ErrJWTKey},
+		{"RS256 of file which is not pem", JWTRS256, &config.Configuration{JWTPrivateKey: broken}, ErrJWTPEM},
+		{"unknown algorithm", "ES256", &config.Configuration{JWTHMACKey: "secret"}, ErrJWTAlgorithm},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			if _, err := newJWTSigner(JWTOptions{Algorithm: testCase.algorithm}, testCase.preference, nil); err != testCase.err {
+				t.Fatalf("got %v, expected %v", err, testCase.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/bomber-team/rest-bomber/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	latencyLowest      = int64(time.Microsecond)
+	latencyHighest     = int64(10 * time.Minute)
+	latencySignificant = 3
+	// status of modes, which have no status for each message
+	noStatus int32 = -1
+)
+
+func newLatencyHistogram() *hdrhistogram.Histogram {
+	return hdrhistogram.New(latencyLowest, latencyHighest, latencySignificant)
+}
+
+/*
+recordLatency - records latency of request in nanoseconds into histograms and timeline, raw
+latencies are kept and samples are written only if task asks for them. Bytes are size of
+the response, zero if mode does not measure it. Must be called under results lock of the attack
+*/
+func (attack *Attack) recordLatency(latency int64, status int32, failed bool, bytes int) {
+	if latency > latencyHighest {
+		latency = latencyHighest
+	}
+	if err := attack.resultLatency.RecordValue(latency); err != nil {
+		logrus.Debug("Can not record latency: ", err)
+	}
+	if status != noStatus {
+		histogram, ok := attack.resultLatencyByStatus[status]
+		if !ok {
+			histogram = newLatencyHistogram()
+			attack.resultLatencyByStatus[status] = histogram
+		}
+		histogram.RecordValue(latency)
+	}
+	attack.resultTimeline.add(latency, failed)
+	attack.resultApdex.add(latency, failed)
+	attack.resultSummary.add(latency)
+	metrics.RequestCompleted(status, time.Duration(latency))
+	if attack.options != nil && attack.options.RawLatencies {
+		attack.resultTimesForRequests = append(attack.resultTimesForRequests, latency)
+	}
+	attack.resultSamples.write(requestSample{
+		TimestampNs: time.Now().UnixNano(),
+		Status:      status,
+		LatencyNs:   latency,
+		Bytes:       bytes,
+	})
+}
+
+/*
+recordTimeout - counts failed request, failures of all categories are counted as timeouts
+of result. Must be called under results lock of the attack
+*/
+func (attack *Attack) recordTimeout(category string) {
+	attack.resultTimeouts++
+	attack.resultErrors[category]++
+	attack.resultTimeline.addTimeout()
+	attack.resultApdex.addFailed()
+	metrics.RequestFailed(category)
+	attack.resultSamples.write(requestSample{
+		TimestampNs: time.Now().UnixNano(),
+		Status:      noStatus,
+		Error:       category,
+	})
+}
+
+type LatencyReport struct {
+	Count  int64 `json:"count"`
+	MinNs  int64 `json:"min_ns"`
+	MeanNs int64 `json:"mean_ns"`
+	MaxNs  int64 `json:"max_ns"`
+	P50Ns  int64 `json:"p50_ns"`
+	P90Ns  int64 `json:"p90_ns"`
+	P95Ns  int64 `json:"p95_ns"`
+	P99Ns  int64 `json:"p99_ns"`
+	P999Ns int64 `json:"p999_ns"`
+	// base64 of compressed hdr histogram of latencies in nanoseconds,
+	// histograms of bombers can be decoded and merged
+	Histogram string `json:"histogram"`
+}
+
+func (attack *Attack) latencyReport() LatencyReport {
+	return latencyReport(attack.resultLatency)
+}
+
+// latencyByStatusReport - latency of requests per status of response or grpc code
+func (attack *Attack) latencyByStatusReport() map[int32]LatencyReport {
+	report := make(map[int32]LatencyReport, len(attack.resultLatencyByStatus))
+	for status, histogram := range attack.resultLatencyByStatus {
+		report[status] = latencyReport(histogram)
+	}
+	return report
+}
+
+func latencyReport(histogram *hdrhistogram.Histogram) LatencyReport {
+	encoded, err := histogram.Encode(hdrhistogram.V2CompressedEncodingCookieBase)
+	if err != nil {
+		logrus.Error("Can not encode latency histogram: ", err)
+	}
+	return LatencyReport{
+		Count:     histogram.TotalCount(),
+		MinNs:     histogram.Min(),
+		MeanNs:    int64(histogram.Mean()),
+		MaxNs:     histogram.Max(),
+		P50Ns:     histogram.ValueAtQuantile(50),
+		P90Ns:     histogram.ValueAtQuantile(90),
+		P95Ns:     histogram.ValueAtQuantile(95),
+		P99Ns:     histogram.ValueAtQuantile(99),
+		P999Ns:    histogram.ValueAtQuantile(99.9),
+		Histogram: string(encoded),
+	}
+}
+
+// MergeLatencyReports - latency of requests of all reports, as if they were recorded by one bomber
+func MergeLatencyReports(reports []LatencyReport) (LatencyReport, error) {
+	merged := newLatencyHistogram()
+	for _, report := range reports {
+		if report.Histogram == "" {
+			continue
+		}
+		histogram, err := hdrhistogram.Decode([]byte(report.Histogram))
+		if err != nil {
+			return LatencyReport{}, err
+		}
+		merged.Merge(histogram)
+	}
+	return latencyReport(merged), nil
+}
@@ -0,0 +1,231 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	LoginPerBomber = "bomber"
+	LoginPerUser   = "user"
+)
+
+var (
+	ErrLoginPer    = errors.New("login is done per bomber or per user")
+	ErrLoginStatus = errors.New("login is answered with status 400 and above")
+	ErrLoginToken  = errors.New("token is not found in response of login")
+)
+
+/*
+LoginOptions - request sent before requests of the attack, its token and cookies are applied to all
+of them. Login is disabled without path
+*/
+type LoginOptions struct {
+	// POST if empty
+	Method string `json:"method,omitempty"`
+	// appended to address of the task, absolute url replaces it
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// template with {{name}} placeholders of body params
+	Body string `json:"body,omitempty"`
+	// bomber if empty, the bomber logs in once for all virtual users
+	Per string `json:"per,omitempty"`
+	// json path of token in response body, only cookies are applied without token and token_header
+	Token string `json:"token,omitempty"`
+	// header of response with token, for responses which are not json
+	TokenHeader string `json:"token_header,omitempty"`
+	// header of requests with token, Authorization with Bearer prefix if empty
+	Header string `json:"header,omitempty"`
+}
+
+func (options LoginOptions) enabled() bool {
+	return options.Path != ""
+}
+
+// Redacted - copy of options without body with password, safe to store
+func (options LoginOptions) Redacted() LoginOptions {
+	if options.Body != "" {
+		options.Body = redactedSecret
+	}
+	return options
+}
+
+// loginSession - token and cookies of login, it is read only and shared by virtual users of login per bomber
+type loginSession struct {
+	header  string
+	value   string
+	token   string // value without Bearer prefix
+	cookies []storedCookie
+}
+
+func (session *loginSession) apply(request *fasthttp.Request) {
+	if session == nil {
+		return
+	}
+	if session.header != "" {
+		request.Header.Set(session.header, session.value)
+	}
+	host := string(request.URI().Host())
+	path := string(request.URI().Path())
+	now := time.Now()
+	for _, cookie := range session.cookies {
+		if !cookie.expires.IsZero() && now.After(cookie.expires) {
+			continue
+		}
+		if domainMatch(host, cookie.domain) && strings.HasPrefix(path, cookie.path) {
+			request.Header.SetCookie(cookie.name, cookie.value)
+		}
+	}
+}
+
+type loginStats struct {
+	logins int64
+	failed int64
+}
+
+type LoginReport struct {
+	Logins int64 `json:"logins"`
+	Failed int64 `json:"failed"`
+}
+
+// loginsReport - nil if the task has no login
+func (attack *Attack) loginsReport() *LoginReport {
+	if attack.options == nil || !attack.options.Login.enabled() {
+		return nil
+	}
+	return &LoginReport{Logins: attack.resultLogin.logins, Failed: attack.resultLogin.failed}
+}
+
+// prepareLogin - login per bomber is done while the task is prepared, its failure fails the task
+func (attack *Attack) prepareLogin() error {
+	attack.loginShared = nil
+	if !attack.options.Login.enabled() || attack.options.Login.Per == LoginPerUser || attack.options.Mode != ModeHTTP {
+		return nil
+	}
+	user := attack.newVirtualUser()
+	attack.fetchCSRF(user)
+	session, err := attack.login(user)
+	if err != nil {
+		return err
+	}
+	attack.loginShared = session
+	return nil
+}
+
+// loginUser - user of login per user logs in before its first request, failed one attacks without session
+func (attack *Attack) loginUser(user *virtualUser) {
+	if !attack.options.Login.enabled() || attack.options.Login.Per != LoginPerUser {
+		return
+	}
+	session, err := attack.login(user)
+	if err != nil {
+		logrus.Error("Can not login virtual user: ", err)
+		return
+	}
+	user.session = session
+}
+
+func (attack *Attack) login(user *virtualUser) (*loginSession, error) {
+	options := attack.options.Login
+	task := attack.setupTask
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	method := options.Method
+	if method == "" {
+		method = fasthttp.MethodPost
+	}
+	request.Header.SetMethod(method)
+	if strings.Contains(options.Path, "://") {
+		request.SetRequestURI(options.Path)
+	} else {
+		request.SetRequestURI(strings.TrimRight(task.Script.Address, "/") + "/" + strings.TrimLeft(options.Path, "/"))
+	}
+	attack.enhancedHeadersInRequest(request, task)
+	variables := user.variables(nil)
+	for key, value := range options.Headers {
+		request.Header.Set(key, renderVariables(value, task.Schema.Body, variables))
+	}
+	if options.Body != "" {
+		request.SetBodyString(renderVariables(options.Body, task.Schema.Body, variables))
+	}
+	session, err := readLogin(options, user, request, response)
+	attack.results.Lock()
+	attack.resultLogin.logins++
+	if err != nil {
+		attack.resultLogin.failed++
+	}
+	attack.results.Unlock()
+	return session, err
+}
+
+func readLogin(options LoginOptions, user *virtualUser, request *fasthttp.Request, response *fasthttp.Response) (*loginSession, error) {
+	user.beginRequest()
+	if err := user.do(request, response); err != nil {
+		return nil, err
+	}
+	if response.StatusCode() >= fasthttp.StatusBadRequest {
+		return nil, fmt.Errorf("%w: %d", ErrLoginStatus, response.StatusCode())
+	}
+	session := &loginSession{cookies: []storedCookie{}}
+	jar := newCookieJar()
+	jar.store(request, response)
+	for _, cookie := range jar.cookies {
+		session.cookies = append(session.cookies, *cookie)
+	}
+	token := ""
+	switch {
+	case options.TokenHeader != "":
+		token = string(response.Header.Peek(options.TokenHeader))
+	case options.Token != "":
+		path, err := compileJSONPath(options.Token)
+		if err != nil {
+			return nil, err
+		}
+		variables := map[string]string{}
+		if err := extractJSON(response, map[string]jsonPath{"token": path}, variables); err != nil {
+			return nil, err
+		}
+		token = variables["token"]
+	default:
+		return session, nil
+	}
+	if token == "" {
+		return nil, ErrLoginToken
+	}
+	session.header, session.value, session.token = options.Header, token, token
+	if options.Header == "" {
+		session.header, session.value = fasthttp.HeaderAuthorization, "Bearer "+token
+	}
+	return session, nil
+}
+
+func validateLogin(options LoginOptions, add func(field string, reason string)) {
+	field := "schema.headers." + OptionsHeader + ".login"
+	if !options.enabled() {
+		if options.Body != "" || options.Token != "" || options.TokenHeader != "" {
+			add(field+".path", "is required")
+		}
+		return
+	}
+	if options.Per != "" && options.Per != LoginPerBomber && options.Per != LoginPerUser {
+		add(field+".per", ErrLoginPer.Error())
+	}
+	if options.Method != "" && !methodPattern.MatchString(options.Method) {
+		add(field+".method", "must be upper case http method")
+	}
+	if options.Token != "" {
+		if _, err := compileJSONPath(options.Token); err != nil {
+			add(field+".token", err.Error())
+		}
+	}
+	if options.Token != "" && options.TokenHeader != "" {
+		add(field+".token_header", "can not be set with token")
+	}
+}
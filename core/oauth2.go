@@ -0,0 +1,224 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	oauth2FetchTimeout  = 10 * time.Second
+	oauth2RetryInterval = 5 * time.Second
+	// lifetime of token, which endpoint returns without expires_in
+	oauth2DefaultLifetime = time.Hour
+)
+
+var (
+	ErrOAuth2Status = errors.New("token endpoint answered with status other than 200")
+	ErrOAuth2Token  = errors.New("token endpoint answered without access_token")
+)
+
+/*
+OAuth2Options - client credentials grant, token is fetched before the attack and refreshed
+by the bomber before it expires, requests of the attack do not wait for it
+*/
+type OAuth2Options struct {
+	TokenURL     string   `json:"token_url"`
+	ClientId     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes,omitempty"`
+	Audience     string   `json:"audience,omitempty"`
+	// client credentials are sent in form instead of basic auth
+	CredentialsInBody bool `json:"credentials_in_body,omitempty"`
+	// token is refreshed this amount of seconds before its expiry, 60 if empty
+	RefreshBeforeS int `json:"refresh_before_s,omitempty"`
+}
+
+func (options OAuth2Options) refreshBefore() time.Duration {
+	if options.RefreshBeforeS <= 0 {
+		return time.Minute
+	}
+	return time.Duration(options.RefreshBeforeS) * time.Second
+}
+
+func (options *OAuth2Options) redacted() *OAuth2Options {
+	if options == nil {
+		return nil
+	}
+	copied := *options
+	if copied.ClientSecret != "" {
+		copied.ClientSecret = redactedSecret
+	}
+	return &copied
+}
+
+// oauth2Token - current token of the attack, shared by all virtual users
+type oauth2Token struct {
+	mutex    sync.RWMutex
+	options  OAuth2Options
+	value    string
+	expires  time.Time
+	fetched  int64
+	failures int64
+}
+
+type OAuth2Report struct {
+	Fetched  int64 `json:"fetched"`
+	Failures int64 `json:"failures"`
+}
+
+func (token *oauth2Token) apply(request *fasthttp.Request) {
+	if token == nil {
+		return
+	}
+	token.mutex.RLock()
+	value := token.value
+	token.mutex.RUnlock()
+	request.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+value)
+}
+
+func (token *oauth2Token) report() *OAuth2Report {
+	if token == nil {
+		return nil
+	}
+	token.mutex.RLock()
+	defer token.mutex.RUnlock()
+	return &OAuth2Report{Fetched: token.fetched, Failures: token.failures}
+}
+
+// fetch - the previous token is kept if a new one can not be fetched
+func (token *oauth2Token) fetch() error {
+	value, lifetime, err := requestOAuth2Token(token.options)
+	token.mutex.Lock()
+	defer token.mutex.Unlock()
+	if err != nil {
+		token.failures++
+		return err
+	}
+	token.fetched++
+	token.value = value
+	token.expires = time.Now().Add(lifetime)
+	return nil
+}
+
+// refreshAt - before expiry by refresh_before_s, but not earlier than half of lifetime of the token
+func (token *oauth2Token) refreshAt() time.Time {
+	token.mutex.RLock()
+	defer token.mutex.RUnlock()
+	before := token.options.refreshBefore()
+	if remaining := time.Until(token.expires); before > remaining/2 {
+		before = remaining / 2
+	}
+	return token.expires.Add(-before)
+}
+
+// refresh - refreshes the token until the attack ends, failed refresh is retried every 5 seconds
+func (token *oauth2Token) refresh(ctx context.Context) {
+	wait := time.Until(token.refreshAt())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		if err := token.fetch(); err != nil {
+			logrus.Error("Can not refresh oauth2 token: ", err)
+			wait = oauth2RetryInterval
+			continue
+		}
+		wait = time.Until(token.refreshAt())
+	}
+}
+
+func requestOAuth2Token(options OAuth2Options) (string, time.Duration, error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(options.Scopes) > 0 {
+		form.Set("scope", strings.Join(options.Scopes, " "))
+	}
+	if options.Audience != "" {
+		form.Set("audience", options.Audience)
+	}
+	if options.CredentialsInBody {
+		form.Set("client_id", options.ClientId)
+		form.Set("client_secret", options.ClientSecret)
+	}
+	request, err := http.NewRequest(http.MethodPost, options.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if !options.CredentialsInBody {
+		request.SetBasicAuth(url.QueryEscape(options.ClientId), url.QueryEscape(options.ClientSecret))
+	}
+	client := &http.Client{Timeout: oauth2FetchTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return "", 0, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("%w: %d", ErrOAuth2Status, response.StatusCode)
+	}
+	var answer struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return "", 0, err
+	}
+	if answer.AccessToken == "" {
+		return "", 0, ErrOAuth2Token
+	}
+	lifetime := oauth2DefaultLifetime
+	if answer.ExpiresIn > 0 {
+		lifetime = time.Duration(answer.ExpiresIn) * time.Second
+	}
+	return answer.AccessToken, lifetime, nil
+}
+
+// prepareOAuth2 - the first token is fetched while the task is prepared, the task fails without it
+func (attack *Attack) prepareOAuth2() error {
+	attack.oauth2 = nil
+	options := attack.options.Auth.OAuth2
+	if options == nil || attack.options.Mode != ModeHTTP {
+		return nil
+	}
+	token := &oauth2Token{options: *options}
+	if err := token.fetch(); err != nil {
+		return err
+	}
+	attack.oauth2 = token
+	return nil
+}
+
+func validateOAuth2(options *OAuth2Options, add func(field string, reason string)) {
+	if options == nil {
+		return
+	}
+	field := "schema.headers." + OptionsHeader + ".auth.oauth2"
+	parsed, err := url.Parse(options.TokenURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		add(field+".token_url", "must be http or https url")
+	}
+	if options.ClientId == "" {
+		add(field+".client_id", "is required")
+	}
+	if options.RefreshBeforeS < 0 {
+		add(field+".refresh_before_s", "must not be negative")
+	}
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+)
+
+// task contracts have no place for bomber specific settings, so they are sent
+// as json in this header of the schema and never reach the target
+const OptionsHeader = "X-Bomber-Options"
+
+const (
+	ModeHTTP      = "http"
+	ModeWebsocket = "websocket"
+	ModeSSE       = "sse"
+	ModeGRPC      = "grpc"
+	ModeRaw       = "raw"
+)
+
+var ErrUnknownMode = errors.New("mode is http, websocket, sse, grpc or raw")
+
+type TaskOptions struct {
+	// version of bomber-proto-contracts the task is built with, it is not checked if empty
+	ContractVersion string `json:"contract_version,omitempty"`
+	// deliveries of tasks with the same key are executed once, form id is the key if empty
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	// queued tasks of higher priority are started before tasks of lower one, 0 if empty
+	Priority int `json:"priority,omitempty"`
+	// task of higher priority cancels running attack of lower priority instead of waiting for its end
+	Preempt bool `json:"preempt,omitempty"`
+	// kind of attack, http if empty
+	Mode     string            `json:"mode,omitempty"`
+	Hosts    map[string]string `json:"hosts,omitempty"`
+	Resolver string            `json:"resolver,omitempty"`
+	// ipv4, ipv6 or dual
+	AddressFamily string `json:"address_family,omitempty"`
+	// local ips for outgoing connections
+	SourceAddrs []string        `json:"source_addrs,omitempty"`
+	Redirects   RedirectOptions `json:"redirects"`
+	// gzip or deflate, bodies are sent as is if empty
+	BodyEncoding string `json:"body_encoding,omitempty"`
+	// value of Accept-Encoding header, header is not set if empty
+	AcceptEncoding      string         `json:"accept_encoding,omitempty"`
+	DecompressResponses bool           `json:"decompress_responses,omitempty"`
+	Chunked             ChunkedOptions `json:"chunked"`
+	// each worker stores cookies from responses and sends them back
+	Cookies bool         `json:"cookies,omitempty"`
+	Retry   RetryOptions `json:"retry"`
+	Auth    AuthOptions  `json:"auth"`
+
+	CircuitBreaker CircuitBreakerOptions `json:"circuit_breaker"`
+	// limit of outgoing traffic of the bomber per task, unlimited if zero
+	MaxBytesPerSecond int64      `json:"max_bytes_per_second,omitempty"`
+	TCP               TCPOptions `json:"tcp"`
+	TLS               TLSOptions `json:"tls"`
+	// establish connections of all workers before the attack
+	Prewarm        bool                  `json:"prewarm,omitempty"`
+	ExpectContinue ExpectContinueOptions `json:"expect_continue"`
+
+	// send latency of each request in result, only histogram is sent by default
+	RawLatencies bool `json:"raw_latencies,omitempty"`
+	// interval of buckets of timeline, 1 second if empty
+	BucketIntervalMs int64 `json:"bucket_interval_ms,omitempty"`
+	// part of the task attacked by this bomber, the whole task if empty
+	Shard ShardOptions `json:"shard"`
+	// period of publishing interim results during the attack, disabled if empty
+	InterimIntervalMs int64 `json:"interim_interval_ms,omitempty"`
+	// traceparent header in sampled requests, ids of slow and failed ones are reported
+	Tracing TracingOptions `json:"tracing"`
+	// unique X-Bomber-Request-Id header in each request, ids of slow and failed ones are reported
+	RequestIds bool `json:"request_ids,omitempty"`
+	// csv or ndjson file with record of each request in REPORT_DIR of the bomber, disabled if empty
+	Samples string `json:"samples,omitempty"`
+	// limits of metrics checked at the end of the attack
+	Thresholds []Threshold  `json:"thresholds,omitempty"`
+	Apdex      ApdexOptions `json:"apdex"`
+	// previous run, which results of the attack are compared with
+	Baseline BaselineOptions `json:"baseline"`
+	// the first failed responses with headers and bodies, and messages of transport errors
+	FailureSamples FailureSamplesOptions `json:"failure_samples"`
+	// amount of the most frequent messages of errors in report, 10 if empty
+	TopErrors int `json:"top_errors,omitempty"`
+	// random successful responses to check content returned under load
+	BodySamples BodySamplesOptions `json:"body_samples"`
+	// checks of responses to the request of the task
+	Assertions AssertionOptions `json:"assertions"`
+	// request sent before the attack, its token and cookies are applied to requests of the attack
+	Login LoginOptions `json:"login"`
+	// token of page fetched by each virtual user, sent with its mutating requests
+	CSRF CSRFOptions `json:"csrf"`
+	// new signed token in each request
+	JWT JWTOptions `json:"jwt"`
+	// aws signature v4 of each request, for API Gateway and S3
+	SigV4 SigV4Options `json:"sigv4"`
+	// bespoke hmac signature of each request
+	HMACSigning HMACSigningOptions `json:"hmac_signing"`
+	// response headers, which values are aggregated in report, for example X-RateLimit-Remaining
+	CaptureHeaders []string `json:"capture_headers,omitempty"`
+	// steps executed by each virtual user instead of the single request of the task
+	Scenario ScenarioOptions `json:"scenario"`
+	// steps sent once before the attack, for example to seed data, in the format of steps of scenario
+	Setup []ScenarioStep `json:"setup,omitempty"`
+	// steps sent once after the attack, for example to clean up data of setup
+	Teardown []ScenarioStep `json:"teardown,omitempty"`
+	// tenant the task belongs to, bomber of another tenant rejects it
+	Tenant string `json:"tenant,omitempty"`
+
+	Websocket WebsocketOptions `json:"websocket"`
+	SSE       SSEOptions       `json:"sse"`
+	GRPC      GRPCOptions      `json:"grpc"`
+	Raw       RawOptions       `json:"raw"`
+}
+
+type TLSOptions struct {
+	// resume sessions of previous handshakes in new connections
+	SessionResumption  bool `json:"session_resumption,omitempty"`
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+}
+
+// tlsConfig - client config of modes, which make tls handshakes by own clients
+func (options TLSOptions) tlsConfig() *tls.Config {
+	return &tls.Config{InsecureSkipVerify: options.InsecureSkipVerify}
+}
+
+type TCPOptions struct {
+	NoDelay    *bool `json:"no_delay,omitempty"`
+	ReuseAddr  bool  `json:"reuse_addr,omitempty"`
+	ReusePort  bool  `json:"reuse_port,omitempty"`
+	SendBuffer int   `json:"send_buffer,omitempty"`
+	RecvBuffer int   `json:"recv_buffer,omitempty"`
+}
+
+func ParseTaskOptions(task rest_contracts.Task) (*TaskOptions, error) {
+	options := &TaskOptions{Mode: ModeHTTP}
+	if task.Schema == nil {
+		return options, nil
+	}
+	raw, ok := task.Schema.Headers[OptionsHeader]
+	if !ok || raw == "" {
+		return options, nil
+	}
+	if err := json.Unmarshal([]byte(raw), options); err != nil {
+		return nil, err
+	}
+	switch options.Mode {
+	case "":
+		options.Mode = ModeHTTP
+	case ModeHTTP, ModeWebsocket, ModeSSE, ModeGRPC, ModeRaw:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownMode, options.Mode)
+	}
+	return options, nil
+}
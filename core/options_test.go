@@ -0,0 +1,115 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/config"
+)
+
+func taskWithOptions(options string) rest_contracts.Task {
+	return rest_contracts.Task{
+		FormId: "form",
+		Script: &rest_contracts.RestScript{
+			Address:       "http://127.0.0.1:8080/items",
+			RequestMethod: "GET",
+			Config:        &rest_contracts.ConfigurationScript{Rps: 10, Time: 5},
+		},
+		Schema: &rest_contracts.RestSchema{Headers: map[string]string{OptionsHeader: options}},
+	}
+}
+
+func TestParseTaskOptions(t *testing.T) {
+	cases := []struct {
+		name    string
+		options string
+		mode    string
+		err     error
+	}{
+		{"without options", "", ModeHTTP, nil},
+		{"empty mode", `{"priority": 3}`, ModeHTTP, nil},
+		{"http", `{"mode": "http"}`, ModeHTTP, nil},
+		{"websocket", `{"mode": "websocket"}`, ModeWebsocket, nil},
+		{"sse", `{"mode": "sse"}`, ModeSSE, nil},
+		{"grpc", `{"mode": "grpc"}`, ModeGRPC, nil},
+		{"raw", `{"mode": "raw"}`, ModeRaw, nil},
+		{"typo of mode", `{"mode": "htttp"}`, "", ErrUnknownMode},
+		{"upper case mode", `{"mode": "HTTP"}`, "", ErrUnknownMode},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			options, err := ParseTaskOptions(taskWithOptions(tc.options))
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("error %v, expected %v", err, tc.err)
+			}
+			if err == nil && options.Mode != tc.mode {
+				t.Fatalf("mode %q, expected %q", options.Mode, tc.mode)
+			}
+		})
+	}
+}
+
+func TestParseTaskOptionsFields(t *testing.T) {
+	options, err := ParseTaskOptions(taskWithOptions(`{"priority": 2, "preempt": true, "tenant": "team-a",
+		"scenario": {"users": 3, "steps": [{"name": "get", "path": "/items/{{id}}"}]}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.Priority != 2 || !options.Preempt || options.Tenant != "team-a" {
+		t.Fatalf("options %+v", options)
+	}
+	if options.Scenario.Users != 3 || len(options.Scenario.Steps) != 1 || options.Scenario.Steps[0].Path != "/items/{{id}}" {
+		t.Fatalf("scenario %+v", options.Scenario)
+	}
+	if _, err := ParseTaskOptions(taskWithOptions(`{"priority": `)); err == nil {
+		t.Fatal("broken json of options is parsed")
+	}
+	if _, err := ParseTaskOptions(rest_contracts.Task{}); err != nil {
+		t.Fatal("task without schema has no default options: ", err)
+	}
+}
+
+func TestRawLatenciesOptIn(t *testing.T) {
+	cases := []struct {
+		options string
+		keeps   bool
+	}{
+		{"", false},
+		{`{"raw_latencies": true}`, true},
+		{`{"raw_latencies": false}`, false},
+	}
+	for _, tc := range cases {
+		options, err := ParseTaskOptions(taskWithOptions(tc.options))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if options.RawLatencies != tc.keeps {
+			t.Errorf("options %q keep raw latencies %v, expected %v", tc.options, !tc.keeps, tc.keeps)
+		}
+	}
+}
+
+func TestRecordLatencyRawOptIn(t *testing.T) {
+	bomber := &Core{config: &config.Configuration{}, attacks: attacks{byFormId: map[string]*Attack{}}}
+	for _, tc := range []struct {
+		options string
+		kept    int
+	}{{"", 0}, {`{"raw_latencies": true}`, 3}} {
+		attack, err := bomber.PreparingData(taskWithOptions(tc.options))
+		if err != nil {
+			t.Fatal(err)
+		}
+		attack.results.Lock()
+		for i := 0; i < 3; i++ {
+			attack.recordLatency(int64(time.Millisecond), 200, false, 0)
+		}
+		attack.results.Unlock()
+		if len(attack.resultTimesForRequests) != tc.kept || attack.resultLatency.TotalCount() != 3 {
+			t.Errorf("options %q keep %d raw latencies of %d recorded, expected %d", tc.options,
+				len(attack.resultTimesForRequests), attack.resultLatency.TotalCount(), tc.kept)
+		}
+		attack.EndProgress()
+	}
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"time"
+
+	"github.com/jamiealquiza/tachymeter"
+)
+
+// phaseTimings - timings of phases of one request, dns, connect and tls are zero for reused connections
+type phaseTimings struct {
+	reused   bool
+	dns      time.Duration
+	connect  time.Duration
+	tls      time.Duration
+	resumed  bool          // tls session was resumed
+	wait     time.Duration // from sending request until the first byte of response
+	transfer time.Duration // reading of response after the first byte
+	ttfb     time.Duration // from start of request until the first byte, including connecting
+	total    time.Duration
+}
+
+type phaseMeters struct {
+	dns      *tachymeter.Tachymeter
+	connect  *tachymeter.Tachymeter
+	tls      *tachymeter.Tachymeter
+	tlsFull  *tachymeter.Tachymeter
+	resumed  *tachymeter.Tachymeter
+	wait     *tachymeter.Tachymeter
+	transfer *tachymeter.Tachymeter
+	ttfb     *tachymeter.Tachymeter
+	total    *tachymeter.Tachymeter
+	newConns int64
+}
+
+func newPhaseMeters(size int) *phaseMeters {
+	if size <= 0 {
+		size = 1
+	}
+	return &phaseMeters{
+		dns:      tachymeter.New(&tachymeter.Config{Size: size}),
+		connect:  tachymeter.New(&tachymeter.Config{Size: size}),
+		tls:      tachymeter.New(&tachymeter.Config{Size: size}),
+		tlsFull:  tachymeter.New(&tachymeter.Config{Size: size}),
+		resumed:  tachymeter.New(&tachymeter.Config{Size: size}),
+		wait:     tachymeter.New(&tachymeter.Config{Size: size}),
+		transfer: tachymeter.New(&tachymeter.Config{Size: size}),
+		ttfb:     tachymeter.New(&tachymeter.Config{Size: size}),
+		total:    tachymeter.New(&tachymeter.Config{Size: size}),
+	}
+}
+
+func (meters *phaseMeters) add(timings phaseTimings) {
+	if !timings.reused {
+		meters.newConns++
+		meters.dns.AddTime(timings.dns)
+		meters.connect.AddTime(timings.connect)
+		if timings.tls > 0 {
+			meters.tls.AddTime(timings.tls)
+			if timings.resumed {
+				meters.resumed.AddTime(timings.tls)
+			} else {
+				meters.tlsFull.AddTime(timings.tls)
+			}
+		}
+	}
+	meters.wait.AddTime(timings.wait)
+	meters.transfer.AddTime(timings.transfer)
+	meters.ttfb.AddTime(timings.ttfb)
+	meters.total.AddTime(timings.total)
+}
+
+type PhaseReport struct {
+	Count  int   `json:"count"`
+	MinNs  int64 `json:"min_ns"`
+	MeanNs int64 `json:"mean_ns"`
+	P50Ns  int64 `json:"p50_ns"`
+	P95Ns  int64 `json:"p95_ns"`
+	P99Ns  int64 `json:"p99_ns"`
+	MaxNs  int64 `json:"max_ns"`
+}
+
+type PhasesReport struct {
+	NewConnections int64       `json:"new_connections"`
+	DNS            PhaseReport `json:"dns"`
+	Connect        PhaseReport `json:"connect"`
+	TLS            PhaseReport `json:"tls"`
+	TLSFull        PhaseReport `json:"tls_full"`
+	TLSResumed     PhaseReport `json:"tls_resumed"`
+	Wait           PhaseReport `json:"wait"`
+	Transfer       PhaseReport `json:"transfer"`
+	TTFB           PhaseReport `json:"ttfb"`
+	Total          PhaseReport `json:"total"`
+}
+
+func phaseReport(meter *tachymeter.Tachymeter) PhaseReport {
+	metrics := meter.Calc()
+	return PhaseReport{
+		Count:  metrics.Count,
+		MinNs:  metrics.Time.Min.Nanoseconds(),
+		MeanNs: metrics.Time.Avg.Nanoseconds(),
+		P50Ns:  metrics.Time.P50.Nanoseconds(),
+		P95Ns:  metrics.Time.P95.Nanoseconds(),
+		P99Ns:  metrics.Time.P99.Nanoseconds(),
+		MaxNs:  metrics.Time.Max.Nanoseconds(),
+	}
+}
+
+func (meters *phaseMeters) report() PhasesReport {
+	return PhasesReport{
+		NewConnections: meters.newConns,
+		DNS:            phaseReport(meters.dns),
+		Connect:        phaseReport(meters.connect),
+		TLS:            phaseReport(meters.tls),
+		TLSFull:        phaseReport(meters.tlsFull),
+		TLSResumed:     phaseReport(meters.resumed),
+		Wait:           phaseReport(meters.wait),
+		Transfer:       phaseReport(meters.transfer),
+		TTFB:           phaseReport(meters.ttfb),
+		Total:          phaseReport(meters.total),
+	}
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+type prewarmStats struct {
+	connections int64
+	failures    int64
+	elapsed     time.Duration
+}
+
+func addMissingPort(host string, isTLS bool) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if isTLS {
+		return net.JoinHostPort(host, "443")
+	}
+	return net.JoinHostPort(host, "80")
+}
+
+// Prewarming - whether the task establishes connections before the attack
+func (attack *Attack) Prewarming() bool {
+	return attack.options != nil && attack.options.Prewarm
+}
+
+/*
+WarmUp - establishes connections of all workers to targets of the attack before it starts,
+so handshakes are not measured as latency of the first requests
+*/
+func (attack *Attack) WarmUp() {
+	attack.resultPrewarm = prewarmStats{}
+	if !attack.options.Prewarm {
+		return
+	}
+	attack.stage(StagePrewarming)
+	timeStart := time.Now()
+	workers := int(currentWorkers)
+	if attack.options.Scenario.enabled() && attack.options.Scenario.Users > 0 {
+		workers = attack.options.Scenario.Users
+	}
+	for addr, isTLS := range attack.prewarmTargets() {
+		established, err := attack.dialer.Prewarm(addr, isTLS, workers)
+		if err != nil {
+			logrus.Error("Can not prewarm connections to ", addr, ": ", err)
+		}
+		attack.resultPrewarm.connections += int64(established)
+		attack.resultPrewarm.failures += int64(workers - established)
+	}
+	attack.resultPrewarm.elapsed = time.Since(timeStart)
+	logrus.Info("Prewarmed ", attack.resultPrewarm.connections, " connections for ", attack.resultPrewarm.elapsed)
+}
+
+/*
+prewarmTargets - tls of hosts of prepared requests or of steps of the scenario. Hosts of absolute
+urls of steps with placeholders are known only during the attack, they are not prewarmed
+*/
+func (attack *Attack) prewarmTargets() map[string]bool {
+	targets := map[string]bool{}
+	add := func(uri *fasthttp.URI) {
+		isTLS := string(uri.Scheme()) == "https"
+		targets[addMissingPort(string(uri.Host()), isTLS)] = isTLS
+	}
+	for _, request := range attack.dataAttack {
+		if request == nil {
+			continue
+		}
+		add(request.URI())
+	}
+	if len(attack.scenarioSteps) == 0 {
+		return targets
+	}
+	uri := fasthttp.AcquireURI()
+	defer fasthttp.ReleaseURI(uri)
+	for _, step := range attack.scenarioSteps {
+		address := stepAddress(attack.setupTask.Script.Address, step.Path)
+		if strings.Contains(step.Path, "://") && strings.Contains(step.Path, "{{") {
+			continue
+		}
+		uri.Update(address)
+		add(uri)
+	}
+	return targets
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+)
+
+// connectionCounter - server which counts accepted connections
+func connectionCounter(t *testing.T) (*httptest.Server, *int64) {
+	var accepted int64
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(&accepted, 1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+	return server, &accepted
+}
+
+func TestWarmUpScenario(t *testing.T) {
+	origin, originAccepted := connectionCounter(t)
+	other, otherAccepted := connectionCounter(t)
+	bomber := &Core{config: &config.Configuration{}, attacks: attacks{byFormId: map[string]*Attack{}}}
+	task := taskWithOptions(`{"prewarm": true, "scenario": {"users": 2, "steps": [
+		{"name": "list", "path": "/items"},
+		{"name": "item", "path": "/items/{{id}}"},
+		{"name": "other", "path": "` + other.URL + `/audit"},
+		{"name": "templated", "path": "http://{{host}}/audit"}
+	]}}`)
+	task.Script.Address = origin.URL
+	attack, err := bomber.PreparingData(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attack.WarmUp()
+	defer attack.dialer.CloseWarm()
+	if attack.resultPrewarm.connections != 4 || attack.resultPrewarm.failures != 0 {
+		t.Fatalf("prewarmed %d connections with %d failures, expected 4", attack.resultPrewarm.connections, attack.resultPrewarm.failures)
+	}
+	// servers accept connections asynchronously to dials of the bomber
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && (atomic.LoadInt64(originAccepted) < 2 || atomic.LoadInt64(otherAccepted) < 2) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt64(originAccepted) != 2 || atomic.LoadInt64(otherAccepted) != 2 {
+		t.Fatalf("servers accepted %d and %d connections, expected 2 by each of users",
+			atomic.LoadInt64(originAccepted), atomic.LoadInt64(otherAccepted))
+	}
+}
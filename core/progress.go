@@ -0,0 +1,212 @@
+package core
+
+import (
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/sirupsen/logrus"
+)
+
+const topicProgress = "bombers.server.progress"
+
+// stages of the task in progress of it
+const (
+	StagePreparing  = "preparing"
+	StagePrewarming = "prewarming"
+	StageAttack     = "attack"
+	// results of the attack are formed and published
+	StageFinishing = "finishing"
+	StageFinished  = "finished"
+)
+
+/*
+Progress - published periodically and on each change of the stage, from preparing of the task until its results
+are published. Planned requests and total time are known by rps and time of the task
+*/
+type Progress struct {
+	FormId          string    `json:"form_id"`
+	BomberId        string    `json:"bomber_id"`
+	Tenant          string    `json:"tenant,omitempty"`
+	ContractVersion string    `json:"contract_version"`
+	Stage           string    `json:"stage"`
+	Time            time.Time `json:"time"`
+	StageMs         int64     `json:"stage_ms"` // since start of the stage
+	Sent            int64     `json:"sent"`     // completed and in flight requests
+	Planned         int64     `json:"planned"`
+	ElapsedMs       int64     `json:"elapsed_ms"` // of the attack, zero before it
+	TotalMs         int64     `json:"total_ms"`
+	Progress        float64   `json:"progress_percent"`
+}
+
+// progressState - stage of the task of the attack, progress is not published without it
+type progressState struct {
+	mutex   sync.Mutex
+	formId  string
+	stage   string
+	since   time.Time
+	planned int64
+	total   time.Duration
+	// task failed before its attack, if it finished without one
+	attacked bool
+}
+
+// StartProgress - publishes progress of tasks every interval, zero interval disables progress completely
+func (core *Core) StartProgress(interval time.Duration) {
+	atomic.StoreInt64(&core.progressInterval, int64(interval))
+	if interval <= 0 {
+		logrus.Info("Progress of tasks is disabled")
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, attack := range core.Attacks() {
+				if progress := attack.FormProgress(); progress != nil {
+					core.publishProgress(progress)
+				}
+			}
+		}
+	}()
+}
+
+func (attack *Attack) beginProgress(task rest_contracts.Task) {
+	state := &attack.progress
+	state.mutex.Lock()
+	state.formId = task.FormId
+	state.attacked = false
+	state.mutex.Unlock()
+	attack.planProgress(task)
+	attack.stage(StagePreparing)
+}
+
+// planProgress - plan of the task, sharded task plans its part only
+func (attack *Attack) planProgress(task rest_contracts.Task) {
+	state := &attack.progress
+	state.mutex.Lock()
+	defer state.mutex.Unlock()
+	if task.Script != nil && task.Script.Config != nil {
+		state.planned = task.Script.Config.Rps * task.Script.Config.Time
+		// each iteration of scenario sends all its steps
+		if attack.options != nil && attack.options.Scenario.enabled() {
+			state.planned *= int64(len(attack.options.Scenario.Steps))
+		}
+		state.total = time.Duration(task.Script.Config.Time) * time.Second
+	}
+}
+
+// stage - progress is published at once, as the stage is changed
+func (attack *Attack) stage(stage string) {
+	state := &attack.progress
+	state.mutex.Lock()
+	if state.formId == "" {
+		state.mutex.Unlock()
+		return
+	}
+	state.stage = stage
+	state.since = time.Now()
+	state.attacked = state.attacked || stage == StageAttack
+	state.mutex.Unlock()
+	if atomic.LoadInt64(&attack.core.progressInterval) <= 0 {
+		return
+	}
+	if progress := attack.FormProgress(); progress != nil {
+		attack.core.publishProgress(progress)
+	}
+}
+
+/*
+EndProgress - the last progress of the task is published, when its results and status are published.
+The bomber forgets the attack then
+*/
+func (attack *Attack) EndProgress() {
+	attack.stage(StageFinished)
+	state := &attack.progress
+	state.mutex.Lock()
+	state.formId = ""
+	state.stage = ""
+	state.mutex.Unlock()
+	attack.core.forget(attack)
+}
+
+// FormProgress - nil if the attack is not prepared yet or its progress ended
+func (attack *Attack) FormProgress() *Progress {
+	state := &attack.progress
+	state.mutex.Lock()
+	progress := &Progress{
+		FormId:          state.formId,
+		BomberId:        attack.core.config.CurrentServiceID,
+		Tenant:          attack.core.config.TenantId(),
+		ContractVersion: ContractVersion,
+		Stage:           state.stage,
+		Time:            time.Now(),
+		StageMs:         time.Since(state.since).Milliseconds(),
+		Planned:         state.planned,
+		TotalMs:         state.total.Milliseconds(),
+	}
+	total, attacked := state.total, state.attacked
+	state.mutex.Unlock()
+	if progress.Stage == "" {
+		return nil
+	}
+	if !attacked {
+		return progress
+	}
+	switch progress.Stage {
+	case StageAttack:
+		state := &attack.state
+		state.mutex.Lock()
+		started := state.started
+		state.mutex.Unlock()
+		progress.Sent = attack.completedRequests() + atomic.LoadInt64(&attack.inFlight)
+		progress.ElapsedMs = time.Since(started).Milliseconds()
+		progress.Progress = attack.attackProgress(progress.Sent, time.Since(started), progress.Planned, total)
+	case StageFinishing, StageFinished:
+		progress.Sent = attack.completedRequests()
+		progress.ElapsedMs = attack.attackElapsed.Milliseconds()
+		progress.Progress = 100
+		if attack.Cancelled() {
+			progress.Progress = attack.attackProgress(progress.Sent, attack.attackElapsed, progress.Planned, total)
+		}
+	}
+	return progress
+}
+
+func (attack *Attack) completedRequests() int64 {
+	attack.results.Lock()
+	defer attack.results.Unlock()
+	completed := attack.resultTimeouts
+	for _, amount := range attack.resultsAttack {
+		completed += amount
+	}
+	return completed
+}
+
+// attackProgress - percent of planned requests of http attack, percent of time of attacks in other modes
+func (attack *Attack) attackProgress(completed int64, elapsed time.Duration, requests int64, duration time.Duration) float64 {
+	var percent float64
+	// http attack is planned by requests, it can take longer than its time
+	if attack.options != nil && attack.options.Mode == ModeHTTP && requests > 0 {
+		percent = float64(completed) / float64(requests) * 100
+	} else if duration > 0 {
+		percent = float64(elapsed) / float64(duration) * 100
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	return percent
+}
+
+func (core *Core) publishProgress(progress *Progress) {
+	data, err := json.Marshal(progress)
+	if err != nil {
+		logrus.Error("Can not marshal progress: ", err)
+		return
+	}
+	if errPublish := core.publisher.PublishNewMessage(topicProgress, data); errPublish != nil {
+		logrus.Error("Can not publish progress: ", errPublish)
+	}
+}
@@ -0,0 +1,43 @@
+package core
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/metrics"
+)
+
+func TestProgressCountsInFlightOfAttack(t *testing.T) {
+	bomber := &Core{config: &config.Configuration{}, attacks: attacks{byFormId: map[string]*Attack{}}}
+	first, err := bomber.PreparingData(taskWithOptions(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.EndProgress()
+	second := taskWithOptions("")
+	second.FormId = "second"
+	other, err := bomber.PreparingData(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.EndProgress()
+	first.stage(StageAttack)
+	// requests of the other attack are in flight of the process, but not of the first attack
+	for i := 0; i < 5; i++ {
+		metrics.RequestStarted()
+		atomic.AddInt64(&other.inFlight, 1)
+	}
+	defer func() {
+		for i := 0; i < 5; i++ {
+			metrics.RequestFinished()
+		}
+	}()
+	atomic.AddInt64(&first.inFlight, 2)
+	first.results.Lock()
+	first.resultsAttack[200] = 3
+	first.results.Unlock()
+	if sent := first.FormProgress().Sent; sent != 5 {
+		t.Fatalf("sent %d, expected 3 completed and 2 in flight", sent)
+	}
+}
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	rawReadBuffer = 64 * 1024
+
+	PayloadText   = "text"
+	PayloadHex    = "hex"
+	PayloadBase64 = "base64"
+)
+
+var (
+	ErrRawNetwork = errors.New("address of raw mode must be tcp:// or udp://")
+	ErrRawPayload = errors.New("unknown encoding of raw payload")
+)
+
+type RawOptions struct {
+	// template of payload with {{name}} placeholders of body params
+	Payload string `json:"payload"`
+	// text (default), hex or base64, rendered payload is decoded before sending
+	PayloadEncoding string `json:"payload_encoding,omitempty"`
+	// amount of concurrent connections, amount of workers if empty
+	Connections int `json:"connections,omitempty"`
+	// wait for a reply after each payload, latency becomes round trip
+	ReadReply      bool  `json:"read_reply,omitempty"`
+	ReplyTimeoutMs int64 `json:"reply_timeout_ms,omitempty"`
+}
+
+type rawStats struct {
+	mutex         sync.Mutex
+	connections   int64
+	connectErrors int64
+	sent          int64
+	sentBytes     int64
+	replies       int64
+	errors        int64
+}
+
+type RawReport struct {
+	Network       string `json:"network"`
+	Connections   int64  `json:"connections"`
+	ConnectErrors int64  `json:"connect_errors"`
+	Sent          int64  `json:"sent"`
+	SentBytes     int64  `json:"sent_bytes"`
+	Replies       int64  `json:"replies"`
+	Errors        int64  `json:"errors"`
+}
+
+func (stats *rawStats) report(network string) *RawReport {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return &RawReport{
+		Network:       network,
+		Connections:   stats.connections,
+		ConnectErrors: stats.connectErrors,
+		Sent:          stats.sent,
+		SentBytes:     stats.sentBytes,
+		Replies:       stats.replies,
+		Errors:        stats.errors,
+	}
+}
+
+func parseRawAddress(address string) (string, string, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return "", "", err
+	}
+	if parsed.Scheme != "tcp" && parsed.Scheme != "udp" {
+		return "", "", ErrRawNetwork
+	}
+	return parsed.Scheme, parsed.Host, nil
+}
+
+func (attack *Attack) rawPayload(task rest_contracts.Task) ([]byte, error) {
+	rendered := renderTemplate(attack.options.Raw.Payload, task.Schema.Body)
+	switch attack.options.Raw.PayloadEncoding {
+	case "", PayloadText:
+		return []byte(rendered), nil
+	case PayloadHex:
+		return hex.DecodeString(rendered)
+	case PayloadBase64:
+		return base64.StdEncoding.DecodeString(rendered)
+	}
+	return nil, ErrRawPayload
+}
+
+/*
+startRawAttack - sends payloads to tcp or udp target with rps of the task
+divided between connections during time of the task
+*/
+func (attack *Attack) startRawAttack(task rest_contracts.Task) {
+	network, address, err := parseRawAddress(task.Script.Address)
+	if err != nil {
+		logrus.Error("Can not parse raw address: ", err)
+		return
+	}
+	connections := attack.options.Raw.Connections
+	if connections <= 0 {
+		connections = currentWorkers
+	}
+	attack.resultRaw = &rawStats{}
+	attack.resultRawNetwork = network
+	interval := time.Duration(float64(time.Second) * float64(connections) / float64(task.Script.Config.Rps))
+	deadline := time.Now().Add(time.Duration(task.Script.Config.Time) * time.Second)
+	var wg sync.WaitGroup
+	for index := 0; index < connections; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attack.runRawUser(task, network, address, interval, deadline)
+		}()
+	}
+	wg.Wait()
+}
+
+func (attack *Attack) dialRaw(network string, address string) (net.Conn, error) {
+	stats := attack.resultRaw
+	var conn net.Conn
+	var err error
+	if network == "udp" {
+		conn, err = attack.dialer.DialUDP(address)
+	} else {
+		conn, err = attack.dialer.Dial(address)
+	}
+	if err != nil {
+		attack.countError(err)
+	}
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if err != nil {
+		stats.connectErrors++
+		return nil, err
+	}
+	stats.connections++
+	return conn, nil
+}
+
+func (attack *Attack) runRawUser(task rest_contracts.Task, network string, address string, interval time.Duration, deadline time.Time) {
+	options := attack.options.Raw
+	stats := attack.resultRaw
+	replyTimeout := time.Duration(options.ReplyTimeoutMs) * time.Millisecond
+	if replyTimeout <= 0 {
+		replyTimeout = defaultReplyTimeout
+	}
+	reply := make([]byte, rawReadBuffer)
+	var conn net.Conn
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+	attackCtx := attack.attackContext()
+	for time.Now().Before(deadline) && attackCtx.Err() == nil {
+		timeStart := time.Now()
+		if conn == nil {
+			var err error
+			if conn, err = attack.dialRaw(network, address); err != nil {
+				logrus.Debug("Can not connect to raw target: ", err)
+				time.Sleep(interval)
+				continue
+			}
+		}
+		payload, err := attack.rawPayload(task)
+		if err != nil {
+			logrus.Error("Can not form raw payload: ", err)
+			return
+		}
+		if _, err := conn.Write(payload); err != nil {
+			attack.rawFailed(conn, err)
+			conn = nil
+			continue
+		}
+		stats.mutex.Lock()
+		stats.sent++
+		stats.sentBytes += int64(len(payload))
+		stats.mutex.Unlock()
+		if options.ReadReply {
+			conn.SetReadDeadline(time.Now().Add(replyTimeout))
+			if _, err := conn.Read(reply); err != nil {
+				attack.rawFailed(conn, err)
+				conn = nil
+				continue
+			}
+			stats.mutex.Lock()
+			stats.replies++
+			stats.mutex.Unlock()
+		}
+		latency := time.Since(timeStart)
+		attack.tahometr.AddTime(latency)
+		attack.results.Lock()
+		attack.recordLatency(latency.Nanoseconds(), noStatus, false, 0)
+		attack.results.Unlock()
+		if elapsed := time.Since(timeStart); elapsed < interval {
+			time.Sleep(interval - elapsed)
+		}
+	}
+}
+
+// rawFailed - closes broken connection, reply timeouts are counted as timeouts of requests
+func (attack *Attack) rawFailed(conn net.Conn, err error) {
+	conn.Close()
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		attack.results.Lock()
+		attack.recordTimeout(ErrorTimeout)
+		attack.results.Unlock()
+		return
+	}
+	attack.countError(err)
+	attack.resultRaw.mutex.Lock()
+	attack.resultRaw.errors++
+	attack.resultRaw.mutex.Unlock()
+}
@@ -0,0 +1,90 @@
+package core
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const defaultMaxRedirectHops = 10
+
+type RedirectOptions struct {
+	Follow  bool `json:"follow"`
+	MaxHops int  `json:"max_hops,omitempty"`
+	// latency of request includes time of all hops, otherwise only the first one
+	CountHopsLatency bool `json:"count_hops_latency,omitempty"`
+}
+
+type redirectResult struct {
+	elapsed       time.Duration
+	hops          int
+	limitExceeded bool
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case fasthttp.StatusMovedPermanently, fasthttp.StatusFound, fasthttp.StatusSeeOther,
+		fasthttp.StatusTemporaryRedirect, fasthttp.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// crossOrigin - hop to other scheme or host than the original request
+func crossOrigin(original *fasthttp.URI, hop *fasthttp.URI) bool {
+	return !bytes.Equal(original.Scheme(), hop.Scheme()) || !bytes.EqualFold(original.Host(), hop.Host())
+}
+
+/*
+doFollowingRedirects - executes request and follows redirects by policy of the task.
+Response contains the last response in the chain. Hops to other scheme or host and all
+hops after them are sent without credentials and signatures
+*/
+func (attack *Attack) doFollowingRedirects(user *virtualUser, request *fasthttp.Request, response *fasthttp.Response) (redirectResult, error) {
+	timeStart := time.Now()
+	if err := user.do(request, response); err != nil {
+		return redirectResult{}, err
+	}
+	result := redirectResult{elapsed: time.Since(timeStart)}
+	policy := attack.options.Redirects
+	if !policy.Follow {
+		return result, nil
+	}
+	maxHops := policy.MaxHops
+	if maxHops <= 0 {
+		maxHops = defaultMaxRedirectHops
+	}
+	hop := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(hop)
+	request.CopyTo(hop)
+	credentials := true
+	for isRedirect(response.StatusCode()) {
+		location := response.Header.Peek(fasthttp.HeaderLocation)
+		if len(location) == 0 {
+			break
+		}
+		if result.hops == maxHops {
+			result.limitExceeded = true
+			break
+		}
+		hop.URI().UpdateBytes(location)
+		if response.StatusCode() == fasthttp.StatusSeeOther {
+			hop.Header.SetMethod(fasthttp.MethodGet)
+			hop.ResetBody()
+		}
+		if credentials && crossOrigin(request.URI(), hop.URI()) {
+			credentials = false
+			user.stripCredentials(hop)
+		}
+		hopStart := time.Now()
+		if err := user.exchange(hop, response, credentials); err != nil {
+			return result, err
+		}
+		result.hops++
+		if policy.CountHopsLatency {
+			result.elapsed += time.Since(hopStart)
+		}
+	}
+	return result, nil
+}
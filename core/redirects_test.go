@@ -0,0 +1,93 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/valyala/fasthttp"
+)
+
+// recorder - answers by handler, headers of requests are kept by path
+type recorder struct {
+	*httptest.Server
+	mutex   sync.Mutex
+	headers map[string]http.Header
+}
+
+func newRecorder(t *testing.T, handler http.HandlerFunc) *recorder {
+	server := &recorder{headers: map[string]http.Header{}}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		server.mutex.Lock()
+		server.headers[request.URL.Path] = request.Header.Clone()
+		server.mutex.Unlock()
+		handler(writer, request)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (server *recorder) header(path string, name string) string {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+	return server.headers[path].Get(name)
+}
+
+func TestRedirectCredentials(t *testing.T) {
+	other := newRecorder(t, func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/elsewhere" {
+			http.Redirect(writer, request, "/landing", http.StatusFound)
+		}
+	})
+	origin := newRecorder(t, func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/start":
+			http.Redirect(writer, request, "/moved", http.StatusFound)
+		case "/moved":
+			http.Redirect(writer, request, other.URL+"/elsewhere", http.StatusFound)
+		}
+	})
+	bomber := &Core{config: &config.Configuration{RequestHMACKey: "secret"}, attacks: attacks{byFormId: map[string]*Attack{}}}
+	task := taskWithOptions(`{"redirects": {"follow": true}, "auth": {"bearer": "token"},
+		"hmac_signing": {"header": "X-Signature", "timestamp_header": "X-Timestamp", "parts": ["method", "timestamp"]}}`)
+	task.Script.Address = origin.URL + "/start"
+	attack, err := bomber.PreparingData(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	response := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(response)
+	request.SetRequestURI(origin.URL + "/start")
+	request.Header.SetCookie("session", "schema")
+	result, err := attack.doFollowingRedirects(attack.newVirtualUser(), request, response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.hops != 3 || response.StatusCode() != http.StatusOK {
+		t.Fatalf("got %d hops and status %d", result.hops, response.StatusCode())
+	}
+	cases := []struct {
+		name        string
+		server      *recorder
+		path        string
+		credentials bool
+	}{
+		{"original request", origin, "/start", true},
+		{"hop to the same host", origin, "/moved", true},
+		{"hop to other host", other, "/elsewhere", false},
+		{"next hop on other host", other, "/landing", false},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			for _, name := range []string{"Authorization", "X-Signature", "X-Timestamp", "Cookie"} {
+				if sent := testCase.server.header(testCase.path, name) != ""; sent != testCase.credentials {
+					t.Fatalf("%s is sent %v, expected %v", name, sent, testCase.credentials)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package core
+
+// AttackReport - bomber specific details of an attack, which are not present
+// in BomberResult contract. Published as json next to the result.
+type AttackReport struct {
+	FormId          string                    `json:"form_id"`
+	BomberId        string                    `json:"bomber_id"`
+	Tenant          string                    `json:"tenant,omitempty"`
+	ContractVersion string                    `json:"contract_version"`
+	Mode            string                    `json:"mode"`
+	Cancelled       bool                      `json:"cancelled,omitempty"`
+	PreemptedBy     string                    `json:"preempted_by,omitempty"`
+	Shard           *ShardReport              `json:"shard,omitempty"`
+	Connections     ConnectionsReport         `json:"connections"`
+	Redirects       RedirectsReport           `json:"redirects"`
+	Compression     CompressionReport         `json:"compression"`
+	Responses       ResponsesReport           `json:"responses"`
+	Retries         RetriesReport             `json:"retries"`
+	Breaker         BreakerReport             `json:"circuit_breaker"`
+	Prewarm         PrewarmReport             `json:"prewarm"`
+	Errors          map[string]int64          `json:"errors"`
+	ErrorMessages   ErrorMessagesReport       `json:"error_messages"`
+	Traffic         TrafficReport             `json:"traffic"`
+	Latency         LatencyReport             `json:"latency"`
+	Summary         SummaryReport             `json:"summary"`
+	StatusClasses   *StatusClassesReport      `json:"status_classes,omitempty"`
+	Apdex           ApdexReport               `json:"apdex"`
+	LatencyByStatus map[int32]LatencyReport   `json:"latency_by_status"`
+	Endpoints       map[string]EndpointReport `json:"endpoints,omitempty"`
+	Timeline        TimelineReport            `json:"timeline"`
+	Phases          PhasesReport              `json:"phases"`
+	Continue        ContinueReport            `json:"expect_continue"`
+	Tracing         TracingReport             `json:"tracing"`
+	Websocket       *WebsocketReport          `json:"websocket,omitempty"`
+	SSE             *SSEReport                `json:"sse,omitempty"`
+	GRPC            *GRPCReport               `json:"grpc,omitempty"`
+	Raw             *RawReport                `json:"raw,omitempty"`
+	Scenario        *ScenarioReport           `json:"scenario,omitempty"`
+	Assertions      *AssertionsReport         `json:"assertions,omitempty"`
+	Headers         map[string]HeaderReport   `json:"headers,omitempty"`
+	Login           *LoginReport              `json:"login,omitempty"`
+	OAuth2          *OAuth2Report             `json:"oauth2,omitempty"`
+	CSRF            *CSRFReport               `json:"csrf,omitempty"`
+	Setup           []HookReport              `json:"setup,omitempty"`
+	Teardown        []HookReport              `json:"teardown,omitempty"`
+	Thresholds      []ThresholdResult         `json:"thresholds,omitempty"`
+	// verdict of thresholds, empty if task has none
+	ThresholdsPassed *bool                 `json:"thresholds_passed,omitempty"`
+	Baseline         *BaselineReport       `json:"baseline,omitempty"`
+	FailureSamples   *FailureSamplesReport `json:"failure_samples,omitempty"`
+	BodySamples      []ResponseSample      `json:"body_samples,omitempty"`
+	// full result in object storage, result published into NATS has no latency of each request then
+	ResultObject *ResultObjectReport `json:"result_object,omitempty"`
+}
+
+type ResultObjectReport struct {
+	Bucket          string `json:"bucket"`
+	Key             string `json:"key"`
+	URL             string `json:"url"`
+	Bytes           int64  `json:"bytes"`
+	ContentType     string `json:"content_type"`
+	ContentEncoding string `json:"content_encoding"`
+}
+
+type ConnectionsReport struct {
+	IPv4      int64            `json:"ipv4"`
+	IPv6      int64            `json:"ipv6"`
+	PerSource map[string]int64 `json:"per_source,omitempty"`
+	// tls handshakes of connections, resumed ones reused session of previous connection
+	TLSFull    int64 `json:"tls_full"`
+	TLSResumed int64 `json:"tls_resumed"`
+}
+
+type RedirectsReport struct {
+	Followed      int64 `json:"followed"`
+	LimitExceeded int64 `json:"limit_exceeded"`
+}
+
+type CompressionReport struct {
+	Encoding     string `json:"encoding,omitempty"`
+	RawBytes     int64  `json:"raw_bytes"`
+	EncodedBytes int64  `json:"encoded_bytes"`
+}
+
+type ResponsesReport struct {
+	WireBytes    int64 `json:"wire_bytes"`
+	DecodedBytes int64 `json:"decoded_bytes"`
+	DecodeErrors int64 `json:"decode_errors"`
+}
+
+type RetriesReport struct {
+	Retried       int64 `json:"retried"`
+	ExtraAttempts int64 `json:"extra_attempts"`
+	Recovered     int64 `json:"recovered"`
+	// outcome of the first attempts, as it would be without retries
+	FirstAttemptStatuses map[int32]int64 `json:"first_attempt_statuses"`
+	FirstAttemptFailures int64           `json:"first_attempt_failures"`
+}
+
+type BreakerReport struct {
+	Skipped int64           `json:"skipped"`
+	Periods []BreakerPeriod `json:"periods,omitempty"`
+}
+
+type ContinueReport struct {
+	Requests     int64 `json:"requests"`
+	Accepted     int64 `json:"accepted"`
+	WaitTimeouts int64 `json:"wait_timeouts"`
+	// requests answered by final status before body was sent
+	Rejected         int64           `json:"rejected"`
+	RejectedStatuses map[int32]int64 `json:"rejected_statuses"`
+}
+
+type PrewarmReport struct {
+	Connections int64 `json:"connections"`
+	Failures    int64 `json:"failures"`
+	ElapsedMs   int64 `json:"elapsed_ms"`
+}
+
+func (attack *Attack) FormReportAttack() *AttackReport {
+	dialStats := attack.dialer.Stats()
+	report := &AttackReport{
+		FormId:          attack.formId,
+		BomberId:        attack.core.config.CurrentServiceID,
+		Tenant:          attack.core.config.TenantId(),
+		ContractVersion: ContractVersion,
+		Mode:            attack.options.Mode,
+		Cancelled:       attack.Cancelled(),
+		PreemptedBy:     attack.PreemptedBy(),
+		Shard:           attack.shardReport(),
+		Connections: ConnectionsReport{
+			IPv4:       dialStats.IPv4,
+			IPv6:       dialStats.IPv6,
+			PerSource:  dialStats.PerSource,
+			TLSFull:    dialStats.TLSFull,
+			TLSResumed: dialStats.TLSResumed,
+		},
+		Redirects: RedirectsReport{
+			Followed:      attack.resultRedirects,
+			LimitExceeded: attack.resultRedirectsLimit,
+		},
+		Compression: CompressionReport{
+			Encoding:     attack.options.BodyEncoding,
+			RawBytes:     attack.resultBodyRawBytes,
+			EncodedBytes: attack.resultBodyEncodedBytes,
+		},
+		Responses: ResponsesReport{
+			WireBytes:    attack.resultWireBytes,
+			DecodedBytes: attack.resultDecodedBytes,
+			DecodeErrors: attack.resultDecodeErrors,
+		},
+		Retries: RetriesReport{
+			Retried:              attack.resultRetries.retried,
+			ExtraAttempts:        attack.resultRetries.extraAttempts,
+			Recovered:            attack.resultRetries.recovered,
+			FirstAttemptStatuses: attack.resultRetries.firstStatuses,
+			FirstAttemptFailures: attack.resultRetries.firstFailures,
+		},
+		Breaker: BreakerReport{
+			Skipped: attack.resultSkipped,
+			Periods: attack.breaker.finish(),
+		},
+		Prewarm: PrewarmReport{
+			Connections: attack.resultPrewarm.connections,
+			Failures:    attack.resultPrewarm.failures,
+			ElapsedMs:   attack.resultPrewarm.elapsed.Milliseconds(),
+		},
+		Errors:          attack.resultErrors,
+		Traffic:         attack.trafficReport(),
+		Latency:         attack.latencyReport(),
+		Apdex:           attack.resultApdex.finish(),
+		Summary:         attack.resultSummary.report(),
+		ErrorMessages:   attack.resultMessages.report(),
+		LatencyByStatus: attack.latencyByStatusReport(),
+		Endpoints:       attack.endpointsReport(),
+		Assertions:      attack.assertionsReport(),
+		Headers:         attack.headersReport(),
+		Login:           attack.loginsReport(),
+		OAuth2:          attack.oauth2.report(),
+		CSRF:            attack.csrfReport(),
+		Setup:           attack.resultSetup,
+		Teardown:        attack.resultTeardown,
+		Timeline:        attack.resultTimeline.report(),
+		Phases:          attack.resultPhases.report(),
+		Tracing:         attack.resultTracing.report(),
+		Continue: ContinueReport{
+			Requests:         attack.resultContinue.requests,
+			Accepted:         attack.resultContinue.accepted,
+			WaitTimeouts:     attack.resultContinue.waitTimeouts,
+			Rejected:         attack.resultContinue.rejected,
+			RejectedStatuses: attack.resultContinue.rejectedStatuses,
+		},
+	}
+	if attack.resultWebsocket != nil {
+		report.Websocket = attack.resultWebsocket.report()
+	}
+	if attack.resultSSE != nil {
+		report.SSE = attack.resultSSE.report()
+	}
+	if attack.resultGRPC != nil {
+		report.GRPC = attack.resultGRPC.report()
+	}
+	if attack.resultRaw != nil {
+		report.Raw = attack.resultRaw.report(attack.resultRawNetwork)
+	}
+	if attack.resultScenario != nil {
+		report.Scenario = attack.resultScenario.report(attack.options.Scenario)
+	}
+	report.StatusClasses = statusClassesReport(attack.options.Mode, attack.resultsAttack, attack.resultTimeouts)
+	report.FailureSamples = attack.resultFailures.report()
+	report.BodySamples = attack.resultBodies.report()
+	if attack.baseline != nil {
+		report.Baseline = compareBaseline(attack.options.Baseline, attack.baseline, attack.attackSummary(report))
+	}
+	if len(attack.options.Thresholds) > 0 {
+		report.Thresholds = evaluateThresholds(attack.options.Thresholds, attack.attackSummary(report))
+		passed := true
+		for _, threshold := range report.Thresholds {
+			passed = passed && threshold.Passed
+		}
+		report.ThresholdsPassed = &passed
+	}
+	return report
+}
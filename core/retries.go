@@ -0,0 +1,135 @@
+package core
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"syscall"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	defaultInitialBackoff = 50 * time.Millisecond
+	defaultMaxBackoff     = time.Second
+)
+
+var defaultRetryStatuses = []int{
+	fasthttp.StatusBadGateway,
+	fasthttp.StatusServiceUnavailable,
+	fasthttp.StatusGatewayTimeout,
+}
+
+type RetryOptions struct {
+	// amount of attempts including the first one, requests are not retried if less than 2
+	MaxAttempts      int     `json:"max_attempts,omitempty"`
+	InitialBackoffMs int64   `json:"initial_backoff_ms,omitempty"`
+	MaxBackoffMs     int64   `json:"max_backoff_ms,omitempty"`
+	Jitter           float64 `json:"jitter,omitempty"` // part of backoff in [0, 1]
+	Statuses         []int   `json:"statuses,omitempty"`
+}
+
+type retryResult struct {
+	attempts    int
+	firstStatus int
+	firstFailed bool // first attempt failed with transport error
+}
+
+func (options RetryOptions) retryStatus(status int) bool {
+	statuses := options.Statuses
+	if len(statuses) == 0 {
+		statuses = defaultRetryStatuses
+	}
+	for _, retryStatus := range statuses {
+		if status == retryStatus {
+			return true
+		}
+	}
+	return false
+}
+
+func (options RetryOptions) backoff(attempt int) time.Duration {
+	initial := time.Duration(options.InitialBackoffMs) * time.Millisecond
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+	max := time.Duration(options.MaxBackoffMs) * time.Millisecond
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+	backoff := initial << uint(attempt-1)
+	if backoff > max || backoff <= 0 {
+		backoff = max
+	}
+	if options.Jitter > 0 {
+		jitter := float64(backoff) * options.Jitter
+		backoff += time.Duration(jitter * (2*rand.Float64() - 1))
+	}
+	return backoff
+}
+
+func isTransientError(err error) bool {
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, fasthttp.ErrConnectionClosed)
+}
+
+/*
+doWithRetries - executes request with retries of transient failures by exponential backoff.
+Latency is the summary time of all attempts without backoff pauses
+*/
+func (attack *Attack) doWithRetries(user *virtualUser, request *fasthttp.Request, response *fasthttp.Response) (redirectResult, retryResult, error) {
+	options := attack.options.Retry
+	result := retryResult{}
+	var elapsed time.Duration
+	for {
+		result.attempts++
+		redirected, err := attack.doFollowingRedirects(user, request, response)
+		elapsed += redirected.elapsed
+		if result.attempts == 1 {
+			result.firstFailed = err != nil
+			if err == nil {
+				result.firstStatus = response.StatusCode()
+			}
+		}
+		retryable := (err != nil && isTransientError(err)) || (err == nil && options.retryStatus(response.StatusCode()))
+		if !retryable || result.attempts >= options.MaxAttempts || request.IsBodyStream() {
+			redirected.elapsed = elapsed
+			return redirected, result, err
+		}
+		time.Sleep(options.backoff(result.attempts))
+	}
+}
+
+type retriesStats struct {
+	retried       int64 // requests with more than one attempt
+	extraAttempts int64
+	recovered     int64 // retried requests, which completed successfully
+	firstStatuses map[int32]int64
+	firstFailures int64
+}
+
+func newRetriesStats() retriesStats {
+	return retriesStats{
+		firstStatuses: map[int32]int64{},
+	}
+}
+
+func (stats *retriesStats) add(result SliceResult, options RetryOptions) {
+	if result.FirstFailed {
+		stats.firstFailures++
+	} else {
+		stats.firstStatuses[int32(result.FirstStatus)]++
+	}
+	if result.Attempts <= 1 {
+		return
+	}
+	stats.retried++
+	stats.extraAttempts += int64(result.Attempts - 1)
+	if !result.Timeout && !options.retryStatus(result.Status) {
+		stats.recovered++
+	}
+}
@@ -0,0 +1,144 @@
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SamplesCSV    = "csv"
+	SamplesNDJSON = "ndjson"
+
+	samplesBuffer = 64 * 1024
+)
+
+var ErrSamplesFormat = errors.New("unsupported format of samples, csv and ndjson are supported")
+
+var samplesHeader = []byte("timestamp_ns,status,latency_ns,error,bytes\n")
+
+type requestSample struct {
+	TimestampNs int64  `json:"timestamp_ns"`
+	Status      int32  `json:"status,omitempty"` // empty for failed requests and modes without status
+	LatencyNs   int64  `json:"latency_ns,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Bytes       int    `json:"bytes,omitempty"`
+}
+
+/*
+samplesWriter - streams record of each completed or failed request into a file in directory
+of reports. Must be used under results lock of the attack
+*/
+type samplesWriter struct {
+	file   *os.File
+	writer *bufio.Writer
+	format string
+	failed bool
+}
+
+func openSamples(dir string, name string, format string) (*samplesWriter, error) {
+	if format != SamplesCSV && format != SamplesNDJSON {
+		return nil, ErrSamplesFormat
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file, err := os.Create(filepath.Join(dir, filepath.Base(name+"."+format)))
+	if err != nil {
+		return nil, err
+	}
+	samples := &samplesWriter{
+		file:   file,
+		writer: bufio.NewWriterSize(file, samplesBuffer),
+		format: format,
+	}
+	if format == SamplesCSV {
+		samples.writer.Write(samplesHeader)
+	}
+	return samples, nil
+}
+
+func (samples *samplesWriter) write(sample requestSample) {
+	if samples == nil || samples.failed {
+		return
+	}
+	if sample.Status == noStatus {
+		sample.Status = 0
+	}
+	var err error
+	if samples.format == SamplesNDJSON {
+		var line []byte
+		line, err = json.Marshal(sample)
+		if err == nil {
+			line = append(line, '\n')
+			_, err = samples.writer.Write(line)
+		}
+	} else {
+		line := make([]byte, 0, 64)
+		line = strconv.AppendInt(line, sample.TimestampNs, 10)
+		line = append(line, ',')
+		if sample.Status != 0 {
+			line = strconv.AppendInt(line, int64(sample.Status), 10)
+		}
+		line = append(line, ',')
+		line = strconv.AppendInt(line, sample.LatencyNs, 10)
+		line = append(line, ',')
+		line = append(line, sample.Error...)
+		line = append(line, ',')
+		line = strconv.AppendInt(line, int64(sample.Bytes), 10)
+		line = append(line, '\n')
+		_, err = samples.writer.Write(line)
+	}
+	if err != nil {
+		// disk is full or gone, the attack goes on without samples
+		logrus.Error("Can not write samples: ", err)
+		samples.failed = true
+	}
+}
+
+func (samples *samplesWriter) close() {
+	if samples == nil {
+		return
+	}
+	if err := samples.writer.Flush(); err != nil {
+		logrus.Error("Can not flush samples: ", err)
+	}
+	if err := samples.file.Close(); err != nil {
+		logrus.Error("Can not close samples: ", err)
+	}
+	logrus.Info("Samples of attack were written to ", samples.file.Name())
+}
+
+// startSamples - opens file of samples if task asks for them, file is named like the report file
+func (attack *Attack) startSamples() {
+	if attack.options == nil || attack.options.Samples == "" {
+		return
+	}
+	dir := attack.core.config.TenantReportDir()
+	if dir == "" || dir == "off" {
+		logrus.Error("Can not write samples: REPORT_DIR is not configured")
+		return
+	}
+	name := attack.formId + "-" + attack.core.config.CurrentServiceID + "-" + strconv.FormatInt(time.Now().Unix(), 10) + ".samples"
+	samples, err := openSamples(dir, name, attack.options.Samples)
+	if err != nil {
+		logrus.Error("Can not open samples: ", err)
+		return
+	}
+	attack.results.Lock()
+	attack.resultSamples = samples
+	attack.results.Unlock()
+}
+
+func (attack *Attack) stopSamples() {
+	attack.results.Lock()
+	defer attack.results.Unlock()
+	attack.resultSamples.close()
+	attack.resultSamples = nil
+}
@@ -0,0 +1,471 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+var methodPattern = regexp.MustCompile(`^[A-Z]+$`)
+
+/*
+ScenarioOptions - each virtual user executes steps of the scenario one by one, rps and time of the task
+are iterations of the scenario instead of single requests. Scenario is disabled without steps
+*/
+type ScenarioOptions struct {
+	Steps []ScenarioStep `json:"steps,omitempty"`
+	// amount of virtual users, amount of workers if empty
+	Users int `json:"users,omitempty"`
+	// pause after each step without own think time
+	Think ThinkTime `json:"think"`
+}
+
+// ThinkTime - pause of the virtual user after the step, random from min_ms to max_ms, fixed min_ms without max_ms
+type ThinkTime struct {
+	MinMs int `json:"min_ms,omitempty"`
+	MaxMs int `json:"max_ms,omitempty"`
+}
+
+func (think ThinkTime) enabled() bool {
+	return think.MinMs > 0 || think.MaxMs > 0
+}
+
+func (think ThinkTime) pause() time.Duration {
+	pause := think.MinMs
+	if think.MaxMs > think.MinMs {
+		pause += rand.Intn(think.MaxMs - think.MinMs + 1)
+	}
+	return time.Duration(pause) * time.Millisecond
+}
+
+/*
+ScenarioStep - request of the scenario, path, headers and body are templates with {{name}} placeholders
+of variables extracted by previous steps of the iteration and of body params
+*/
+type ScenarioStep struct {
+	Name string `json:"name"`
+	// GET if empty
+	Method string `json:"method,omitempty"`
+	// appended to address of the task, absolute url replaces it
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// step is sent without body if empty
+	Body string `json:"body,omitempty"`
+	// json paths of values of response body by names of variables, the step fails if one of them is not found
+	Extract map[string]string `json:"extract,omitempty"`
+	// regexes of values of response body or headers by names of variables, for responses which are not json
+	ExtractRegex map[string]RegexExtract `json:"extract_regex,omitempty"`
+	// names of response headers by names of variables, the step fails if one of them is absent
+	ExtractHeaders map[string]string `json:"extract_headers,omitempty"`
+	// iteration if empty, variables of user scope are kept by the virtual user for its next iterations
+	Scope string `json:"scope,omitempty"`
+	// step is sent only if the condition holds
+	When *StepCondition `json:"when,omitempty"`
+	// pause before the next step, think of the scenario if empty
+	Think ThinkTime `json:"think"`
+	// statuses of assertions replace check of status below 400
+	Assertions AssertionOptions `json:"assertions"`
+}
+
+// scenarioStep - step with compiled extractors, it is prepared once for all iterations
+type scenarioStep struct {
+	ScenarioStep
+	extract    map[string]jsonPath
+	regex      map[string]regexExtractor
+	assertions *assertions
+	when       *stepCondition
+	// a later step has condition on this step, so its status 400 and above is a branch instead of failure
+	branched bool
+}
+
+func compileScenario(scenario ScenarioOptions) ([]scenarioStep, error) {
+	steps := make([]scenarioStep, len(scenario.Steps))
+	for index, step := range scenario.Steps {
+		steps[index] = scenarioStep{ScenarioStep: step, extract: map[string]jsonPath{}, regex: map[string]regexExtractor{}}
+		if !step.Think.enabled() {
+			steps[index].Think = scenario.Think
+		}
+		for name, path := range step.Extract {
+			compiled, err := compileJSONPath(path)
+			if err != nil {
+				return nil, err
+			}
+			steps[index].extract[name] = compiled
+		}
+		for name, extract := range step.ExtractRegex {
+			compiled, err := compileRegexExtract(extract)
+			if err != nil {
+				return nil, err
+			}
+			steps[index].regex[name] = compiled
+		}
+		assertions, err := compileAssertions(step.Assertions)
+		if err != nil {
+			return nil, err
+		}
+		steps[index].assertions = assertions
+		when, err := compileCondition(step.When)
+		if err != nil {
+			return nil, err
+		}
+		steps[index].when = when
+		for previous := 0; when != nil && previous < index; previous++ {
+			if steps[previous].Name == when.Step {
+				steps[previous].branched = true
+			}
+		}
+	}
+	return steps, nil
+}
+
+// failedByStatus - status 400 and above fails the step, unless assertions of the step check statuses
+func (step scenarioStep) failedByStatus(status int) bool {
+	return !step.assertions.checksStatus() && status >= fasthttp.StatusBadRequest
+}
+
+func (scenario ScenarioOptions) enabled() bool {
+	return len(scenario.Steps) > 0
+}
+
+type scenarioStats struct {
+	iterations int64
+	completed  int64
+	failed     int64
+	duration   *hdrhistogram.Histogram // of completed iterations
+	steps      []*endpointStats
+	// steps not sent, as the previous step of the iteration failed
+	skipped []int64
+	// responses without values to extract
+	extractFailures   []int64
+	assertionFailures []int64
+	// steps passed over, as their conditions did not hold
+	unmet []int64
+}
+
+// ScenarioReport - iterations of the scenario, requests of steps are counted in the whole report too
+type ScenarioReport struct {
+	Iterations int64         `json:"iterations"`
+	Completed  int64         `json:"completed"`
+	Failed     int64         `json:"failed"`
+	Duration   LatencyReport `json:"duration"`
+	Steps      []StepReport  `json:"steps"`
+}
+
+type StepReport struct {
+	Name string `json:"name"`
+	EndpointReport
+	Skipped           int64 `json:"skipped"`
+	ExtractFailures   int64 `json:"extract_failures"`
+	AssertionFailures int64 `json:"assertion_failures"`
+	ConditionsUnmet   int64 `json:"conditions_unmet"`
+}
+
+func newScenarioStats(scenario ScenarioOptions) *scenarioStats {
+	stats := &scenarioStats{
+		duration:          newLatencyHistogram(),
+		steps:             make([]*endpointStats, len(scenario.Steps)),
+		skipped:           make([]int64, len(scenario.Steps)),
+		extractFailures:   make([]int64, len(scenario.Steps)),
+		assertionFailures: make([]int64, len(scenario.Steps)),
+		unmet:             make([]int64, len(scenario.Steps)),
+	}
+	for index := range stats.steps {
+		stats.steps[index] = newEndpointStats()
+	}
+	return stats
+}
+
+// end - iteration ended by the step, must be called under results lock of the attack
+func (stats *scenarioStats) end(step int, completed bool, elapsed time.Duration) {
+	stats.iterations++
+	if !completed {
+		stats.failed++
+		for index := step + 1; index < len(stats.skipped); index++ {
+			stats.skipped[index]++
+		}
+		return
+	}
+	stats.completed++
+	duration := elapsed.Nanoseconds()
+	if duration > latencyHighest {
+		duration = latencyHighest
+	}
+	stats.duration.RecordValue(duration)
+}
+
+func (stats *scenarioStats) report(scenario ScenarioOptions) *ScenarioReport {
+	report := &ScenarioReport{
+		Iterations: stats.iterations,
+		Completed:  stats.completed,
+		Failed:     stats.failed,
+		Duration:   latencyReport(stats.duration),
+		Steps:      make([]StepReport, len(stats.steps)),
+	}
+	for index, step := range stats.steps {
+		report.Steps[index] = StepReport{
+			Name:              scenario.Steps[index].Name,
+			EndpointReport:    step.report(),
+			Skipped:           stats.skipped[index],
+			ExtractFailures:   stats.extractFailures[index],
+			AssertionFailures: stats.assertionFailures[index],
+			ConditionsUnmet:   stats.unmet[index],
+		}
+	}
+	return report
+}
+
+/*
+startScenarioAttack - virtual users take iterations until all of them are started, each user
+keeps its share of rps of the task by pause after its iteration
+*/
+func (attack *Attack) startScenarioAttack(ctx context.Context, task rest_contracts.Task) {
+	scenario := attack.options.Scenario
+	users := scenario.Users
+	if users <= 0 {
+		users = currentWorkers
+	}
+	attack.results.Lock()
+	attack.resultScenario = newScenarioStats(scenario)
+	attack.results.Unlock()
+	interval := time.Duration(float64(time.Second) * float64(users) / float64(task.Script.Config.Rps))
+	iterations := make(chan int64)
+	usersDone := make(chan struct{})
+	for index := 0; index < users; index++ {
+		go func() {
+			defer func() { usersDone <- struct{}{} }()
+			attack.runScenarioUser(ctx, task, iterations, interval)
+		}()
+	}
+	amount := task.Script.Config.Rps * task.Script.Config.Time
+feed:
+	for iteration := int64(0); iteration < amount; iteration++ {
+		select {
+		case iterations <- iteration:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(iterations)
+	for index := 0; index < users; index++ {
+		<-usersDone
+	}
+}
+
+func (attack *Attack) runScenarioUser(ctx context.Context, task rest_contracts.Task, iterations chan int64, interval time.Duration) {
+	user := attack.newVirtualUser()
+	attack.fetchCSRF(user)
+	attack.loginUser(user)
+	for range iterations {
+		started := time.Now()
+		attack.runIteration(ctx, user, task)
+		if elapsed := time.Since(started); elapsed < interval {
+			select {
+			case <-time.After(interval - elapsed):
+			case <-ctx.Done():
+			}
+		}
+	}
+}
+
+/*
+runIteration - steps after the failed one are not sent, iteration interrupted by cancel is not counted.
+Step with condition, which does not hold, is passed over without think time after it. Status 400 and above
+of a step, which later conditions refer to, does not end the iteration, so they can take alternate path.
+Variables are extracted anew by each iteration, except the ones of steps of user scope, think time between steps is a part of its duration
+*/
+func (attack *Attack) runIteration(ctx context.Context, user *virtualUser, task rest_contracts.Task) {
+	started := time.Now()
+	stats := attack.resultScenario
+	variables := map[string]string{}
+	statuses := map[string]int{}
+	user.state.iterations++
+	for index, step := range attack.scenarioSteps {
+		if ctx.Err() != nil {
+			return
+		}
+		if step.when != nil && !step.when.holds(statuses, user.variables(variables)) {
+			attack.results.Lock()
+			stats.unmet[index]++
+			attack.results.Unlock()
+			continue
+		}
+		request, err := attack.stepRequest(task, step, user.variables(variables))
+		if err != nil {
+			logrus.Error("Can not form request of step ", step.Name, ": ", err)
+			attack.results.Lock()
+			stats.end(index, false, time.Since(started))
+			attack.results.Unlock()
+			return
+		}
+		response := fasthttp.AcquireResponse()
+		result := attack.exchange(user, request, response)
+		attack.inspectResponse(&result, response, step.assertions)
+		statuses[step.Name] = result.Status
+		// status of a branched step is checked by conditions of later steps, variables are not extracted from it
+		statusFailed := step.failedByStatus(result.Status)
+		failed := result.Skipped || result.Timeout || result.AssertionFailed != "" || (statusFailed && !step.branched)
+		extractFailed := false
+		if !failed && !statusFailed {
+			if errExtract := step.extractVariables(response, user.extractInto(step, variables)); errExtract != nil {
+				logrus.Debug("Can not extract variables of step ", step.Name, ": ", errExtract)
+				failed, extractFailed = true, true
+			}
+		}
+		fasthttp.ReleaseResponse(response)
+		fasthttp.ReleaseRequest(request)
+		attack.results.Lock()
+		attack.saveResult(result)
+		stats.steps[index].add(result)
+		if extractFailed {
+			stats.extractFailures[index]++
+		}
+		if result.AssertionFailed != "" {
+			stats.assertionFailures[index]++
+		}
+		if failed {
+			stats.end(index, false, time.Since(started))
+		}
+		attack.results.Unlock()
+		if failed {
+			return
+		}
+		if index < len(attack.scenarioSteps)-1 && step.Think.enabled() {
+			select {
+			case <-time.After(step.Think.pause()):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	attack.results.Lock()
+	stats.end(len(attack.scenarioSteps)-1, true, time.Since(started))
+	attack.results.Unlock()
+}
+
+func (step scenarioStep) extractVariables(response *fasthttp.Response, variables map[string]string) error {
+	if len(step.extract) > 0 {
+		if err := extractJSON(response, step.extract, variables); err != nil {
+			return err
+		}
+	}
+	if len(step.regex) > 0 {
+		if err := extractRegex(response, step.regex, variables); err != nil {
+			return err
+		}
+	}
+	if len(step.ExtractHeaders) > 0 {
+		return extractHeaders(response, step.ExtractHeaders, variables)
+	}
+	return nil
+}
+
+// stepAddress - path of the step is appended to address of the task, absolute url replaces it
+func stepAddress(address string, path string) string {
+	switch {
+	case strings.Contains(path, "://"):
+		return path
+	case path == "":
+		return address
+	}
+	return strings.TrimRight(address, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// stepRequest - new values of generated body params are rendered into each request of the step
+func (attack *Attack) stepRequest(task rest_contracts.Task, step scenarioStep, variables map[string]string) (*fasthttp.Request, error) {
+	request := fasthttp.AcquireRequest()
+	method := step.Method
+	if method == "" {
+		method = fasthttp.MethodGet
+	}
+	request.Header.SetMethod(method)
+	request.SetRequestURI(stepAddress(task.Script.Address, renderVariables(step.Path, task.Schema.Body, variables)))
+	attack.enhancedHeadersInRequest(request, task)
+	for key, value := range step.Headers {
+		request.Header.Set(key, renderVariables(value, task.Schema.Body, variables))
+	}
+	attack.acceptEncoding(request)
+	if step.Body == "" {
+		return request, nil
+	}
+	attack.results.Lock()
+	err := attack.compressBody(request, []byte(renderVariables(step.Body, task.Schema.Body, variables)))
+	attack.results.Unlock()
+	if err != nil {
+		fasthttp.ReleaseRequest(request)
+		return nil, err
+	}
+	attack.streamBody(request)
+	return request, nil
+}
+
+func validateScenario(scenario ScenarioOptions, mode string, add func(field string, reason string)) {
+	if !scenario.enabled() {
+		return
+	}
+	field := "schema.headers." + OptionsHeader + ".scenario"
+	if mode != ModeHTTP {
+		add(field, "is supported in http mode only")
+		return
+	}
+	if scenario.Users < 0 {
+		add(field+".users", "must not be negative")
+	}
+	validateThink(field+".think", scenario.Think, add)
+	validateSteps(field, scenario.Steps, add)
+}
+
+// validateSteps - steps of scenario, setup or teardown
+func validateSteps(field string, steps []ScenarioStep, add func(field string, reason string)) {
+	names := map[string]bool{}
+	for index, step := range steps {
+		stepField := fmt.Sprintf("%s.steps[%d]", field, index)
+		switch {
+		case step.Name == "":
+			add(stepField+".name", "is required")
+		case names[step.Name]:
+			add(stepField+".name", "must be unique in the steps")
+		}
+		validateCondition(stepField+".when", step.When, names, add)
+		names[step.Name] = true
+		if step.Scope != "" && step.Scope != ScopeIteration && step.Scope != ScopeUser {
+			add(stepField+".scope", "must be iteration or user")
+		}
+		if step.Method != "" && !methodPattern.MatchString(step.Method) {
+			add(stepField+".method", "must be upper case http method")
+		}
+		for name, path := range step.Extract {
+			if _, err := compileJSONPath(path); err != nil {
+				add(stepField+".extract."+name, err.Error())
+			}
+		}
+		for name, extract := range step.ExtractRegex {
+			if _, err := compileRegexExtract(extract); err != nil {
+				add(stepField+".extract_regex."+name, err.Error())
+			}
+		}
+		for name, header := range step.ExtractHeaders {
+			if header == "" {
+				add(stepField+".extract_headers."+name, "must be name of header")
+			}
+		}
+		validateAssertions(stepField+".assertions", step.Assertions, add)
+		validateThink(stepField+".think", step.Think, add)
+	}
+}
+
+func validateThink(field string, think ThinkTime, add func(field string, reason string)) {
+	switch {
+	case think.MinMs < 0 || think.MaxMs < 0:
+		add(field, "must not be negative")
+	case think.MaxMs > 0 && think.MaxMs < think.MinMs:
+		add(field+".max_ms", "must not be less than min_ms")
+	}
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+)
+
+// runScenario - one iteration of the scenario against the server, paths of requests in order
+func runScenario(t *testing.T, handler http.HandlerFunc, scenario string) (*Attack, []string) {
+	t.Helper()
+	var mutex sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		mutex.Lock()
+		paths = append(paths, request.Method+" "+request.URL.Path)
+		mutex.Unlock()
+		handler(writer, request)
+	}))
+	defer server.Close()
+	bomber := &Core{config: &config.Configuration{}, attacks: attacks{byFormId: map[string]*Attack{}}}
+	task := taskWithOptions(`{"scenario": ` + scenario + `}`)
+	task.Script.Address = server.URL
+	task.Script.Config.Rps = 1
+	task.Script.Config.Time = 1
+	attack, err := bomber.PreparingData(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	done := make(chan struct{})
+	go func() {
+		attack.Start(task, &wg)
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("scenario did not end")
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	return attack, append([]string{}, paths...)
+}
+
+const branchScenario = `{"users": 1, "steps": [
+	{"name": "create", "method": "POST", "path": "/orders", "extract": {"id": "$.id"}},
+	{"name": "confirm", "method": "POST", "path": "/orders/{{id}}/confirm", "when": {"step": "create", "statuses": [201]}},
+	{"name": "retry", "method": "POST", "path": "/retry", "when": {"step": "create", "statuses": [201], "not": true}}
+]}`
+
+func TestScenarioBranchOnFailedStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		status int
+		paths  []string
+	}{
+		{"created", http.StatusCreated, []string{"POST /orders", "POST /orders/7/confirm"}},
+		{"conflict", http.StatusConflict, []string{"POST /orders", "POST /retry"}},
+		{"unavailable", http.StatusServiceUnavailable, []string{"POST /orders", "POST /retry"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attack, paths := runScenario(t, func(writer http.ResponseWriter, request *http.Request) {
+				if request.URL.Path == "/orders" {
+					writer.WriteHeader(tc.status)
+					writer.Write([]byte(`{"id": 7}`))
+				}
+			}, branchScenario)
+			if len(paths) != len(tc.paths) {
+				t.Fatalf("requests %v, expected %v", paths, tc.paths)
+			}
+			for index := range paths {
+				if paths[index] != tc.paths[index] {
+					t.Fatalf("requests %v, expected %v", paths, tc.paths)
+				}
+			}
+			stats := attack.resultScenario
+			if stats.completed != 1 || stats.failed != 0 || stats.extractFailures[0] != 0 {
+				t.Fatalf("completed %d, failed %d, extract failures %d", stats.completed, stats.failed, stats.extractFailures[0])
+			}
+		})
+	}
+}
+
+func TestScenarioFailedStatusWithoutBranch(t *testing.T) {
+	attack, paths := runScenario(t, func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/orders" {
+			writer.WriteHeader(http.StatusConflict)
+		}
+	}, `{"users": 1, "steps": [
+		{"name": "create", "method": "POST", "path": "/orders"},
+		{"name": "list", "path": "/orders/all"}
+	]}`)
+	if len(paths) != 1 {
+		t.Fatalf("requests %v after failed step without branches", paths)
+	}
+	if stats := attack.resultScenario; stats.failed != 1 || stats.skipped[1] != 1 {
+		t.Fatalf("failed %d, skipped %d", stats.failed, stats.skipped[1])
+	}
+}
+
+func TestCompileScenarioBranched(t *testing.T) {
+	steps, err := compileScenario(ScenarioOptions{Steps: []ScenarioStep{
+		{Name: "create"},
+		{Name: "list"},
+		{Name: "retry", When: &StepCondition{Step: "create", Statuses: []int{201}, Not: true}},
+		{Name: "admin", When: &StepCondition{Variable: "role"}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, expected := range []bool{true, false, false, false} {
+		if steps[index].branched != expected {
+			t.Errorf("step %s branched %v, expected %v", steps[index].Name, steps[index].branched, expected)
+		}
+	}
+}
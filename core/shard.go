@@ -0,0 +1,147 @@
+package core
+
+import (
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/sirupsen/logrus"
+)
+
+/*
+ShardOptions - task is run by total bombers at once, each of them with own index.
+Shard attacks by its part of rps, digits of generators are taken from its part of their ranges,
+so values of shards do not repeat each other. Task is not sharded if total is less than 2
+*/
+type ShardOptions struct {
+	Index int64 `json:"index,omitempty"`
+	Total int64 `json:"total,omitempty"`
+}
+
+// ShardReport - shard of the attack, results of all shards of the task are aggregated by it
+type ShardReport struct {
+	Index int64 `json:"index"`
+	Total int64 `json:"total"`
+	// rps of the shard, rps of all shards sum up to rps of the task
+	Rps int64 `json:"rps"`
+}
+
+func (shard ShardOptions) enabled() bool {
+	return shard.Total > 1
+}
+
+// part - [from, to) of [0, amount), parts of all shards cover it without gaps, the first shards get the remainder
+func (shard ShardOptions) part(amount int64) (int64, int64) {
+	size, remainder := amount/shard.Total, amount%shard.Total
+	from := shard.Index*size + minInt64(shard.Index, remainder)
+	to := from + size
+	if shard.Index < remainder {
+		to++
+	}
+	return from, to
+}
+
+func minInt64(first int64, second int64) int64 {
+	if first < second {
+		return first
+	}
+	return second
+}
+
+/*
+apply - copy of the task with rps and generators of the shard, the task itself is not changed.
+The same task has the same parts on each bomber
+*/
+func (shard ShardOptions) apply(task rest_contracts.Task) rest_contracts.Task {
+	if !shard.enabled() || task.Script == nil || task.Script.Config == nil {
+		return task
+	}
+	data, err := task.Marshal()
+	if err != nil {
+		logrus.Error("Can not copy task for its shard: ", err)
+		return task
+	}
+	var sharded rest_contracts.Task
+	if err := sharded.Unmarshal(data); err != nil {
+		logrus.Error("Can not copy task for its shard: ", err)
+		return task
+	}
+	from, to := shard.part(task.Script.Config.Rps)
+	sharded.Script.Config.Rps = to - from
+	if sharded.Schema == nil {
+		return sharded
+	}
+	for _, param := range sharded.Schema.Request {
+		if param.IsGeneratorNeed {
+			shard.digits(param.GeneratorConfig)
+		}
+	}
+	shard.body(sharded.Schema.Body)
+	return sharded
+}
+
+func (shard ShardOptions) body(params []*rest_contracts.BodyParam) {
+	for _, param := range params {
+		if param == nil {
+			continue
+		}
+		if param.IsGenerated {
+			shard.digits(param.Config)
+			continue
+		}
+		if properties, ok := param.Value.(*rest_contracts.BodyParam_Properties); ok && properties.Properties != nil {
+			for _, property := range properties.Properties.Properties {
+				shard.body([]*rest_contracts.BodyParam{property})
+			}
+		}
+	}
+}
+
+// digits - range of the shard, shards of range shorter than total repeat the last value
+func (shard ShardOptions) digits(config *rest_contracts.GeneratorConfig) {
+	if config == nil {
+		return
+	}
+	generator, ok := config.Res.(*rest_contracts.GeneratorConfig_DigitGeneratorConfig)
+	if !ok || generator.DigitGeneratorConfig == nil {
+		return
+	}
+	digits := generator.DigitGeneratorConfig
+	from, to := shard.part(int64(digits.EndTo - digits.StartFrom))
+	if to == from {
+		from = int64(digits.EndTo-digits.StartFrom) - 1
+		to = from + 1
+	}
+	digits.StartFrom, digits.EndTo = digits.StartFrom+int32(from), digits.StartFrom+int32(to)
+}
+
+// ShardRps - part of rps of the task attacked by this bomber, the whole rps if the task is not sharded
+func (attack *Attack) ShardRps(rps int64) int64 {
+	if attack.options == nil || !attack.options.Shard.enabled() {
+		return rps
+	}
+	from, to := attack.options.Shard.part(rps)
+	return to - from
+}
+
+// shardReport - nil if the task is not sharded
+func (attack *Attack) shardReport() *ShardReport {
+	if attack.options == nil || !attack.options.Shard.enabled() {
+		return nil
+	}
+	return &ShardReport{Index: attack.options.Shard.Index, Total: attack.options.Shard.Total, Rps: attack.attackTargetRps}
+}
+
+func validateShard(shard ShardOptions, rps int64, add func(field string, reason string)) {
+	field := "schema.headers." + OptionsHeader + ".shard"
+	if shard.Total < 0 {
+		add(field+".total", "must not be negative")
+		return
+	}
+	if !shard.enabled() {
+		return
+	}
+	if shard.Index < 0 || shard.Index >= shard.Total {
+		add(field+".index", "must be from 0 to total - 1")
+	}
+	if rps > 0 && rps < shard.Total {
+		add(field+".total", "must not be greater than rps of the task, each shard sends at least one request per second")
+	}
+}
@@ -0,0 +1,26 @@
+package core
+
+import "testing"
+
+func TestShardRps(t *testing.T) {
+	cases := []struct {
+		name  string
+		shard ShardOptions
+		rps   int64
+		part  int64
+	}{
+		{"not sharded", ShardOptions{}, 10, 10},
+		{"single shard", ShardOptions{Index: 0, Total: 1}, 10, 10},
+		{"even part", ShardOptions{Index: 1, Total: 2}, 10, 5},
+		{"first shard gets remainder", ShardOptions{Index: 0, Total: 3}, 10, 4},
+		{"last shard without remainder", ShardOptions{Index: 2, Total: 3}, 10, 3},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			attack := &Attack{options: &TaskOptions{Shard: testCase.shard}}
+			if part := attack.ShardRps(testCase.rps); part != testCase.part {
+				t.Fatalf("got %d, expected %d", part, testCase.part)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/sigv4"
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+// parts of request signed by hmac signing
+const (
+	SignedMethod     = "method"
+	SignedHost       = "host"
+	SignedPath       = "path"
+	SignedQuery      = "query"
+	SignedBody       = "body"
+	SignedBodySHA256 = "body_sha256"
+	SignedTimestamp  = "timestamp"
+	SignedNonce      = "nonce"
+	// header:<name> is value of the header of the request
+	signedHeaderPrefix = "header:"
+)
+
+var (
+	ErrSigningAlgorithm = errors.New("algorithm of hmac signing is sha1, sha256 or sha512")
+	ErrSigningEncoding  = errors.New("encoding of hmac signature is hex or base64")
+	ErrSigningPart      = errors.New("part of hmac signing is method, host, path, query, body, body_sha256, timestamp, nonce or header:<name>")
+	ErrSigningSecret    = errors.New("hmac signing requires REQUEST_HMAC_KEY of the bomber")
+)
+
+/*
+HMACSigningOptions - signature of bespoke hmac auth in each request: parts of the request are joined by separator
+and signed by REQUEST_HMAC_KEY of the bomber. Signing is disabled without header
+*/
+type HMACSigningOptions struct {
+	// header of requests with signature
+	Header string `json:"header,omitempty"`
+	// sha256 if empty
+	Algorithm string `json:"algorithm,omitempty"`
+	// hex if empty
+	Encoding string `json:"encoding,omitempty"`
+	// method, path and body if empty
+	Parts []string `json:"parts,omitempty"`
+	// new line if empty
+	Separator string `json:"separator,omitempty"`
+	// prepended to signature in header, for example "HMAC client-1:"
+	Prefix string `json:"prefix,omitempty"`
+	// header of unix seconds of signing, the timestamp part requires it
+	TimestampHeader string `json:"timestamp_header,omitempty"`
+	// header of unique nonce of the request, the nonce part requires it
+	NonceHeader string `json:"nonce_header,omitempty"`
+}
+
+func (options HMACSigningOptions) enabled() bool {
+	return options.Header != ""
+}
+
+type hmacSigner struct {
+	options HMACSigningOptions
+	secret  []byte
+	hash    func() hash.Hash
+	parts   []string
+}
+
+func newHMACSigner(options HMACSigningOptions, preference *config.Configuration) (*hmacSigner, error) {
+	if !configuredValue(preference.RequestHMACKey) {
+		return nil, ErrSigningSecret
+	}
+	hashing, err := signingHash(options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	signer := &hmacSigner{options: options, secret: []byte(preference.RequestHMACKey), hash: hashing, parts: options.Parts}
+	if len(signer.parts) == 0 {
+		signer.parts = []string{SignedMethod, SignedPath, SignedBody}
+	}
+	if signer.options.Separator == "" {
+		signer.options.Separator = "\n"
+	}
+	return signer, nil
+}
+
+func signingHash(algorithm string) (func() hash.Hash, error) {
+	switch algorithm {
+	case "", "sha256":
+		return sha256.New, nil
+	case "sha1":
+		return sha1.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, ErrSigningAlgorithm
+}
+
+// sign - must be called after all headers and body of the request are set, each attempt is signed anew
+func (signer *hmacSigner) sign(request *fasthttp.Request, moment time.Time) {
+	if signer == nil {
+		return
+	}
+	options := signer.options
+	timestamp := strconv.FormatInt(moment.Unix(), 10)
+	if options.TimestampHeader != "" {
+		request.Header.Set(options.TimestampHeader, timestamp)
+	}
+	nonce := ""
+	if options.NonceHeader != "" {
+		nonce = uuid.New().String()
+		request.Header.Set(options.NonceHeader, nonce)
+	}
+	// streamed body is not read here, it is signed as empty
+	var body []byte
+	if !request.IsBodyStream() {
+		body = request.Body()
+	}
+	values := make([]string, len(signer.parts))
+	for index, part := range signer.parts {
+		switch part {
+		case SignedMethod:
+			values[index] = string(request.Header.Method())
+		case SignedHost:
+			values[index] = string(request.Host())
+		case SignedPath:
+			values[index] = string(request.URI().PathOriginal())
+		case SignedQuery:
+			values[index] = string(request.URI().QueryString())
+		case SignedBody:
+			values[index] = string(body)
+		case SignedBodySHA256:
+			values[index] = sigv4.HashPayload(body)
+		case SignedTimestamp:
+			values[index] = timestamp
+		case SignedNonce:
+			values[index] = nonce
+		default:
+			values[index] = string(request.Header.Peek(strings.TrimPrefix(part, signedHeaderPrefix)))
+		}
+	}
+	mac := hmac.New(signer.hash, signer.secret)
+	mac.Write([]byte(strings.Join(values, options.Separator)))
+	signature := hex.EncodeToString(mac.Sum(nil))
+	if options.Encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	}
+	request.Header.Set(options.Header, options.Prefix+signature)
+}
+
+func (attack *Attack) prepareHMACSigning() error {
+	attack.hmacSigning = nil
+	if !attack.options.HMACSigning.enabled() || attack.options.Mode != ModeHTTP {
+		return nil
+	}
+	signer, err := newHMACSigner(attack.options.HMACSigning, attack.core.config)
+	if err != nil {
+		return err
+	}
+	attack.hmacSigning = signer
+	return nil
+}
+
+func validateHMACSigning(options HMACSigningOptions, add func(field string, reason string)) {
+	if !options.enabled() {
+		return
+	}
+	field := "schema.headers." + OptionsHeader + ".hmac_signing"
+	if _, err := signingHash(options.Algorithm); err != nil {
+		add(field+".algorithm", err.Error())
+	}
+	if options.Encoding != "" && options.Encoding != "hex" && options.Encoding != "base64" {
+		add(field+".encoding", ErrSigningEncoding.Error())
+	}
+	for index, part := range options.Parts {
+		partField := field + ".parts[" + strconv.Itoa(index) + "]"
+		switch {
+		case part == SignedMethod, part == SignedHost, part == SignedPath, part == SignedQuery, part == SignedBody,
+			part == SignedBodySHA256:
+		case part == SignedTimestamp && options.TimestampHeader == "":
+			add(partField, "requires timestamp_header")
+		case part == SignedNonce && options.NonceHeader == "":
+			add(partField, "requires nonce_header")
+		case part == SignedTimestamp, part == SignedNonce:
+		case strings.HasPrefix(part, signedHeaderPrefix) && len(part) > len(signedHeaderPrefix):
+		default:
+			add(partField, ErrSigningPart.Error())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/valyala/fasthttp"
+)
+
+// key, data and hmac of test case 2 of RFC 2202 and RFC 4231
+const (
+	rfcHMACKey    = "Jefe"
+	rfcHMACData   = "what do ya want for nothing?"
+	rfcHMACSHA1   = "effcdf6ae5eb2fa2d27416d5f184df9c259a7c79"
+	rfcHMACSHA256 = "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843"
+	rfcHMACSHA512 = "164b7a7bfcf819e2e395fbe73b56e0a387bd64222e831fd610270cd7ea250554" +
+		"9758bf75c05a994a6d034f65f8f0e6fdcaeab1a34d4a6b4b636e070a38bce737"
+)
+
+func base64OfHex(t *testing.T, value string) string {
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(decoded)
+}
+
+func signRequest(t *testing.T, options HMACSigningOptions, secret string, request *fasthttp.Request) string {
+	t.Helper()
+	signer, err := newHMACSigner(options, &config.Configuration{RequestHMACKey: secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.sign(request, time.Unix(1600000000, 0))
+	return string(request.Header.Peek(options.Header))
+}
+
+func TestHMACSignVectors(t *testing.T) {
+	cases := []struct {
+		name      string
+		algorithm string
+		encoding  string
+		signature string
+	}{
+		{"sha256 by default", "", "", rfcHMACSHA256},
+		{"sha1", "sha1", "hex", rfcHMACSHA1},
+		{"sha512", "sha512", "", rfcHMACSHA512},
+		{"sha256 in base64", "sha256", "base64", base64OfHex(t, rfcHMACSHA256)},
+		{"sha512 in base64", "sha512", "base64", base64OfHex(t, rfcHMACSHA512)},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(request)
+			request.SetBodyString(rfcHMACData)
+			options := HMACSigningOptions{Header: "X-Signature", Algorithm: testCase.algorithm, Encoding: testCase.encoding,
+				Parts: []string{SignedBody}, Prefix: "HMAC client-1:"}
+			if signature := signRequest(t, options, rfcHMACKey, request); signature != "HMAC client-1:"+testCase.signature {
+				t.Fatalf("got %s, expected %s", signature, testCase.signature)
+			}
+		})
+	}
+}
+
+func TestHMACSignParts(t *testing.T) {
+	cases := []struct {
+		name      string
+		parts     []string
+		separator string
+		signed    string
+	}{
+		{"method, path and body by default", nil, "", "POST\n/orders/a%20b\n{\"id\": 7}"},
+		{"host and raw query", []string{SignedHost, SignedQuery}, "|", "api.example.com|b=2&a=1"},
+		{"path as sent", []string{SignedPath}, "", "/orders/a%20b"},
+		{"hash of body", []string{SignedBodySHA256}, "", "ad559f4e2220ee7317330787fe05a065f5a22e4a1ca866b4f480553939f553e2"},
+		{"timestamp and nonce", []string{SignedTimestamp, SignedNonce}, ":", "1600000000:nonce"},
+		{"headers", []string{"header:Content-Type", "header:X-Missing"}, ",", "application/json,"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := fasthttp.AcquireRequest()
+			defer fasthttp.ReleaseRequest(request)
+			request.Header.SetMethod(fasthttp.MethodPost)
+			request.SetRequestURI("http://api.example.com/orders/a%20b?b=2&a=1")
+			request.Header.SetContentType("application/json")
+			request.SetBodyString(`{"id": 7}`)
+			options := HMACSigningOptions{Header: "X-Signature", Parts: testCase.parts, Separator: testCase.separator,
+				TimestampHeader: "X-Timestamp", NonceHeader: "X-Nonce"}
+			signature := signRequest(t, options, "secret", request)
+			if request.Header.Peek("X-Timestamp") == nil || len(request.Header.Peek("X-Nonce")) != 36 {
+				t.Fatalf("got timestamp %q and nonce %q", request.Header.Peek("X-Timestamp"), request.Header.Peek("X-Nonce"))
+			}
+			signed := strings.Replace(testCase.signed, "nonce", string(request.Header.Peek("X-Nonce")), 1)
+			mac := hmac.New(sha256.New, []byte("secret"))
+			mac.Write([]byte(signed))
+			if expected := hex.EncodeToString(mac.Sum(nil)); signature != expected {
+				t.Fatalf("got %s, expected signature of %q", signature, signed)
+			}
+		})
+	}
+}
+
+func TestHMACSignStreamedBody(t *testing.T) {
+	request := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(request)
+	request.Header.SetMethod(fasthttp.MethodPut)
+	request.SetRequestURI("http://api.example.com/upload")
+	request.SetBodyStream(strings.NewReader("streamed body"), -1)
+	options := HMACSigningOptions{Header: "X-Signature", Parts: []string{SignedMethod, SignedBody, SignedBodySHA256}}
+	signature := signRequest(t, options, "secret", request)
+	if !request.IsBodyStream() {
+		t.Fatal("streamed body is read by signing")
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("PUT\n\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
+	if expected := hex.EncodeToString(mac.Sum(nil)); signature != expected {
+		t.Fatalf("got %s, expected signature of empty body", signature)
+	}
+}
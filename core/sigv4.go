@@ -0,0 +1,117 @@
+package core
+
+import (
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/sigv4"
+	"github.com/sirupsen/logrus"
+	"github.com/valyala/fasthttp"
+)
+
+// SigV4Options - requests are signed by aws signature v4, credentials are settings of the bomber or its instance role
+type SigV4Options struct {
+	Region string `json:"region,omitempty"`
+	// execute-api for API Gateway, s3, lambda
+	Service string `json:"service,omitempty"`
+	// payload is not hashed, S3 accepts it
+	UnsignedPayload bool `json:"unsigned_payload,omitempty"`
+}
+
+func (options SigV4Options) enabled() bool {
+	return options.Service != ""
+}
+
+// sigv4Signer - signs requests of all virtual users, credentials of instance role are replaced by refresh
+type sigv4Signer struct {
+	options     SigV4Options
+	credentials *sigv4.Provider
+}
+
+// newSigV4Signer - credentials are taken before the attack, so missing ones fail its preparing
+func newSigV4Signer(options SigV4Options, preference *config.Configuration) (*sigv4Signer, error) {
+	static := sigv4.Credentials{}
+	if configuredValue(preference.AWSAccessKeyId) && configuredValue(preference.AWSSecretAccessKey) {
+		static = sigv4.Credentials{AccessKey: preference.AWSAccessKeyId, SecretKey: preference.AWSSecretAccessKey}
+		if configuredValue(preference.AWSSessionToken) {
+			static.SessionToken = preference.AWSSessionToken
+		}
+	}
+	metadataURL := ""
+	if configuredValue(preference.AWSMetadataURL) {
+		metadataURL = preference.AWSMetadataURL
+	}
+	signer := &sigv4Signer{options: options, credentials: sigv4.NewProvider(static, metadataURL)}
+	if _, err := signer.credentials.Current(); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+func configuredValue(value string) bool {
+	return value != "" && value != "off"
+}
+
+// sign - must be called after all headers and body of the request are set, each attempt is signed anew
+func (signer *sigv4Signer) sign(request *fasthttp.Request, moment time.Time) {
+	if signer == nil {
+		return
+	}
+	credentials, err := signer.credentials.Current()
+	if err != nil {
+		logrus.Error("Can not sign request by aws signature: ", err)
+		return
+	}
+	payloadHash := sigv4.UnsignedPayload
+	if !signer.options.UnsignedPayload && !request.IsBodyStream() {
+		payloadHash = sigv4.HashPayload(request.Body())
+	}
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	query := url.Values{}
+	request.URI().QueryArgs().VisitAll(func(key, value []byte) {
+		query.Add(string(key), string(value))
+	})
+	headers := sigv4.Sign(sigv4.Request{
+		Method:      string(request.Header.Method()),
+		Host:        string(request.Host()),
+		Path:        string(request.URI().Path()),
+		Query:       query,
+		Headers:     map[string]string{"x-amz-content-sha256": payloadHash},
+		PayloadHash: payloadHash,
+	}, sigv4.Scope{Region: signer.options.Region, Service: signer.options.Service}, credentials, moment)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+}
+
+func (attack *Attack) prepareSigV4() error {
+	attack.sigv4 = nil
+	if !attack.options.SigV4.enabled() || attack.options.Mode != ModeHTTP {
+		return nil
+	}
+	signer, err := newSigV4Signer(attack.options.SigV4, attack.core.config)
+	// missing credentials are not fetched by other attempt, instance metadata may answer later
+	if errors.Is(err, sigv4.ErrCredentials) {
+		return err
+	}
+	if err != nil {
+		return &UnavailableError{Err: err}
+	}
+	attack.sigv4 = signer
+	return nil
+}
+
+func validateSigV4(options SigV4Options, add func(field string, reason string)) {
+	field := "schema.headers." + OptionsHeader + ".sigv4"
+	if !options.enabled() {
+		if options.Region != "" {
+			add(field+".service", "is required")
+		}
+		return
+	}
+	if options.Region == "" {
+		add(field+".region", "is required")
+	}
+}
@@ -0,0 +1,167 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/jamiealquiza/tachymeter"
+	"github.com/sirupsen/logrus"
+)
+
+type SSEOptions struct {
+	// amount of concurrent streams, amount of workers if empty
+	Connections int `json:"connections,omitempty"`
+}
+
+type sseStats struct {
+	mutex         sync.Mutex
+	firstEvent    *tachymeter.Tachymeter
+	streams       int64
+	connectErrors int64
+	dropped       int64
+	events        int64
+	rates         []float64 // events per second of each stream
+}
+
+type SSEReport struct {
+	Streams       int64       `json:"streams"`
+	ConnectErrors int64       `json:"connect_errors"`
+	Dropped       int64       `json:"dropped"`
+	DropRate      float64     `json:"drop_rate"`
+	Events        int64       `json:"events"`
+	FirstEvent    PhaseReport `json:"first_event"`
+	MinRate       float64     `json:"min_events_per_second"`
+	MeanRate      float64     `json:"mean_events_per_second"`
+	MaxRate       float64     `json:"max_events_per_second"`
+}
+
+func (stats *sseStats) report() *SSEReport {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	report := &SSEReport{
+		Streams:       stats.streams,
+		ConnectErrors: stats.connectErrors,
+		Dropped:       stats.dropped,
+		Events:        stats.events,
+		FirstEvent:    phaseReport(stats.firstEvent),
+	}
+	if stats.streams > 0 {
+		report.DropRate = float64(stats.dropped) / float64(stats.streams)
+	}
+	for index, rate := range stats.rates {
+		if index == 0 || rate < report.MinRate {
+			report.MinRate = rate
+		}
+		if rate > report.MaxRate {
+			report.MaxRate = rate
+		}
+		report.MeanRate += rate / float64(len(stats.rates))
+	}
+	return report
+}
+
+/*
+startSSEAttack - opens event streams to the target and holds them during time of the task,
+dropped streams are opened again
+*/
+func (attack *Attack) startSSEAttack(task rest_contracts.Task) {
+	connections := attack.options.SSE.Connections
+	if connections <= 0 {
+		connections = currentWorkers
+	}
+	attack.resultSSE = &sseStats{
+		firstEvent: tachymeter.New(&tachymeter.Config{Size: connections * 10}),
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(_ context.Context, _, addr string) (net.Conn, error) {
+				return attack.dialer.Dial(addr)
+			},
+			TLSClientConfig: attack.options.TLS.tlsConfig(),
+		},
+	}
+	ctx, cancel := context.WithTimeout(attack.attackContext(), time.Duration(task.Script.Config.Time)*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for index := 0; index < connections; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				attack.holdStream(ctx, client, task)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func (attack *Attack) holdStream(ctx context.Context, client *http.Client, task rest_contracts.Task) {
+	stats := attack.resultSSE
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, task.Script.Address, nil)
+	if err != nil {
+		logrus.Error("Can not form sse request: ", err)
+		return
+	}
+	request.Header = attack.schemaHeaders(task)
+	request.Header.Set("Accept", "text/event-stream")
+	timeStart := time.Now()
+	response, err := client.Do(request)
+	if err != nil {
+		if ctx.Err() == nil {
+			attack.countError(err)
+			stats.mutex.Lock()
+			stats.connectErrors++
+			stats.mutex.Unlock()
+			time.Sleep(time.Second)
+		}
+		return
+	}
+	defer response.Body.Close()
+	attack.results.Lock()
+	attack.resultsAttack[int32(response.StatusCode)]++
+	attack.results.Unlock()
+	if response.StatusCode != http.StatusOK {
+		time.Sleep(time.Second)
+		return
+	}
+	var events int64
+	hasData := false
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line != "" {
+			hasData = true
+			continue
+		}
+		if !hasData {
+			continue
+		}
+		// empty line dispatches the event
+		hasData = false
+		events++
+		if events == 1 {
+			firstEvent := time.Since(timeStart)
+			stats.firstEvent.AddTime(firstEvent)
+			attack.tahometr.AddTime(firstEvent)
+			attack.results.Lock()
+			attack.recordLatency(firstEvent.Nanoseconds(), int32(response.StatusCode), false, 0)
+			attack.results.Unlock()
+		}
+	}
+	held := time.Since(timeStart)
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	stats.streams++
+	stats.events += events
+	if held > 0 {
+		stats.rates = append(stats.rates, float64(events)/held.Seconds())
+	}
+	if ctx.Err() == nil {
+		stats.dropped++
+	}
+}
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"strconv"
+	"sync/atomic"
+)
+
+// scopes of variables extracted by steps
+const (
+	ScopeIteration = "iteration"
+	ScopeUser      = "user"
+)
+
+/*
+userState - variables and counters of the virtual user, which live across its iterations.
+Steps of user scope extract into it, variables of setup are its first values
+*/
+type userState struct {
+	id         int64
+	iterations int64
+	requests   int64
+	values     map[string]string
+}
+
+func (attack *Attack) newUserState() userState {
+	state := userState{
+		id:     atomic.AddInt64(&attack.virtualUsers, 1),
+		values: make(map[string]string, len(attack.hookVariables)),
+	}
+	for name, value := range attack.hookVariables {
+		state.values[name] = value
+	}
+	return state
+}
+
+/*
+variables - variables of templates of the user: its state, counters, tokens and cookies under variables
+of the iteration, which take precedence over them
+*/
+func (user *virtualUser) variables(iteration map[string]string) map[string]string {
+	variables := make(map[string]string, len(user.state.values)+len(iteration)+5)
+	for name, value := range user.state.values {
+		variables[name] = value
+	}
+	variables["user.id"] = strconv.FormatInt(user.state.id, 10)
+	variables["user.iteration"] = strconv.FormatInt(user.state.iterations, 10)
+	variables["user.requests"] = strconv.FormatInt(user.state.requests, 10)
+	if user.session != nil && user.session.token != "" {
+		variables["user.token"] = user.session.token
+	}
+	if user.csrf != nil {
+		variables["user.csrf"] = user.csrf.token
+	}
+	if user.jar != nil {
+		for _, cookie := range user.jar.cookies {
+			variables["cookie."+cookie.name] = cookie.value
+		}
+	}
+	for name, value := range iteration {
+		variables[name] = value
+	}
+	return variables
+}
+
+// extractInto - variables of the iteration or state of the user by scope of the step
+func (user *virtualUser) extractInto(step scenarioStep, iteration map[string]string) map[string]string {
+	if step.Scope == ScopeUser {
+		return user.state.values
+	}
+	return iteration
+}
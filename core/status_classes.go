@@ -0,0 +1,92 @@
+package core
+
+import "strconv"
+
+// statusFailed - class of requests, which got no response
+const statusFailed = "failed"
+
+type StatusClassReport struct {
+	Count int64 `json:"count"`
+	// percent of all requests
+	Rate float64 `json:"rate"`
+}
+
+/*
+StatusClassesReport - amount of requests by class of http status (1xx to 5xx) and of failed ones,
+error_rate is percent of 4xx, 5xx and failed requests
+*/
+type StatusClassesReport struct {
+	Requests  int64                        `json:"requests"`
+	Classes   map[string]StatusClassReport `json:"classes"`
+	ErrorRate float64                      `json:"error_rate"`
+}
+
+func statusClass(status int32) string {
+	return strconv.Itoa(int(status)/100) + "xx"
+}
+
+// statusClassesReport - nil for modes, which have no http statuses
+func statusClassesReport(mode string, statuses map[int32]int64, failed int64) *StatusClassesReport {
+	if mode != ModeHTTP && mode != ModeSSE {
+		return nil
+	}
+	counts := map[string]int64{}
+	requests := failed
+	failures := failed
+	for status, amount := range statuses {
+		if status < 100 || status > 599 {
+			continue
+		}
+		counts[statusClass(status)] += amount
+		requests += amount
+		if status >= 400 {
+			failures += amount
+		}
+	}
+	if failed > 0 {
+		counts[statusFailed] = failed
+	}
+	report := &StatusClassesReport{
+		Requests: requests,
+		Classes:  make(map[string]StatusClassReport, len(counts)),
+	}
+	for class, amount := range counts {
+		report.Classes[class] = StatusClassReport{Count: amount, Rate: percentOf(amount, requests)}
+	}
+	report.ErrorRate = percentOf(failures, requests)
+	return report
+}
+
+func percentOf(amount int64, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(amount) * 100 / float64(total)
+}
+
+// MergeStatusClasses - classes of requests of all reports, nil if none of them has classes
+func MergeStatusClasses(reports []*StatusClassesReport) *StatusClassesReport {
+	var merged *StatusClassesReport
+	counts := map[string]int64{}
+	for _, report := range reports {
+		if report == nil {
+			continue
+		}
+		if merged == nil {
+			merged = &StatusClassesReport{}
+		}
+		merged.Requests += report.Requests
+		for class, classReport := range report.Classes {
+			counts[class] += classReport.Count
+		}
+	}
+	if merged == nil {
+		return nil
+	}
+	merged.Classes = make(map[string]StatusClassReport, len(counts))
+	for class, amount := range counts {
+		merged.Classes[class] = StatusClassReport{Count: amount, Rate: percentOf(amount, merged.Requests)}
+	}
+	merged.ErrorRate = percentOf(counts["4xx"]+counts["5xx"]+counts[statusFailed], merged.Requests)
+	return merged
+}
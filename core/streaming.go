@@ -0,0 +1,50 @@
+package core
+
+import (
+	"bufio"
+	"time"
+
+	"github.com/valyala/fasthttp"
+)
+
+const defaultChunkSize = 4096
+
+type ChunkedOptions struct {
+	Enabled   bool  `json:"enabled"`
+	ChunkSize int   `json:"chunk_size,omitempty"`
+	DelayMs   int64 `json:"chunk_delay_ms,omitempty"`
+}
+
+/*
+streamBody - replaces body of request by stream, which is sent with chunked transfer encoding
+by chunks of configured size with delay between them
+*/
+func (attack *Attack) streamBody(request *fasthttp.Request) {
+	options := attack.options.Chunked
+	if !options.Enabled {
+		return
+	}
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+	delay := time.Duration(options.DelayMs) * time.Millisecond
+	body := append([]byte(nil), request.Body()...)
+	request.SetBodyStreamWriter(func(writer *bufio.Writer) {
+		for offset := 0; offset < len(body); offset += chunkSize {
+			end := offset + chunkSize
+			if end > len(body) {
+				end = len(body)
+			}
+			if _, err := writer.Write(body[offset:end]); err != nil {
+				return
+			}
+			if err := writer.Flush(); err != nil {
+				return
+			}
+			if delay > 0 && end < len(body) {
+				time.Sleep(delay)
+			}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package core
+
+import "math"
+
+/*
+SummaryReport - exact descriptive statistics of latency of completed requests, percentiles
+of latency report are estimated by histogram
+*/
+type SummaryReport struct {
+	Count    int64   `json:"count"`
+	MeanNs   float64 `json:"mean_ns"`
+	StdDevNs float64 `json:"stddev_ns"`
+	MinNs    int64   `json:"min_ns"`
+	MaxNs    int64   `json:"max_ns"`
+	TotalNs  int64   `json:"total_ns"`
+}
+
+// summaryStats - running mean and variance by Welford, so long attacks do not lose precision
+type summaryStats struct {
+	count int64
+	mean  float64
+	m2    float64
+	min   int64
+	max   int64
+	total int64
+}
+
+func (stats *summaryStats) add(latency int64) {
+	stats.count++
+	delta := float64(latency) - stats.mean
+	stats.mean += delta / float64(stats.count)
+	stats.m2 += delta * (float64(latency) - stats.mean)
+	if stats.count == 1 || latency < stats.min {
+		stats.min = latency
+	}
+	if latency > stats.max {
+		stats.max = latency
+	}
+	stats.total += latency
+}
+
+func (stats *summaryStats) report() SummaryReport {
+	report := SummaryReport{
+		Count:   stats.count,
+		MeanNs:  stats.mean,
+		MinNs:   stats.min,
+		MaxNs:   stats.max,
+		TotalNs: stats.total,
+	}
+	if stats.count > 1 {
+		report.StdDevNs = math.Sqrt(stats.m2 / float64(stats.count-1))
+	}
+	return report
+}
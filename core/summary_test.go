@@ -0,0 +1,54 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummaryStats(t *testing.T) {
+	cases := []struct {
+		name      string
+		latencies []int64
+		expected  SummaryReport
+	}{
+		{"empty", nil, SummaryReport{}},
+		{"single", []int64{42}, SummaryReport{Count: 1, MeanNs: 42, MinNs: 42, MaxNs: 42, TotalNs: 42}},
+		{"several", []int64{2, 4, 4, 4, 5, 5, 7, 9}, SummaryReport{
+			Count: 8, MeanNs: 5, StdDevNs: math.Sqrt(32.0 / 7), MinNs: 2, MaxNs: 9, TotalNs: 40,
+		}},
+		{"minimum after the first", []int64{10, 3, 20}, SummaryReport{
+			Count: 3, MeanNs: 11, StdDevNs: math.Sqrt(73), MinNs: 3, MaxNs: 20, TotalNs: 33,
+		}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stats summaryStats
+			for _, latency := range tc.latencies {
+				stats.add(latency)
+			}
+			report := stats.report()
+			if report.Count != tc.expected.Count || report.MinNs != tc.expected.MinNs ||
+				report.MaxNs != tc.expected.MaxNs || report.TotalNs != tc.expected.TotalNs {
+				t.Fatalf("report %+v, expected %+v", report, tc.expected)
+			}
+			if math.Abs(report.MeanNs-tc.expected.MeanNs) > 1e-9 || math.Abs(report.StdDevNs-tc.expected.StdDevNs) > 1e-9 {
+				t.Fatalf("mean %v and stddev %v, expected %v and %v", report.MeanNs, report.StdDevNs, tc.expected.MeanNs, tc.expected.StdDevNs)
+			}
+		})
+	}
+}
+
+func TestSummaryStatsPrecision(t *testing.T) {
+	// large latencies with small spread lose precision in naive sum of squares
+	var stats summaryStats
+	for i := 0; i < 100000; i++ {
+		stats.add(int64(1e12) + int64(i%2))
+	}
+	report := stats.report()
+	if math.Abs(report.MeanNs-(1e12+0.5)) > 1e-3 {
+		t.Fatalf("mean %v, expected %v", report.MeanNs, 1e12+0.5)
+	}
+	if math.Abs(report.StdDevNs-0.5) > 1e-3 {
+		t.Fatalf("stddev %v, expected 0.5", report.StdDevNs)
+	}
+}
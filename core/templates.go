@@ -0,0 +1,66 @@
+package core
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/generators"
+)
+
+// placeholders of templates look like {{name}}, name is a name of body param in schema
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([\w.-]+)\s*\}\}`)
+
+func generateParam(param *rest_contracts.BodyParam) string {
+	if param.IsGenerated && param.Config != nil {
+		switch x := param.Config.Res.(type) {
+		case *rest_contracts.GeneratorConfig_WordGeneratorConfig:
+			return generators.GenerateWord(*x)
+		case *rest_contracts.GeneratorConfig_DigitGeneratorConfig:
+			return strconv.Itoa(int(generators.GenerateDigits(*x)))
+		case *rest_contracts.GeneratorConfig_RegexpConfig:
+			return generators.GenerateByRegexp(x)
+		}
+		return ""
+	}
+	simple, ok := param.Value.(*rest_contracts.BodyParam_SimpleProperty)
+	if !ok || simple.SimpleProperty == nil {
+		return ""
+	}
+	switch x := simple.SimpleProperty.Value.(type) {
+	case *rest_contracts.SimpleValue_StringValue:
+		return x.StringValue
+	case *rest_contracts.SimpleValue_Int32Value:
+		return strconv.Itoa(int(x.Int32Value))
+	case *rest_contracts.SimpleValue_Int64Value:
+		return strconv.FormatInt(x.Int64Value, 10)
+	}
+	return ""
+}
+
+/*
+renderTemplate - replaces placeholders of template by values of body params,
+each call generates new values
+*/
+func renderTemplate(template string, params []*rest_contracts.BodyParam) string {
+	return renderVariables(template, params, nil)
+}
+
+// renderVariables - variables of the scenario are rendered before body params of the same name
+func renderVariables(template string, params []*rest_contracts.BodyParam, variables map[string]string) string {
+	byName := make(map[string]*rest_contracts.BodyParam, len(params))
+	for _, param := range params {
+		byName[param.Name] = param
+	}
+	return placeholderPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+		if value, ok := variables[name]; ok {
+			return value
+		}
+		param, ok := byName[name]
+		if !ok {
+			return placeholder
+		}
+		return generateParam(param)
+	})
+}
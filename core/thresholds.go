@@ -0,0 +1,140 @@
+package core
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrThresholdMetric = errors.New("unknown metric of threshold")
+	ErrThresholdLimit  = errors.New("threshold has neither max nor min")
+)
+
+/*
+Threshold - limits of a metric of the attack. Latency metrics are in milliseconds: mean, p50,
+p90, p95, p99, p999 and max of latency. error_rate is percent of failed requests and responses
+with errors, rps is achieved requests per second, rps_ratio is percent of rps of the task,
+apdex is score of the attack from 0 to 1, assertion_failure_rate is percent of responses failed assertions
+*/
+type Threshold struct {
+	Metric string   `json:"metric"`
+	Max    *float64 `json:"max,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+}
+
+type ThresholdResult struct {
+	Metric string   `json:"metric"`
+	Max    *float64 `json:"max,omitempty"`
+	Min    *float64 `json:"min,omitempty"`
+	Actual float64  `json:"actual"`
+	Passed bool     `json:"passed"`
+}
+
+// attackSummary - numbers of the attack, which are checked by thresholds
+type attackSummary struct {
+	report    *AttackReport
+	requests  int64
+	failed    int64
+	elapsed   time.Duration
+	targetRps int64
+}
+
+func (attack *Attack) attackSummary(report *AttackReport) attackSummary {
+	summary := attackSummary{
+		report:    report,
+		elapsed:   attack.attackElapsed,
+		targetRps: attack.attackTargetRps,
+	}
+	for _, bucket := range report.Timeline.Buckets {
+		summary.requests += bucket.Requests
+		summary.failed += bucket.Errors + bucket.Timeouts
+	}
+	return summary
+}
+
+func (summary attackSummary) rps() float64 {
+	if summary.elapsed <= 0 {
+		return 0
+	}
+	return float64(summary.requests) / summary.elapsed.Seconds()
+}
+
+func latencyMetric(report *AttackReport, metric string) (int64, bool) {
+	latency := report.Latency
+	switch metric {
+	case "mean":
+		return latency.MeanNs, true
+	case "p50":
+		return latency.P50Ns, true
+	case "p90":
+		return latency.P90Ns, true
+	case "p95":
+		return latency.P95Ns, true
+	case "p99":
+		return latency.P99Ns, true
+	case "p999":
+		return latency.P999Ns, true
+	case "max":
+		return latency.MaxNs, true
+	}
+	return 0, false
+}
+
+// thresholdValue - actual value of metric of threshold in units of its limits
+func thresholdValue(summary attackSummary, metric string) (float64, error) {
+	if latency, ok := latencyMetric(summary.report, metric); ok {
+		return float64(latency) / float64(time.Millisecond), nil
+	}
+	switch metric {
+	case "error_rate":
+		if summary.requests == 0 {
+			return 0, nil
+		}
+		return float64(summary.failed) * 100 / float64(summary.requests), nil
+	case "rps":
+		return summary.rps(), nil
+	case "apdex":
+		return summary.report.Apdex.Score, nil
+	case "assertion_failure_rate":
+		if summary.report.Assertions == nil {
+			return 0, nil
+		}
+		return float64(summary.report.Assertions.Failed) * 100 / float64(summary.report.Assertions.Checked), nil
+	case "rps_ratio":
+		if summary.targetRps == 0 {
+			return 0, nil
+		}
+		return summary.rps() * 100 / float64(summary.targetRps), nil
+	}
+	return 0, ErrThresholdMetric
+}
+
+func validateThresholds(thresholds []Threshold) error {
+	for _, threshold := range thresholds {
+		if _, err := thresholdValue(attackSummary{report: &AttackReport{}}, threshold.Metric); err != nil {
+			return err
+		}
+		if threshold.Max == nil && threshold.Min == nil {
+			return ErrThresholdLimit
+		}
+	}
+	return nil
+}
+
+func evaluateThresholds(thresholds []Threshold, summary attackSummary) []ThresholdResult {
+	results := make([]ThresholdResult, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		actual, err := thresholdValue(summary, threshold.Metric)
+		if err != nil {
+			continue
+		}
+		results = append(results, ThresholdResult{
+			Metric: threshold.Metric,
+			Max:    threshold.Max,
+			Min:    threshold.Min,
+			Actual: actual,
+			Passed: (threshold.Max == nil || actual <= *threshold.Max) && (threshold.Min == nil || actual >= *threshold.Min),
+		})
+	}
+	return results
+}
@@ -0,0 +1,192 @@
+package core
+
+import (
+	"time"
+
+	"github.com/HdrHistogram/hdrhistogram-go"
+)
+
+const (
+	defaultBucketInterval = time.Second
+	bucketSignificant     = 2
+	// buckets older than this amount are closed, late results of them are dropped
+	openBuckets = 2
+)
+
+type timeBucket struct {
+	requests  int64
+	errors    int64
+	timeouts  int64
+	histogram *hdrhistogram.Histogram // nil after bucket is closed
+	summary   BucketReport
+}
+
+/*
+timeline - results of the attack aggregated by intervals of time from its start.
+Latencies are kept in histogram only for the last buckets, closed buckets keep summaries
+*/
+type timeline struct {
+	start    time.Time
+	interval time.Duration
+	buckets  []*timeBucket
+	dropped  int64
+	traffic  []trafficSample // by index of bucket
+	elapsed  time.Duration   // duration of sampled traffic
+}
+
+type trafficSample struct {
+	bytesIn  int64
+	bytesOut int64
+}
+
+type BucketReport struct {
+	StartMs  int64   `json:"start_ms"`
+	Requests int64   `json:"requests"`
+	Rps      float64 `json:"rps"`
+	Errors   int64   `json:"errors"`
+	Timeouts int64   `json:"timeouts"`
+	MeanNs   int64   `json:"mean_ns"`
+	P50Ns    int64   `json:"p50_ns"`
+	P90Ns    int64   `json:"p90_ns"`
+	P99Ns    int64   `json:"p99_ns"`
+	MaxNs    int64   `json:"max_ns"`
+	BytesIn  int64   `json:"bytes_in"`
+	BytesOut int64   `json:"bytes_out"`
+}
+
+type TimelineReport struct {
+	StartUnixMs int64          `json:"start_unix_ms"`
+	IntervalMs  int64          `json:"interval_ms"`
+	Dropped     int64          `json:"dropped,omitempty"`
+	Buckets     []BucketReport `json:"buckets"`
+}
+
+func newTimeline(intervalMs int64) *timeline {
+	interval := time.Duration(intervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultBucketInterval
+	}
+	return &timeline{
+		start:    time.Now(),
+		interval: interval,
+	}
+}
+
+// bucket - bucket of current time, nil if it is already closed
+func (line *timeline) bucket() *timeBucket {
+	index := int(time.Since(line.start) / line.interval)
+	for len(line.buckets) <= index {
+		line.buckets = append(line.buckets, &timeBucket{
+			histogram: hdrhistogram.New(latencyLowest, latencyHighest, bucketSignificant),
+		})
+	}
+	for closed := index - openBuckets; closed >= 0 && line.buckets[closed].histogram != nil; closed-- {
+		line.close(closed)
+	}
+	return line.buckets[index]
+}
+
+func (line *timeline) close(index int) {
+	bucket := line.buckets[index]
+	histogram := bucket.histogram
+	bucket.summary = BucketReport{
+		StartMs:  (time.Duration(index) * line.interval).Milliseconds(),
+		Requests: bucket.requests,
+		Rps:      float64(bucket.requests) / line.interval.Seconds(),
+		Errors:   bucket.errors,
+		Timeouts: bucket.timeouts,
+		MeanNs:   int64(histogram.Mean()),
+		P50Ns:    histogram.ValueAtQuantile(50),
+		P90Ns:    histogram.ValueAtQuantile(90),
+		P99Ns:    histogram.ValueAtQuantile(99),
+		MaxNs:    histogram.Max(),
+	}
+	bucket.histogram = nil
+}
+
+func (line *timeline) add(latency int64, failed bool) {
+	bucket := line.bucket()
+	if bucket.histogram == nil {
+		line.dropped++
+		return
+	}
+	bucket.requests++
+	if failed {
+		bucket.errors++
+	}
+	bucket.histogram.RecordValue(latency)
+}
+
+func (line *timeline) addTimeout() {
+	bucket := line.bucket()
+	if bucket.histogram == nil {
+		line.dropped++
+		return
+	}
+	bucket.requests++
+	bucket.timeouts++
+}
+
+// addTraffic - bytes of connections of bucket by index, sampled at the end of its interval
+func (line *timeline) addTraffic(index int, bytesIn int64, bytesOut int64) {
+	for len(line.traffic) <= index {
+		line.traffic = append(line.traffic, trafficSample{})
+	}
+	line.traffic[index].bytesIn += bytesIn
+	line.traffic[index].bytesOut += bytesOut
+}
+
+// window - summary of the last elapsed interval, empty until the first interval ends
+func (line *timeline) window() BucketReport {
+	index := int(time.Since(line.start)/line.interval) - 1
+	if index < 0 || index >= len(line.buckets) {
+		return BucketReport{}
+	}
+	bucket := line.buckets[index]
+	if bucket.histogram == nil {
+		return bucket.summary
+	}
+	histogram := bucket.histogram
+	return BucketReport{
+		StartMs:  (time.Duration(index) * line.interval).Milliseconds(),
+		Requests: bucket.requests,
+		Rps:      float64(bucket.requests) / line.interval.Seconds(),
+		Errors:   bucket.errors,
+		Timeouts: bucket.timeouts,
+		MeanNs:   int64(histogram.Mean()),
+		P50Ns:    histogram.ValueAtQuantile(50),
+		P90Ns:    histogram.ValueAtQuantile(90),
+		P99Ns:    histogram.ValueAtQuantile(99),
+		MaxNs:    histogram.Max(),
+	}
+}
+
+func (line *timeline) report() TimelineReport {
+	amount := len(line.buckets)
+	if len(line.traffic) > amount {
+		amount = len(line.traffic)
+	}
+	report := TimelineReport{
+		StartUnixMs: line.start.UnixNano() / int64(time.Millisecond),
+		IntervalMs:  line.interval.Milliseconds(),
+		Dropped:     line.dropped,
+		Buckets:     make([]BucketReport, 0, amount),
+	}
+	for index := 0; index < amount; index++ {
+		summary := BucketReport{
+			StartMs: (time.Duration(index) * line.interval).Milliseconds(),
+		}
+		if index < len(line.buckets) {
+			if line.buckets[index].histogram != nil {
+				line.close(index)
+			}
+			summary = line.buckets[index].summary
+		}
+		if index < len(line.traffic) {
+			summary.BytesIn = line.traffic[index].bytesIn
+			summary.BytesOut = line.traffic[index].bytesOut
+		}
+		report.Buckets = append(report.Buckets, summary)
+	}
+	return report
+}
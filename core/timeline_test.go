@@ -0,0 +1,106 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+// moveTimeline - moves start of the timeline back, so the current time is in later bucket
+func moveTimeline(line *timeline, buckets int) {
+	line.start = line.start.Add(-time.Duration(buckets) * line.interval)
+}
+
+func TestTimelineBuckets(t *testing.T) {
+	line := newTimeline(int64(time.Hour / time.Millisecond))
+	line.add(int64(10*time.Millisecond), false)
+	line.add(int64(30*time.Millisecond), true)
+	line.addTimeout()
+	moveTimeline(line, 1)
+	line.add(int64(20*time.Millisecond), false)
+	line.addTraffic(0, 100, 50)
+	line.addTraffic(2, 7, 3)
+	report := line.report()
+	if report.IntervalMs != int64(time.Hour/time.Millisecond) {
+		t.Fatalf("interval %d ms", report.IntervalMs)
+	}
+	if len(report.Buckets) != 3 {
+		t.Fatalf("%d buckets, expected 3 by traffic", len(report.Buckets))
+	}
+	first, second, third := report.Buckets[0], report.Buckets[1], report.Buckets[2]
+	if first.Requests != 3 || first.Errors != 1 || first.Timeouts != 1 || first.BytesIn != 100 || first.BytesOut != 50 {
+		t.Errorf("first bucket %+v", first)
+	}
+	if first.Rps != 3/time.Hour.Seconds() {
+		t.Errorf("rps of first bucket %v", first.Rps)
+	}
+	if !near(first.MaxNs, int64(30*time.Millisecond)) || !near(first.P50Ns, int64(10*time.Millisecond)) {
+		t.Errorf("latency of first bucket %+v", first)
+	}
+	if second.StartMs != report.IntervalMs || second.Requests != 1 || !near(second.MeanNs, int64(20*time.Millisecond)) {
+		t.Errorf("second bucket %+v", second)
+	}
+	if third.StartMs != 2*report.IntervalMs || third.Requests != 0 || third.BytesIn != 7 || third.BytesOut != 3 {
+		t.Errorf("third bucket %+v", third)
+	}
+}
+
+// near - histogram of the timeline keeps 2 significant digits
+func near(value int64, expected int64) bool {
+	difference := value - expected
+	if difference < 0 {
+		difference = -difference
+	}
+	return difference <= expected/100
+}
+
+func TestTimelineClosesOldBuckets(t *testing.T) {
+	line := newTimeline(int64(time.Hour / time.Millisecond))
+	line.add(int64(time.Millisecond), false)
+	stale := line.buckets[0]
+	moveTimeline(line, openBuckets)
+	line.add(int64(time.Millisecond), false)
+	if stale.histogram != nil {
+		t.Fatal("bucket older than open buckets is not closed")
+	}
+	if stale.summary.Requests != 1 {
+		t.Fatalf("summary of closed bucket %+v", stale.summary)
+	}
+	if line.buckets[1].histogram == nil {
+		t.Fatal("open bucket is closed")
+	}
+	// late result of the closed bucket is dropped
+	moveTimeline(line, -openBuckets)
+	line.add(int64(time.Millisecond), false)
+	line.addTimeout()
+	if line.dropped != 2 || stale.requests != 1 {
+		t.Fatalf("dropped %d, requests of closed bucket %d", line.dropped, stale.requests)
+	}
+	if report := line.report(); report.Dropped != 2 || len(report.Buckets) != openBuckets+1 {
+		t.Fatalf("report %+v", report)
+	}
+}
+
+func TestTimelineWindow(t *testing.T) {
+	line := newTimeline(int64(time.Hour / time.Millisecond))
+	if window := line.window(); window.Requests != 0 {
+		t.Fatalf("window before the first interval ends %+v", window)
+	}
+	line.add(int64(5*time.Millisecond), false)
+	line.add(int64(5*time.Millisecond), true)
+	moveTimeline(line, 1)
+	if window := line.window(); window.Requests != 2 || window.Errors != 1 || !near(window.MaxNs, int64(5*time.Millisecond)) {
+		t.Fatalf("window of open bucket %+v", window)
+	}
+	moveTimeline(line, openBuckets)
+	line.add(int64(time.Millisecond), false)
+	moveTimeline(line, -openBuckets)
+	if window := line.window(); window.Requests != 2 || window.Errors != 1 {
+		t.Fatalf("window of closed bucket %+v", window)
+	}
+}
+
+func TestTimelineDefaultInterval(t *testing.T) {
+	if line := newTimeline(0); line.interval != defaultBucketInterval {
+		t.Fatalf("interval %v, expected %v", line.interval, defaultBucketInterval)
+	}
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	mathrand "math/rand"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	traceparentHeader = "traceparent"
+	requestIdHeader   = "X-Bomber-Request-Id"
+	defaultTracedKept = 20
+)
+
+type TracingOptions struct {
+	// fraction of requests with traceparent header from 0 to 1, disabled if empty
+	SampleRate float64 `json:"sample_rate,omitempty"`
+	// amount of the slowest and of the failed traced requests in report, 20 if empty
+	Keep int `json:"keep,omitempty"`
+}
+
+type TracedRequest struct {
+	TraceId   string `json:"trace_id,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	LatencyNs int64  `json:"latency_ns,omitempty"`
+	Timeout   bool   `json:"timeout,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+type TracingReport struct {
+	Sampled int64           `json:"sampled"`
+	Slowest []TracedRequest `json:"slowest"`
+	// timeouts, transport errors and 5xx responses
+	Failed []TracedRequest `json:"failed"`
+}
+
+type tracingStats struct {
+	keep    int
+	sampled int64
+	slowest []TracedRequest
+	failed  []TracedRequest
+}
+
+func newTracingStats(options TracingOptions) tracingStats {
+	keep := options.Keep
+	if keep <= 0 {
+		keep = defaultTracedKept
+	}
+	return tracingStats{keep: keep}
+}
+
+func (stats *tracingStats) add(result SliceResult) {
+	if result.TraceId == "" && result.RequestId == "" {
+		return
+	}
+	if result.TraceId != "" {
+		stats.sampled++
+	}
+	traced := TracedRequest{
+		TraceId:   result.TraceId,
+		RequestId: result.RequestId,
+		Status:    result.Status,
+		LatencyNs: result.TimeElapsed,
+		Timeout:   result.Timeout,
+		Error:     result.Error,
+	}
+	if result.Timeout || result.Status >= fasthttp.StatusInternalServerError {
+		if len(stats.failed) < stats.keep {
+			stats.failed = append(stats.failed, traced)
+		}
+		if result.Timeout {
+			return
+		}
+	}
+	index := sort.Search(len(stats.slowest), func(i int) bool {
+		return stats.slowest[i].LatencyNs < traced.LatencyNs
+	})
+	if index >= stats.keep {
+		return
+	}
+	stats.slowest = append(stats.slowest, TracedRequest{})
+	copy(stats.slowest[index+1:], stats.slowest[index:])
+	stats.slowest[index] = traced
+	if len(stats.slowest) > stats.keep {
+		stats.slowest = stats.slowest[:stats.keep]
+	}
+}
+
+func (stats *tracingStats) report() TracingReport {
+	report := TracingReport{
+		Sampled: stats.sampled,
+		Slowest: stats.slowest,
+		Failed:  stats.failed,
+	}
+	if report.Slowest == nil {
+		report.Slowest = []TracedRequest{}
+	}
+	if report.Failed == nil {
+		report.Failed = []TracedRequest{}
+	}
+	return report
+}
+
+func (options TracingOptions) sample() string {
+	if options.SampleRate <= 0 || mathrand.Float64() >= options.SampleRate {
+		return ""
+	}
+	return randomHex(16)
+}
+
+// setTraceparent - every exchange of traced request is a new span of the same trace
+func setTraceparent(request *fasthttp.Request, traceID string) {
+	request.Header.Set(traceparentHeader, "00-"+traceID+"-"+randomHex(8)+"-01")
+}
+
+// requestId - id of the request, the same in all its retries and redirects, empty if request ids are disabled
+func requestId(enabled bool) string {
+	if !enabled {
+		return ""
+	}
+	return uuid.New().String()
+}
+
+func randomHex(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,72 @@
+package core
+
+import (
+	"time"
+
+	"github.com/bomber-team/rest-bomber/metrics"
+)
+
+type TrafficReport struct {
+	// bytes of all connections of the task including tls records and handshakes
+	BytesIn  int64 `json:"bytes_in"`
+	BytesOut int64 `json:"bytes_out"`
+	// mean throughput of connections during the attack
+	InPerSecond  float64 `json:"in_per_second"`
+	OutPerSecond float64 `json:"out_per_second"`
+}
+
+/*
+sampleTraffic - samples bytes of connections of the dialer into buckets of timeline
+until returned func is called
+*/
+func (attack *Attack) sampleTraffic() func() {
+	line := attack.resultTimeline
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(line.interval)
+		defer ticker.Stop()
+		last := attack.dialer.Stats()
+		for index := 0; ; index++ {
+			stopped := false
+			select {
+			case <-ticker.C:
+			case <-stop:
+				stopped = true
+			}
+			stats := attack.dialer.Stats()
+			metrics.Traffic(stats.BytesRead-last.BytesRead, stats.BytesWritten-last.BytesWritten)
+			attack.results.Lock()
+			line.addTraffic(index, stats.BytesRead-last.BytesRead, stats.BytesWritten-last.BytesWritten)
+			if index < len(line.buckets) {
+				metrics.AchievedRps(float64(line.buckets[index].requests) / line.interval.Seconds())
+			}
+			if stopped {
+				line.elapsed = time.Since(line.start)
+			}
+			attack.results.Unlock()
+			last = stats
+			if stopped {
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-done
+	}
+}
+
+func (attack *Attack) trafficReport() TrafficReport {
+	var report TrafficReport
+	for _, sample := range attack.resultTimeline.traffic {
+		report.BytesIn += sample.bytesIn
+		report.BytesOut += sample.bytesOut
+	}
+	if seconds := attack.resultTimeline.elapsed.Seconds(); seconds > 0 {
+		report.InPerSecond = float64(report.BytesIn) / seconds
+		report.OutPerSecond = float64(report.BytesOut) / seconds
+	}
+	return report
+}
@@ -0,0 +1,187 @@
+package core
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/transport"
+	"github.com/valyala/fasthttp"
+)
+
+var ErrUnsupportedScheme = errors.New("unsupported scheme, http and https are supported")
+
+/*
+virtualUser - state of one worker, which executes requests of the attack one by one
+*/
+type virtualUser struct {
+	dialer  *transport.Dialer
+	clients map[string]*fasthttp.HostClient // by scheme and host
+	jar     *cookieJar
+	trace   transport.Trace
+	expect  ExpectContinueOptions
+	auth    AuthOptions
+	session *loginSession // token and cookies of login, nil without it
+	csrf    *csrfSession
+	oauth2  *oauth2Token
+	jwt     *jwtSigner
+	sigv4   *sigv4Signer
+	hmac    *hmacSigner
+	// token of current request, empty without jwt
+	jwtToken string
+	tracing  TracingOptions
+	// id of trace of current request, empty if it is not sampled
+	traceID string
+	// id of current request in X-Bomber-Request-Id header, empty if task has no request ids
+	requestIDs bool
+	requestID  string
+	// outcome of 100-continue handshake of the first exchange of current request
+	continued int
+	state     userState
+}
+
+func (attack *Attack) newVirtualUser() *virtualUser {
+	user := &virtualUser{
+		dialer:     attack.dialer,
+		clients:    map[string]*fasthttp.HostClient{},
+		expect:     attack.options.ExpectContinue,
+		auth:       attack.options.Auth,
+		tracing:    attack.options.Tracing,
+		requestIDs: attack.options.RequestIds,
+		session:    attack.loginShared,
+		oauth2:     attack.oauth2,
+		jwt:        attack.jwt,
+		sigv4:      attack.sigv4,
+		hmac:       attack.hmacSigning,
+		state:      attack.newUserState(),
+	}
+	if attack.options.Cookies {
+		user.jar = newCookieJar()
+	}
+	return user
+}
+
+func (user *virtualUser) client(uri *fasthttp.URI) (*fasthttp.HostClient, error) {
+	scheme := string(uri.Scheme())
+	if scheme != "http" && scheme != "https" {
+		return nil, ErrUnsupportedScheme
+	}
+	host := string(uri.Host())
+	key := scheme + "://" + host
+	if client, ok := user.clients[key]; ok {
+		return client, nil
+	}
+	isTLS := scheme == "https"
+	client := &fasthttp.HostClient{
+		Addr:     addMissingPort(host, isTLS),
+		IsTLS:    isTLS,
+		MaxConns: 10000,
+		Dial: func(addr string) (net.Conn, error) {
+			return user.dialer.DialTraced(addr, isTLS, &user.trace)
+		},
+	}
+	user.clients[key] = client
+	return client, nil
+}
+
+func (user *virtualUser) do(request *fasthttp.Request, response *fasthttp.Response) error {
+	return user.exchange(request, response, true)
+}
+
+// exchange - request without credentials and signatures of the task if credentials is false
+func (user *virtualUser) exchange(request *fasthttp.Request, response *fasthttp.Response, credentials bool) error {
+	client, err := user.client(request.URI())
+	if err != nil {
+		return err
+	}
+	if user.jar != nil {
+		user.jar.apply(request)
+	}
+	if credentials {
+		user.auth.apply(request)
+		user.oauth2.apply(request)
+		user.jwt.apply(request, user.jwtToken)
+		user.session.apply(request)
+		user.csrf.apply(request)
+	}
+	if user.traceID != "" {
+		setTraceparent(request, user.traceID)
+	}
+	if user.requestID != "" {
+		request.Header.Set(requestIdHeader, user.requestID)
+	}
+	// signature covers all headers set above
+	if credentials {
+		user.hmac.sign(request, time.Now())
+		user.sigv4.sign(request, time.Now())
+	}
+	if user.expect.applies(request) {
+		var outcome int
+		outcome, err = user.doExpectContinue(client, request, response)
+		if user.continued == continueNone {
+			// only the first exchange of request is reported
+			user.continued = outcome
+		}
+	} else {
+		err = client.Do(request, response)
+	}
+	user.trace.Finish()
+	if err != nil {
+		return err
+	}
+	if user.jar != nil {
+		user.jar.store(request, response)
+	}
+	return nil
+}
+
+// stripCredentials - removes headers of credentials and signatures, the ones of the schema too
+func (user *virtualUser) stripCredentials(request *fasthttp.Request) {
+	headers := []string{fasthttp.HeaderAuthorization, fasthttp.HeaderCookie}
+	if user.jwt != nil && user.jwt.options.Header != "" {
+		headers = append(headers, user.jwt.options.Header)
+	}
+	if user.session != nil && user.session.header != "" {
+		headers = append(headers, user.session.header)
+	}
+	if user.csrf != nil {
+		headers = append(headers, user.csrf.header)
+	}
+	if user.hmac != nil {
+		headers = append(headers, user.hmac.options.Header, user.hmac.options.TimestampHeader, user.hmac.options.NonceHeader)
+	}
+	if user.sigv4 != nil {
+		headers = append(headers, "X-Amz-Date", "X-Amz-Content-Sha256", "X-Amz-Security-Token")
+	}
+	for _, header := range headers {
+		if header != "" {
+			request.Header.Del(header)
+		}
+	}
+}
+
+// beginRequest - starts trace of the next request of the attack
+func (user *virtualUser) beginRequest() {
+	user.trace.Start()
+	user.continued = continueNone
+	user.traceID = user.tracing.sample()
+	user.requestID = requestId(user.requestIDs)
+	user.state.requests++
+	if user.jwt != nil {
+		user.jwtToken = user.jwt.token(user.variables(nil))
+	}
+}
+
+func (user *virtualUser) timings() phaseTimings {
+	return phaseTimings{
+		reused:   user.trace.Reused,
+		dns:      user.trace.DNS,
+		connect:  user.trace.Connect,
+		tls:      user.trace.TLS,
+		resumed:  user.trace.TLSResumed,
+		wait:     user.trace.Wait(),
+		transfer: user.trace.Transfer(),
+		ttfb:     user.trace.TTFB(),
+		total:    user.trace.Total(),
+	}
+}
@@ -0,0 +1,193 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp/syntax"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+)
+
+// FieldError - reason why the field of the task can not be executed
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+/*
+ValidateTask - checks the task before preparing, so invalid task is rejected with all its
+reasons instead of panic or attack by broken requests. Empty if the task is valid
+*/
+func ValidateTask(task rest_contracts.Task) []FieldError {
+	var fieldErrors []FieldError
+	add := func(field string, reason string) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Reason: reason})
+	}
+	options, errOptions := ParseTaskOptions(task)
+	switch {
+	case errors.Is(errOptions, ErrUnknownMode):
+		add("schema.headers."+OptionsHeader+".mode", ErrUnknownMode.Error())
+		options = &TaskOptions{Mode: ModeHTTP}
+	case errOptions != nil:
+		add("schema.headers."+OptionsHeader, "can not parse options: "+errOptions.Error())
+		options = &TaskOptions{Mode: ModeHTTP}
+	}
+	if !validateContract(&task, options, add) {
+		return fieldErrors
+	}
+	if task.Script == nil {
+		add("script", "is required")
+	} else {
+		if task.Script.Config == nil {
+			add("script.config", "is required")
+		} else {
+			if task.Script.Config.Rps <= 0 {
+				add("script.config.rps", "must be positive")
+			}
+			if task.Script.Config.Time <= 0 {
+				add("script.config.time", "must be positive")
+			}
+			validateShard(options.Shard, task.Script.Config.Rps, add)
+		}
+		validateAddress(task.Script.Address, options.Mode, add)
+	}
+	validateScenario(options.Scenario, options.Mode, add)
+	validateHooks("setup", options.Setup, options.Mode, add)
+	validateHooks("teardown", options.Teardown, options.Mode, add)
+	if options.Mode != ModeHTTP {
+		if options.Assertions.enabled() {
+			add("schema.headers."+OptionsHeader+".assertions", "are supported in http mode only")
+		}
+		if options.CSRF.enabled() {
+			add("schema.headers."+OptionsHeader+".csrf", "is supported in http mode only")
+		}
+		if options.Login.enabled() {
+			add("schema.headers."+OptionsHeader+".login", "is supported in http mode only")
+		}
+		if options.HMACSigning.enabled() {
+			add("schema.headers."+OptionsHeader+".hmac_signing", "is supported in http mode only")
+		}
+		if options.SigV4.enabled() {
+			add("schema.headers."+OptionsHeader+".sigv4", "is supported in http mode only")
+		}
+		if options.JWT.enabled() {
+			add("schema.headers."+OptionsHeader+".jwt", "is supported in http mode only")
+		}
+		if options.Auth.OAuth2 != nil {
+			add("schema.headers."+OptionsHeader+".auth.oauth2", "is supported in http mode only")
+		}
+		if len(options.CaptureHeaders) > 0 {
+			add("schema.headers."+OptionsHeader+".capture_headers", "are supported in http mode only")
+		}
+		return fieldErrors
+	}
+	validateAssertions("schema.headers."+OptionsHeader+".assertions", options.Assertions, add)
+	validateLogin(options.Login, add)
+	validateCSRF(options.CSRF, add)
+	validateOAuth2(options.Auth.OAuth2, add)
+	validateJWT(options.JWT, add)
+	validateSigV4(options.SigV4, add)
+	validateHMACSigning(options.HMACSigning, add)
+	for index, header := range options.CaptureHeaders {
+		if header == "" {
+			add(fmt.Sprintf("schema.headers.%s.capture_headers[%d]", OptionsHeader, index), "must not be empty")
+		}
+	}
+	if task.Schema == nil {
+		add("schema", "is required")
+		return fieldErrors
+	}
+	for index, param := range task.Schema.Request {
+		if param.IsGeneratorNeed {
+			validateGenerator(fmt.Sprintf("schema.request[%d].generatorConfig", index), param.GeneratorConfig, add)
+		}
+	}
+	validateBody("schema.body", task.Schema.Body, add)
+	return fieldErrors
+}
+
+/*
+ValidateTenant - task of another tenant is rejected, subjects of tenants are apart already,
+so it is the task published into subject of wrong tenant. Task without tenant belongs to any of them
+*/
+func ValidateTenant(task rest_contracts.Task, tenant string) []FieldError {
+	options, err := ParseTaskOptions(task)
+	if err != nil || options.Tenant == "" || options.Tenant == tenant {
+		return nil
+	}
+	return []FieldError{{Field: "schema.headers." + OptionsHeader + ".tenant", Reason: "task of tenant " + options.Tenant + " is not executed by bomber of tenant " + tenant}}
+}
+
+func validateAddress(address string, mode string, add func(field string, reason string)) {
+	if address == "" {
+		add("script.address", "is required")
+		return
+	}
+	// raw address is host:port, modes with own clients check their addresses while preparing
+	if mode != ModeHTTP {
+		return
+	}
+	parsed, err := url.Parse(address)
+	if err != nil {
+		add("script.address", "can not parse: "+err.Error())
+		return
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		add("script.address", "scheme must be http or https")
+	}
+	if parsed.Host == "" {
+		add("script.address", "host is required")
+	}
+}
+
+func validateBody(field string, params []*rest_contracts.BodyParam, add func(field string, reason string)) {
+	for index, param := range params {
+		validateBodyParam(fmt.Sprintf("%s[%d]", field, index), param, add)
+	}
+}
+
+func validateBodyParam(field string, param *rest_contracts.BodyParam, add func(field string, reason string)) {
+	if param == nil {
+		add(field, "is empty")
+		return
+	}
+	if param.IsGenerated {
+		validateGenerator(field+".config", param.Config, add)
+		return
+	}
+	if properties, ok := param.Value.(*rest_contracts.BodyParam_Properties); ok && properties.Properties != nil {
+		for name, property := range properties.Properties.Properties {
+			validateBodyParam(field+".properties."+name, property, add)
+		}
+	}
+}
+
+func validateGenerator(field string, config *rest_contracts.GeneratorConfig, add func(field string, reason string)) {
+	if config == nil || config.Res == nil {
+		add(field, "generator config is required")
+		return
+	}
+	switch generator := config.Res.(type) {
+	case *rest_contracts.GeneratorConfig_WordGeneratorConfig:
+		words := generator.WordGeneratorConfig
+		if words == nil {
+			add(field+".wordGeneratorConfig", "is empty")
+		} else if words.MinLetters < 0 || words.MaxLetters <= words.MinLetters {
+			add(field+".wordGeneratorConfig", "maxLetters must be greater than minLetters, which must not be negative")
+		}
+	case *rest_contracts.GeneratorConfig_DigitGeneratorConfig:
+		digits := generator.DigitGeneratorConfig
+		if digits == nil {
+			add(field+".digitGeneratorConfig", "is empty")
+		} else if digits.EndTo <= digits.StartFrom {
+			add(field+".digitGeneratorConfig", "endTo must be greater than startFrom")
+		}
+	case *rest_contracts.GeneratorConfig_RegexpConfig:
+		if generator.RegexpConfig == nil {
+			add(field+".regexpConfig", "is empty")
+		} else if _, err := syntax.Parse(generator.RegexpConfig.Pattern, syntax.Perl); err != nil {
+			add(field+".regexpConfig.pattern", "can not parse: "+err.Error())
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+)
+
+func fields(fieldErrors []FieldError) map[string]bool {
+	byField := map[string]bool{}
+	for _, fieldError := range fieldErrors {
+		byField[fieldError.Field] = true
+	}
+	return byField
+}
+
+func TestValidateTask(t *testing.T) {
+	options := "schema.headers." + OptionsHeader
+	cases := []struct {
+		name   string
+		task   func() rest_contracts.Task
+		fields []string
+	}{
+		{"valid task", func() rest_contracts.Task { return taskWithOptions("") }, nil},
+		{"unknown mode", func() rest_contracts.Task { return taskWithOptions(`{"mode": "htttp"}`) }, []string{options + ".mode"}},
+		{"broken options", func() rest_contracts.Task { return taskWithOptions(`{"mode": `) }, []string{options}},
+		{"without script", func() rest_contracts.Task {
+			task := taskWithOptions("")
+			task.Script = nil
+			return task
+		}, []string{"script"}},
+		{"zero rps and time", func() rest_contracts.Task {
+			task := taskWithOptions("")
+			task.Script.Config = &rest_contracts.ConfigurationScript{}
+			return task
+		}, []string{"script.config.rps", "script.config.time"}},
+		{"rps below amount of workers", func() rest_contracts.Task {
+			task := taskWithOptions("")
+			task.Script.Config.Rps = 1
+			return task
+		}, nil},
+		{"address without scheme", func() rest_contracts.Task {
+			task := taskWithOptions("")
+			task.Script.Address = "127.0.0.1:8080"
+			return task
+		}, []string{"script.address"}},
+		{"raw address", func() rest_contracts.Task {
+			task := taskWithOptions(`{"mode": "raw"}`)
+			task.Script.Address = "127.0.0.1:8080"
+			return task
+		}, nil},
+		{"without schema", func() rest_contracts.Task {
+			task := taskWithOptions("")
+			task.Schema = nil
+			return task
+		}, []string{"schema"}},
+		{"scenario of other mode", func() rest_contracts.Task {
+			return taskWithOptions(`{"mode": "sse", "scenario": {"steps": [{"name": "get"}]}}`)
+		}, []string{options + ".scenario"}},
+		{"duplicate steps", func() rest_contracts.Task {
+			return taskWithOptions(`{"scenario": {"steps": [{"name": "get"}, {"name": "get", "method": "get"}]}}`)
+		}, []string{options + ".scenario.steps[1].name", options + ".scenario.steps[1].method"}},
+		{"condition on later step", func() rest_contracts.Task {
+			return taskWithOptions(`{"scenario": {"steps": [{"name": "a", "when": {"step": "b"}}, {"name": "b"}]}}`)
+		}, []string{options + ".scenario.steps[0].when.step"}},
+		{"generator of digits", func() rest_contracts.Task {
+			task := taskWithOptions("")
+			task.Schema.Body = []*rest_contracts.BodyParam{{
+				Name:        "id",
+				IsGenerated: true,
+				Config: &rest_contracts.GeneratorConfig{Res: &rest_contracts.GeneratorConfig_DigitGeneratorConfig{
+					DigitGeneratorConfig: &rest_contracts.DigitGeneratorConfig{StartFrom: 5, EndTo: 5},
+				}},
+			}}
+			return task
+		}, []string{"schema.body[0].config.digitGeneratorConfig"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			fieldErrors := ValidateTask(tc.task())
+			got := fields(fieldErrors)
+			for _, field := range tc.fields {
+				if !got[field] {
+					t.Errorf("no error of %s in %v", field, fieldErrors)
+				}
+			}
+			if len(tc.fields) == 0 && len(fieldErrors) > 0 {
+				t.Errorf("valid task has errors %v", fieldErrors)
+			}
+		})
+	}
+}
+
+func TestValidateTenant(t *testing.T) {
+	task := taskWithOptions(`{"tenant": "team-a"}`)
+	if fieldErrors := ValidateTenant(task, "team-a"); len(fieldErrors) > 0 {
+		t.Fatalf("task of own tenant is rejected: %v", fieldErrors)
+	}
+	if fieldErrors := ValidateTenant(task, "team-b"); len(fieldErrors) != 1 {
+		t.Fatalf("task of other tenant is not rejected: %v", fieldErrors)
+	}
+	if fieldErrors := ValidateTenant(taskWithOptions(""), "team-b"); len(fieldErrors) > 0 {
+		t.Fatalf("task without tenant is rejected: %v", fieldErrors)
+	}
+}
+
+func TestWorkerInterval(t *testing.T) {
+	cases := []struct {
+		rps      int64
+		interval time.Duration
+	}{
+		{100, 100 * time.Millisecond},
+		{currentWorkers, time.Second},
+		{1, currentWorkers * time.Second},
+		{5, 2 * time.Second},
+	}
+	for _, tc := range cases {
+		if interval := workerInterval(tc.rps); interval != tc.interval {
+			t.Errorf("interval of rps %d is %s, expected %s", tc.rps, interval, tc.interval)
+		}
+	}
+}
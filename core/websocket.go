@@ -0,0 +1,240 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/gorilla/websocket"
+	"github.com/jamiealquiza/tachymeter"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultReplyTimeout       = 5 * time.Second
+	websocketHandshakeTimeout = 5 * time.Second
+)
+
+type WebsocketOptions struct {
+	// amount of concurrent connections, amount of workers if empty
+	Connections int `json:"connections,omitempty"`
+	// template of message with {{name}} placeholders of body params,
+	// generated json body is sent if empty
+	Message string `json:"message,omitempty"`
+	// received frames containing this substring are counted as matched
+	Expect         string `json:"expect,omitempty"`
+	ReplyTimeoutMs int64  `json:"reply_timeout_ms,omitempty"`
+}
+
+type websocketStats struct {
+	mutex         sync.Mutex
+	connect       *tachymeter.Tachymeter
+	connections   int64
+	connectErrors int64
+	disconnects   int64
+	sent          int64
+	received      int64
+	matched       int64
+	unmatched     int64
+	errors        int64
+}
+
+type WebsocketReport struct {
+	Connections   int64       `json:"connections"`
+	ConnectErrors int64       `json:"connect_errors"`
+	Disconnects   int64       `json:"disconnects"`
+	Sent          int64       `json:"sent"`
+	Received      int64       `json:"received"`
+	Matched       int64       `json:"matched"`
+	Unmatched     int64       `json:"unmatched"`
+	Errors        int64       `json:"errors"`
+	Connect       PhaseReport `json:"connect"`
+}
+
+func (stats *websocketStats) report() *WebsocketReport {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return &WebsocketReport{
+		Connections:   stats.connections,
+		ConnectErrors: stats.connectErrors,
+		Disconnects:   stats.disconnects,
+		Sent:          stats.sent,
+		Received:      stats.received,
+		Matched:       stats.matched,
+		Unmatched:     stats.unmatched,
+		Errors:        stats.errors,
+		Connect:       phaseReport(stats.connect),
+	}
+}
+
+// schemaHeaders - headers of schema with credentials for modes without fasthttp requests
+func (attack *Attack) schemaHeaders(task rest_contracts.Task) http.Header {
+	headers := http.Header{}
+	for key, value := range task.Schema.Headers {
+		if key == OptionsHeader {
+			continue
+		}
+		headers.Set(key, value)
+	}
+	if attack.options.Auth.configured() {
+		headers.Del("Authorization")
+		if authorization := attack.options.Auth.addressAuthorization(task.Script.Address); authorization != "" {
+			headers.Set("Authorization", authorization)
+		}
+	}
+	return headers
+}
+
+/*
+startWebsocketAttack - opens connections to the target and sends messages with rps of the task
+divided between connections during time of the task
+*/
+func (attack *Attack) startWebsocketAttack(task rest_contracts.Task) {
+	options := attack.options.Websocket
+	connections := options.Connections
+	if connections <= 0 {
+		connections = currentWorkers
+	}
+	attack.resultWebsocket = &websocketStats{
+		connect: tachymeter.New(&tachymeter.Config{Size: connections * 10}),
+	}
+	interval := time.Duration(float64(time.Second) * float64(connections) / float64(task.Script.Config.Rps))
+	deadline := time.Now().Add(time.Duration(task.Script.Config.Time) * time.Second)
+	var wg sync.WaitGroup
+	for index := 0; index < connections; index++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attack.runWebsocketUser(task, interval, deadline)
+		}()
+	}
+	wg.Wait()
+}
+
+func (attack *Attack) dialWebsocket(task rest_contracts.Task) (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		NetDial: func(_, addr string) (net.Conn, error) {
+			return attack.dialer.Dial(addr)
+		},
+		HandshakeTimeout: websocketHandshakeTimeout,
+		TLSClientConfig:  attack.options.TLS.tlsConfig(),
+	}
+	timeStart := time.Now()
+	conn, response, err := dialer.DialContext(context.Background(), task.Script.Address, attack.schemaHeaders(task))
+	stats := attack.resultWebsocket
+	attack.results.Lock()
+	if response != nil {
+		attack.resultsAttack[int32(response.StatusCode)]++
+	}
+	attack.results.Unlock()
+	if err != nil {
+		attack.countError(err)
+		stats.mutex.Lock()
+		stats.connectErrors++
+		stats.mutex.Unlock()
+		return nil, err
+	}
+	stats.connect.AddTime(time.Since(timeStart))
+	stats.mutex.Lock()
+	stats.connections++
+	stats.mutex.Unlock()
+	return conn, nil
+}
+
+func (attack *Attack) websocketMessage(task rest_contracts.Task) ([]byte, error) {
+	if attack.options.Websocket.Message != "" {
+		return []byte(renderTemplate(attack.options.Websocket.Message, task.Schema.Body)), nil
+	}
+	return attack.preparingBody(task.Schema.Body)
+}
+
+func (attack *Attack) runWebsocketUser(task rest_contracts.Task, interval time.Duration, deadline time.Time) {
+	options := attack.options.Websocket
+	stats := attack.resultWebsocket
+	replyTimeout := time.Duration(options.ReplyTimeoutMs) * time.Millisecond
+	if replyTimeout <= 0 {
+		replyTimeout = defaultReplyTimeout
+	}
+	var conn *websocket.Conn
+	defer func() {
+		if conn != nil {
+			conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+			conn.Close()
+		}
+	}()
+	attackCtx := attack.attackContext()
+	for time.Now().Before(deadline) && attackCtx.Err() == nil {
+		timeStart := time.Now()
+		if conn == nil {
+			var err error
+			if conn, err = attack.dialWebsocket(task); err != nil {
+				logrus.Debug("Can not connect to websocket: ", err)
+				time.Sleep(interval)
+				continue
+			}
+		}
+		message, err := attack.websocketMessage(task)
+		if err != nil {
+			logrus.Error("Can not form websocket message: ", err)
+			return
+		}
+		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			attack.websocketFailed(conn, err)
+			conn = nil
+			continue
+		}
+		stats.mutex.Lock()
+		stats.sent++
+		stats.mutex.Unlock()
+		conn.SetReadDeadline(time.Now().Add(replyTimeout))
+		_, reply, err := conn.ReadMessage()
+		if err != nil {
+			attack.websocketFailed(conn, err)
+			conn = nil
+			continue
+		}
+		rtt := time.Since(timeStart)
+		attack.tahometr.AddTime(rtt)
+		stats.mutex.Lock()
+		stats.received++
+		if options.Expect == "" || strings.Contains(string(reply), options.Expect) {
+			stats.matched++
+		} else {
+			stats.unmatched++
+		}
+		stats.mutex.Unlock()
+		attack.results.Lock()
+		attack.recordLatency(rtt.Nanoseconds(), noStatus, false, len(reply))
+		attack.results.Unlock()
+		if elapsed := time.Since(timeStart); elapsed < interval {
+			time.Sleep(interval - elapsed)
+		}
+	}
+}
+
+// websocketFailed - counts broken connection, reply timeouts are counted as timeouts of requests
+func (attack *Attack) websocketFailed(conn *websocket.Conn, err error) {
+	stats := attack.resultWebsocket
+	conn.Close()
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		attack.results.Lock()
+		attack.recordTimeout(ErrorTimeout)
+		attack.results.Unlock()
+		return
+	}
+	attack.countError(err)
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if _, closed := err.(*websocket.CloseError); closed || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
+		stats.disconnects++
+		return
+	}
+	stats.errors++
+}
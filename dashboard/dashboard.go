@@ -0,0 +1,107 @@
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ModeOff  = "off"
+	ModeOn   = "on"
+	ModeAuto = "auto" // only when stdout is a terminal
+
+	refreshInterval = time.Second
+
+	clearScreen = "\033[H\033[2J"
+	bold        = "\033[1m"
+	red         = "\033[31m"
+	reset       = "\033[0m"
+)
+
+// Enabled - dashboard is drawn in mode on and in mode auto if bomber runs in a terminal
+func Enabled(mode string) bool {
+	switch mode {
+	case ModeOn:
+		return true
+	case ModeAuto:
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0
+	}
+	return false
+}
+
+/*
+Run - redraws live results of running attack every second until stop is closed.
+Logs would break the screen, so they are discarded while dashboard is drawn
+*/
+func Run(attack *core.Attack, target int64, stop chan struct{}) {
+	logs := logrus.StandardLogger().Out
+	logrus.SetOutput(ioutil.Discard)
+	defer logrus.SetOutput(logs)
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			draw(os.Stdout, attack.FormInterimResult(), target, metrics.InFlight())
+		case <-stop:
+			draw(os.Stdout, attack.FormInterimResult(), target, metrics.InFlight())
+			return
+		}
+	}
+}
+
+func draw(out io.Writer, result *core.InterimResult, target int64, inFlight int64) {
+	var screen bytes.Buffer
+	window := result.Window
+	elapsed := time.Duration(result.ElapsedMs) * time.Millisecond
+	screen.WriteString(clearScreen)
+	fmt.Fprintf(&screen, "%sAttack %s%s  bomber %s  elapsed %s\n\n", bold, result.FormId, reset, result.BomberId, elapsed.Round(time.Second))
+	fmt.Fprintf(&screen, "rps        %8.1f of %d\n", window.Rps, target)
+	fmt.Fprintf(&screen, "in flight  %8d\n", inFlight)
+	fmt.Fprintf(&screen, "completed  %8d\n", result.Completed)
+	fmt.Fprintf(&screen, "failed     %8d\n\n", result.Timeouts)
+	fmt.Fprintf(&screen, "%slatency         last bucket       total%s\n", bold, reset)
+	row(&screen, "p50", window.P50Ns, result.Latency.P50Ns)
+	row(&screen, "p90", window.P90Ns, result.Latency.P90Ns)
+	row(&screen, "p99", window.P99Ns, result.Latency.P99Ns)
+	row(&screen, "max", window.MaxNs, result.Latency.MaxNs)
+	fmt.Fprintf(&screen, "\n%sstatuses%s\n", bold, reset)
+	statuses := make([]int, 0, len(result.Statuses))
+	for status := range result.Statuses {
+		statuses = append(statuses, int(status))
+	}
+	sort.Ints(statuses)
+	for _, status := range statuses {
+		color := ""
+		if status >= 400 {
+			color = red
+		}
+		fmt.Fprintf(&screen, "%s%-10d %8d%s\n", color, status, result.Statuses[int32(status)], reset)
+	}
+	if len(result.Errors) > 0 {
+		fmt.Fprintf(&screen, "\n%serrors%s\n", bold, reset)
+		categories := make([]string, 0, len(result.Errors))
+		for category := range result.Errors {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			fmt.Fprintf(&screen, "%s%-20s %8d%s\n", red, category, result.Errors[category], reset)
+		}
+	}
+	out.Write(screen.Bytes())
+}
+
+func row(screen *bytes.Buffer, name string, window int64, total int64) {
+	fmt.Fprintf(screen, "%-10s %16s %11s\n", name, time.Duration(window).Round(time.Microsecond), time.Duration(total).Round(time.Microsecond))
+}
@@ -3,16 +3,24 @@ module github.com/bomber-team/rest-bomber
 go 1.15
 
 require (
+	github.com/HdrHistogram/hdrhistogram-go v1.0.1
 	github.com/bomber-team/bomber-proto-contracts/golang v0.2.15
-	github.com/gogo/protobuf v1.3.1 // indirect
-	github.com/golang/protobuf v1.4.3 // indirect
+	github.com/gogo/protobuf v1.3.1
+	github.com/golang/protobuf v1.4.3
 	github.com/google/uuid v1.1.2
-	github.com/goreflect/gostructor v0.4.5
+	github.com/gorilla/websocket v1.4.2
 	github.com/jamiealquiza/tachymeter v2.0.0+incompatible
+	github.com/klauspost/compress v1.10.7
 	github.com/lucasjones/reggen v0.0.0-20200904144131-37ba4fa293bb
 	github.com/nats-io/nats-server/v2 v2.1.9 // indirect
 	github.com/nats-io/nats.go v1.10.0
+	github.com/nats-io/nkeys v0.1.4
+	github.com/prometheus/client_golang v1.8.0
+	github.com/prometheus/client_model v0.2.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/valyala/fasthttp v1.17.0
-	google.golang.org/protobuf v1.25.0 // indirect
+	golang.org/x/sys v0.0.0-20201015000850-e3ed0017c211
+	google.golang.org/grpc v1.33.2
+	google.golang.org/protobuf v1.25.0
+	gopkg.in/yaml.v2 v2.3.0
 )
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/sirupsen/logrus"
+)
+
+// TasksSnapshot - tasks of the bomber, running task is empty if the bomber is idle
+type TasksSnapshot struct {
+	BomberId string `json:"bomber_id"`
+	// the earliest of running tasks, all of them are in attacks
+	Running string              `json:"running,omitempty"`
+	Attacks []string            `json:"attacks,omitempty"`
+	Paused  bool                `json:"paused"`
+	Queued  []TaskQueuePosition `json:"queued"`
+}
+
+// LastResult - outcome of the last attack of the bomber
+type LastResult struct {
+	TaskID   string             `json:"task_id"`
+	Status   int                `json:"status"`
+	Finished time.Time          `json:"finished"`
+	Report   *core.AttackReport `json:"report,omitempty"`
+}
+
+type lastResult struct {
+	mutex  sync.Mutex
+	result *LastResult
+}
+
+func (last *lastResult) store(result *LastResult) {
+	last.mutex.Lock()
+	defer last.mutex.Unlock()
+	last.result = result
+}
+
+func (last *lastResult) load() *LastResult {
+	last.mutex.Lock()
+	defer last.mutex.Unlock()
+	return last.result
+}
+
+func (core *CoreHandlers) Tasks() TasksSnapshot {
+	running, queued := core.tasks.snapshot()
+	snapshot := TasksSnapshot{
+		BomberId: core.config.CurrentServiceID,
+		Attacks:  running,
+		Paused:   core.tasks.isPaused(),
+		Queued:   queued,
+	}
+	if len(running) > 0 {
+		snapshot.Running = running[0]
+	}
+	return snapshot
+}
+
+// LastResult - nil if the bomber did not finish any attack yet
+func (core *CoreHandlers) LastResult() *LastResult {
+	return core.results.load()
+}
+
+// Pause - the bomber finishes its running task, queued and new tasks wait until Resume
+func (core *CoreHandlers) Pause() {
+	logrus.Info("Bomber is paused")
+	core.tasks.pause()
+}
+
+func (core *CoreHandlers) Resume() {
+	logrus.Info("Bomber is resumed")
+	core.tasks.resume()
+}
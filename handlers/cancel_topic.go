@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const taskTopicCancel = "bombers.cancel.tasks"
+
+// CancelTask - cancel of the task is sent to all bombers, only bombers with this task stop their attacks
+type CancelTask struct {
+	TaskID string `json:"task_id"`
+}
+
+type CancelTopicHandler struct {
+	subscriber *nats_listener.Subscriber
+	publisher  *nats_listener.Publisher
+	core       *core.Core
+	tasks      *taskQueue
+	verifier   *nats_listener.Verifier
+	bracket    chan int
+}
+
+func newCancelTopicHandler(bus broker.Broker, core *core.Core, tasks *taskQueue, verifier *nats_listener.Verifier) *CancelTopicHandler {
+	return &CancelTopicHandler{
+		subscriber: nats_listener.NewSubscriber(bus, taskTopicCancel),
+		publisher:  nats_listener.NewPublisher(bus),
+		core:       core,
+		tasks:      tasks,
+		verifier:   verifier,
+	}
+}
+
+func (handl *CancelTopicHandler) Configuration(signal chan int) error {
+	logrus.Info("Start cancel topic handler")
+	errSubscription := handl.subscriber.Subscribe(handl.handle)
+	handl.bracket = signal
+	if errSubscription != nil {
+		return errSubscription
+	}
+	return nil
+}
+
+func (handl *CancelTopicHandler) handle(message *broker.Message) {
+	logrus.Info("Handled request by cancel topic handler. Subject: ", message.Subject, "Data: ", string(message.Data))
+	data, err := handl.verifier.Open(broker.SubjectOf(handl.publisher.Broker, message.Subject), message.Data)
+	if err != nil {
+		logrus.Error("Can not verify cancel from bomber server: ", err)
+		return
+	}
+	var paylaod CancelTask
+	if err := json.Unmarshal(data, &paylaod); err != nil {
+		logrus.Error("Can not unmarshal cancel from bomber server: ", err)
+		return
+	}
+	handl.cancel(paylaod.TaskID)
+}
+
+// cancel - false if the bomber neither attacks by the task nor has it in queue
+func (handl *CancelTopicHandler) cancel(taskId string) bool {
+	if handl.core.Cancel(taskId) {
+		logrus.Info("Attack of task ", taskId, " was cancelled")
+		return true
+	}
+	// queued task is not attacked at all, it has no results
+	if handl.tasks.remove(taskId) {
+		logrus.Info("Queued task ", taskId, " was cancelled")
+		formatResultStatusTask(taskId, CANCELLED_ATTACK, handl.publisher)
+		return true
+	}
+	return false
+}
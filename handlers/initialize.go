@@ -4,36 +4,93 @@ import (
 	"errors"
 
 	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
 	"github.com/bomber-team/rest-bomber/core"
 	"github.com/bomber-team/rest-bomber/nats_listener"
 	"github.com/bomber-team/rest-bomber/tools"
-	"github.com/nats-io/nats.go"
 	"github.com/sirupsen/logrus"
 )
 
 type CoreHandlers struct {
-	connection      *nats.Conn
+	broker          broker.Broker
+	bomber          *core.Core
+	tasks           *taskQueue
+	taskHandler     *TaskTopicHandler
+	cancelHandler   *CancelTopicHandler
+	reloadHandler   *ReloadTopicHandler
+	results         *lastResult
 	currentHandlers []IHandlerTopic
-	config          *nats_listener.NatsConnectionConfiguration
+	config          *config.Configuration
 }
 
-func NewCoreHandlers(core *core.Core) (*CoreHandlers, error) {
+// NewCoreHandlers - reloader applies reloaded configuration to sinks of attacks
+func NewCoreHandlers(core *core.Core, reloader *config.Reloader) (*CoreHandlers, error) {
+	verifier, err := nats_listener.NewVerifier(core.GetConfig())
+	if err != nil {
+		logrus.Error("Can not verify messages from bomber server: ", err)
+		return nil, err
+	}
+	tasks := newTaskQueue(core, nats_listener.NewPublisher(core.GetBroker()), core.GetConfig())
+	taskHandler := newTaskTopicHandler(core.GetBroker(), core, core.GetConfig(), tasks, verifier)
+	results := &lastResult{}
+	starterHandler := newStarterTaskTopicHandler(core.GetBroker(), core, core.GetConfig(), tasks, results)
+	cancelHandler := newCancelTopicHandler(core.GetBroker(), core, tasks, verifier)
+	reloadHandler := newReloadTopicHandler(core.GetBroker(), reloader, core.GetConfig().CurrentServiceID, verifier)
+	taskHandler.started = starterHandler.start
+	reloader.OnReload(starterHandler.reloadSinks)
 	return &CoreHandlers{
-		connection: core.GetConnection(),
+		broker:        core.GetBroker(),
+		bomber:        core,
+		tasks:         tasks,
+		taskHandler:   taskHandler,
+		cancelHandler: cancelHandler,
+		reloadHandler: reloadHandler,
+		results:       results,
 		currentHandlers: []IHandlerTopic{
-			newTaskTopicHandler(core.GetConnection(), core, core.GetConfig()),
-			newStarterTaskTopicHandler(core.GetConnection(), core, core.GetConfig()),
+			taskHandler,
+			starterHandler,
+			cancelHandler,
+			reloadHandler,
 		},
 		config: core.GetConfig(),
 	}, nil
 }
 
+/*
+StartAttack - task given directly, not by NATS, it is acknowledged as task sent by request.
+It is opened by the same verifier as tasks of NATS, as if it came into the subject of tasks of the bomber
+*/
+func (core *CoreHandlers) StartAttack(task []byte) *TaskAck {
+	subject := broker.SubjectOf(core.taskHandler.publisher.Broker, taskTopicName+core.config.CurrentServiceID)
+	data, err := core.taskHandler.verifier.Open(subject, task)
+	if err != nil {
+		logrus.Error("Can not verify task of control api: ", err)
+		return core.taskHandler.unverified()
+	}
+	ack := core.taskHandler.accept(data, true)
+	if ack.Accepted && ack.QueuePosition == 0 {
+		go core.taskHandler.configure(data)
+	}
+	return ack
+}
+
+// Reload - configuration of the bomber is read again, as by reload topic
+func (core *CoreHandlers) Reload() *ReloadAnswer {
+	return core.reloadHandler.reload()
+}
+
+// CancelAttack - false if the bomber neither attacks by the task nor has it in queue
+func (core *CoreHandlers) CancelAttack(taskId string) bool {
+	return core.cancelHandler.cancel(taskId)
+}
+
 const (
 	bomberInitTopic = "bombers.server.init_bomber"
 	bomberDownTopic = "bombers.server.delete"
 )
 
-func (core *CoreHandlers) TestSendTask(config *nats_listener.NatsConnectionConfiguration) error {
+func (core *CoreHandlers) TestSendTask(config *config.Configuration) error {
 	data := rest_contracts.Task{
 		FormId: "test",
 		Schema: &rest_contracts.RestSchema{
@@ -54,7 +111,7 @@ func (core *CoreHandlers) TestSendTask(config *nats_listener.NatsConnectionConfi
 	}
 	res, _ := data.Marshal()
 
-	err := nats_listener.NewPublisher(core.connection).PublishNewMessage("bombers.tasks."+config.CurrentServiceID, res)
+	err := nats_listener.NewPublisher(core.broker).PublishNewMessage("bombers.tasks."+config.CurrentServiceID, res)
 	if err != nil {
 		logrus.Error("Error while init bomber: ", err)
 	}
@@ -84,7 +141,7 @@ func (core *CoreHandlers) initBomber() error {
 	}
 	data, _ := res.Marshal()
 
-	err := nats_listener.NewPublisher(core.connection).PublishNewMessage(bomberInitTopic, data)
+	err := nats_listener.NewPublisher(core.broker).PublishNewMessage(bomberInitTopic, data)
 	if err != nil {
 		logrus.Error("Error while init bomber: ", err)
 	}
@@ -108,7 +165,7 @@ func (core *CoreHandlers) ShutdownToServer() {
 		BomberId: core.config.CurrentServiceID,
 	}
 	data, _ := res.Marshal()
-	err := nats_listener.NewPublisher(core.connection).PublishNewMessage(bomberDownTopic, data)
+	err := nats_listener.NewPublisher(core.broker).PublishNewMessage(bomberDownTopic, data)
 	if err != nil {
 		logrus.Error("Error while send deleting bomber from server: ", err)
 	}
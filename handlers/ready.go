@@ -0,0 +1,27 @@
+package handlers
+
+import "errors"
+
+var (
+	ErrBrokerDisconnected = errors.New("not connected to broker")
+	ErrShuttingDown       = errors.New("bomber is shutting down")
+	ErrOverloaded         = errors.New("bomber is overloaded: task queue is full")
+	ErrPaused             = errors.New("bomber is paused")
+)
+
+// Ready - nil while the bomber is connected to its broker and can take tasks
+func (core *CoreHandlers) Ready() error {
+	if !core.broker.Connected() {
+		return ErrBrokerDisconnected
+	}
+	if core.tasks.isClosed() {
+		return ErrShuttingDown
+	}
+	if core.tasks.isPaused() {
+		return ErrPaused
+	}
+	if core.tasks.full() {
+		return ErrOverloaded
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const topicReload = "bombers.reload.config"
+
+// ReloadAnswer - reply to reload command, error is set if configuration is invalid and nothing was applied
+type ReloadAnswer struct {
+	BomberId string `json:"bomber_id"`
+	*config.Reload
+	Error string `json:"error,omitempty"`
+}
+
+// ReloadTopicHandler - reload command is sent to all bombers, each of them reads its configuration again
+type ReloadTopicHandler struct {
+	subscriber *nats_listener.Subscriber
+	publisher  *nats_listener.Publisher
+	reloader   *config.Reloader
+	bomberId   string
+	verifier   *nats_listener.Verifier
+	bracket    chan int
+}
+
+func newReloadTopicHandler(bus broker.Broker, reloader *config.Reloader, bomberId string, verifier *nats_listener.Verifier) *ReloadTopicHandler {
+	return &ReloadTopicHandler{
+		subscriber: nats_listener.NewSubscriber(bus, topicReload),
+		publisher:  nats_listener.NewPublisher(bus),
+		reloader:   reloader,
+		bomberId:   bomberId,
+		verifier:   verifier,
+	}
+}
+
+func (handl *ReloadTopicHandler) Configuration(signal chan int) error {
+	logrus.Info("Start reload topic handler")
+	errSubscription := handl.subscriber.Subscribe(handl.handle)
+	handl.bracket = signal
+	if errSubscription != nil {
+		return errSubscription
+	}
+	return nil
+}
+
+func (handl *ReloadTopicHandler) handle(message *broker.Message) {
+	logrus.Info("Handled request by reload topic handler. Subject: ", message.Subject)
+	if _, err := handl.verifier.Open(broker.SubjectOf(handl.publisher.Broker, message.Subject), message.Data); err != nil {
+		logrus.Error("Can not verify reload from bomber server: ", err)
+		return
+	}
+	answer := handl.reload()
+	if message.Reply == "" {
+		return
+	}
+	data, err := json.Marshal(answer)
+	if err != nil {
+		logrus.Error("Can not marshal answer of reload: ", err)
+		return
+	}
+	if err := handl.publisher.PublishNewMessage(message.Reply, data); err != nil {
+		logrus.Error("Can not reply to reload: ", err)
+	}
+}
+
+func (handl *ReloadTopicHandler) reload() *ReloadAnswer {
+	answer := &ReloadAnswer{BomberId: handl.bomberId}
+	reload, err := handl.reloader.Reload()
+	if err != nil {
+		logrus.Error("Can not reload configuration: ", err)
+		answer.Error = err.Error()
+		return answer
+	}
+	answer.Reload = reload
+	return answer
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// time for publishing partial results of cancelled attack
+	shutdownAbortTimeout = 10 * time.Second
+	shutdownFlushTimeout = 5 * time.Second
+)
+
+/*
+GracefulShutdown - bomber stops taking tasks and rejects queued ones, in-flight attacks are finished
+during drain timeout or cancelled with partial results, then the bomber is announced down
+*/
+func (core *CoreHandlers) GracefulShutdown(drain time.Duration) {
+	logrus.Info("Graceful shutdown of bomber, drain timeout: ", drain)
+	for _, handler := range core.currentHandlers {
+		if consumer, ok := handler.(interface{ stopConsuming() }); ok {
+			consumer.stopConsuming()
+		}
+	}
+	publisher := nats_listener.NewPublisher(core.broker)
+	for _, task := range core.tasks.close() {
+		logrus.Info("Queued task ", task.formId, " is rejected by shutdown")
+		formatResultStatusTask(task.formId, REJECTED_TASK, publisher)
+	}
+	if !core.waitIdle(drain) {
+		for _, formId := range core.tasks.running() {
+			if core.bomber.Cancel(formId) {
+				logrus.Info("Attack of task ", formId, " is cancelled by shutdown")
+			}
+		}
+		if !core.waitIdle(shutdownAbortTimeout) {
+			logrus.Error("Can not complete task before shutdown in ", shutdownAbortTimeout)
+		}
+	}
+	core.bomber.GracefullDownService()
+	core.ShutdownToServer()
+	if err := core.broker.Flush(shutdownFlushTimeout); err != nil {
+		logrus.Error("Can not flush messages before shutdown: ", err)
+	}
+}
+
+// waitIdle - false if the bomber is still busy by its tasks after timeout
+func (core *CoreHandlers) waitIdle(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for !core.bomber.Idle() {
+		if !time.Now().Before(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// stopConsuming - tasks of the queue group are left to other bombers, addressed tasks are rejected
+func (handl *TaskTopicHandler) stopConsuming() {
+	if handl.queueSubscriber == nil {
+		return
+	}
+	if err := handl.queueSubscriber.Unsubscribe(); err != nil {
+		logrus.Error("Can not leave queue group of tasks: ", err)
+	}
+}
@@ -1,77 +1,261 @@
 package handlers
 
 import (
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
 	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/dashboard"
 	"github.com/bomber-team/rest-bomber/nats_listener"
-	"github.com/nats-io/nats.go"
+	"github.com/bomber-team/rest-bomber/sinks"
 	"github.com/sirupsen/logrus"
 )
 
+// StarterTopicHandler - runs attacks prepared by task topic handler, they are handed over in process
 type StarterTopicHandler struct {
-	subscriber *nats_listener.Subscriber
-	publisher  *nats_listener.Publisher
-	core       *core.Core
-	bracket    chan int
+	publisher *nats_listener.Publisher
+	core      *core.Core
+	tasks     *taskQueue
+	results   *lastResult
+	bracket   chan int
+	dashboard bool
+	mutex     sync.Mutex
+	opened    *attackSinks
+	// sinks are opened again by reloaded configuration before the next attack, running attacks keep their sinks
+	reloaded *config.Configuration
 }
 
-const (
-	taskTopicStarter = "bombers.starter.tasks."
-	taskTopicResult  = "bombers.server.task_result"
-	taskStatusResult = "bombers.server.task_status"
-)
+// attackSinks - sinks of the attack from its start until its results, there can be several attacks at once
+type attackSinks struct {
+	sinks   *sinks.Sinks
+	s3      *sinks.S3Sink
+	grafana *sinks.GrafanaSink
+}
+
+const taskStatusResult = "bombers.server.task_status"
+
+func newStarterTaskTopicHandler(bus broker.Broker, core *core.Core, config *config.Configuration, tasks *taskQueue, results *lastResult) *StarterTopicHandler {
+	handl := &StarterTopicHandler{
+		publisher: nats_listener.NewPublisher(bus),
+		core:      core,
+		tasks:     tasks,
+		results:   results,
+		dashboard: dashboard.Enabled(config.Dashboard),
+	}
+	handl.opened = handl.openSinks(config)
+	return handl
+}
 
-func newStarterTaskTopicHandler(conn *nats.Conn, core *core.Core, config *nats_listener.NatsConnectionConfiguration) *StarterTopicHandler {
-	return &StarterTopicHandler{
-		subscriber: nats_listener.NewSubscriber(conn, taskTopicStarter+config.CurrentServiceID),
-		publisher:  nats_listener.NewPublisher(conn),
-		core:       core,
+func (handl *StarterTopicHandler) openSinks(config *config.Configuration) *attackSinks {
+	return &attackSinks{
+		sinks: sinks.Open(config, handl.publisher),
+		s3: sinks.NewS3Sink(sinks.S3Options{
+			Endpoint:    config.S3Endpoint,
+			Region:      config.S3Region,
+			Bucket:      config.S3Bucket,
+			AccessKey:   config.S3AccessKey,
+			SecretKey:   config.S3SecretKey,
+			Prefix:      tenantPrefix(config),
+			MetadataURL: config.AWSMetadataURL,
+		}),
+		grafana: sinks.NewGrafanaSink(config.GrafanaURL, config.GrafanaToken, config.GrafanaDashboardUID),
 	}
 }
 
+// tenantPrefix - objects of the tenant are stored under its own prefix
+func tenantPrefix(config *config.Configuration) string {
+	if config.TenantId() == "" {
+		return config.S3Prefix
+	}
+	if config.S3Prefix == "" || config.S3Prefix == sinks.S3Disabled {
+		return config.TenantId()
+	}
+	return strings.TrimRight(config.S3Prefix, "/") + "/" + config.TenantId()
+}
+
+// reloadSinks - listener of reloaded configuration
+func (handl *StarterTopicHandler) reloadSinks(config *config.Configuration, reload *config.Reload) {
+	if !reload.Changed("ReportDir", "InfluxURL", "InfluxToken", "S3Endpoint", "S3Region", "S3Bucket", "S3AccessKey",
+		"S3SecretKey", "S3Prefix", "GrafanaURL", "GrafanaToken", "GrafanaDashboardUID") {
+		return
+	}
+	handl.mutex.Lock()
+	defer handl.mutex.Unlock()
+	handl.reloaded = config
+}
+
+// currentSinks - sinks for the next attack, they are opened by reloaded configuration if it changed them
+func (handl *StarterTopicHandler) currentSinks() *attackSinks {
+	handl.mutex.Lock()
+	defer handl.mutex.Unlock()
+	if handl.reloaded != nil {
+		handl.opened = handl.openSinks(handl.reloaded)
+		handl.reloaded = nil
+	}
+	return handl.opened
+}
+
 func (handl *StarterTopicHandler) Configuration(signal chan int) error {
 	logrus.Info("Start starter topic handler")
-	errSubscription := handl.subscriber.Subscribe(handl.handle)
 	handl.bracket = signal
-	if errSubscription != nil {
-		return errSubscription
-	}
 	return nil
 }
 
-func (handl *StarterTopicHandler) handle(message *nats.Msg) {
-	logrus.Info("Handled request by task topic starter handler. Subject: ", message.Subject, "Data: ", string(message.Data))
-	// starting task
-	var paylaod rest_contracts.Task
-	if err := paylaod.Unmarshal(message.Data); err != nil {
-		logrus.Error("Can not unmarshal message from bomber server: ", err)
-		return
-	}
+// start - attack runs aside of the caller, so the bomber with spare capacity starts the next attack meanwhile
+func (handl *StarterTopicHandler) start(formId string) {
+	go handl.attack(formId)
+}
+
+// attack - runs the task the attack was prepared by, never a task from the broker
+func (handl *StarterTopicHandler) attack(formId string) {
+	defer handl.tasks.release(formId)
+	out := handl.currentSinks()
 
-	logrus.Info("Starting checking reading for attack: ", paylaod.FormId)
-	if handl.core.CheckReady() {
+	logrus.Info("Starting checking reading for attack: ", formId)
+	attack := handl.core.Attack(formId)
+	if attack != nil && attack.CheckReady() {
+		paylaod := attack.Task()
 		logrus.Debug("Attack REady")
 		var wg sync.WaitGroup
 		wg.Add(1)
+		annotation := handl.annotateStarted(out, paylaod)
+		outcome := sinks.OutcomeAborted
+		var report *core.AttackReport
+		defer func() {
+			handl.annotateFinished(out, annotation, outcome, report)
+		}()
+		attack.WarmUp()
 		timeStart := time.Now()
-		handl.core.Start(paylaod, &wg)
+		if attack.Prewarming() {
+			handl.annotateStage(out, annotation, "attack", timeStart)
+		}
+		stopInterim := make(chan struct{})
+		go handl.publishInterim(out, attack, stopInterim)
+		stopDashboard := make(chan struct{})
+		dashboardDone := make(chan struct{})
+		if handl.dashboard {
+			go func() {
+				dashboard.Run(attack, attack.ShardRps(paylaod.Script.Config.Rps), stopDashboard)
+				close(dashboardDone)
+			}()
+		} else {
+			close(dashboardDone)
+		}
+		attack.Start(paylaod, &wg)
 		wg.Wait()
+		close(stopInterim)
+		close(stopDashboard)
+		<-dashboardDone
 		timeEnd := time.Since(timeStart)
+		attack.Teardown()
 		logrus.Debug("Attacks completed. Start extracting data")
-		result := handl.core.FormResultAttack()
+		result := attack.FormResultAttack()
 		result.ElapsedTimeAttack = timeEnd.Nanoseconds()
 		result.BomberId = handl.core.GetConfig().CurrentServiceID
 		logrus.Debug("Summary estimated time for attack: ", timeEnd.Nanoseconds(), " ns")
-		marshaledData, err := result.Marshal()
-		if err != nil {
-			logrus.Error("Error marshaled result attack: ", err)
-			formatResultStatusTask(paylaod.FormId, ERROR_ATTACK, handl.publisher)
+		object := handl.uploadResult(out, result, timeStart)
+		report = attack.FormReportAttack()
+		report.ResultObject = object
+		errPublish := out.sinks.PublishFinal(&sinks.FinalResult{
+			Task:    paylaod,
+			Started: timeStart,
+			Result:  result,
+			Report:  report,
+		})
+		if errPublish != nil {
+			logrus.Error("Error while publish result by task: ", errPublish)
+			handl.finish(attack, ERROR_ATTACK, report)
+			return
+		}
+		if errAttack := attack.Failure(); errAttack != nil {
+			logrus.Error("Attack of task ", paylaod.FormId, " failed: ", errAttack)
+			handl.finish(attack, ERROR_ATTACK, report)
+			return
+		}
+		if report.PreemptedBy != "" {
+			outcome = sinks.OutcomeCancelled
+			handl.finish(attack, PREEMPTED_ATTACK, report)
+			return
+		}
+		if report.Cancelled {
+			outcome = sinks.OutcomeCancelled
+			handl.finish(attack, CANCELLED_ATTACK, report)
+			return
+		}
+		outcome = sinks.OutcomeCompleted
+		handl.finish(attack, COMPLETED_ATTACK, report)
+	}
+}
+
+// finish - publishes status of the attack, it is the last result of the bomber then
+func (handl *StarterTopicHandler) finish(attack *core.Attack, status int, report *core.AttackReport) {
+	formatResultStatusTask(attack.FormId(), status, handl.publisher)
+	attack.EndProgress()
+	handl.results.store(&LastResult{TaskID: attack.FormId(), Status: status, Finished: time.Now(), Report: report})
+}
+
+// uploadResult - nil if object storage is disabled or upload failed, full result is published into NATS then
+func (handl *StarterTopicHandler) uploadResult(out *attackSinks, result *rest_contracts.BomberResult, started time.Time) *core.ResultObjectReport {
+	if out.s3 == nil {
+		return nil
+	}
+	object, err := out.s3.Upload(result, started)
+	if err != nil {
+		logrus.Error("Can not upload result into object storage: ", err)
+		return nil
+	}
+	logrus.Info("Result of attack was uploaded to ", object.URL)
+	return object
+}
+
+// annotateStarted - nil if annotations are disabled or grafana is not available
+func (handl *StarterTopicHandler) annotateStarted(out *attackSinks, task rest_contracts.Task) *sinks.GrafanaAnnotation {
+	if out.grafana == nil {
+		return nil
+	}
+	annotation, err := out.grafana.Started(task, handl.core.GetConfig().CurrentServiceID, time.Now())
+	if err != nil {
+		logrus.Error("Can not annotate start of attack in grafana: ", err)
+		return nil
+	}
+	return annotation
+}
+
+func (handl *StarterTopicHandler) annotateStage(out *attackSinks, annotation *sinks.GrafanaAnnotation, stage string, moment time.Time) {
+	if annotation == nil {
+		return
+	}
+	if err := out.grafana.Stage(annotation, stage, moment); err != nil {
+		logrus.Error("Can not annotate stage of attack in grafana: ", err)
+	}
+}
+
+func (handl *StarterTopicHandler) annotateFinished(out *attackSinks, annotation *sinks.GrafanaAnnotation, outcome string, report *core.AttackReport) {
+	if annotation == nil {
+		return
+	}
+	if err := out.grafana.Finished(annotation, outcome, report, time.Now()); err != nil {
+		logrus.Error("Can not annotate end of attack in grafana: ", err)
+	}
+}
+
+func (handl *StarterTopicHandler) publishInterim(out *attackSinks, attack *core.Attack, stop chan struct{}) {
+	interval := attack.InterimInterval()
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			out.sinks.PublishInterim(attack.FormInterimResult())
+		case <-stop:
 			return
 		}
-		handl.publisher.PublishNewMessage(taskTopicResult, marshaledData)
-		formatResultStatusTask(paylaod.FormId, COMPLETED_ATTACK, handl.publisher)
 	}
 }
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	rejectInvalidTask  = "invalid task"
+	rejectBusy         = "bomber is busy"
+	rejectIncompatible = "incompatible contract version"
+	rejectQueueFull    = "task queue is full"
+	rejectDuplicate    = "duplicate ignored"
+	rejectShutdown     = "bomber is shutting down"
+	rejectUnverified   = "task can not be verified"
+)
+
+/*
+TaskAck - reply to task sent by request, orchestrator knows which bomber took the task
+without waiting for its statuses. Estimated start is known only for tasks started right away,
+queued tasks have their position in queue
+*/
+type TaskAck struct {
+	TaskID          string            `json:"task_id"`
+	BomberId        string            `json:"bomber_id"`
+	Tenant          string            `json:"tenant,omitempty"`
+	ContractVersion string            `json:"contract_version"`
+	Accepted        bool              `json:"accepted"`
+	Reason          string            `json:"reason,omitempty"`
+	Errors          []core.FieldError `json:"errors,omitempty"`
+	EstimatedStart  *time.Time        `json:"estimated_start,omitempty"`
+	QueuePosition   int               `json:"queue_position,omitempty"`
+}
+
+// decide - accepted task engages the bomber or waits in queue, start is estimated by speed of preparing of previous task
+func (handl *TaskTopicHandler) decide(task *rest_contracts.Task, data []byte, fieldErrors []core.FieldError) TaskAck {
+	ack := TaskAck{
+		TaskID:          task.FormId,
+		BomberId:        handl.config.CurrentServiceID,
+		Tenant:          handl.config.TenantId(),
+		ContractVersion: core.ContractVersion,
+	}
+	switch {
+	case incompatibleContract(fieldErrors):
+		ack.Reason = rejectIncompatible
+		ack.Errors = fieldErrors
+		return ack
+	case len(fieldErrors) > 0:
+		ack.Reason = rejectInvalidTask
+		ack.Errors = fieldErrors
+		return ack
+	}
+	key := idempotencyKey(*task)
+	if first, duplicate := handl.dedup.claim(key); duplicate {
+		ack.Reason = rejectDuplicate
+		publishDuplicate(*task, key, first, handl.config.CurrentServiceID, handl.publisher)
+		return ack
+	}
+	position, admitted := handl.tasks.admit(newQueuedTask(*task, data))
+	if !admitted {
+		handl.dedup.forget(key)
+	}
+	switch {
+	case !admitted && handl.tasks.isClosed():
+		ack.Reason = rejectShutdown
+	case !admitted && handl.tasks.depth > 0:
+		ack.Reason = rejectQueueFull
+	case !admitted:
+		ack.Reason = rejectBusy
+	case position > 0:
+		ack.Accepted = true
+		ack.QueuePosition = position
+	default:
+		ack.Accepted = true
+		amount := task.Script.Config.Rps * task.Script.Config.Time
+		start := time.Now().Add(time.Duration(atomic.LoadInt64(&handl.preparingNsPerRequest) * amount))
+		ack.EstimatedStart = &start
+	}
+	return ack
+}
+
+// unverified - rejection of the task which envelope can not be opened, its id is unknown
+func (handl *TaskTopicHandler) unverified() *TaskAck {
+	return &TaskAck{
+		BomberId:        handl.config.CurrentServiceID,
+		Tenant:          handl.config.TenantId(),
+		ContractVersion: core.ContractVersion,
+		Reason:          rejectUnverified,
+	}
+}
+
+func incompatibleContract(fieldErrors []core.FieldError) bool {
+	for _, fieldError := range fieldErrors {
+		if fieldError.Field == core.ContractField {
+			return true
+		}
+	}
+	return false
+}
+
+func reply(message *broker.Message, ack TaskAck, publisher *nats_listener.Publisher) {
+	data, err := json.Marshal(ack)
+	if err != nil {
+		logrus.Error("Can not marshal acknowledgment of task: ", err)
+		return
+	}
+	if err := publisher.PublishNewMessage(message.Reply, data); err != nil {
+		logrus.Error("Can not reply to task: ", err)
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const taskDuplicate = "bombers.server.task_duplicate"
+
+// TaskDuplicate - notice of ignored delivery of the task, which was already taken by the bomber
+type TaskDuplicate struct {
+	TaskID          string    `json:"task_id"`
+	BomberId        string    `json:"bomber_id"`
+	ContractVersion string    `json:"contract_version"`
+	IdempotencyKey  string    `json:"idempotency_key"`
+	FirstSeen       time.Time `json:"first_seen"`
+}
+
+/*
+taskDeduplicator - keys of tasks taken by the bomber during the window, redeliveries of them are ignored.
+Keys of tasks which were not started are forgotten, so such tasks can be sent again
+*/
+type taskDeduplicator struct {
+	mutex  sync.Mutex
+	window time.Duration
+	seen   map[string]time.Time
+}
+
+// newTaskDeduplicator - nil if deduplication is disabled by zero window
+func newTaskDeduplicator(window time.Duration) *taskDeduplicator {
+	if window <= 0 {
+		return nil
+	}
+	return &taskDeduplicator{window: window, seen: map[string]time.Time{}}
+}
+
+// idempotencyKey - key from options of the task, form id if it is not set
+func idempotencyKey(task rest_contracts.Task) string {
+	if options, err := core.ParseTaskOptions(task); err == nil && options.IdempotencyKey != "" {
+		return options.IdempotencyKey
+	}
+	return task.FormId
+}
+
+// claim - true and time of the first delivery if the key was already taken during the window
+func (dedup *taskDeduplicator) claim(key string) (time.Time, bool) {
+	if dedup == nil || key == "" {
+		return time.Time{}, false
+	}
+	dedup.mutex.Lock()
+	defer dedup.mutex.Unlock()
+	now := time.Now()
+	for seenKey, seen := range dedup.seen {
+		if now.Sub(seen) > dedup.window {
+			delete(dedup.seen, seenKey)
+		}
+	}
+	if first, ok := dedup.seen[key]; ok {
+		return first, true
+	}
+	dedup.seen[key] = now
+	return time.Time{}, false
+}
+
+func (dedup *taskDeduplicator) forget(key string) {
+	if dedup == nil {
+		return
+	}
+	dedup.mutex.Lock()
+	defer dedup.mutex.Unlock()
+	delete(dedup.seen, key)
+}
+
+func publishDuplicate(task rest_contracts.Task, key string, first time.Time, bomberId string, publisher *nats_listener.Publisher) {
+	logrus.Info("Duplicate delivery of task ", task.FormId, " with key ", key, " is ignored")
+	duplicateMarshaled, err := json.Marshal(TaskDuplicate{
+		TaskID:          task.FormId,
+		BomberId:        bomberId,
+		ContractVersion: core.ContractVersion,
+		IdempotencyKey:  key,
+		FirstSeen:       first,
+	})
+	if err != nil {
+		logrus.Error("Error forming notice of duplicate task: ", err)
+		return
+	}
+	if errPublish := publisher.PublishNewMessage(taskDuplicate, duplicateMarshaled); errPublish != nil {
+		logrus.Error("Error while publish notice of duplicate task: ", errPublish)
+	}
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+const taskQueuePositions = "bombers.server.task_queue"
+
+// TaskQueuePosition - position of the task waiting in queue of the bomber, 1 is the next task
+type TaskQueuePosition struct {
+	TaskID          string `json:"task_id"`
+	BomberId        string `json:"bomber_id"`
+	ContractVersion string `json:"contract_version"`
+	Position        int    `json:"position"`
+	Depth           int    `json:"depth"`
+	Priority        int    `json:"priority"`
+}
+
+type queuedTask struct {
+	formId   string
+	data     []byte
+	priority int
+	preempt  bool
+}
+
+// newQueuedTask - priority of the task is taken from its options, invalid options give default priority
+func newQueuedTask(task rest_contracts.Task, data []byte) queuedTask {
+	queued := queuedTask{formId: task.FormId, data: data}
+	if options, err := core.ParseTaskOptions(task); err == nil {
+		queued.priority = options.Priority
+		queued.preempt = options.Preempt
+	}
+	return queued
+}
+
+/*
+taskQueue - tasks which came while the bomber is busy, they are started by priority and in order
+of arrival within the same priority. Engagement of the bomber is changed under lock of the queue,
+so a task can not stay in queue of the bomber with spare capacity
+*/
+type taskQueue struct {
+	mutex     sync.Mutex
+	tasks     []queuedTask
+	depth     int
+	core      *core.Core
+	publisher *nats_listener.Publisher
+	bomberId  string
+	// tasks which engaged the bomber, in order of their start
+	current []queuedTask
+	// bomber is shutting down, it does not take tasks anymore
+	closed bool
+	// queued tasks are not started until resume, new tasks are queued even if the bomber is idle
+	paused bool
+	// configures the next task of the queue, which has already engaged the bomber
+	start func(data []byte) error
+}
+
+func newTaskQueue(core *core.Core, publisher *nats_listener.Publisher, config *config.Configuration) *taskQueue {
+	return &taskQueue{
+		depth:     config.TaskQueueDepth,
+		core:      core,
+		publisher: publisher,
+		bomberId:  config.CurrentServiceID,
+	}
+}
+
+// idle - the bomber has spare capacity and nobody waits in queue
+func (queue *taskQueue) idle() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return !queue.closed && !queue.paused && len(queue.tasks) == 0 && queue.core.Spare()
+}
+
+// engage - true if the bomber has spare capacity and nobody waits in queue, the bomber is engaged by the task then
+func (queue *taskQueue) engage(task queuedTask) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if queue.closed || queue.paused || len(queue.tasks) > 0 || !queue.core.TryEngage() {
+		return false
+	}
+	queue.current = append(queue.current, task)
+	return true
+}
+
+/*
+admit - engages the bomber with spare capacity by the task or puts the task into queue. Position is 0 if the task
+engaged the bomber, false if the bomber is busy and queue is full. Preempting task of higher priority
+than the running one of the least priority and all queued ones cancels that running task and is the next task of the queue
+*/
+func (queue *taskQueue) admit(task queuedTask) (int, bool) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	if queue.closed {
+		return 0, false
+	}
+	if !queue.paused && len(queue.tasks) == 0 && queue.core.TryEngage() {
+		queue.current = append(queue.current, task)
+		return 0, true
+	}
+	if len(queue.tasks) >= queue.depth {
+		return 0, false
+	}
+	position := len(queue.tasks)
+	for index, queued := range queue.tasks {
+		if queued.priority < task.priority {
+			position = index
+			break
+		}
+	}
+	// tasks of the same or higher priority waiting before the task are not preempted
+	if running, ok := queue.leastPriority(); ok && position == 0 && task.preempt && task.priority > running.priority &&
+		queue.core.Preempt(running.formId, task.formId) {
+		logrus.Info("Task ", running.formId, " is preempted by task ", task.formId)
+	}
+	queue.tasks = append(queue.tasks, queuedTask{})
+	copy(queue.tasks[position+1:], queue.tasks[position:])
+	queue.tasks[position] = task
+	queue.publishPositions(position)
+	return position + 1, true
+}
+
+// leastPriority - running task which is preempted first, the latest started of the same priority. Called under lock
+func (queue *taskQueue) leastPriority() (queuedTask, bool) {
+	if len(queue.current) == 0 {
+		return queuedTask{}, false
+	}
+	least := queue.current[0]
+	for _, running := range queue.current[1:] {
+		if running.priority <= least.priority {
+			least = running
+		}
+	}
+	return least, true
+}
+
+/*
+release - the bomber is free from the task, the next task of the queue is started right away.
+Task which does not engage the bomber, a duplicate release for example, does not change its engagement
+*/
+func (queue *taskQueue) release(formId string) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for index, running := range queue.current {
+		if running.formId == formId {
+			queue.current = append(queue.current[:index], queue.current[index+1:]...)
+			if err := queue.core.Release(); err != nil {
+				logrus.Error("Can not release the bomber from task ", formId, ": ", err)
+			}
+			queue.startNext()
+			return
+		}
+	}
+}
+
+// startNext - starts the first task of the queue if the bomber has spare capacity and is not paused, called under lock
+func (queue *taskQueue) startNext() {
+	if queue.paused || len(queue.tasks) == 0 || !queue.core.TryEngage() {
+		return
+	}
+	next := queue.tasks[0]
+	queue.tasks = queue.tasks[1:]
+	queue.current = append(queue.current, next)
+	queue.publishPositions(0)
+	logrus.Info("Starting queued task ", next.formId)
+	go func() {
+		if err := queue.start(next.data); err != nil {
+			logrus.Error("Can not start queued task ", next.formId, ": ", err)
+		}
+	}()
+}
+
+// close - queue does not take tasks anymore, result is tasks which were waiting in it
+func (queue *taskQueue) close() []queuedTask {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.closed = true
+	waiting := queue.tasks
+	queue.tasks = nil
+	return waiting
+}
+
+// pause - running task is not affected, queued and new tasks wait for resume
+func (queue *taskQueue) pause() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.paused = true
+}
+
+func (queue *taskQueue) resume() {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	queue.paused = false
+	queue.startNext()
+}
+
+func (queue *taskQueue) isPaused() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.paused
+}
+
+func (queue *taskQueue) isClosed() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.closed
+}
+
+// full - busy bomber can not queue more tasks
+func (queue *taskQueue) full() bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return !queue.core.Spare() && len(queue.tasks) >= queue.depth
+}
+
+// running - form ids of the tasks which engaged the bomber, empty if the bomber is idle
+func (queue *taskQueue) running() []string {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	return queue.runningIds()
+}
+
+func (queue *taskQueue) runningIds() []string {
+	formIds := make([]string, 0, len(queue.current))
+	for _, running := range queue.current {
+		formIds = append(formIds, running.formId)
+	}
+	return formIds
+}
+
+// remove - false if the task does not wait in queue
+func (queue *taskQueue) remove(formId string) bool {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	for index, task := range queue.tasks {
+		if task.formId != formId {
+			continue
+		}
+		queue.tasks = append(queue.tasks[:index], queue.tasks[index+1:]...)
+		queue.publishPositions(index)
+		return true
+	}
+	return false
+}
+
+// snapshot - running tasks, empty if the bomber is idle, and positions of tasks waiting in queue
+func (queue *taskQueue) snapshot() ([]string, []TaskQueuePosition) {
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+	positions := make([]TaskQueuePosition, 0, len(queue.tasks))
+	for index, task := range queue.tasks {
+		positions = append(positions, queue.position(task, index+1))
+	}
+	return queue.runningIds(), positions
+}
+
+// publishPositions - positions of tasks from the index, which are changed
+func (queue *taskQueue) publishPositions(from int) {
+	for index := from; index < len(queue.tasks); index++ {
+		queue.publishPosition(queue.tasks[index], index+1)
+	}
+}
+
+func (queue *taskQueue) publishPosition(task queuedTask, position int) {
+	positionMarshaled, err := json.Marshal(queue.position(task, position))
+	if err != nil {
+		logrus.Error("Error forming position of task in queue: ", err)
+		return
+	}
+	if errPublish := queue.publisher.PublishNewMessage(taskQueuePositions, positionMarshaled); errPublish != nil {
+		logrus.Error("Error while publish position of task in queue: ", errPublish)
+	}
+}
+
+func (queue *taskQueue) position(task queuedTask, position int) TaskQueuePosition {
+	return TaskQueuePosition{
+		TaskID:          task.formId,
+		BomberId:        queue.bomberId,
+		ContractVersion: core.ContractVersion,
+		Position:        position,
+		Depth:           queue.depth,
+		Priority:        task.priority,
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+)
+
+func newTestQueue(t *testing.T, capacity int, depth int) (*taskQueue, chan string) {
+	bomber := core.NewLocalCore(&config.Configuration{MaxAttacks: capacity, CurrentServiceID: "bomber"})
+	queue := newTaskQueue(bomber, nats_listener.NewPublisher(bomber.GetBroker()), &config.Configuration{
+		TaskQueueDepth:   depth,
+		CurrentServiceID: "bomber",
+	})
+	started := make(chan string, 10)
+	queue.start = func(data []byte) error {
+		started <- string(data)
+		return nil
+	}
+	return queue, started
+}
+
+func queued(formId string, priority int, preempt bool) queuedTask {
+	return queuedTask{formId: formId, data: []byte(formId), priority: priority, preempt: preempt}
+}
+
+func expectStarted(t *testing.T, started chan string, formId string) {
+	t.Helper()
+	select {
+	case got := <-started:
+		if got != formId {
+			t.Fatalf("started %q, expected %q", got, formId)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("task %q was not started", formId)
+	}
+}
+
+func TestTaskQueueAdmit(t *testing.T) {
+	queue, _ := newTestQueue(t, 1, 2)
+	cases := []struct {
+		task     queuedTask
+		position int
+		admitted bool
+	}{
+		{queued("running", 0, false), 0, true},
+		{queued("low", 0, false), 1, true},
+		{queued("high", 5, false), 1, true},
+		{queued("full", 9, false), 0, false},
+	}
+	for _, tc := range cases {
+		position, admitted := queue.admit(tc.task)
+		if position != tc.position || admitted != tc.admitted {
+			t.Errorf("admit %s = %d, %v, expected %d, %v", tc.task.formId, position, admitted, tc.position, tc.admitted)
+		}
+	}
+	running, positions := queue.snapshot()
+	if len(running) != 1 || running[0] != "running" {
+		t.Errorf("running %v, expected [running]", running)
+	}
+	if len(positions) != 2 || positions[0].TaskID != "high" || positions[1].TaskID != "low" {
+		t.Errorf("queue %v, expected high before low", positions)
+	}
+}
+
+func TestTaskQueueRelease(t *testing.T) {
+	queue, started := newTestQueue(t, 1, 2)
+	queue.admit(queued("first", 0, false))
+	queue.admit(queued("second", 0, false))
+	queue.release("first")
+	expectStarted(t, started, "second")
+	if queue.core.Spare() {
+		t.Fatal("bomber has spare capacity while the second task runs")
+	}
+	// releases of tasks which do not engage the bomber do not give capacity
+	queue.release("first")
+	queue.release("")
+	queue.release("unknown")
+	if queue.core.Spare() {
+		t.Fatal("duplicate releases gave spare capacity")
+	}
+	if position, _ := queue.admit(queued("third", 0, false)); position != 1 {
+		t.Fatalf("third task was started at once beyond capacity, position %d", position)
+	}
+	queue.release("second")
+	expectStarted(t, started, "third")
+	queue.release("third")
+	if !queue.core.Idle() {
+		t.Fatal("bomber is not idle after release of all tasks")
+	}
+	queue.release("third")
+	if position, admitted := queue.admit(queued("fourth", 0, false)); position != 0 || !admitted {
+		t.Fatalf("idle bomber did not take the task: %d, %v", position, admitted)
+	}
+	if queue.core.Spare() {
+		t.Fatal("engagement went below zero by duplicate release")
+	}
+}
+
+func TestTaskQueuePreempt(t *testing.T) {
+	queue, _ := newTestQueue(t, 1, 2)
+	task := rest_contracts.Task{
+		FormId: "low",
+		Script: &rest_contracts.RestScript{
+			Address:       "http://127.0.0.1:1",
+			RequestMethod: "GET",
+			Config:        &rest_contracts.ConfigurationScript{Rps: 1, Time: 1},
+		},
+		Schema: &rest_contracts.RestSchema{Headers: map[string]string{}},
+	}
+	attack, err := queue.core.PreparingData(task)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer attack.EndProgress()
+	queue.admit(queued("low", 1, false))
+	if position, _ := queue.admit(queued("equal", 1, true)); position != 1 || attack.PreemptedBy() != "" {
+		t.Fatalf("task of the same priority preempted the running one")
+	}
+	queue.remove("equal")
+	if position, _ := queue.admit(queued("urgent", 5, true)); position != 1 {
+		t.Fatalf("preempting task is at position %d, expected 1", position)
+	}
+	if attack.PreemptedBy() != "urgent" {
+		t.Fatalf("running task is preempted by %q, expected urgent", attack.PreemptedBy())
+	}
+}
@@ -2,25 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
 
 	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
 	"github.com/bomber-team/rest-bomber/core"
 	"github.com/bomber-team/rest-bomber/nats_listener"
-	"github.com/nats-io/nats.go"
 	"github.com/sirupsen/logrus"
 )
 
 type TaskTopicHandler struct {
 	subscriber *nats_listener.Subscriber
-	publisher  *nats_listener.Publisher
-	core       *core.Core
-	bracket    chan int
-	config     *nats_listener.NatsConnectionConfiguration
+	// nil if queue group is disabled or its tasks are consumed from JetStream
+	queueSubscriber *nats_listener.Subscriber
+	// nil if JetStream is disabled
+	jetStream *nats_listener.JetStreamConsumer
+	publisher *nats_listener.Publisher
+	core      *core.Core
+	tasks     *taskQueue
+	dedup     *taskDeduplicator
+	bracket   chan int
+	config    *config.Configuration
+	// opens signed and encrypted tasks
+	verifier *nats_listener.Verifier
+	// starts the prepared attack of the task in process, so only verified tasks are attacked
+	started func(formId string)
+	// duration of preparing of one request in the last task, start of the next task is estimated by it
+	preparingNsPerRequest int64
 }
 
 const (
 	taskTopicName     = "bombers.tasks."
 	taskStatusChanger = "bombers.server.task_status"
+	taskRejected      = "bombers.server.task_rejected"
+	// task returned by busy bomber, or by unavailable service it depends on, is not delivered again sooner
+	jetStreamRetryDelay = 5 * time.Second
 )
 
 const (
@@ -28,20 +47,66 @@ const (
 	COMPLETED_ATTACK    = 1
 	ERROR_CONFIGURATION = 3
 	ERROR_ATTACK        = 2
+	CANCELLED_ATTACK    = 4
+	QUEUED_TASK         = 5
+	REJECTED_TASK       = 6
+	PREEMPTED_ATTACK    = 7
 )
 
 type ResultConfiguration struct {
-	TaskID string `json:"task_id"`
-	Result int    `json:"status"`
+	TaskID          string `json:"task_id"`
+	Result          int    `json:"status"`
+	ContractVersion string `json:"contract_version"`
 }
 
-func newTaskTopicHandler(conn *nats.Conn, core *core.Core, config *nats_listener.NatsConnectionConfiguration) *TaskTopicHandler {
-	return &TaskTopicHandler{
-		subscriber: nats_listener.NewSubscriber(conn, taskTopicName+config.CurrentServiceID),
-		publisher:  nats_listener.NewPublisher(conn),
-		core:       core,
-		config:     config,
+// TaskRejection - reasons of ERROR_CONFIGURATION of the invalid task by its fields
+type TaskRejection struct {
+	TaskID          string            `json:"task_id"`
+	BomberId        string            `json:"bomber_id"`
+	ContractVersion string            `json:"contract_version"`
+	Errors          []core.FieldError `json:"errors"`
+}
+
+var (
+	errInvalidTask = errors.New("task can not be unmarshaled")
+	errConfiguring = errors.New("task can not be configured")
+	errUnavailable = errors.New("task can not be configured while service it depends on is unavailable")
+)
+
+func newTaskTopicHandler(bus broker.Broker, core *core.Core, config *config.Configuration, tasks *taskQueue,
+	verifier *nats_listener.Verifier) *TaskTopicHandler {
+	handler := &TaskTopicHandler{
+		subscriber:      nats_listener.NewSubscriber(bus, taskTopicName+config.CurrentServiceID),
+		queueSubscriber: nats_listener.NewQueueSubscriber(bus, taskTopicName+config.TaskQueueGroup, config.TaskQueueGroup),
+		publisher:       nats_listener.NewPublisher(bus),
+		core:            core,
+		tasks:           tasks,
+		dedup:           newTaskDeduplicator(time.Duration(config.TaskDedupWindow) * time.Second),
+		config:          config,
+		verifier:        verifier,
+	}
+	tasks.start = handler.configure
+	if config.JetStreamStream == nats_listener.JetStreamDisabled {
+		return handler
+	}
+	if handler.queueSubscriber == nil {
+		logrus.Error("Can not consume tasks from JetStream: queue group of tasks is disabled")
+		return handler
+	}
+	connection := nats_listener.NatsConnection(bus)
+	if connection == nil {
+		logrus.Error("Can not consume tasks from JetStream: ", nats_listener.ErrNatsRequired)
+		return handler
+	}
+	// durable consumer is shared by bombers of the queue group and survives their restarts, tenants have own consumers
+	durable := config.TaskQueueGroup
+	if tenant := config.TenantId(); tenant != "" {
+		durable = tenant + "-" + durable
 	}
+	handler.queueSubscriber = nil
+	handler.jetStream = nats_listener.NewJetStreamConsumer(connection, config.JetStreamStream, durable,
+		broker.SubjectOf(bus, taskTopicName+config.TaskQueueGroup), time.Duration(config.JetStreamAckWait)*time.Second)
+	return handler
 }
 
 func (handl *TaskTopicHandler) Configuration(signal chan int) error {
@@ -51,30 +116,208 @@ func (handl *TaskTopicHandler) Configuration(signal chan int) error {
 	if errSubscription != nil {
 		return errSubscription
 	}
+	if handl.queueSubscriber != nil {
+		return handl.queueSubscriber.Subscribe(handl.handle)
+	}
+	if handl.jetStream != nil {
+		return handl.jetStream.Subscribe(handl.handleJetStream, handl.tasks.idle)
+	}
 	return nil
 }
 
-func (handl *TaskTopicHandler) handle(message *nats.Msg) {
+func (handl *TaskTopicHandler) handle(message *broker.Message) {
 	logrus.Info("Handled request by task topic handler. Subject: ", message.Subject, "Data: ", string(message.Data))
-	// starting task
+	// envelope is signed for the subject as it is published, namespace of the broker included
+	data, err := handl.verifier.Open(broker.SubjectOf(handl.publisher.Broker, message.Subject), message.Data)
+	if err != nil {
+		logrus.Error("Can not verify task from bomber server: ", err)
+		return
+	}
+	ack := handl.accept(data, message.Reply != "")
+	if ack == nil {
+		return
+	}
+	if message.Reply != "" {
+		reply(message, *ack, handl.publisher)
+	}
+	if ack.Accepted && ack.QueuePosition == 0 {
+		handl.configure(data)
+	}
+}
+
+/*
+accept - decides about the task and publishes its statuses, the task which engaged the bomber has to be configured
+by the caller. Answered tasks get reasons in acknowledgment, others in statuses. Nil if unanswered task can not be unmarshaled
+*/
+func (handl *TaskTopicHandler) accept(data []byte, answered bool) *TaskAck {
+	var paylaod rest_contracts.Task
+	var fieldErrors []core.FieldError
+	if err := paylaod.Unmarshal(data); err != nil {
+		if !answered {
+			logrus.Error("Can not unmarshal message from bomber server: ", err)
+			return nil
+		}
+		fieldErrors = []core.FieldError{{Field: "task", Reason: "can not unmarshal: " + err.Error()}}
+	} else {
+		fieldErrors = handl.validate(paylaod)
+	}
+	ack := handl.decide(&paylaod, data, fieldErrors)
+	switch {
+	case !ack.Accepted:
+		logrus.Info("Task ", paylaod.FormId, " was rejected: ", ack.Reason)
+		if !answered {
+			handl.publishRefusal(paylaod.FormId, ack)
+		}
+	case ack.QueuePosition > 0:
+		logrus.Info("Task ", paylaod.FormId, " was queued at position ", ack.QueuePosition)
+		formatResultStatusTask(paylaod.FormId, QUEUED_TASK, handl.publisher)
+	}
+	return &ack
+}
+
+// validate - reasons why the task can not be executed by this bomber
+func (handl *TaskTopicHandler) validate(paylaod rest_contracts.Task) []core.FieldError {
+	return append(core.ValidateTask(paylaod), core.ValidateTenant(paylaod, handl.config.TenantId())...)
+}
+
+// publishRefusal - statuses of rejected task sent without reply subject
+func (handl *TaskTopicHandler) publishRefusal(taskId string, ack TaskAck) {
+	if ack.Reason == rejectDuplicate {
+		return
+	}
+	if len(ack.Errors) > 0 {
+		handl.publishRejection(taskId, ack.Errors)
+		formatResultStatusTask(taskId, ERROR_CONFIGURATION, handl.publisher)
+		return
+	}
+	formatResultStatusTask(taskId, REJECTED_TASK, handl.publisher)
+}
+
+/*
+handleJetStream - task is acknowledged when it is configured and started, so it is executed once.
+Invalid tasks are terminated, tasks which could not be started are delivered again, their nonces are forgotten for it
+*/
+func (handl *TaskTopicHandler) handleJetStream(message *nats_listener.JetStreamMessage) {
+	logrus.Info("Handled request by task topic handler from JetStream. Subject: ", message.Subject, "Data: ", string(message.Data))
+	// subjects of JetStream are the ones of NATS
+	data, err := handl.verifier.Open(message.Subject, message.Data)
+	if err != nil {
+		// forged task is not delivered again
+		logrus.Error("Can not verify task from bomber server: ", err)
+		if errAnswer := message.Term(); errAnswer != nil {
+			logrus.Error("Can not answer task to JetStream: ", errAnswer)
+		}
+		return
+	}
 	var paylaod rest_contracts.Task
-	if err := paylaod.Unmarshal(message.Data); err != nil {
+	if err := paylaod.Unmarshal(data); err != nil {
 		logrus.Error("Can not unmarshal message from bomber server: ", err)
+		if errAnswer := message.Term(); errAnswer != nil {
+			logrus.Error("Can not answer task to JetStream: ", errAnswer)
+		}
+		return
+	}
+	// redelivery of the task taken before is acknowledged, it is not delivered again
+	key := idempotencyKey(paylaod)
+	if first, duplicate := handl.dedup.claim(key); duplicate {
+		publishDuplicate(paylaod, key, first, handl.config.CurrentServiceID, handl.publisher)
+		if err := message.Ack(); err != nil {
+			logrus.Error("Can not answer task to JetStream: ", err)
+		}
+		return
+	}
+	if !handl.tasks.engage(newQueuedTask(paylaod, data)) {
+		handl.dedup.forget(key)
+		handl.verifier.Forget(message.Data)
+		if err := message.NakWithDelay(jetStreamRetryDelay); err != nil {
+			logrus.Error("Can not answer task to JetStream: ", err)
+		}
 		return
 	}
+	var errAnswer error
+	switch err := handl.configureTask(data, true); err {
+	case nil:
+		errAnswer = message.Ack()
+	case errUnavailable:
+		logrus.Error("Can not start task, it is returned into JetStream: ", err)
+		handl.verifier.Forget(message.Data)
+		errAnswer = message.NakWithDelay(jetStreamRetryDelay)
+	default:
+		errAnswer = message.Term()
+	}
+	if errAnswer != nil {
+		logrus.Error("Can not answer task to JetStream: ", errAnswer)
+	}
+}
+
+// configure - task has engaged the bomber, it is released when starter handler completes the task or configuring fails
+func (handl *TaskTopicHandler) configure(data []byte) error {
+	return handl.configureTask(data, false)
+}
+
+/*
+configureTask - task of JetStream does not fail by unavailable service it depends on,
+it has no status then and errUnavailable is returned, so it is delivered again
+*/
+func (handl *TaskTopicHandler) configureTask(data []byte, redeliverable bool) error {
+	// starting task
+	var paylaod rest_contracts.Task
+	if err := paylaod.Unmarshal(data); err != nil {
+		logrus.Error("Can not unmarshal message from bomber server: ", err)
+		return errInvalidTask
+	}
+	if fieldErrors := handl.validate(paylaod); len(fieldErrors) > 0 {
+		logrus.Error("Can not start invalid task: ", paylaod.FormId, " ", fieldErrors)
+		handl.publishRejection(paylaod.FormId, fieldErrors)
+		formatResultStatusTask(paylaod.FormId, ERROR_CONFIGURATION, handl.publisher)
+		handl.dedup.forget(idempotencyKey(paylaod))
+		handl.tasks.release(paylaod.FormId)
+		return errConfiguring
+	}
 
 	logrus.Info("Starting working on task ID: ", paylaod.FormId)
 	logrus.Info("Starting building ", paylaod.Script.Config.Rps*paylaod.Script.Config.Time, " amount request")
-	handl.core.PreparingData(paylaod)
+	preparingStart := time.Now()
+	attack, err := handl.core.PreparingData(paylaod)
+	if err != nil {
+		handl.dedup.forget(idempotencyKey(paylaod))
+		handl.tasks.release(paylaod.FormId)
+		if redeliverable && core.Unavailable(err) {
+			return errUnavailable
+		}
+		formatResultStatusTask(paylaod.FormId, ERROR_CONFIGURATION, handl.publisher)
+		return errConfiguring
+	}
+	if amount := paylaod.Script.Config.Rps * paylaod.Script.Config.Time; amount > 0 {
+		atomic.StoreInt64(&handl.preparingNsPerRequest, time.Since(preparingStart).Nanoseconds()/amount)
+	}
 	logrus.Info("Completed builded Requests for attack")
 	formatResultStatusTask(paylaod.FormId, CONFIGURED, handl.publisher)
-	handl.publisher.PublishNewMessage(taskTopicStarter+handl.config.CurrentServiceID, message.Data)
+	handl.started(attack.FormId())
+	return nil
+}
+
+func (handl *TaskTopicHandler) publishRejection(taskId string, fieldErrors []core.FieldError) {
+	rejectionMarshaled, err := json.Marshal(TaskRejection{
+		TaskID:          taskId,
+		BomberId:        handl.config.CurrentServiceID,
+		ContractVersion: core.ContractVersion,
+		Errors:          fieldErrors,
+	})
+	if err != nil {
+		logrus.Error("Error forming rejection for backend: ", err)
+		return
+	}
+	if errPublish := handl.publisher.PublishNewMessage(taskRejected, rejectionMarshaled); errPublish != nil {
+		logrus.Error("Error while publish rejection of task: ", errPublish)
+	}
 }
 
 func formatResultStatusTask(taskId string, status int, publisher *nats_listener.Publisher) {
 	resultMarshaled, err := json.Marshal(ResultConfiguration{
-		TaskID: taskId,
-		Result: status,
+		TaskID:          taskId,
+		Result:          status,
+		ContractVersion: core.ContractVersion,
 	})
 	if err != nil {
 		logrus.Error("Error forming result for backend")
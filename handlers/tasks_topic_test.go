@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+)
+
+// refusedURL - url of server which is closed already, connections to it are refused
+func refusedURL() string {
+	server := httptest.NewServer(nil)
+	server.Close()
+	return server.URL
+}
+
+func taskData(t *testing.T, formId string, options string) []byte {
+	task := rest_contracts.Task{
+		FormId: formId,
+		Script: &rest_contracts.RestScript{
+			Address:       "http://127.0.0.1:8080/items",
+			RequestMethod: "GET",
+			Config:        &rest_contracts.ConfigurationScript{Rps: 1, Time: 1},
+		},
+		Schema: &rest_contracts.RestSchema{Headers: map[string]string{core.OptionsHeader: options}},
+	}
+	data, err := task.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// statuses - statuses of tasks published by the bomber
+func statuses(t *testing.T, bus broker.Broker) chan ResultConfiguration {
+	published := make(chan ResultConfiguration, 10)
+	_, err := bus.Subscribe(broker.SubjectOf(bus, taskStatusChanger), "", func(message *broker.Message) {
+		var status ResultConfiguration
+		if err := json.Unmarshal(message.Data, &status); err == nil {
+			published <- status
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return published
+}
+
+func TestConfigureTaskUnavailable(t *testing.T) {
+	oauth2 := `{"auth": {"oauth2": {"token_url": "` + refusedURL() + `/token", "client_id": "bomber"}}}`
+	sigv4 := `{"sigv4": {"service": "execute-api", "region": "eu-west-1"}}`
+	cases := []struct {
+		name          string
+		options       string
+		redeliverable bool
+		err           error
+		status        bool
+	}{
+		{"unavailable oauth2 server of jetstream task", oauth2, true, errUnavailable, false},
+		{"unavailable oauth2 server of plain task", oauth2, false, errConfiguring, true},
+		{"missing aws credentials of jetstream task", sigv4, true, errConfiguring, true},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			preference := &config.Configuration{MaxAttacks: 1, CurrentServiceID: "bomber", TaskQueueGroup: "off",
+				JetStreamStream: "off", AWSMetadataURL: "off"}
+			bomber := core.NewLocalCore(preference)
+			published := statuses(t, bomber.GetBroker())
+			queue := newTaskQueue(bomber, nats_listener.NewPublisher(bomber.GetBroker()), preference)
+			handler := newTaskTopicHandler(bomber.GetBroker(), bomber, preference, queue, nil)
+			handler.started = func(formId string) {
+				t.Fatalf("task %s is started", formId)
+			}
+			err := handler.configureTask(taskData(t, "form", testCase.options), testCase.redeliverable)
+			if err != testCase.err {
+				t.Fatalf("got %v, expected %v", err, testCase.err)
+			}
+			select {
+			case status := <-published:
+				if !testCase.status || status.Result != ERROR_CONFIGURATION {
+					t.Fatalf("unexpected status %+v", status)
+				}
+			case <-time.After(200 * time.Millisecond):
+				if testCase.status {
+					t.Fatal("status of failed task is not published")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Disabled - value of address which turns off probes
+const Disabled = "off"
+
+/*
+Serve - exposes probes of the bomber: /healthz answers while the process serves, /readyz answers 503
+with the reason while ready returns error. Blocks until listener fails
+*/
+func Serve(addr string, ready func() error) {
+	if addr == Disabled || addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(writer http.ResponseWriter, _ *http.Request) {
+		if err := ready(); err != nil {
+			http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		writer.Write([]byte("ready"))
+	})
+	logrus.Info("Serving probes on ", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logrus.Error("Can not serve probes: ", err)
+	}
+}
@@ -0,0 +1,145 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
+)
+
+const namespace = "bomber"
+
+var (
+	inFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "requests_in_flight",
+		Help:      "Requests of the attack waiting for response",
+	})
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "requests_total",
+		Help:      "Completed requests by status of response or grpc code",
+	}, []string{"status"})
+	failures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "request_errors_total",
+		Help:      "Failed requests and connections by category",
+	}, []string{"category"})
+	latency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "request_duration_seconds",
+		Help:      "Latency of completed requests",
+		Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 16),
+	})
+	achievedRps = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "attack_rps",
+		Help:      "Requests completed during the last interval of the attack per second",
+	})
+	bytesRead = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_read_total",
+		Help:      "Bytes received by connections of attacks",
+	})
+	bytesWritten = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "bytes_written_total",
+		Help:      "Bytes sent by connections of attacks",
+	})
+	attackRunning = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "attack_running",
+		Help:      "Attacks which the bomber executes at once",
+	})
+	status = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "status",
+		Help:      "Current status of the bomber as value of StatusBomber",
+	})
+)
+
+// attacks - amount of attacks running at once
+var attacks int64
+
+// InFlight - amount of requests waiting for response now
+func InFlight() int64 {
+	return atomic.LoadInt64(&inFlightCount)
+}
+
+func RequestStarted() {
+	inFlight.Inc()
+	atomic.AddInt64(&inFlightCount, 1)
+}
+
+func RequestFinished() {
+	inFlight.Dec()
+	atomic.AddInt64(&inFlightCount, -1)
+}
+
+// RequestCompleted - status is negative for modes without status of each message
+func RequestCompleted(statusCode int32, elapsed time.Duration) {
+	label := "none"
+	if statusCode >= 0 {
+		label = strconv.Itoa(int(statusCode))
+	}
+	requests.WithLabelValues(label).Inc()
+	latency.Observe(elapsed.Seconds())
+	emitter.count("requests", 1, "status", label)
+	emitter.timing("request_duration", elapsed)
+}
+
+func RequestFailed(category string) {
+	failures.WithLabelValues(category).Inc()
+	emitter.count("request_errors", 1, "category", category)
+}
+
+func Traffic(read int64, written int64) {
+	bytesRead.Add(float64(read))
+	bytesWritten.Add(float64(written))
+	emitter.count("bytes_read", read, "", "")
+	emitter.count("bytes_written", written, "", "")
+}
+
+func AchievedRps(rps float64) {
+	achievedRps.Set(rps)
+	emitter.gauge("attack_rps", rps, "", "")
+}
+
+func AttackStarted() {
+	running := atomic.AddInt64(&attacks, 1)
+	attackRunning.Set(float64(running))
+	emitter.gauge("attack_running", float64(running), "", "")
+}
+
+func AttackFinished() {
+	running := atomic.AddInt64(&attacks, -1)
+	attackRunning.Set(float64(running))
+	emitter.gauge("attack_running", float64(running), "", "")
+	if running == 0 {
+		achievedRps.Set(0)
+		emitter.gauge("attack_rps", 0, "", "")
+	}
+	flushOTLP()
+}
+
+func Status(value int32) {
+	status.Set(float64(value))
+	emitter.gauge("status", float64(value), "", "")
+}
+
+/*
+Serve - exposes metrics of attacks and of the process on /metrics, blocks until listener fails
+*/
+func Serve(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	logrus.Info("Serving metrics on ", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logrus.Error("Can not serve metrics: ", err)
+	}
+}
@@ -0,0 +1,232 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	otlpPath = "/v1/metrics"
+	/*OTLPDisabled - value of endpoint which turns off exporting*/
+	OTLPDisabled = "off"
+
+	defaultOTLPInterval = 10 * time.Second
+
+	temporalityCumulative = 2
+)
+
+type otlpExporter struct {
+	url       string
+	client    *http.Client
+	resource  otlpResource
+	startTime string
+	flush     chan struct{}
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpPoint struct {
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	TimeUnixNano      string          `json:"timeUnixNano"`
+	AsDouble          *float64        `json:"asDouble,omitempty"`
+	Count             string          `json:"count,omitempty"`
+	Sum               *float64        `json:"sum,omitempty"`
+	BucketCounts      []string        `json:"bucketCounts,omitempty"`
+	ExplicitBounds    []float64       `json:"explicitBounds,omitempty"`
+}
+
+type otlpData struct {
+	DataPoints             []otlpPoint `json:"dataPoints"`
+	AggregationTemporality int         `json:"aggregationTemporality,omitempty"`
+	IsMonotonic            bool        `json:"isMonotonic,omitempty"`
+}
+
+type otlpMetric struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Gauge       *otlpData `json:"gauge,omitempty"`
+	Sum         *otlpData `json:"sum,omitempty"`
+	Histogram   *otlpData `json:"histogram,omitempty"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+var exporter *otlpExporter
+
+/*
+StartOTLP - starts pushing metrics of the bomber in OTLP/HTTP json to the collector at endpoint
+every interval and once more after each attack
+*/
+func StartOTLP(endpoint string, bomberID string, interval time.Duration) {
+	if endpoint == OTLPDisabled || endpoint == "" {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultOTLPInterval
+	}
+	exporter = &otlpExporter{
+		url:    strings.TrimSuffix(endpoint, "/") + otlpPath,
+		client: &http.Client{Timeout: 10 * time.Second},
+		resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: otlpValue{StringValue: "rest-bomber"}},
+			{Key: "service.instance.id", Value: otlpValue{StringValue: bomberID}},
+		}},
+		startTime: unixNano(time.Now()),
+		flush:     make(chan struct{}, 1),
+	}
+	logrus.Info("Exporting metrics to ", exporter.url)
+	go exporter.run(interval)
+}
+
+func (exporter *otlpExporter) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-exporter.flush:
+		}
+		exporter.push()
+	}
+}
+
+func flushOTLP() {
+	if exporter == nil {
+		return
+	}
+	select {
+	case exporter.flush <- struct{}{}:
+	default:
+	}
+}
+
+func (exporter *otlpExporter) push() {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		logrus.Error("Can not gather metrics: ", err)
+		return
+	}
+	payload := otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: exporter.resource,
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   otlpScope{Name: "rest-bomber"},
+			Metrics: exporter.convert(families),
+		}},
+	}}}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		logrus.Error("Can not marshal otlp metrics: ", err)
+		return
+	}
+	response, err := exporter.client.Post(exporter.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		logrus.Error("Can not export otlp metrics: ", err)
+		return
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		logrus.Error("Collector rejected otlp metrics: ", response.Status)
+	}
+}
+
+func (exporter *otlpExporter) convert(families []*dto.MetricFamily) []otlpMetric {
+	now := unixNano(time.Now())
+	result := make([]otlpMetric, 0, len(families))
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), namespace+"_") {
+			continue
+		}
+		points := make([]otlpPoint, 0, len(family.Metric))
+		for _, metric := range family.Metric {
+			point := otlpPoint{
+				Attributes:        labels(metric.Label),
+				StartTimeUnixNano: exporter.startTime,
+				TimeUnixNano:      now,
+			}
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				value := metric.GetGauge().GetValue()
+				point.AsDouble = &value
+			case dto.MetricType_COUNTER:
+				value := metric.GetCounter().GetValue()
+				point.AsDouble = &value
+			case dto.MetricType_HISTOGRAM:
+				histogram := metric.GetHistogram()
+				sum := histogram.GetSampleSum()
+				point.Sum = &sum
+				point.Count = strconv.FormatUint(histogram.GetSampleCount(), 10)
+				var previous uint64
+				for _, bucket := range histogram.Bucket {
+					point.ExplicitBounds = append(point.ExplicitBounds, bucket.GetUpperBound())
+					point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(bucket.GetCumulativeCount()-previous, 10))
+					previous = bucket.GetCumulativeCount()
+				}
+				point.BucketCounts = append(point.BucketCounts, strconv.FormatUint(histogram.GetSampleCount()-previous, 10))
+			default:
+				continue
+			}
+			points = append(points, point)
+		}
+		metric := otlpMetric{Name: family.GetName(), Description: family.GetHelp()}
+		switch family.GetType() {
+		case dto.MetricType_GAUGE:
+			metric.Gauge = &otlpData{DataPoints: points}
+		case dto.MetricType_COUNTER:
+			metric.Sum = &otlpData{DataPoints: points, AggregationTemporality: temporalityCumulative, IsMonotonic: true}
+		case dto.MetricType_HISTOGRAM:
+			metric.Histogram = &otlpData{DataPoints: points, AggregationTemporality: temporalityCumulative}
+		default:
+			continue
+		}
+		result = append(result, metric)
+	}
+	return result
+}
+
+func labels(pairs []*dto.LabelPair) []otlpAttribute {
+	result := make([]otlpAttribute, 0, len(pairs))
+	for _, pair := range pairs {
+		result = append(result, otlpAttribute{Key: pair.GetName(), Value: otlpValue{StringValue: pair.GetValue()}})
+	}
+	return result
+}
+
+func unixNano(moment time.Time) string {
+	return strconv.FormatInt(moment.UnixNano(), 10)
+}
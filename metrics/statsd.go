@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	/*StatsDDisabled - value of address which turns off emitting*/
+	StatsDDisabled = "off"
+
+	statsdFlushInterval = 100 * time.Millisecond
+	// fits into udp packet without fragmentation on most networks
+	statsdMaxPacket = 1432
+	statsdQueue     = 65536
+)
+
+type statsdEmitter struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+	lines     chan string
+}
+
+var (
+	emitter       *statsdEmitter
+	inFlightCount int64
+)
+
+/*
+StartStatsD - starts sending counters, gauges and timings of attacks to statsd at addr.
+With dogstatsd labels are sent as tags, otherwise they are appended to the name of metric.
+Metrics are dropped if the queue of sending is full, so requests of attacks never wait for it
+*/
+func StartStatsD(addr string, prefix string, dogstatsd bool) {
+	if addr == StatsDDisabled || addr == "" {
+		return
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		logrus.Error("Can not connect to statsd: ", err)
+		return
+	}
+	emitter = &statsdEmitter{
+		conn:      conn,
+		prefix:    prefix,
+		dogstatsd: dogstatsd,
+		lines:     make(chan string, statsdQueue),
+	}
+	logrus.Info("Sending metrics to statsd ", addr)
+	go emitter.run()
+}
+
+func (emitter *statsdEmitter) run() {
+	var packet bytes.Buffer
+	ticker := time.NewTicker(statsdFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line := <-emitter.lines:
+			if packet.Len() > 0 && packet.Len()+len(line)+1 > statsdMaxPacket {
+				emitter.flush(&packet)
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		case <-ticker.C:
+			emitter.gauge("requests_in_flight", float64(atomic.LoadInt64(&inFlightCount)), "", "")
+			emitter.flush(&packet)
+		}
+	}
+}
+
+func (emitter *statsdEmitter) flush(packet *bytes.Buffer) {
+	if packet.Len() == 0 {
+		return
+	}
+	if _, err := emitter.conn.Write(packet.Bytes()); err != nil {
+		logrus.Debug("Can not send metrics to statsd: ", err)
+	}
+	packet.Reset()
+}
+
+func (emitter *statsdEmitter) send(name string, value string, kind string, label string, labelValue string) {
+	if emitter == nil {
+		return
+	}
+	line := emitter.prefix + name
+	if label != "" && !emitter.dogstatsd {
+		line += "." + labelValue
+	}
+	line += ":" + value + "|" + kind
+	if label != "" && emitter.dogstatsd {
+		line += "|#" + label + ":" + labelValue
+	}
+	select {
+	case emitter.lines <- line:
+	default:
+	}
+}
+
+func (emitter *statsdEmitter) count(name string, value int64, label string, labelValue string) {
+	emitter.send(name, strconv.FormatInt(value, 10), "c", label, labelValue)
+}
+
+func (emitter *statsdEmitter) gauge(name string, value float64, label string, labelValue string) {
+	emitter.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", label, labelValue)
+}
+
+func (emitter *statsdEmitter) timing(name string, elapsed time.Duration) {
+	emitter.send(name, strconv.FormatFloat(float64(elapsed)/float64(time.Millisecond), 'f', 3, 64), "ms", "", "")
+}
@@ -0,0 +1,105 @@
+package nats_listener
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/nats-io/nats.go"
+)
+
+var ErrNatsRequired = errors.New("feature requires NATS broker")
+
+// NatsBroker - NATS connection as broker of the bomber, groups of subscribers are queue groups
+type NatsBroker struct {
+	Connection *nats.Conn
+}
+
+// Publish - message is published directly, Publisher buffers messages of lost connection
+func (bus *NatsBroker) Publish(subject string, data []byte) error {
+	return bus.Connection.Publish(subject, data)
+}
+
+func (bus *NatsBroker) Subscribe(subject string, group string, handler broker.Handler) (broker.Subscription, error) {
+	callback := func(msg *nats.Msg) {
+		handler(&broker.Message{Subject: msg.Subject, Data: msg.Data, Reply: msg.Reply})
+	}
+	if group != "" {
+		return bus.Connection.QueueSubscribe(subject, group, callback)
+	}
+	return bus.Connection.Subscribe(subject, callback)
+}
+
+func (bus *NatsBroker) Connected() bool {
+	return bus.Connection.IsConnected()
+}
+
+func (bus *NatsBroker) Flush(timeout time.Duration) error {
+	return bus.Connection.FlushTimeout(timeout)
+}
+
+func (bus *NatsBroker) MaxPayload() int {
+	return int(bus.Connection.MaxPayload())
+}
+
+func (bus *NatsBroker) Close() error {
+	bus.Connection.Close()
+	return nil
+}
+
+// NatsConnection - nil if the broker is not NATS, connection of namespaced broker is not namespaced
+func NatsConnection(bus broker.Broker) *nats.Conn {
+	if namespaced, ok := bus.(*broker.Namespaced); ok {
+		bus = namespaced.Unwrap()
+	}
+	if natsBroker, ok := bus.(*NatsBroker); ok {
+		return natsBroker.Connection
+	}
+	return nil
+}
+
+// OpenBroker - broker of the configured kind, NATS by default. Subjects are namespaced by tenant of the bomber
+func OpenBroker(preference *config.Configuration) (broker.Broker, error) {
+	bus, err := openBroker(preference)
+	if err != nil || preference.TenantId() == "" {
+		return bus, err
+	}
+	return broker.NewNamespaced(bus, preference.TenantId()), nil
+}
+
+func openBroker(preference *config.Configuration) (broker.Broker, error) {
+	switch preference.Broker {
+	case broker.KindNats, "":
+		connection, err := CreateNewConnectionToNats(preference)
+		if err != nil {
+			return nil, err
+		}
+		return &NatsBroker{Connection: connection}, nil
+	case broker.KindKafka:
+		return broker.NewKafka(broker.KafkaOptions{
+			Brokers:    strings.Split(preference.KafkaBrokers, ","),
+			ClientID:   preference.NameClient,
+			MaxPayload: preference.KafkaMaxMessageBytes,
+		})
+	case broker.KindRabbitMQ:
+		return broker.NewRabbitMQ(broker.RabbitMQOptions{
+			URL:        preference.RabbitMQURL,
+			Exchange:   preference.RabbitMQExchange,
+			Name:       preference.NameClient,
+			MaxPayload: preference.RabbitMQMaxMessageBytes,
+		})
+	case broker.KindLocal:
+		return broker.NewLocal(), nil
+	case broker.KindRedis:
+		return broker.NewRedis(broker.RedisOptions{
+			URL:        preference.RedisURL,
+			Name:       preference.NameClient,
+			Consumer:   preference.CurrentServiceID,
+			MaxLen:     preference.RedisStreamMaxLen,
+			MaxPayload: preference.RedisMaxMessageBytes,
+		})
+	}
+	return nil, broker.ErrUnknownKind
+}
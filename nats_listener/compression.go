@@ -0,0 +1,55 @@
+package nats_listener
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	EncodingNone = "none"
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+var ErrUnknownEncoding = errors.New("unknown encoding of payloads, expected none, gzip or zstd")
+
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+func ValidateEncoding(encoding string) error {
+	switch encoding {
+	case EncodingNone, EncodingGzip, EncodingZstd:
+		return nil
+	}
+	return ErrUnknownEncoding
+}
+
+// EncodingSubject - compressed payloads are published into subject with suffix of encoding, NATS messages have no headers
+func EncodingSubject(subject string, encoding string) string {
+	if encoding == EncodingNone || encoding == "" {
+		return subject
+	}
+	return subject + "." + encoding
+}
+
+func Compress(encoding string, payload []byte) ([]byte, error) {
+	switch encoding {
+	case EncodingNone, "":
+		return payload, nil
+	case EncodingGzip:
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		if _, err := writer.Write(payload); err != nil {
+			return nil, err
+		}
+		if err := writer.Close(); err != nil {
+			return nil, err
+		}
+		return compressed.Bytes(), nil
+	case EncodingZstd:
+		return zstdEncoder.EncodeAll(payload, make([]byte, 0, len(payload)/4)), nil
+	}
+	return nil, ErrUnknownEncoding
+}
@@ -1,26 +1,83 @@
 package nats_listener
 
 import (
+	"sync"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
 	"github.com/nats-io/nats.go"
 	"github.com/sirupsen/logrus"
 )
 
+// connectionState - buffer of messages and callbacks of reconnect, which are shared by publishers of the connection
+type connectionState struct {
+	buffer    *reconnectBuffer
+	mutex     sync.Mutex
+	callbacks []func()
+}
+
+var connections sync.Map
+
+func stateOf(connection *nats.Conn) *connectionState {
+	state, ok := connections.Load(connection)
+	if !ok {
+		return nil
+	}
+	return state.(*connectionState)
+}
+
+// OnReconnect - callback is called after buffered messages of the connection are published again
+func OnReconnect(connection *nats.Conn, callback func()) {
+	state := stateOf(connection)
+	if state == nil {
+		connection.SetReconnectHandler(func(nc *nats.Conn) {
+			callback()
+		})
+		return
+	}
+	state.mutex.Lock()
+	state.callbacks = append(state.callbacks, callback)
+	state.mutex.Unlock()
+}
+
+func reconnected(nc *nats.Conn) {
+	logrus.Println("Reconnected: ", nc.ConnectedUrl())
+	state := stateOf(nc)
+	if state == nil {
+		return
+	}
+	state.buffer.flush(nc)
+	state.mutex.Lock()
+	callbacks := append([]func(){}, state.callbacks...)
+	state.mutex.Unlock()
+	for _, callback := range callbacks {
+		callback()
+	}
+}
+
 /*
 CreateNewConnectionToNats - initialize new connection to nats
 */
-func CreateNewConnectionToNats(preference *NatsConnectionConfiguration) (*nats.Conn, error) {
+func CreateNewConnectionToNats(preference *config.Configuration) (*nats.Conn, error) {
 	logrus.Info("Starting configuring service...")
-	// totalWait := time.Minute * time.Duration(preference.MaxWait)
-	connectionOpts := []nats.Option{}
+	totalWait := time.Minute * time.Duration(preference.MaxWait)
+	delay := time.Second * time.Duration(preference.ReconnectDelay)
+	if delay <= 0 {
+		delay = nats.DefaultReconnectWait
+	}
+	connectionOpts, errSecurity := securityOptions(preference)
+	if errSecurity != nil {
+		return nil, errSecurity
+	}
 	connectionOpts = append(connectionOpts, nats.Name(preference.NameClient))
-	// connectionOpts = append(connectionOpts, nats.ReconnectWait(time.Duration(preference.ReconnectDelay)))
-	// connectionOpts = append(connectionOpts, nats.MaxReconnects(int(totalWait/time.Duration(preference.ReconnectDelay))))
+	connectionOpts = append(connectionOpts, nats.ReconnectWait(delay))
+	connectionOpts = append(connectionOpts, nats.MaxReconnects(int(totalWait/delay)))
+	// messages are buffered by publishers while reconnecting, so they are kept in order and their loss is logged
+	connectionOpts = append(connectionOpts, nats.ReconnectBufSize(-1))
 	connectionOpts = append(connectionOpts, nats.DisconnectErrHandler(func(nc *nats.Conn, err error) {
-		logrus.Println("Disconnected dut to: ", err, " will attempt reconnects for: ")
-	}))
-	connectionOpts = append(connectionOpts, nats.ReconnectHandler(func(nc *nats.Conn) {
-		logrus.Println("Reconnected: ", nc.ConnectedUrl())
+		logrus.Println("Disconnected dut to: ", err, " will attempt reconnects for: ", totalWait)
 	}))
+	connectionOpts = append(connectionOpts, nats.ReconnectHandler(reconnected))
 	connectionOpts = append(connectionOpts, nats.ClosedHandler(func(nc *nats.Conn) {
 		logrus.Panic("Exiting ", nc.LastError())
 		// TODO: impletemend channel for gracefull shutdown
@@ -29,6 +86,7 @@ func CreateNewConnectionToNats(preference *NatsConnectionConfiguration) (*nats.C
 	if errConnect != nil {
 		return nil, errConnect
 	}
+	connections.Store(connet, &connectionState{buffer: newReconnectBuffer(preference.ReconnectBuffer)})
 	logrus.Info("Completed configuring service and connection to nats")
 	return connet, nil
 }
@@ -0,0 +1,271 @@
+package nats_listener
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/nats-io/nkeys"
+)
+
+const (
+	SigningDisabled = "off"
+	SigningHMAC     = "hmac"
+	SigningNKey     = "nkey"
+)
+
+var (
+	ErrUnknownSigning   = errors.New("unknown signing of messages, expected off, hmac or nkey")
+	ErrSigningKey       = errors.New("hmac signing requires MESSAGE_HMAC_KEY, nkey signing requires MESSAGE_SIGNERS")
+	ErrEncryptionKey    = errors.New("MESSAGE_ENCRYPTION_KEY has to be base64 of 32 bytes")
+	ErrNotEnvelope      = errors.New("message is not an envelope")
+	ErrSignature        = errors.New("signature of message is not valid")
+	ErrUntrustedSigner  = errors.New("signer of message is not one of MESSAGE_SIGNERS")
+	ErrExpiredEnvelope  = errors.New("time of message is out of MESSAGE_MAX_AGE")
+	ErrEncryptedPayload = errors.New("payload of message can not be decrypted")
+	ErrReplayedEnvelope = errors.New("nonce of message was opened already within MESSAGE_MAX_AGE")
+	ErrMaxAge           = errors.New("signing of messages requires MESSAGE_MAX_AGE above 0, nonces are kept for it")
+)
+
+// envelopeNonceSize - random bytes of each envelope, the same envelope is opened once
+const envelopeNonceSize = 16
+
+/*
+Envelope - control message from the orchestrator, signature covers time, subject the message is published to,
+nonce and payload. Payload is nonce and AES-256-GCM ciphertext of the message if encryption is configured
+*/
+type Envelope struct {
+	Time      int64  `json:"time"`             // unix ms of sealing
+	Nonce     []byte `json:"nonce"`            // random bytes of sealing
+	Signer    string `json:"signer,omitempty"` // public nkey of the signer in nkey signing
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature,omitempty"`
+}
+
+func (envelope *Envelope) signed(subject string) []byte {
+	header := strconv.FormatInt(envelope.Time, 10) + "\n" + subject + "\n" + base64.StdEncoding.EncodeToString(envelope.Nonce) + "\n"
+	return append([]byte(header), envelope.Payload...)
+}
+
+/*
+Verifier - opens control messages, so a stolen credential of the broker is not enough to make bombers attack.
+Messages are passed as they are if neither signing nor encryption is configured
+*/
+type Verifier struct {
+	signing string
+	hmacKey []byte
+	signers map[string]nkeys.KeyPair // by public key
+	aead    cipher.AEAD
+	maxAge  time.Duration
+	mutex   sync.Mutex
+	// nonces of opened signed envelopes until their time is out of max age
+	opened map[string]time.Time
+}
+
+func NewVerifier(preference *config.Configuration) (*Verifier, error) {
+	verifier := &Verifier{
+		signing: preference.MessageSigning,
+		signers: map[string]nkeys.KeyPair{},
+		maxAge:  time.Duration(preference.MessageMaxAge) * time.Second,
+		opened:  map[string]time.Time{},
+	}
+	switch verifier.signing {
+	case SigningDisabled, "":
+		verifier.signing = SigningDisabled
+	case SigningHMAC:
+		if !enabled(preference.MessageHMACKey) {
+			return nil, ErrSigningKey
+		}
+		verifier.hmacKey = []byte(preference.MessageHMACKey)
+	case SigningNKey:
+		if !enabled(preference.MessageSigners) {
+			return nil, ErrSigningKey
+		}
+		for _, public := range strings.Split(preference.MessageSigners, ",") {
+			public = strings.TrimSpace(public)
+			signer, err := nkeys.FromPublicKey(public)
+			if err != nil {
+				return nil, err
+			}
+			verifier.signers[public] = signer
+		}
+	default:
+		return nil, ErrUnknownSigning
+	}
+	// replayed envelope is known by its nonce only while it is not expired
+	if verifier.signing != SigningDisabled && verifier.maxAge <= 0 {
+		return nil, ErrMaxAge
+	}
+	if enabled(preference.MessageEncryptionKey) {
+		aead, err := newAEAD(preference.MessageEncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		verifier.aead = aead
+	}
+	return verifier, nil
+}
+
+func newAEAD(encoded string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, ErrEncryptionKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Enabled - false if messages are not enveloped at all
+func (verifier *Verifier) Enabled() bool {
+	return verifier.signing != SigningDisabled || verifier.aead != nil
+}
+
+/*
+Open - message inside of the envelope published to the subject, as it is in the broker with its namespace.
+Error if the envelope is not signed by trusted signer for the subject, is too old or was opened already
+*/
+func (verifier *Verifier) Open(subject string, data []byte) ([]byte, error) {
+	if !verifier.Enabled() {
+		return data, nil
+	}
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Time == 0 {
+		return nil, ErrNotEnvelope
+	}
+	sealed := time.Unix(0, envelope.Time*int64(time.Millisecond))
+	if verifier.maxAge > 0 {
+		age := time.Since(sealed)
+		// clocks of the orchestrator and the bomber differ both ways
+		if age > verifier.maxAge || age < -verifier.maxAge {
+			return nil, ErrExpiredEnvelope
+		}
+	}
+	switch verifier.signing {
+	case SigningHMAC:
+		if !hmac.Equal(envelope.Signature, signHMAC(verifier.hmacKey, envelope.signed(subject))) {
+			return nil, ErrSignature
+		}
+	case SigningNKey:
+		signer, ok := verifier.signers[envelope.Signer]
+		if !ok {
+			return nil, ErrUntrustedSigner
+		}
+		if err := signer.Verify(envelope.signed(subject), envelope.Signature); err != nil {
+			return nil, ErrSignature
+		}
+	}
+	if verifier.signing != SigningDisabled {
+		if len(envelope.Nonce) == 0 {
+			return nil, ErrNotEnvelope
+		}
+		if !verifier.claimNonce(envelope.Nonce, sealed.Add(verifier.maxAge)) {
+			return nil, ErrReplayedEnvelope
+		}
+	}
+	if verifier.aead == nil {
+		return envelope.Payload, nil
+	}
+	size := verifier.aead.NonceSize()
+	if len(envelope.Payload) < size {
+		return nil, ErrEncryptedPayload
+	}
+	message, err := verifier.aead.Open(nil, envelope.Payload[:size], envelope.Payload[size:], nil)
+	if err != nil {
+		return nil, ErrEncryptedPayload
+	}
+	return message, nil
+}
+
+// claimNonce - false if the nonce was claimed before, it is forgotten after expiration of its envelope
+func (verifier *Verifier) claimNonce(nonce []byte, expires time.Time) bool {
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+	now := time.Now()
+	for key, until := range verifier.opened {
+		if now.After(until) {
+			delete(verifier.opened, key)
+		}
+	}
+	key := string(nonce)
+	if _, seen := verifier.opened[key]; seen {
+		return false
+	}
+	verifier.opened[key] = expires
+	return true
+}
+
+// Forget - nonce of the opened envelope is forgotten, so the envelope delivered again is opened too
+func (verifier *Verifier) Forget(data []byte) {
+	var envelope Envelope
+	if !verifier.Enabled() || json.Unmarshal(data, &envelope) != nil {
+		return
+	}
+	verifier.mutex.Lock()
+	defer verifier.mutex.Unlock()
+	delete(verifier.opened, string(envelope.Nonce))
+}
+
+func signHMAC(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+/*
+Seal - envelope of the message for bombers published to the subject, as orchestrators written in go send it.
+Key is secret of hmac or seed of nkey by signing, encryption key is off if payload is not encrypted
+*/
+func Seal(subject string, message []byte, signing string, key string, encryptionKey string) ([]byte, error) {
+	envelope := Envelope{
+		Time:    time.Now().UnixNano() / int64(time.Millisecond),
+		Nonce:   make([]byte, envelopeNonceSize),
+		Payload: message,
+	}
+	if _, err := io.ReadFull(rand.Reader, envelope.Nonce); err != nil {
+		return nil, err
+	}
+	if enabled(encryptionKey) {
+		aead, err := newAEAD(encryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return nil, err
+		}
+		envelope.Payload = aead.Seal(nonce, nonce, message, nil)
+	}
+	switch signing {
+	case SigningDisabled, "":
+	case SigningHMAC:
+		envelope.Signature = signHMAC([]byte(key), envelope.signed(subject))
+	case SigningNKey:
+		signer, err := nkeys.FromSeed([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		if envelope.Signer, err = signer.PublicKey(); err != nil {
+			return nil, err
+		}
+		if envelope.Signature, err = signer.Sign(envelope.signed(subject)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnknownSigning
+	}
+	return json.Marshal(envelope)
+}
@@ -0,0 +1,275 @@
+package nats_listener
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/nats-io/nkeys"
+)
+
+var (
+	testEncryptionKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{7}, 32))
+	testMessage       = []byte(`{"formId":"form"}`)
+)
+
+const testSubject = "team-a.bombers.tasks.bomber"
+
+func newTestSigner(t *testing.T) (seed string, public string) {
+	t.Helper()
+	signer, err := nkeys.CreateUser()
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedBytes, err := signer.Seed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	public, err = signer.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(seedBytes), public
+}
+
+func TestSealOpen(t *testing.T) {
+	seed, public := newTestSigner(t)
+	cases := []struct {
+		name       string
+		preference config.Configuration
+		signing    string
+		key        string
+		encryption string
+	}{
+		{"hmac", config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret", MessageMaxAge: 60}, SigningHMAC, "secret", ""},
+		{"nkey", config.Configuration{MessageSigning: SigningNKey, MessageSigners: public, MessageMaxAge: 60}, SigningNKey, seed, ""},
+		{"encryption only", config.Configuration{MessageEncryptionKey: testEncryptionKey}, SigningDisabled, "", testEncryptionKey},
+		{"hmac and encryption", config.Configuration{
+			MessageSigning: SigningHMAC, MessageHMACKey: "secret", MessageEncryptionKey: testEncryptionKey, MessageMaxAge: 60,
+		}, SigningHMAC, "secret", testEncryptionKey},
+		{"nkey and encryption", config.Configuration{
+			MessageSigning: SigningNKey, MessageSigners: "  " + public + " ", MessageEncryptionKey: testEncryptionKey, MessageMaxAge: 60,
+		}, SigningNKey, seed, testEncryptionKey},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier, err := NewVerifier(&tc.preference)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sealed, err := Seal(testSubject, testMessage, tc.signing, tc.key, tc.encryption)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.encryption != "" && bytes.Contains(sealed, []byte("form")) {
+				t.Fatal("encrypted envelope contains the message")
+			}
+			opened, err := verifier.Open(testSubject, sealed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(opened, testMessage) {
+				t.Fatalf("opened %s, expected %s", opened, testMessage)
+			}
+		})
+	}
+}
+
+func TestOpenDisabled(t *testing.T) {
+	verifier, err := NewVerifier(&config.Configuration{MessageSigning: SigningDisabled, MessageEncryptionKey: SecurityDisabled})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verifier.Enabled() {
+		t.Fatal("verifier is enabled without signing and encryption")
+	}
+	opened, err := verifier.Open(testSubject, testMessage)
+	if err != nil || !bytes.Equal(opened, testMessage) {
+		t.Fatalf("message is not passed as it is: %s, %v", opened, err)
+	}
+}
+
+func TestNewVerifierErrors(t *testing.T) {
+	cases := []struct {
+		name       string
+		preference config.Configuration
+		err        error
+	}{
+		{"unknown signing", config.Configuration{MessageSigning: "rsa"}, ErrUnknownSigning},
+		{"hmac without key", config.Configuration{MessageSigning: SigningHMAC}, ErrSigningKey},
+		{"nkey without signers", config.Configuration{MessageSigning: SigningNKey, MessageSigners: SecurityDisabled}, ErrSigningKey},
+		{"short encryption key", config.Configuration{MessageEncryptionKey: base64.StdEncoding.EncodeToString([]byte("short"))}, ErrEncryptionKey},
+		{"encryption key of not base64", config.Configuration{MessageEncryptionKey: "not base64!"}, ErrEncryptionKey},
+		{"hmac without max age", config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret"}, ErrMaxAge},
+		{"negative max age", config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret", MessageMaxAge: -1}, ErrMaxAge},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewVerifier(&tc.preference); err != tc.err {
+				t.Fatalf("error %v, expected %v", err, tc.err)
+			}
+		})
+	}
+	if _, err := NewVerifier(&config.Configuration{MessageSigning: SigningNKey, MessageSigners: "not a key"}); err == nil {
+		t.Fatal("signer, which is not public nkey, is accepted")
+	}
+}
+
+// sealAt - envelope signed by hmac with the changed time of sealing and nonce
+func sealAt(t *testing.T, key string, at time.Time, nonce []byte) []byte {
+	t.Helper()
+	envelope := Envelope{Time: at.UnixNano() / int64(time.Millisecond), Nonce: nonce, Payload: testMessage}
+	envelope.Signature = signHMAC([]byte(key), envelope.signed(testSubject))
+	data, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestOpenRejects(t *testing.T) {
+	seed, public := newTestSigner(t)
+	otherSeed, _ := newTestSigner(t)
+	hmacVerifier, err := NewVerifier(&config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret", MessageMaxAge: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	nkeyVerifier, err := NewVerifier(&config.Configuration{MessageSigning: SigningNKey, MessageSigners: public, MessageMaxAge: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encryptedVerifier, err := NewVerifier(&config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret",
+		MessageEncryptionKey: testEncryptionKey, MessageMaxAge: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealFor := func(subject string, signing string, key string, encryption string) []byte {
+		sealed, err := Seal(subject, testMessage, signing, key, encryption)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sealed
+	}
+	seal := func(signing string, key string, encryption string) []byte {
+		return sealFor(testSubject, signing, key, encryption)
+	}
+	tampered := func(sealed []byte) []byte {
+		var envelope Envelope
+		if err := json.Unmarshal(sealed, &envelope); err != nil {
+			t.Fatal(err)
+		}
+		envelope.Payload = append([]byte{}, envelope.Payload...)
+		envelope.Payload[len(envelope.Payload)-1] ^= 1
+		data, err := json.Marshal(envelope)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	wrongEncryption := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{8}, 32))
+	cases := []struct {
+		name     string
+		verifier *Verifier
+		data     []byte
+		err      error
+	}{
+		{"plain message", hmacVerifier, testMessage, ErrNotEnvelope},
+		{"envelope without time", hmacVerifier, []byte(`{"payload":"e30="}`), ErrNotEnvelope},
+		{"unsigned", hmacVerifier, seal(SigningDisabled, "", ""), ErrSignature},
+		{"wrong hmac key", hmacVerifier, seal(SigningHMAC, "guess", ""), ErrSignature},
+		{"tampered payload", hmacVerifier, tampered(seal(SigningHMAC, "secret", "")), ErrSignature},
+		{"other subject", hmacVerifier, sealFor("team-a.bombers.tasks.other", SigningHMAC, "secret", ""), ErrSignature},
+		{"subject without namespace", hmacVerifier, sealFor("bombers.tasks.bomber", SigningHMAC, "secret", ""), ErrSignature},
+		{"without nonce", hmacVerifier, sealAt(t, "secret", time.Now(), nil), ErrNotEnvelope},
+		{"expired", hmacVerifier, sealAt(t, "secret", time.Now().Add(-2*time.Minute), []byte("expired")), ErrExpiredEnvelope},
+		{"from future", hmacVerifier, sealAt(t, "secret", time.Now().Add(2*time.Minute), []byte("future")), ErrExpiredEnvelope},
+		{"untrusted signer", nkeyVerifier, seal(SigningNKey, otherSeed, ""), ErrUntrustedSigner},
+		{"hmac instead of nkey", nkeyVerifier, seal(SigningHMAC, seed, ""), ErrUntrustedSigner},
+		{"not encrypted", encryptedVerifier, seal(SigningHMAC, "secret", ""), ErrEncryptedPayload},
+		{"wrong encryption key", encryptedVerifier, seal(SigningHMAC, "secret", wrongEncryption), ErrEncryptedPayload},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := tc.verifier.Open(testSubject, tc.data); err != tc.err {
+				t.Fatalf("error %v, expected %v", err, tc.err)
+			}
+		})
+	}
+	if _, err := hmacVerifier.Open(testSubject, sealAt(t, "secret", time.Now().Add(-30*time.Second), []byte("recent"))); err != nil {
+		t.Fatalf("envelope within max age is rejected: %v", err)
+	}
+}
+
+func TestOpenReplayed(t *testing.T) {
+	seed, public := newTestSigner(t)
+	cases := []struct {
+		name       string
+		preference config.Configuration
+		signing    string
+		key        string
+	}{
+		{"hmac", config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret", MessageMaxAge: 60}, SigningHMAC, "secret"},
+		{"nkey", config.Configuration{MessageSigning: SigningNKey, MessageSigners: public, MessageMaxAge: 60}, SigningNKey, seed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			verifier, err := NewVerifier(&tc.preference)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sealed, err := Seal(testSubject, testMessage, tc.signing, tc.key, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifier.Open(testSubject, sealed); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifier.Open(testSubject, sealed); err != ErrReplayedEnvelope {
+				t.Fatalf("error %v, expected %v", err, ErrReplayedEnvelope)
+			}
+			verifier.Forget(sealed)
+			if _, err := verifier.Open(testSubject, sealed); err != nil {
+				t.Fatalf("forgotten envelope is rejected: %v", err)
+			}
+			other, err := Seal(testSubject, testMessage, tc.signing, tc.key, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := verifier.Open(testSubject, other); err != nil {
+				t.Fatalf("envelope of the same message with other nonce is rejected: %v", err)
+			}
+		})
+	}
+}
+
+func TestClaimNonce(t *testing.T) {
+	verifier, err := NewVerifier(&config.Configuration{MessageSigning: SigningHMAC, MessageHMACKey: "secret", MessageMaxAge: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !verifier.claimNonce([]byte("expired"), time.Now().Add(-time.Second)) || !verifier.claimNonce([]byte("valid"), time.Now().Add(time.Minute)) {
+		t.Fatal("new nonce is not claimed")
+	}
+	// nonce of expired envelope is forgotten, the envelope itself is rejected by its time
+	if !verifier.claimNonce([]byte("expired"), time.Now().Add(time.Minute)) {
+		t.Fatal("nonce of expired envelope is kept")
+	}
+	if verifier.claimNonce([]byte("valid"), time.Now().Add(time.Minute)) {
+		t.Fatal("nonce is claimed twice")
+	}
+}
+
+func TestSealErrors(t *testing.T) {
+	if _, err := Seal(testSubject, testMessage, "rsa", "", ""); err != ErrUnknownSigning {
+		t.Fatalf("error %v, expected ErrUnknownSigning", err)
+	}
+	if _, err := Seal(testSubject, testMessage, SigningNKey, "not a seed", ""); err == nil {
+		t.Fatal("nkey signing accepts seed, which is not nkey")
+	}
+	if _, err := Seal(testSubject, testMessage, SigningHMAC, "secret", "short"); err != ErrEncryptionKey {
+		t.Fatalf("error %v, expected ErrEncryptionKey", err)
+	}
+}
@@ -0,0 +1,212 @@
+package nats_listener
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	/*JetStreamDisabled - value of stream which turns off consuming of tasks from JetStream*/
+	JetStreamDisabled = "off"
+
+	jetStreamConsumerCreate = "$JS.API.CONSUMER.DURABLE.CREATE."
+	jetStreamConsumerNext   = "$JS.API.CONSUMER.MSG.NEXT."
+	jetStreamRequestTimeout = 5 * time.Second
+	// pull request is repeated while bomber is idle, server may drop waiting requests
+	jetStreamPullInterval = 30 * time.Second
+)
+
+var ErrJetStreamRejected = errors.New("jetstream rejected durable consumer of tasks")
+
+type jetStreamConsumerConfig struct {
+	DurableName   string `json:"durable_name"`
+	DeliverPolicy string `json:"deliver_policy"`
+	AckPolicy     string `json:"ack_policy"`
+	AckWait       int64  `json:"ack_wait"`
+	FilterSubject string `json:"filter_subject"`
+	ReplayPolicy  string `json:"replay_policy"`
+}
+
+type jetStreamConsumerRequest struct {
+	Stream string                  `json:"stream_name"`
+	Config jetStreamConsumerConfig `json:"config"`
+}
+
+type jetStreamResponse struct {
+	Error *struct {
+		Code        int    `json:"code"`
+		Description string `json:"description"`
+	} `json:"error,omitempty"`
+}
+
+/*
+JetStreamMessage - task delivered by durable consumer, it is redelivered until
+it is acknowledged or terminated, also after ack wait if bomber did not answer
+*/
+type JetStreamMessage struct {
+	*nats.Msg
+}
+
+// Ack - task is accepted, it is not delivered again
+func (message *JetStreamMessage) Ack() error {
+	return message.Respond([]byte("+ACK"))
+}
+
+// Nak - task is delivered again, maybe to another bomber
+func (message *JetStreamMessage) Nak() error {
+	return message.Respond([]byte("-NAK"))
+}
+
+// NakWithDelay - task is delivered again not earlier than after the delay
+func (message *JetStreamMessage) NakWithDelay(delay time.Duration) error {
+	answer, err := json.Marshal(struct {
+		Delay int64 `json:"delay"`
+	}{Delay: delay.Nanoseconds()})
+	if err != nil {
+		return err
+	}
+	return message.Respond(append([]byte("-NAK "), answer...))
+}
+
+// Term - task can not be executed by any bomber, it is not delivered again
+func (message *JetStreamMessage) Term() error {
+	return message.Respond([]byte("+TERM"))
+}
+
+// InProgress - resets ack wait of the task
+func (message *JetStreamMessage) InProgress() error {
+	return message.Respond([]byte("+WPI"))
+}
+
+/*
+JetStreamConsumer - pulls tasks of the subject from stream by durable consumer with explicit ack.
+Tasks published while no bomber is running stay in the stream, bombers sharing the durable
+consumer take them one by one, when they are idle
+*/
+type JetStreamConsumer struct {
+	Connection *nats.Conn
+	stream     string
+	durable    string
+	subject    string
+	ackWait    time.Duration
+}
+
+// NewJetStreamConsumer - nil if stream is disabled, stream has to be created on the server
+func NewJetStreamConsumer(connection *nats.Conn, stream string, durable string, subject string, ackWait time.Duration) *JetStreamConsumer {
+	if stream == JetStreamDisabled || stream == "" {
+		return nil
+	}
+	return &JetStreamConsumer{
+		Connection: connection,
+		stream:     stream,
+		durable:    durable,
+		subject:    subject,
+		ackWait:    ackWait,
+	}
+}
+
+/*
+Subscribe - handler has to answer each message by Ack, Nak or Term, while it works
+the message is kept in progress. Next message is pulled when idle is true
+*/
+func (consumer *JetStreamConsumer) Subscribe(handler func(message *JetStreamMessage), idle func() bool) error {
+	if err := consumer.ensureConsumer(); err != nil {
+		return err
+	}
+	inbox := nats.NewInbox()
+	subscription, err := consumer.Connection.Subscribe(inbox, func(msg *nats.Msg) {
+		// status messages of pull requests have no payload without headers support
+		if msg.Reply == "" || len(msg.Data) == 0 {
+			return
+		}
+		message := &JetStreamMessage{Msg: msg}
+		if !idle() {
+			if err := message.Nak(); err != nil {
+				logrus.Error("Can not return task into JetStream: ", err)
+			}
+			return
+		}
+		consumer.handle(handler, message)
+	})
+	if err != nil {
+		return err
+	}
+	logrus.Info("Completed subscription: ", consumer.subject, " stream: ", consumer.stream, " durable: ", consumer.durable)
+	go consumer.pull(subscription, inbox, idle)
+	return nil
+}
+
+func (consumer *JetStreamConsumer) handle(handler func(message *JetStreamMessage), message *JetStreamMessage) {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(consumer.ackWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := message.InProgress(); err != nil {
+					logrus.Error("Can not prolong ack wait of task: ", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	handler(message)
+}
+
+func (consumer *JetStreamConsumer) pull(subscription *nats.Subscription, inbox string, idle func() bool) {
+	// batch of one task
+	request := []byte("1")
+	var lastPull time.Time
+	for subscription.IsValid() {
+		if idle() && consumer.Connection.IsConnected() && time.Since(lastPull) >= jetStreamPullInterval {
+			if err := consumer.Connection.PublishRequest(jetStreamConsumerNext+consumer.stream+"."+consumer.durable, inbox, request); err != nil {
+				logrus.Error("Can not pull task from JetStream: ", err)
+			} else {
+				lastPull = time.Now()
+			}
+		}
+		if !idle() {
+			// after the task new pull is sent as soon as bomber is idle
+			lastPull = time.Time{}
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// ensureConsumer - creation of durable consumer with the same config is idempotent
+func (consumer *JetStreamConsumer) ensureConsumer() error {
+	request, err := json.Marshal(jetStreamConsumerRequest{
+		Stream: consumer.stream,
+		Config: jetStreamConsumerConfig{
+			DurableName:   consumer.durable,
+			DeliverPolicy: "all",
+			AckPolicy:     "explicit",
+			AckWait:       consumer.ackWait.Nanoseconds(),
+			FilterSubject: consumer.subject,
+			ReplayPolicy:  "instant",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	reply, err := consumer.Connection.Request(jetStreamConsumerCreate+consumer.stream+"."+consumer.durable, request, jetStreamRequestTimeout)
+	if err != nil {
+		return err
+	}
+	var response jetStreamResponse
+	if err := json.Unmarshal(reply.Data, &response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		logrus.Error("JetStream rejected consumer: ", response.Error.Code, " ", response.Error.Description)
+		return ErrJetStreamRejected
+	}
+	return nil
+}
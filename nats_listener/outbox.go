@@ -0,0 +1,161 @@
+package nats_listener
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	/*OutboxDisabled - value of directory which turns off persisting of messages*/
+	OutboxDisabled = "off"
+
+	outboxSuffix       = ".msg"
+	outboxFlushTimeout = 5 * time.Second
+)
+
+var ErrNotConnected = errors.New("connection to broker is not established")
+
+type outboxMessage struct {
+	Subject string `json:"subject"`
+	Data    []byte `json:"data"`
+}
+
+/*
+Outbox - persists messages into directory before publishing and removes them only after
+server has received them. Messages left by failed publishes or by previous run of the bomber
+are published again in order after reconnect
+*/
+type Outbox struct {
+	dir       string
+	publisher *Publisher
+	mutex     sync.Mutex
+	sequence  int64
+}
+
+// NewOutbox - nil if directory is disabled, messages are published directly then
+func NewOutbox(dir string, publisher *Publisher) *Outbox {
+	if dir == OutboxDisabled || dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logrus.Error("Can not create outbox directory: ", err)
+		return nil
+	}
+	outbox := &Outbox{dir: dir, publisher: publisher}
+	if publisher.Connection != nil {
+		OnReconnect(publisher.Connection, func() {
+			go outbox.Resend()
+		})
+	}
+	go outbox.Resend()
+	return outbox
+}
+
+/*
+Publish - message is kept on disk if it can not be published now, so an error means
+it was neither persisted nor published. Messages persisted earlier are published first
+*/
+func (outbox *Outbox) Publish(subject string, data []byte) error {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+	if err := outbox.persist(subject, data); err != nil {
+		return err
+	}
+	outbox.resend()
+	return nil
+}
+
+// Resend - publishes persisted messages in order until the first failure
+func (outbox *Outbox) Resend() {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+	outbox.resend()
+}
+
+func (outbox *Outbox) resend() {
+	files, err := ioutil.ReadDir(outbox.dir)
+	if err != nil {
+		logrus.Error("Can not read outbox directory: ", err)
+		return
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), outboxSuffix) {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(outbox.dir, name)
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			logrus.Error("Can not read message of outbox: ", err)
+			continue
+		}
+		var message outboxMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			logrus.Error("Can not unmarshal message of outbox, it is dropped: ", err)
+			os.Remove(path)
+			continue
+		}
+		if err := outbox.deliver(message.Subject, message.Data); err != nil {
+			logrus.Error("Can not publish message into ", message.Subject, ", it is kept for re-publish: ", err)
+			return
+		}
+		os.Remove(path)
+	}
+}
+
+// deliver - publishing while reconnecting only buffers the message, so it is not even tried
+func (outbox *Outbox) deliver(subject string, data []byte) error {
+	if !outbox.publisher.Broker.Connected() {
+		return ErrNotConnected
+	}
+	// messages of outbox are kept on disk, they must not be taken by buffer of reconnect
+	if err := outbox.publisher.Broker.Publish(subject, data); err != nil {
+		return err
+	}
+	return outbox.publisher.Broker.Flush(outboxFlushTimeout)
+}
+
+func (outbox *Outbox) persist(subject string, data []byte) error {
+	marshaled, err := json.Marshal(outboxMessage{Subject: subject, Data: data})
+	if err != nil {
+		return err
+	}
+	outbox.sequence++
+	path := filepath.Join(outbox.dir, fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), outbox.sequence%1000000, outboxSuffix))
+	temporary, err := ioutil.TempFile(outbox.dir, ".outbox-*")
+	if err != nil {
+		return err
+	}
+	if _, err := temporary.Write(marshaled); err != nil {
+		temporary.Close()
+		os.Remove(temporary.Name())
+		return err
+	}
+	if err := temporary.Sync(); err != nil {
+		temporary.Close()
+		os.Remove(temporary.Name())
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		os.Remove(temporary.Name())
+		return err
+	}
+	if err := os.Rename(temporary.Name(), path); err != nil {
+		os.Remove(temporary.Name())
+		return err
+	}
+	return nil
+}
@@ -1,17 +1,38 @@
 package nats_listener
 
-import "github.com/nats-io/nats.go"
+import (
+	"github.com/bomber-team/rest-bomber/broker"
+	"github.com/nats-io/nats.go"
+)
 
 type Publisher struct {
+	Broker broker.Broker
+	// nil if the broker is not NATS
 	Connection *nats.Conn
+	// nil if connection was not created by CreateNewConnectionToNats
+	buffer *reconnectBuffer
 }
 
-func NewPublisher(connection *nats.Conn) *Publisher {
-	return &Publisher{
-		Connection: connection,
+func NewPublisher(bus broker.Broker) *Publisher {
+	publisher := &Publisher{
+		Broker:     bus,
+		Connection: NatsConnection(bus),
 	}
+	if state := stateOf(publisher.Connection); state != nil {
+		publisher.buffer = state.buffer
+	}
+	return publisher
 }
 
+// PublishNewMessage - while connection to NATS is lost message is buffered until reconnect
 func (publsh *Publisher) PublishNewMessage(topic string, message []byte) error {
-	return publsh.Connection.Publish(topic, message)
+	if publsh.buffer == nil {
+		return publsh.Broker.Publish(topic, message)
+	}
+	return publsh.buffer.publish(publsh.Connection, broker.SubjectOf(publsh.Broker, topic), message)
+}
+
+// MaxPayload - limit of message size of the connected server
+func (publsh *Publisher) MaxPayload() int {
+	return publsh.Broker.MaxPayload()
 }
@@ -0,0 +1,75 @@
+package nats_listener
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/sirupsen/logrus"
+)
+
+const reconnectFlushTimeout = 5 * time.Second
+
+var ErrReconnectBufferFull = errors.New("buffer of messages published while reconnecting is full")
+
+/*
+reconnectBuffer - messages published while connection is lost, they are published
+in order of publishing after reconnect. Messages over limit of bytes are dropped
+*/
+type reconnectBuffer struct {
+	mutex    sync.Mutex
+	messages []outboxMessage
+	bytes    int
+	limit    int
+}
+
+func newReconnectBuffer(limit int) *reconnectBuffer {
+	return &reconnectBuffer{limit: limit}
+}
+
+func (buffer *reconnectBuffer) publish(connection *nats.Conn, subject string, data []byte) error {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	if len(buffer.messages) == 0 && connection.IsConnected() {
+		err := connection.Publish(subject, data)
+		if err != nats.ErrReconnectBufExceeded {
+			return err
+		}
+	}
+	if connection.IsClosed() {
+		return nats.ErrConnectionClosed
+	}
+	if buffer.bytes+len(data) > buffer.limit {
+		logrus.Error("Can not buffer message into ", subject, " while reconnecting, it is dropped: ", ErrReconnectBufferFull)
+		return ErrReconnectBufferFull
+	}
+	// publisher can reuse its slice after return
+	buffer.messages = append(buffer.messages, outboxMessage{Subject: subject, Data: append([]byte(nil), data...)})
+	buffer.bytes += len(data)
+	logrus.Debug("Message into ", subject, " is buffered until reconnect")
+	return nil
+}
+
+// flush - messages which can not be published yet stay in buffer for the next reconnect
+func (buffer *reconnectBuffer) flush(connection *nats.Conn) {
+	buffer.mutex.Lock()
+	defer buffer.mutex.Unlock()
+	if len(buffer.messages) == 0 {
+		return
+	}
+	published := 0
+	for _, message := range buffer.messages {
+		if err := connection.Publish(message.Subject, message.Data); err != nil {
+			logrus.Error("Can not publish buffered message into ", message.Subject, ": ", err)
+			break
+		}
+		buffer.bytes -= len(message.Data)
+		published++
+	}
+	buffer.messages = buffer.messages[published:]
+	if err := connection.FlushTimeout(reconnectFlushTimeout); err != nil {
+		logrus.Error("Can not flush buffered messages: ", err)
+	}
+	logrus.Info("Published ", published, " messages buffered while reconnecting")
+}
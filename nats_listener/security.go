@@ -0,0 +1,68 @@
+package nats_listener
+
+import (
+	"errors"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/nats-io/nats.go"
+)
+
+/*SecurityDisabled - value of credential or file which turns it off*/
+const SecurityDisabled = "off"
+
+var (
+	ErrIncompleteClientCert = errors.New("client certificate of nats requires both NATS_TLS_CERT and NATS_TLS_KEY")
+	ErrAmbiguousAuth        = errors.New("only one of NATS_USER, NATS_TOKEN, NATS_CREDS and NATS_NKEY_SEED can be used")
+)
+
+func enabled(value string) bool {
+	return value != SecurityDisabled && value != ""
+}
+
+/*
+securityOptions - TLS and authentication of the connection. TLS is turned on by NATS_TLS, by CA or by
+client certificate, server is verified by system roots if CA is not set
+*/
+func securityOptions(preference *config.Configuration) ([]nats.Option, error) {
+	options := []nats.Option{}
+	if preference.TLS {
+		options = append(options, nats.Secure())
+	}
+	if enabled(preference.TLSCA) {
+		options = append(options, nats.RootCAs(preference.TLSCA))
+	}
+	if enabled(preference.TLSCert) != enabled(preference.TLSKey) {
+		return nil, ErrIncompleteClientCert
+	}
+	if enabled(preference.TLSCert) {
+		options = append(options, nats.ClientCert(preference.TLSCert, preference.TLSKey))
+	}
+	methods := 0
+	for _, value := range []string{preference.User, preference.Token, preference.Creds, preference.NKeySeed} {
+		if enabled(value) {
+			methods++
+		}
+	}
+	if methods > 1 {
+		return nil, ErrAmbiguousAuth
+	}
+	switch {
+	case enabled(preference.User):
+		password := preference.Password
+		if !enabled(password) {
+			password = ""
+		}
+		options = append(options, nats.UserInfo(preference.User, password))
+	case enabled(preference.Token):
+		options = append(options, nats.Token(preference.Token))
+	case enabled(preference.Creds):
+		options = append(options, nats.UserCredentials(preference.Creds))
+	case enabled(preference.NKeySeed):
+		option, err := nats.NkeyOptionFromSeed(preference.NKeySeed)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
@@ -1,28 +1,57 @@
 package nats_listener
 
 import (
-	"github.com/nats-io/nats.go"
+	"github.com/bomber-team/rest-bomber/broker"
 	"github.com/sirupsen/logrus"
 )
 
+/*QueueDisabled - value of queue group which turns off shared subscription*/
+const QueueDisabled = "off"
+
 type Subscriber struct {
-	Connection *nats.Conn
-	topic      string
+	Broker       broker.Broker
+	topic        string
+	queue        string
+	subscription broker.Subscription
 }
 
-func NewSubscriber(connection *nats.Conn, topicName string) *Subscriber {
+func NewSubscriber(bus broker.Broker, topicName string) *Subscriber {
 	return &Subscriber{
-		Connection: connection,
-		topic:      topicName,
+		Broker: bus,
+		topic:  topicName,
 	}
 }
 
-func (subscr *Subscriber) Subscribe(handler nats.MsgHandler) error {
-	subscription, err := subscr.Connection.Subscribe(subscr.topic, handler)
+/*
+NewQueueSubscriber - subscribers of the same queue group share the topic, broker delivers
+each message to only one of them. Nil if queue group is disabled
+*/
+func NewQueueSubscriber(bus broker.Broker, topicName string, queue string) *Subscriber {
+	if queue == QueueDisabled || queue == "" {
+		return nil
+	}
+	return &Subscriber{
+		Broker: bus,
+		topic:  topicName,
+		queue:  queue,
+	}
+}
+
+func (subscr *Subscriber) Subscribe(handler broker.Handler) error {
+	subscription, err := subscr.Broker.Subscribe(subscr.topic, subscr.queue, handler)
 	if err != nil {
 		return err
 	}
+	subscr.subscription = subscription
 
-	logrus.Info("Completed subscription: ", subscription)
+	logrus.Info("Completed subscription: ", subscr.topic, " queue: ", subscr.queue)
 	return nil
 }
+
+// Unsubscribe - messages of the topic are not delivered anymore, members of queue group get them instead
+func (subscr *Subscriber) Unsubscribe() error {
+	if subscr.subscription == nil {
+		return nil
+	}
+	return subscr.subscription.Unsubscribe()
+}
@@ -1,14 +1,23 @@
 package main
 
 import (
+	"flag"
 	"os"
 	"os/signal"
 	"runtime"
 	"syscall"
+	"time"
 
+	"github.com/bomber-team/rest-bomber/admin"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/control"
+	"github.com/bomber-team/rest-bomber/coordinator"
 	"github.com/bomber-team/rest-bomber/core"
 	"github.com/bomber-team/rest-bomber/handlers"
+	"github.com/bomber-team/rest-bomber/health"
 	"github.com/bomber-team/rest-bomber/helping"
+	"github.com/bomber-team/rest-bomber/metrics"
+	"github.com/bomber-team/rest-bomber/standalone"
 	"github.com/sirupsen/logrus"
 )
 
@@ -26,17 +35,82 @@ func workOnServiceSingal(signal int) {
 	}
 }
 
+// reloadLogLevel - level set by admin API is kept until log level of configuration is changed
+func reloadLogLevel(configuration *config.Configuration, reload *config.Reload) {
+	if !reload.Changed("LogLevel") {
+		return
+	}
+	level, err := logrus.ParseLevel(configuration.LogLevel)
+	if err != nil {
+		logrus.Error("Can not parse log level: ", err)
+		return
+	}
+	logrus.SetLevel(level)
+	logrus.Info("Log level is changed by reloaded configuration to ", level)
+}
+
+// applyLogLevel - level of LOG_LEVEL, messages of loading configuration are logged by info level before it
+func applyLogLevel(configuration *config.Configuration) {
+	level, err := logrus.ParseLevel(configuration.LogLevel)
+	if err != nil {
+		logrus.Error("Can not parse log level: ", err)
+		return
+	}
+	logrus.SetLevel(level)
+}
+
+// reloadOnHangup - SIGHUP reloads configuration, as reload command does
+func reloadOnHangup(reloader *config.Reloader) {
+	sigHup := make(chan os.Signal, 1)
+	signal.Notify(sigHup, syscall.SIGHUP)
+	for range sigHup {
+		logrus.Info("Service catch signal to reload configuration")
+		if _, err := reloader.Reload(); err != nil {
+			logrus.Error("Can not reload configuration: ", err)
+		}
+	}
+}
+
 func main() {
 	logrus.SetLevel(logrus.InfoLevel)
+	logrus.SetOutput(os.Stdout)
 	runtime.GOMAXPROCS(runtime.NumCPU())
 
-	core := core.NewCore()
-	coreHandler, errorHandling := handlers.NewCoreHandlers(core)
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	settings := config.RegisterFlags(flags)
+	standaloneOptions := standalone.RegisterFlags(flags)
+	flags.Parse(os.Args[1:])
+	configuration, errConfiguration := config.Load(settings)
+	if errConfiguration != nil {
+		workOnServiceSingal(helping.FATALERROR)
+	}
+	applyLogLevel(configuration)
+	if standaloneOptions.Enabled() {
+		os.Exit(standalone.Run(standaloneOptions, configuration))
+	}
+	core := core.NewCore(configuration)
+	reloader := config.NewReloader(settings, configuration)
+	reloader.OnReload(reloadLogLevel)
+	reloader.OnReload(core.ReloadHeartbeat)
+	config := core.GetConfig()
+	go metrics.Serve(config.MetricsAddr)
+	metrics.StartStatsD(config.StatsDAddr, config.StatsDPrefix, config.DogStatsD)
+	metrics.StartOTLP(config.OTLPEndpoint, config.CurrentServiceID, time.Duration(config.OTLPIntervalMs)*time.Millisecond)
+	coreHandler, errorHandling := handlers.NewCoreHandlers(core, reloader)
 	if errorHandling != nil {
 		logrus.Panic("Can not initialize consuming handler")
 	}
+	go health.Serve(config.HealthAddr, coreHandler.Ready)
+	go control.Serve(config.ControlGRPCAddr, config.AdminToken, coreHandler, core)
+	go admin.Serve(config.AdminAddr, config.AdminToken, coreHandler)
 
 	coreHandler.InitBomber()
+	core.InitializeService()
+	core.StartHeartbeat(time.Duration(config.HeartbeatInterval) * time.Second)
+	core.StartProgress(time.Duration(config.ProgressIntervalMs) * time.Millisecond)
+	coordinator.Start(core.GetBroker(), config)
+	go reloader.Watch(time.Duration(config.ConfigWatchInterval) * time.Second)
+	go reloadOnHangup(reloader)
 
 	signalService := make(chan int)
 
@@ -51,8 +125,13 @@ func main() {
 		coreHandler.ShutdownToServer()
 		workOnServiceSingal(servSig)
 	case osSig := <-sigOs:
-		coreHandler.ShutdownToServer()
-		logrus.Error("Service catch signal terminated: ", osSig)
-		os.Exit(1)
+		logrus.Info("Service catch signal terminated: ", osSig)
+		go func() {
+			// second signal does not wait for the drain
+			logrus.Error("Service catch signal terminated again: ", <-sigOs)
+			os.Exit(1)
+		}()
+		coreHandler.GracefulShutdown(time.Duration(config.ShutdownDrain) * time.Second)
+		workOnServiceSingal(helping.STOPSERVICE)
 	}
 }
@@ -0,0 +1,190 @@
+package sigv4
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	metadataTimeout       = 5 * time.Second
+	metadataRetryInterval = 5 * time.Second
+	// credentials of instance metadata are fetched again this time before their expiration
+	metadataRefreshBefore = 5 * time.Minute
+	// Refresh fetches them earlier, so requests being signed do not wait for instance metadata
+	metadataRefreshAhead = time.Minute
+)
+
+var (
+	ErrCredentials    = errors.New("aws credentials are neither configured nor in instance metadata")
+	ErrMetadataStatus = errors.New("instance metadata answered with status other than 200")
+)
+
+type Credentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+	Expiration   time.Time // zero for static credentials
+}
+
+/*
+Provider - static credentials or the ones of instance role by IMDSv2, the latter are
+fetched again before they expire
+*/
+type Provider struct {
+	metadataURL string
+	mutex       sync.RWMutex
+	credentials Credentials
+	// fetching is not retried before, so failing metadata does not hold every signed request
+	retryAt time.Time
+}
+
+// NewProvider - static credentials are used if both keys are set, metadata url is empty if instance role is not used
+func NewProvider(static Credentials, metadataURL string) *Provider {
+	provider := &Provider{metadataURL: strings.TrimSuffix(metadataURL, "/")}
+	if static.AccessKey != "" && static.SecretKey != "" {
+		static.Expiration = time.Time{}
+		provider.credentials = static
+	}
+	return provider
+}
+
+// Current - credentials to sign by, the ones of instance role are fetched if they are missing or about to expire
+func (provider *Provider) Current() (Credentials, error) {
+	provider.mutex.RLock()
+	credentials := provider.credentials
+	provider.mutex.RUnlock()
+	if credentials.AccessKey != "" && !expiring(credentials, time.Now()) {
+		return credentials, nil
+	}
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+	// fetched by other caller meanwhile
+	credentials = provider.credentials
+	now := time.Now()
+	if credentials.AccessKey != "" && (!expiring(credentials, now) || now.Before(provider.retryAt)) {
+		return credentials, nil
+	}
+	if provider.metadataURL == "" {
+		return Credentials{}, ErrCredentials
+	}
+	fetched, err := provider.fetch()
+	if err != nil {
+		provider.retryAt = now.Add(metadataRetryInterval)
+		// the previous credentials are used while they are valid
+		if credentials.AccessKey != "" && now.Before(credentials.Expiration) {
+			logrus.Error("Can not refresh aws credentials: ", err)
+			return credentials, nil
+		}
+		return Credentials{}, err
+	}
+	provider.credentials = fetched
+	return fetched, nil
+}
+
+func expiring(credentials Credentials, now time.Time) bool {
+	return !credentials.Expiration.IsZero() && now.After(credentials.Expiration.Add(-metadataRefreshBefore))
+}
+
+// Refresh - fetches credentials of instance role ahead of Current until ctx is done, static ones never expire
+func (provider *Provider) Refresh(ctx context.Context) {
+	// fetching is not repeated more often, metadata may answer with the same credentials
+	var least time.Duration
+	for {
+		provider.mutex.RLock()
+		expiration := provider.credentials.Expiration
+		provider.mutex.RUnlock()
+		if expiration.IsZero() {
+			return
+		}
+		wait := time.Until(expiration.Add(-metadataRefreshBefore - metadataRefreshAhead))
+		if wait < least {
+			wait = least
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		least = metadataRetryInterval
+		fetched, err := provider.fetch()
+		if err != nil {
+			logrus.Error("Can not refresh aws credentials: ", err)
+			continue
+		}
+		provider.mutex.Lock()
+		provider.credentials = fetched
+		provider.mutex.Unlock()
+	}
+}
+
+// fetch - credentials of the instance role by IMDSv2
+func (provider *Provider) fetch() (Credentials, error) {
+	client := &http.Client{Timeout: metadataTimeout}
+	tokenRequest, err := http.NewRequest(http.MethodPut, provider.metadataURL+"/latest/api/token", nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	tokenRequest.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := readMetadata(client, tokenRequest)
+	if err != nil {
+		return Credentials{}, err
+	}
+	get := func(path string) ([]byte, error) {
+		request, err := http.NewRequest(http.MethodGet, provider.metadataURL+path, nil)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("X-aws-ec2-metadata-token", string(token))
+		return readMetadata(client, request)
+	}
+	roles, err := get("/latest/meta-data/iam/security-credentials/")
+	if err != nil {
+		return Credentials{}, err
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return Credentials{}, ErrCredentials
+	}
+	data, err := get("/latest/meta-data/iam/security-credentials/" + role)
+	if err != nil {
+		return Credentials{}, err
+	}
+	var answer struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		Token           string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(data, &answer); err != nil {
+		return Credentials{}, err
+	}
+	if answer.AccessKeyId == "" || answer.SecretAccessKey == "" {
+		return Credentials{}, ErrCredentials
+	}
+	return Credentials{
+		AccessKey:    answer.AccessKeyId,
+		SecretKey:    answer.SecretAccessKey,
+		SessionToken: answer.Token,
+		Expiration:   answer.Expiration,
+	}, nil
+}
+
+func readMetadata(client *http.Client, request *http.Request) ([]byte, error) {
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, ErrMetadataStatus
+	}
+	return ioutil.ReadAll(response.Body)
+}
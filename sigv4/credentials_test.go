@@ -0,0 +1,127 @@
+package sigv4
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// metadataServer - IMDSv2 of instance with role, each fetch of credentials gets the next access key
+type metadataServer struct {
+	*httptest.Server
+	fetches    int32
+	failing    int32
+	expiration time.Duration
+}
+
+func newMetadataServer(t *testing.T, expiration time.Duration) *metadataServer {
+	server := &metadataServer{expiration: expiration}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if atomic.LoadInt32(&server.failing) == 1 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if request.URL.Path == "/latest/api/token" {
+			if request.Method != http.MethodPut || request.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			writer.Write([]byte("session"))
+			return
+		}
+		if request.Header.Get("X-aws-ec2-metadata-token") != "session" {
+			writer.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch request.URL.Path {
+		case "/latest/meta-data/iam/security-credentials/":
+			writer.Write([]byte("bomber-role\n"))
+		case "/latest/meta-data/iam/security-credentials/bomber-role":
+			fetch := atomic.AddInt32(&server.fetches, 1)
+			json.NewEncoder(writer).Encode(map[string]interface{}{
+				"AccessKeyId":     "ROLEKEY" + string(rune('0'+fetch)),
+				"SecretAccessKey": "secret",
+				"Token":           "token",
+				"Expiration":      time.Now().Add(server.expiration).UTC().Format(time.RFC3339),
+			})
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProviderStatic(t *testing.T) {
+	server := newMetadataServer(t, time.Hour)
+	provider := NewProvider(Credentials{AccessKey: "KEY", SecretKey: "secret", SessionToken: "token"}, server.URL)
+	credentials, err := provider.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credentials.AccessKey != "KEY" || credentials.SessionToken != "token" || !credentials.Expiration.IsZero() {
+		t.Fatalf("unexpected credentials %+v", credentials)
+	}
+	if atomic.LoadInt32(&server.fetches) != 0 {
+		t.Fatal("metadata is asked despite static credentials")
+	}
+}
+
+func TestProviderWithoutCredentials(t *testing.T) {
+	if _, err := NewProvider(Credentials{AccessKey: "KEY"}, "").Current(); err != ErrCredentials {
+		t.Fatalf("got %v, expected %v", err, ErrCredentials)
+	}
+}
+
+func TestProviderMetadata(t *testing.T) {
+	server := newMetadataServer(t, time.Hour)
+	provider := NewProvider(Credentials{}, server.URL+"/")
+	for attempt := 0; attempt < 3; attempt++ {
+		credentials, err := provider.Current()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if credentials.AccessKey != "ROLEKEY1" || credentials.SessionToken != "token" || credentials.Expiration.IsZero() {
+			t.Fatalf("unexpected credentials %+v", credentials)
+		}
+	}
+	if fetches := atomic.LoadInt32(&server.fetches); fetches != 1 {
+		t.Fatalf("credentials are fetched %d times, expected once", fetches)
+	}
+}
+
+func TestProviderFetchesExpiring(t *testing.T) {
+	// expiring sooner than they are fetched again before
+	server := newMetadataServer(t, metadataRefreshBefore-time.Minute)
+	provider := NewProvider(Credentials{}, server.URL)
+	first, err := provider.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := provider.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.AccessKey != "ROLEKEY1" || second.AccessKey != "ROLEKEY2" {
+		t.Fatalf("got %s and %s, expected credentials fetched again", first.AccessKey, second.AccessKey)
+	}
+	// previous credentials are still valid while metadata fails, it is not asked again until retry
+	atomic.StoreInt32(&server.failing, 1)
+	for attempt := 0; attempt < 3; attempt++ {
+		kept, err := provider.Current()
+		if err != nil || kept.AccessKey != "ROLEKEY2" {
+			t.Fatalf("got %+v and %v, expected previous credentials", kept, err)
+		}
+	}
+}
+
+func TestProviderMetadataFails(t *testing.T) {
+	server := newMetadataServer(t, time.Hour)
+	atomic.StoreInt32(&server.failing, 1)
+	if _, err := NewProvider(Credentials{}, server.URL).Current(); err != ErrMetadataStatus {
+		t.Fatalf("got %v, expected %v", err, ErrMetadataStatus)
+	}
+}
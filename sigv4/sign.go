@@ -0,0 +1,155 @@
+package sigv4
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	Algorithm = "AWS4-HMAC-SHA256"
+	// UnsignedPayload - hash of payload which is not signed, S3 accepts it
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// ServiceS3 - the only service which expects path encoded once
+	ServiceS3 = "s3"
+
+	dateFormat = "20060102T150405Z"
+)
+
+/*
+Request - parts of http request covered by the signature, whichever client sends it.
+Headers of the result of Sign have to be set on the sent request
+*/
+type Request struct {
+	Method string
+	Host   string
+	// path as it is before escaping
+	Path  string
+	Query url.Values
+	// signed headers besides host, x-amz-date and x-amz-security-token, they are set on the request already
+	Headers     map[string]string
+	PayloadHash string
+}
+
+// Scope - region and service credentials are used for
+type Scope struct {
+	Region  string
+	Service string
+}
+
+// Sign - headers to set on the request: X-Amz-Date, X-Amz-Security-Token with session token and Authorization
+func Sign(request Request, scope Scope, credentials Credentials, moment time.Time) map[string]string {
+	amzDate := moment.UTC().Format(dateFormat)
+	day := amzDate[:8]
+	headers := map[string]string{}
+	for name, value := range request.Headers {
+		headers[strings.ToLower(name)] = strings.TrimSpace(value)
+	}
+	headers["host"] = request.Host
+	headers["x-amz-date"] = amzDate
+	set := map[string]string{"X-Amz-Date": amzDate}
+	if credentials.SessionToken != "" {
+		headers["x-amz-security-token"] = credentials.SessionToken
+		set["X-Amz-Security-Token"] = credentials.SessionToken
+	}
+	canonical, signedNames := canonicalRequest(request, scope.Service, headers)
+	credentialScope := day + "/" + scope.Region + "/" + scope.Service + "/aws4_request"
+	toSign := Algorithm + "\n" + amzDate + "\n" + credentialScope + "\n" + HashPayload([]byte(canonical))
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretKey), day)
+	key = hmacSHA256(key, scope.Region)
+	key = hmacSHA256(key, scope.Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, toSign))
+	set["Authorization"] = Algorithm + " Credential=" + credentials.AccessKey + "/" + credentialScope +
+		", SignedHeaders=" + signedNames + ", Signature=" + signature
+	return set
+}
+
+// canonicalRequest - the request in canonical form and names of signed headers, headers have lower case names
+func canonicalRequest(request Request, service string, headers map[string]string) (string, string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedNames := strings.Join(names, ";")
+	path := request.Path
+	if path == "" {
+		path = "/"
+	}
+	path = escape(path, false)
+	// services other than s3 expect segments of path encoded twice
+	if service != ServiceS3 {
+		path = escape(path, false)
+	}
+	return strings.Join([]string{
+		request.Method,
+		path,
+		canonicalQuery(request.Query),
+		canonicalHeaders.String(),
+		signedNames,
+		request.PayloadHash,
+	}, "\n"), signedNames
+}
+
+// canonicalQuery - arguments sorted by name and then by value, both escaped
+func canonicalQuery(query url.Values) string {
+	type argument struct{ key, value string }
+	arguments := []argument{}
+	for key, values := range query {
+		for _, value := range values {
+			arguments = append(arguments, argument{escape(key, true), escape(value, true)})
+		}
+	}
+	sort.Slice(arguments, func(i, j int) bool {
+		if arguments[i].key != arguments[j].key {
+			return arguments[i].key < arguments[j].key
+		}
+		return arguments[i].value < arguments[j].value
+	})
+	pairs := make([]string, len(arguments))
+	for index, item := range arguments {
+		pairs[index] = item.key + "=" + item.value
+	}
+	return strings.Join(pairs, "&")
+}
+
+// escape - escapes all except unreserved characters, slashes are kept in paths
+func escape(value string, slash bool) string {
+	var escaped strings.Builder
+	for _, symbol := range []byte(value) {
+		switch {
+		case 'a' <= symbol && symbol <= 'z', 'A' <= symbol && symbol <= 'Z', '0' <= symbol && symbol <= '9',
+			symbol == '-', symbol == '_', symbol == '.', symbol == '~', symbol == '/' && !slash:
+			escaped.WriteByte(symbol)
+		default:
+			escaped.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{symbol})))
+		}
+	}
+	return escaped.String()
+}
+
+// EscapePath - path escaped as signature expects it to be sent, slashes are kept
+func EscapePath(path string) string {
+	return escape(path, false)
+}
+
+// HashPayload - hex of sha256 of the payload
+func HashPayload(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
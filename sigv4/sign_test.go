@@ -0,0 +1,115 @@
+package sigv4
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// credentials, scope and time of requests of aws signature v4 test suite
+var (
+	suiteCredentials = Credentials{AccessKey: "AKIDEXAMPLE", SecretKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	suiteScope       = Scope{Region: "us-east-1", Service: "service"}
+	suiteMoment      = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+)
+
+const suiteToken = "AQoDYXdzEPT//////////wEXAMPLEtc764bNrC9SAPBSM22wDOk4x4HIZ8j4FZTwdQWLWsKWHGBuFqwAeMicRXmxfpSPfIeoIYRqT" +
+	"flfKD8YUuwthAx7mSEI/qkPpKPi/kMcGdQrmGdeehM4IC1NtBmUpp2wUE8phUZampKsburEDy0KPkyQDYwT7WZ0wq5VSXDvp75YU9HFvlRd8Tx6" +
+	"q6fE8YQcHNVXAkiY9q6d+xo0rKwT38xVqr7ZD0u0iPPkUL64lIZbqBAz+scqKmlzm8FDrypNC9Yjc8fPOLn9FX9KSYvKTr4rvx3iSIlTJabIQwj" +
+	"2ICCR/oLxBA=="
+
+func suiteRequest(method string, path string, query url.Values) Request {
+	return Request{
+		Method:      method,
+		Host:        "example.amazonaws.com",
+		Path:        path,
+		Query:       query,
+		PayloadHash: HashPayload(nil),
+	}
+}
+
+func TestSignSuite(t *testing.T) {
+	cases := []struct {
+		name        string
+		request     Request
+		credentials Credentials
+		signed      string
+		signature   string
+	}{
+		{"get-vanilla", suiteRequest("GET", "/", nil), suiteCredentials,
+			"host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-empty-query-key", suiteRequest("GET", "/", url.Values{"Param1": {"value1"}}), suiteCredentials,
+			"host;x-amz-date", "a67d582fa61cc504c4bae71f336f98b97f1ea3c7a6bfe1b6e45aec72011b9aeb"},
+		{"get-vanilla-query-order-key-case", suiteRequest("GET", "/", url.Values{"Param2": {"value2"}, "Param1": {"value1"}}), suiteCredentials,
+			"host;x-amz-date", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"get-unreserved", suiteRequest("GET", "/-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz", nil), suiteCredentials,
+			"host;x-amz-date", "07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f"},
+		{"post-vanilla", suiteRequest("POST", "/", nil), suiteCredentials,
+			"host;x-amz-date", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"post-sts-header-before", suiteRequest("POST", "/", nil),
+			Credentials{AccessKey: suiteCredentials.AccessKey, SecretKey: suiteCredentials.SecretKey, SessionToken: suiteToken},
+			"host;x-amz-date;x-amz-security-token", "85d96828115b5dc0cfc3bd16ad9e210dd772bbebba041836c64533a82be05ead"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			headers := Sign(testCase.request, suiteScope, testCase.credentials, suiteMoment)
+			expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+				testCase.signed + ", Signature=" + testCase.signature
+			if headers["Authorization"] != expected {
+				t.Fatalf("got %s, expected %s", headers["Authorization"], expected)
+			}
+			if headers["X-Amz-Date"] != "20150830T123600Z" {
+				t.Fatalf("got date %s", headers["X-Amz-Date"])
+			}
+			if token, ok := headers["X-Amz-Security-Token"]; ok != (testCase.credentials.SessionToken != "") || token != testCase.credentials.SessionToken {
+				t.Fatalf("got token %q", token)
+			}
+		})
+	}
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	cases := []struct {
+		name    string
+		service string
+		request Request
+		path    string
+		query   string
+	}{
+		{"path encoded twice", "service", suiteRequest("GET", "/example space/ሴ", nil), "/example%2520space/%25E1%2588%25B4", ""},
+		{"path of s3 encoded once", ServiceS3, suiteRequest("GET", "/example space/ሴ", nil), "/example%20space/%E1%88%B4", ""},
+		{"unreserved characters", "service", suiteRequest("GET", "/a-b_c.d~e", nil), "/a-b_c.d~e", ""},
+		{"empty path", "service", suiteRequest("GET", "", nil), "/", ""},
+		{"query sorted by key", "service", suiteRequest("GET", "/", url.Values{"b": {"1"}, "a": {"2"}, "A": {"3"}}), "/", "A=3&a=2&b=1"},
+		{"values of key sorted", "service", suiteRequest("GET", "/", url.Values{"a": {"z", "b", "m"}}), "/", "a=b&a=m&a=z"},
+		{"key before longer key", "service", suiteRequest("GET", "/", url.Values{"a-b": {"1"}, "a": {"2"}}), "/", "a=2&a-b=1"},
+		{"query escaped", "service", suiteRequest("GET", "/", url.Values{"q": {"a b/c=d"}, "€": {""}}), "/", "%E2%82%AC=&q=a%20b%2Fc%3Dd"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			canonical, _ := canonicalRequest(testCase.request, testCase.service, map[string]string{"host": "example.amazonaws.com"})
+			lines := strings.Split(canonical, "\n")
+			if lines[1] != testCase.path || lines[2] != testCase.query {
+				t.Fatalf("got path %q and query %q, expected %q and %q", lines[1], lines[2], testCase.path, testCase.query)
+			}
+		})
+	}
+}
+
+func TestSignHeaders(t *testing.T) {
+	request := suiteRequest("PUT", "/bucket/key", nil)
+	request.PayloadHash = UnsignedPayload
+	request.Headers = map[string]string{"X-Amz-Content-Sha256": UnsignedPayload, "Content-Type": " application/json "}
+	credentials := suiteCredentials
+	credentials.SessionToken = "token"
+	headers := Sign(request, Scope{Region: "eu-west-1", Service: ServiceS3}, credentials, suiteMoment)
+	if !strings.Contains(headers["Authorization"], "/20150830/eu-west-1/s3/aws4_request, "+
+		"SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date;x-amz-security-token, ") {
+		t.Fatalf("unexpected authorization %s", headers["Authorization"])
+	}
+	canonical, _ := canonicalRequest(request, ServiceS3, map[string]string{"content-type": "application/json", "host": "h"})
+	if !strings.HasSuffix(canonical, "\ncontent-type;host\n"+UnsignedPayload) {
+		t.Fatalf("unexpected canonical request %q", canonical)
+	}
+}
@@ -0,0 +1,163 @@
+package sinks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/sirupsen/logrus"
+)
+
+/*FileDisabled - value of directory which turns off writing of reports*/
+const FileDisabled = "off"
+
+/*
+RunRecord - complete record of an attack in report file: the task with options it was
+executed with, the result sent to the server and the report
+*/
+type RunRecord struct {
+	FormId    string                       `json:"form_id"`
+	BomberId  string                       `json:"bomber_id"`
+	StartedAt time.Time                    `json:"started_at"`
+	Address   string                       `json:"address"`
+	Rps       int64                        `json:"rps"`
+	Time      int64                        `json:"time"`
+	Headers   map[string]string            `json:"headers,omitempty"`
+	Options   *core.TaskOptions            `json:"options,omitempty"`
+	Result    *rest_contracts.BomberResult `json:"result"`
+	Report    *core.AttackReport           `json:"report"`
+}
+
+/*
+FileSink - writes records of attacks as json files, html reports and junit verdicts of thresholds
+into directory, file names are <form_id>-<bomber_id>-<unix time of start>.json, .html and .junit.xml
+*/
+type FileSink struct {
+	dir string
+}
+
+// NewFileSink - nil if directory is disabled
+func NewFileSink(dir string) *FileSink {
+	if dir == FileDisabled || dir == "" {
+		return nil
+	}
+	return &FileSink{dir: dir}
+}
+
+func NewRunRecord(task rest_contracts.Task, started time.Time, result *rest_contracts.BomberResult, report *core.AttackReport) *RunRecord {
+	record := &RunRecord{
+		FormId:    task.FormId,
+		BomberId:  result.BomberId,
+		StartedAt: started,
+		Result:    result,
+		Report:    report,
+	}
+	if task.Script != nil {
+		record.Address = task.Script.Address
+		if task.Script.Config != nil {
+			record.Rps = task.Script.Config.Rps
+			record.Time = task.Script.Config.Time
+		}
+	}
+	if task.Schema != nil {
+		record.Headers = redactedHeaders(task.Schema.Headers)
+	}
+	if options, err := core.ParseTaskOptions(task); err == nil {
+		options.Auth = options.Auth.Redacted()
+		options.Login = options.Login.Redacted()
+		record.Options = options
+	}
+	return record
+}
+
+// Write - writes json record, html report and junit verdict of the attack, returns path of the record
+func (sink *FileSink) Write(record *RunRecord) (string, error) {
+	if err := os.MkdirAll(sink.dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := record.FormId + "-" + record.BomberId + "-" + strconv.FormatInt(record.StartedAt.Unix(), 10)
+	path, err := sink.writeFile(name+".json", data)
+	if err != nil {
+		return "", err
+	}
+	page, err := renderHTML(record)
+	if err != nil {
+		return path, err
+	}
+	if _, err := sink.writeFile(name+".html", page); err != nil {
+		return path, err
+	}
+	if len(record.Report.Thresholds) == 0 {
+		return path, nil
+	}
+	verdict, err := renderJUnit(record)
+	if err != nil {
+		return path, err
+	}
+	if _, err := sink.writeFile(name+".junit.xml", verdict); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+func (sink *FileSink) PublishInterim(result *core.InterimResult) error {
+	return nil
+}
+
+func (sink *FileSink) PublishFinal(final *FinalResult) error {
+	path, err := sink.Write(NewRunRecord(final.Task, final.Started, final.Result, final.Report))
+	if err != nil {
+		return err
+	}
+	logrus.Info("Report of attack was written to ", path)
+	return nil
+}
+
+// writeFile - readers of the directory never see partially written files
+func (sink *FileSink) writeFile(name string, data []byte) (string, error) {
+	path := filepath.Join(sink.dir, filepath.Base(name))
+	temporary, err := ioutil.TempFile(sink.dir, ".report-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := temporary.Write(data); err != nil {
+		temporary.Close()
+		os.Remove(temporary.Name())
+		return "", err
+	}
+	if err := temporary.Close(); err != nil {
+		os.Remove(temporary.Name())
+		return "", err
+	}
+	if err := os.Rename(temporary.Name(), path); err != nil {
+		os.Remove(temporary.Name())
+		return "", err
+	}
+	return path, nil
+}
+
+// redactedHeaders - headers of schema without credentials and without options, which are stored parsed
+func redactedHeaders(headers map[string]string) map[string]string {
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if name == core.OptionsHeader {
+			continue
+		}
+		switch strings.ToLower(name) {
+		case "authorization", "proxy-authorization", "cookie":
+			value = "REDACTED"
+		}
+		result[name] = value
+	}
+	return result
+}
@@ -0,0 +1,163 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/core"
+)
+
+const (
+	/*GrafanaDisabled - value of url which turns off annotations*/
+	GrafanaDisabled = "off"
+
+	grafanaAnnotationsPath = "/api/annotations"
+
+	OutcomeCompleted = "completed"
+	OutcomeAborted   = "aborted"
+	OutcomeCancelled = "cancelled"
+)
+
+var ErrGrafanaRejected = errors.New("grafana rejected annotation of the attack")
+
+type grafanaAnnotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty"`
+	Time         int64    `json:"time,omitempty"`
+	TimeEnd      int64    `json:"timeEnd,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Text         string   `json:"text,omitempty"`
+}
+
+type grafanaCreated struct {
+	Id int64 `json:"id"`
+}
+
+/*
+GrafanaAnnotation - region of the attack on dashboards, it starts when the attack starts
+and is closed when the attack completes or aborts
+*/
+type GrafanaAnnotation struct {
+	Id   int64
+	Tags []string
+	Text string
+}
+
+/*
+GrafanaSink - posts annotations of attacks by http api of Grafana, so load events
+line up with metrics of the target on dashboards
+*/
+type GrafanaSink struct {
+	url          string
+	token        string
+	dashboardUID string
+	client       *http.Client
+}
+
+// NewGrafanaSink - nil if url is disabled, annotations without dashboard are shown by their tags
+func NewGrafanaSink(url string, token string, dashboardUID string) *GrafanaSink {
+	if url == GrafanaDisabled || url == "" {
+		return nil
+	}
+	if token == GrafanaDisabled {
+		token = ""
+	}
+	if dashboardUID == GrafanaDisabled {
+		dashboardUID = ""
+	}
+	return &GrafanaSink{
+		url:          strings.TrimSuffix(url, "/"),
+		token:        token,
+		dashboardUID: dashboardUID,
+		client:       &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (sink *GrafanaSink) Started(task rest_contracts.Task, bomberID string, moment time.Time) (*GrafanaAnnotation, error) {
+	annotation := &GrafanaAnnotation{
+		Tags: []string{"bomber", "form_id:" + task.FormId, "bomber_id:" + bomberID},
+		Text: "Attack " + task.FormId + " by " + bomberID,
+	}
+	if task.Script != nil {
+		annotation.Text += " to " + task.Script.RequestMethod + " " + task.Script.Address
+		if task.Script.Config != nil {
+			annotation.Text += fmt.Sprintf(", %d rps for %d s", task.Script.Config.Rps, task.Script.Config.Time)
+		}
+	}
+	var created grafanaCreated
+	err := sink.send(http.MethodPost, grafanaAnnotationsPath, grafanaAnnotation{
+		DashboardUID: sink.dashboardUID,
+		Time:         moment.UnixNano() / int64(time.Millisecond),
+		Tags:         append(annotation.Tags, "started"),
+		Text:         annotation.Text,
+	}, &created)
+	if err != nil {
+		return nil, err
+	}
+	annotation.Id = created.Id
+	return annotation, nil
+}
+
+// Stage - point annotation of the next stage of the attack
+func (sink *GrafanaSink) Stage(annotation *GrafanaAnnotation, stage string, moment time.Time) error {
+	return sink.send(http.MethodPost, grafanaAnnotationsPath, grafanaAnnotation{
+		DashboardUID: sink.dashboardUID,
+		Time:         moment.UnixNano() / int64(time.Millisecond),
+		Tags:         append(append([]string{}, annotation.Tags...), "stage:"+stage),
+		Text:         annotation.Text + ": " + stage,
+	}, nil)
+}
+
+// Finished - closes region of the attack, report is nil if the attack was aborted before it was formed
+func (sink *GrafanaSink) Finished(annotation *GrafanaAnnotation, outcome string, report *core.AttackReport, moment time.Time) error {
+	tags := append(append([]string{}, annotation.Tags...), outcome)
+	text := annotation.Text + ": " + outcome
+	if report != nil {
+		text += fmt.Sprintf(", %d requests, p99 %s", report.Summary.Count, formatNs(report.Latency.P99Ns))
+		if report.StatusClasses != nil {
+			text += ", error rate " + strconv.FormatFloat(report.StatusClasses.ErrorRate, 'f', 2, 64) + "%"
+		}
+		if report.ThresholdsPassed != nil && !*report.ThresholdsPassed {
+			tags = append(tags, "thresholds_failed")
+			text += ", thresholds failed"
+		}
+	}
+	return sink.send(http.MethodPatch, grafanaAnnotationsPath+"/"+strconv.FormatInt(annotation.Id, 10), grafanaAnnotation{
+		TimeEnd: moment.UnixNano() / int64(time.Millisecond),
+		Tags:    tags,
+		Text:    text,
+	}, nil)
+}
+
+func (sink *GrafanaSink) send(method string, path string, annotation grafanaAnnotation, reply interface{}) error {
+	body, err := json.Marshal(annotation)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(method, sink.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if sink.token != "" {
+		request.Header.Set("Authorization", "Bearer "+sink.token)
+	}
+	response, err := sink.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		return ErrGrafanaRejected
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.NewDecoder(response.Body).Decode(reply)
+}
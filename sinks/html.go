@@ -0,0 +1,262 @@
+package sinks
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/core"
+)
+
+const (
+	chartWidth   = 800
+	chartHeight  = 240
+	chartPadding = 40
+)
+
+type htmlSeries struct {
+	Name   string
+	Color  string
+	Points string
+}
+
+type htmlChart struct {
+	Width   int
+	Height  int
+	Series  []htmlSeries
+	MaxY    string
+	MaxX    string
+	Padding int
+	Bottom  int
+	Right   int
+}
+
+type htmlBar struct {
+	Label   string
+	Amount  int64
+	Percent string
+	Width   int
+}
+
+type htmlRow struct {
+	Name  string
+	Value string
+}
+
+type htmlPage struct {
+	Record      *RunRecord
+	Duration    string
+	Summary     []htmlRow
+	Percentiles []htmlRow
+	Latency     htmlChart
+	Rps         htmlChart
+	Statuses    []htmlBar
+	Errors      []htmlBar
+}
+
+var htmlReport = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Attack {{.Record.FormId}} by {{.Record.BomberId}}</title>
+<style>
+body { font-family: sans-serif; margin: 24px; color: #222; }
+table { border-collapse: collapse; margin-bottom: 24px; }
+td, th { border: 1px solid #ccc; padding: 4px 12px; text-align: left; }
+.bar { background: #4a90d9; height: 14px; }
+.legend span { margin-right: 16px; }
+svg { border: 1px solid #ccc; margin-bottom: 8px; }
+</style>
+</head>
+<body>
+<h1>Attack {{.Record.FormId}}</h1>
+<p>Bomber {{.Record.BomberId}}, {{.Record.Address}}, started {{.Record.StartedAt.Format "2006-01-02 15:04:05 MST"}}, {{.Duration}}</p>
+<h2>Summary</h2>
+<table>
+{{range .Summary}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+<h2>Latency percentiles</h2>
+<table>
+{{range .Percentiles}}<tr><th>{{.Name}}</th><td>{{.Value}}</td></tr>
+{{end}}</table>
+<h2>Latency over time</h2>
+{{template "chart" .Latency}}
+<h2>Requests per second</h2>
+{{template "chart" .Rps}}
+<h2>Statuses</h2>
+<table>
+{{range .Statuses}}<tr><th>{{.Label}}</th><td>{{.Amount}}</td><td>{{.Percent}}</td><td><div class="bar" style="width: {{.Width}}px"></div></td></tr>
+{{end}}</table>
+{{if .Errors}}<h2>Errors</h2>
+<table>
+{{range .Errors}}<tr><th>{{.Label}}</th><td>{{.Amount}}</td><td>{{.Percent}}</td><td><div class="bar" style="width: {{.Width}}px"></div></td></tr>
+{{end}}</table>{{end}}
+</body>
+</html>
+{{define "chart"}}<svg width="{{.Width}}" height="{{.Height}}" xmlns="http://www.w3.org/2000/svg">
+<line x1="{{.Padding}}" y1="{{.Padding}}" x2="{{.Padding}}" y2="{{.Bottom}}" stroke="#888"/>
+<line x1="{{.Padding}}" y1="{{.Bottom}}" x2="{{.Right}}" y2="{{.Bottom}}" stroke="#888"/>
+<text x="2" y="{{.Padding}}" font-size="11">{{.MaxY}}</text>
+<text x="{{.Right}}" y="{{.Height}}" font-size="11" text-anchor="end">{{.MaxX}}</text>
+{{range .Series}}<polyline fill="none" stroke="{{.Color}}" stroke-width="2" points="{{.Points}}"/>
+{{end}}</svg>
+<div class="legend">{{range .Series}}<span style="color: {{.Color}}">&#9632; {{.Name}}</span>{{end}}</div>
+{{end}}`))
+
+// renderHTML - single file report without scripts and external resources
+func renderHTML(record *RunRecord) ([]byte, error) {
+	report := record.Report
+	buckets := report.Timeline.Buckets
+	duration := time.Duration(len(buckets)) * time.Duration(report.Timeline.IntervalMs) * time.Millisecond
+	var requests, timeouts int64
+	statuses := map[string]int64{}
+	if record.Result != nil {
+		for status, amount := range record.Result.AmountStatusesPerStatus {
+			statuses[strconv.Itoa(int(status))] = amount
+			requests += amount
+		}
+		timeouts = record.Result.AmountTimeoutsRequests
+		if timeouts > 0 {
+			statuses["failed"] = timeouts
+		}
+		requests += timeouts
+	}
+	pageData := htmlPage{
+		Record:   record,
+		Duration: duration.String(),
+		Summary: []htmlRow{
+			{"Target rps", strconv.FormatInt(record.Rps, 10)},
+			{"Requests", strconv.FormatInt(requests, 10)},
+			{"Failed", strconv.FormatInt(timeouts, 10)},
+			{"Mean rps", fmt.Sprintf("%.1f", perSecond(requests, duration))},
+			{"Received", formatBytes(report.Traffic.BytesIn)},
+			{"Sent", formatBytes(report.Traffic.BytesOut)},
+		},
+		Percentiles: []htmlRow{
+			{"min", formatNs(report.Latency.MinNs)},
+			{"mean", formatNs(report.Latency.MeanNs)},
+			{"p50", formatNs(report.Latency.P50Ns)},
+			{"p90", formatNs(report.Latency.P90Ns)},
+			{"p95", formatNs(report.Latency.P95Ns)},
+			{"p99", formatNs(report.Latency.P99Ns)},
+			{"p99.9", formatNs(report.Latency.P999Ns)},
+			{"max", formatNs(report.Latency.MaxNs)},
+		},
+		Latency: timelineChart(buckets, duration, formatNs,
+			seriesOf("p50", "#4a90d9", func(bucket core.BucketReport) float64 { return float64(bucket.P50Ns) }),
+			seriesOf("p90", "#f5a623", func(bucket core.BucketReport) float64 { return float64(bucket.P90Ns) }),
+			seriesOf("p99", "#d0021b", func(bucket core.BucketReport) float64 { return float64(bucket.P99Ns) }),
+		),
+		Rps: timelineChart(buckets, duration, func(value int64) string { return strconv.FormatInt(value, 10) },
+			seriesOf("rps", "#4a90d9", func(bucket core.BucketReport) float64 { return bucket.Rps }),
+			seriesOf("errors", "#d0021b", func(bucket core.BucketReport) float64 {
+				return perSecond(bucket.Errors+bucket.Timeouts, time.Duration(report.Timeline.IntervalMs)*time.Millisecond)
+			}),
+		),
+		Statuses: bars(statuses),
+		Errors:   bars(report.Errors),
+	}
+	var page bytes.Buffer
+	if err := htmlReport.Execute(&page, pageData); err != nil {
+		return nil, err
+	}
+	return page.Bytes(), nil
+}
+
+type seriesValue struct {
+	name  string
+	color string
+	value func(bucket core.BucketReport) float64
+}
+
+func seriesOf(name string, color string, value func(bucket core.BucketReport) float64) seriesValue {
+	return seriesValue{name: name, color: color, value: value}
+}
+
+func timelineChart(buckets []core.BucketReport, duration time.Duration, format func(int64) string, values ...seriesValue) htmlChart {
+	chart := htmlChart{
+		Width:   chartWidth,
+		Height:  chartHeight,
+		Padding: chartPadding,
+		Bottom:  chartHeight - chartPadding/2,
+		Right:   chartWidth - chartPadding/2,
+		MaxX:    duration.String(),
+	}
+	var maxY float64
+	for _, series := range values {
+		for _, bucket := range buckets {
+			if value := series.value(bucket); value > maxY {
+				maxY = value
+			}
+		}
+	}
+	if maxY == 0 {
+		maxY = 1
+	}
+	chart.MaxY = format(int64(maxY))
+	stepX := float64(chart.Right-chart.Padding) / float64(len(buckets)+1)
+	scaleY := float64(chart.Bottom-chart.Padding) / maxY
+	for _, series := range values {
+		points := make([]string, 0, len(buckets))
+		for index, bucket := range buckets {
+			x := float64(chart.Padding) + stepX*float64(index+1)
+			y := float64(chart.Bottom) - series.value(bucket)*scaleY
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+		}
+		chart.Series = append(chart.Series, htmlSeries{Name: series.name, Color: series.color, Points: strings.Join(points, " ")})
+	}
+	return chart
+}
+
+func bars(amounts map[string]int64) []htmlBar {
+	var total, maximum int64
+	for _, amount := range amounts {
+		total += amount
+		if amount > maximum {
+			maximum = amount
+		}
+	}
+	if maximum == 0 {
+		maximum = 1
+	}
+	result := make([]htmlBar, 0, len(amounts))
+	for label, amount := range amounts {
+		result = append(result, htmlBar{
+			Label:   label,
+			Amount:  amount,
+			Percent: fmt.Sprintf("%.2f%%", float64(amount)*100/float64(total)),
+			Width:   int(amount * 300 / maximum),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Label < result[j].Label
+	})
+	return result
+}
+
+func perSecond(amount int64, duration time.Duration) float64 {
+	if duration <= 0 {
+		return 0
+	}
+	return float64(amount) / duration.Seconds()
+}
+
+func formatNs(value int64) string {
+	return time.Duration(value).Round(time.Microsecond).String()
+}
+
+func formatBytes(value int64) string {
+	switch {
+	case value >= 1<<30:
+		return fmt.Sprintf("%.2f GiB", float64(value)/(1<<30))
+	case value >= 1<<20:
+		return fmt.Sprintf("%.2f MiB", float64(value)/(1<<20))
+	case value >= 1<<10:
+		return fmt.Sprintf("%.2f KiB", float64(value)/(1<<10))
+	}
+	return strconv.FormatInt(value, 10) + " B"
+}
@@ -0,0 +1,149 @@
+package sinks
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/core"
+)
+
+/*InfluxDisabled - value of url which turns off the sink*/
+const InfluxDisabled = "off"
+
+var ErrInfluxRejected = errors.New("influx rejected points of the attack")
+
+var tagEscaper = strings.NewReplacer(",", `\,`, " ", `\ `, "=", `\=`)
+
+/*
+InfluxSink - writes timeline and summary of attacks in line protocol into InfluxDB
+(/write of v1, /api/v2/write of v2) or VictoriaMetrics
+*/
+type InfluxSink struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewInfluxSink - nil if url is disabled
+func NewInfluxSink(url string, token string) *InfluxSink {
+	if url == InfluxDisabled || url == "" {
+		return nil
+	}
+	if token == InfluxDisabled {
+		token = ""
+	}
+	return &InfluxSink{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (sink *InfluxSink) Write(report *core.AttackReport) error {
+	request, err := http.NewRequest(http.MethodPost, sink.url, bytes.NewReader(influxLines(report)))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if sink.token != "" {
+		request.Header.Set("Authorization", "Token "+sink.token)
+	}
+	response, err := sink.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return ErrInfluxRejected
+	}
+	return nil
+}
+
+func (sink *InfluxSink) PublishInterim(result *core.InterimResult) error {
+	return nil
+}
+
+func (sink *InfluxSink) PublishFinal(final *FinalResult) error {
+	if final.Report == nil {
+		return nil
+	}
+	return sink.Write(final.Report)
+}
+
+func influxLines(report *core.AttackReport) []byte {
+	var lines bytes.Buffer
+	tags := tag("form_id", report.FormId) + tag("bomber_id", report.BomberId) + tag("mode", report.Mode)
+	start := time.Unix(0, report.Timeline.StartUnixMs*int64(time.Millisecond))
+	interval := time.Duration(report.Timeline.IntervalMs) * time.Millisecond
+	for _, bucket := range report.Timeline.Buckets {
+		lines.WriteString("bomber_interval" + tags + " ")
+		fields(&lines,
+			intField("requests", bucket.Requests),
+			floatField("rps", bucket.Rps),
+			intField("errors", bucket.Errors),
+			intField("timeouts", bucket.Timeouts),
+			intField("mean_ns", bucket.MeanNs),
+			intField("p50_ns", bucket.P50Ns),
+			intField("p90_ns", bucket.P90Ns),
+			intField("p99_ns", bucket.P99Ns),
+			intField("max_ns", bucket.MaxNs),
+			intField("bytes_in", bucket.BytesIn),
+			intField("bytes_out", bucket.BytesOut),
+		)
+		timestamp(&lines, start.Add(time.Duration(bucket.StartMs)*time.Millisecond))
+	}
+	end := start.Add(time.Duration(len(report.Timeline.Buckets)) * interval)
+	latency := report.Latency
+	lines.WriteString("bomber_attack" + tags + " ")
+	fields(&lines,
+		intField("requests", latency.Count),
+		intField("mean_ns", latency.MeanNs),
+		intField("p50_ns", latency.P50Ns),
+		intField("p90_ns", latency.P90Ns),
+		intField("p99_ns", latency.P99Ns),
+		intField("p999_ns", latency.P999Ns),
+		intField("max_ns", latency.MaxNs),
+		intField("bytes_in", report.Traffic.BytesIn),
+		intField("bytes_out", report.Traffic.BytesOut),
+	)
+	timestamp(&lines, end)
+	for status, byStatus := range report.LatencyByStatus {
+		lines.WriteString("bomber_status" + tags + ",status=" + strconv.Itoa(int(status)) + " ")
+		fields(&lines, intField("requests", byStatus.Count), intField("p99_ns", byStatus.P99Ns))
+		timestamp(&lines, end)
+	}
+	for category, amount := range report.Errors {
+		lines.WriteString("bomber_errors" + tags + tag("category", category) + " ")
+		fields(&lines, intField("requests", amount))
+		timestamp(&lines, end)
+	}
+	return lines.Bytes()
+}
+
+// tag - empty values are not allowed by line protocol, such tags are omitted
+func tag(name string, value string) string {
+	if value == "" {
+		return ""
+	}
+	return "," + name + "=" + tagEscaper.Replace(value)
+}
+
+func intField(name string, value int64) string {
+	return name + "=" + strconv.FormatInt(value, 10) + "i"
+}
+
+func floatField(name string, value float64) string {
+	return name + "=" + strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func fields(lines *bytes.Buffer, values ...string) {
+	lines.WriteString(strings.Join(values, ","))
+}
+
+func timestamp(lines *bytes.Buffer, moment time.Time) {
+	lines.WriteString(" " + strconv.FormatInt(moment.UnixNano(), 10) + "\n")
+}
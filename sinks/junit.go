@@ -0,0 +1,105 @@
+package sinks
+
+import (
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/core"
+)
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Type    string `xml:"type,attr"`
+	Message string `xml:"message,attr"`
+}
+
+type junitCase struct {
+	ClassName string        `xml:"classname,attr"`
+	Name      string        `xml:"name,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitCase     `xml:"testcase"`
+}
+
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+/*
+renderJUnit - verdict of thresholds of the attack as junit xml, each threshold is a test case,
+so pipelines can fail the build by standard test report tooling
+*/
+func renderJUnit(record *RunRecord) ([]byte, error) {
+	report := record.Report
+	duration := time.Duration(len(report.Timeline.Buckets)) * time.Duration(report.Timeline.IntervalMs) * time.Millisecond
+	suite := junitSuite{
+		Name:      "rest-bomber." + record.FormId,
+		Time:      formatSeconds(duration),
+		Timestamp: record.StartedAt.UTC().Format("2006-01-02T15:04:05"),
+		Properties: []junitProperty{
+			{Name: "bomber_id", Value: record.BomberId},
+			{Name: "address", Value: record.Address},
+			{Name: "rps", Value: strconv.FormatInt(record.Rps, 10)},
+			{Name: "time", Value: strconv.FormatInt(record.Time, 10)},
+		},
+	}
+	for _, threshold := range report.Thresholds {
+		limits := thresholdLimits(threshold)
+		testCase := junitCase{
+			ClassName: record.FormId + "." + record.BomberId,
+			Name:      threshold.Metric + " " + limits,
+			Time:      formatSeconds(0),
+		}
+		if !threshold.Passed {
+			testCase.Failure = &junitFailure{
+				Type:    "threshold",
+				Message: threshold.Metric + " is " + formatFloat(threshold.Actual) + ", expected " + limits,
+			}
+			suite.Failures++
+		}
+		suite.Tests++
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	data, err := xml.MarshalIndent(junitSuites{Suites: []junitSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func thresholdLimits(threshold core.ThresholdResult) string {
+	limits := ""
+	if threshold.Min != nil {
+		limits = ">= " + formatFloat(*threshold.Min)
+	}
+	if threshold.Max != nil {
+		if limits != "" {
+			limits += ", "
+		}
+		limits += "<= " + formatFloat(*threshold.Max)
+	}
+	return limits
+}
+
+func formatSeconds(duration time.Duration) string {
+	return strconv.FormatFloat(duration.Seconds(), 'f', 3, 64)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,160 @@
+package sinks
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	taskTopicResult         = "bombers.server.task_result"
+	taskTopicReport         = "bombers.server.task_report"
+	taskTopicInterim        = "bombers.server.task_interim"
+	taskTopicResultManifest = "bombers.server.task_result_manifest"
+	taskTopicResultChunk    = "bombers.server.task_result_chunk."
+)
+
+/*
+ResultManifest - published before chunks of the result, which exceeds payload limit of NATS.
+Chunks are published in order into bombers.server.task_result_chunk.<transfer_id>.<index>,
+joined they are payload of the subject
+*/
+type ResultManifest struct {
+	TransferId      string `json:"transfer_id"`
+	FormId          string `json:"form_id"`
+	BomberId        string `json:"bomber_id"`
+	ContractVersion string `json:"contract_version"`
+	// subject which the whole payload would be published into, its suffix tells encoding
+	Subject    string `json:"subject"`
+	Encoding   string `json:"encoding"`
+	Bytes      int    `json:"bytes"`
+	Chunks     int    `json:"chunks"`
+	ChunkBytes int    `json:"chunk_bytes"`
+	Sha256     string `json:"sha256"`
+}
+
+/*
+NatsSink - publishes results into subjects of bomber server: result in protobuf, report
+and interim results in json
+*/
+type NatsSink struct {
+	publisher *nats_listener.Publisher
+	outbox    *nats_listener.Outbox
+	encoding  string
+}
+
+func NewNatsSink(config *config.Configuration, publisher *nats_listener.Publisher) *NatsSink {
+	encoding := config.ResultEncoding
+	if err := nats_listener.ValidateEncoding(encoding); err != nil {
+		logrus.Error("Can not compress results: ", err)
+		encoding = nats_listener.EncodingNone
+	}
+	return &NatsSink{
+		publisher: publisher,
+		outbox:    nats_listener.NewOutbox(config.OutboxDir, publisher),
+		encoding:  encoding,
+	}
+}
+
+func (sink *NatsSink) PublishInterim(result *core.InterimResult) error {
+	marshaledInterim, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return sink.publisher.PublishNewMessage(taskTopicInterim, marshaledInterim)
+}
+
+/*
+PublishFinal - result uploaded into object storage is published without latency of each request.
+Failed report is only logged, as the result is already published
+*/
+func (sink *NatsSink) PublishFinal(final *FinalResult) error {
+	published := final.Result
+	if final.Report != nil && final.Report.ResultObject != nil {
+		summary := *final.Result
+		summary.MsPerRequest = nil
+		published = &summary
+	}
+	marshaledData, err := published.Marshal()
+	if err != nil {
+		return err
+	}
+	compressedData, err := nats_listener.Compress(sink.encoding, marshaledData)
+	if err != nil {
+		return err
+	}
+	if err := sink.publishResult(published.FormId, published.BomberId, compressedData); err != nil {
+		return err
+	}
+	if final.Report == nil {
+		return nil
+	}
+	marshaledReport, err := json.Marshal(final.Report)
+	if err != nil {
+		logrus.Error("Error marshaled report attack: ", err)
+		return nil
+	}
+	if errPublish := sink.publishDurable(taskTopicReport, marshaledReport); errPublish != nil {
+		logrus.Error("Error while publish report by task: ", errPublish)
+	}
+	return nil
+}
+
+// publishResult - publishes payload as is if it fits into payload limit and by chunks otherwise
+func (sink *NatsSink) publishResult(formId string, bomberId string, payload []byte) error {
+	subject := nats_listener.EncodingSubject(taskTopicResult, sink.encoding)
+	limit := sink.publisher.MaxPayload()
+	if limit <= 0 || len(payload) <= limit {
+		return sink.publishDurable(subject, payload)
+	}
+	transferID, err := uuid.NewRandom()
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(payload)
+	manifest := ResultManifest{
+		TransferId:      transferID.String(),
+		FormId:          formId,
+		BomberId:        bomberId,
+		ContractVersion: core.ContractVersion,
+		Subject:         subject,
+		Encoding:        sink.encoding,
+		Bytes:           len(payload),
+		Chunks:          (len(payload) + limit - 1) / limit,
+		ChunkBytes:      limit,
+		Sha256:          hex.EncodeToString(sum[:]),
+	}
+	marshaledManifest, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := sink.publishDurable(taskTopicResultManifest, marshaledManifest); err != nil {
+		return err
+	}
+	for index := 0; index < manifest.Chunks; index++ {
+		end := (index + 1) * limit
+		if end > len(payload) {
+			end = len(payload)
+		}
+		chunkSubject := taskTopicResultChunk + manifest.TransferId + "." + strconv.Itoa(index)
+		if err := sink.publishDurable(chunkSubject, payload[index*limit:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// publishDurable - results and reports go through outbox if it is enabled, so they survive outage of NATS
+func (sink *NatsSink) publishDurable(subject string, data []byte) error {
+	if sink.outbox != nil {
+		return sink.outbox.Publish(subject, data)
+	}
+	return sink.publisher.PublishNewMessage(subject, data)
+}
@@ -0,0 +1,139 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/sigv4"
+)
+
+const (
+	/*S3Disabled - value of bucket which turns off the sink*/
+	S3Disabled = "off"
+
+	resultContentType     = "application/x-protobuf"
+	resultContentEncoding = "gzip"
+)
+
+var ErrS3Rejected = errors.New("object storage rejected result of the attack")
+
+type S3Options struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string
+	// instance metadata with credentials of the role, they are used without keys, off disables it
+	MetadataURL string
+}
+
+/*
+S3Sink - uploads full results of attacks into bucket of S3 compatible object storage
+(AWS S3, MinIO, Ceph), requests are addressed by path and signed by signature v4
+*/
+type S3Sink struct {
+	options     S3Options
+	client      *http.Client
+	credentials *sigv4.Provider
+}
+
+// NewS3Sink - nil if bucket is disabled
+func NewS3Sink(options S3Options) *S3Sink {
+	if options.Bucket == S3Disabled || options.Bucket == "" {
+		return nil
+	}
+	options.Endpoint = strings.TrimSuffix(options.Endpoint, "/")
+	options.Prefix = strings.Trim(options.Prefix, "/")
+	if options.Prefix == S3Disabled {
+		options.Prefix = ""
+	}
+	static := sigv4.Credentials{}
+	if options.AccessKey != S3Disabled && options.SecretKey != S3Disabled {
+		static = sigv4.Credentials{AccessKey: options.AccessKey, SecretKey: options.SecretKey}
+	}
+	metadataURL := options.MetadataURL
+	if metadataURL == S3Disabled {
+		metadataURL = ""
+	}
+	return &S3Sink{
+		options:     options,
+		client:      &http.Client{Timeout: time.Minute},
+		credentials: sigv4.NewProvider(static, metadataURL),
+	}
+}
+
+// Upload - puts gzipped protobuf of the result as <prefix>/<form_id>-<bomber_id>-<unix time of start>.pb.gz
+func (sink *S3Sink) Upload(result *rest_contracts.BomberResult, started time.Time) (*core.ResultObjectReport, error) {
+	marshaled, err := result.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	var body bytes.Buffer
+	writer := gzip.NewWriter(&body)
+	if _, err := writer.Write(marshaled); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	key := result.FormId + "-" + result.BomberId + "-" + strconv.FormatInt(started.Unix(), 10) + ".pb.gz"
+	if sink.options.Prefix != "" {
+		key = sink.options.Prefix + "/" + key
+	}
+	location := sink.options.Endpoint + "/" + sigv4.EscapePath(sink.options.Bucket) + "/" + sigv4.EscapePath(key)
+	request, err := http.NewRequest(http.MethodPut, location, bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", resultContentType)
+	request.Header.Set("Content-Encoding", resultContentEncoding)
+	if err := sink.sign(request, body.Bytes(), time.Now()); err != nil {
+		return nil, err
+	}
+	response, err := sink.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+	if response.StatusCode >= 300 {
+		return nil, ErrS3Rejected
+	}
+	return &core.ResultObjectReport{
+		Bucket:          sink.options.Bucket,
+		Key:             key,
+		URL:             location,
+		Bytes:           int64(body.Len()),
+		ContentType:     resultContentType,
+		ContentEncoding: resultContentEncoding,
+	}, nil
+}
+
+// sign - credentials of instance role are fetched again by the upload they expire before
+func (sink *S3Sink) sign(request *http.Request, payload []byte, moment time.Time) error {
+	credentials, err := sink.credentials.Current()
+	if err != nil {
+		return err
+	}
+	payloadHash := sigv4.HashPayload(payload)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	headers := sigv4.Sign(sigv4.Request{
+		Method:      request.Method,
+		Host:        request.URL.Host,
+		Path:        request.URL.Path,
+		Query:       request.URL.Query(),
+		Headers:     map[string]string{"x-amz-content-sha256": payloadHash},
+		PayloadHash: payloadHash,
+	}, sigv4.Scope{Region: sink.options.Region, Service: sigv4.ServiceS3}, credentials, moment)
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	return nil
+}
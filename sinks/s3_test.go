@@ -0,0 +1,99 @@
+package sinks
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+)
+
+// storage - answers uploads, requests are kept for checks
+func storage(t *testing.T) (*httptest.Server, *[]*http.Request) {
+	requests := &[]*http.Request{}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		*requests = append(*requests, request)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+// instanceMetadata - IMDSv2 with credentials of role
+func instanceMetadata(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/latest/api/token":
+			writer.Write([]byte("session"))
+		case "/latest/meta-data/iam/security-credentials/":
+			writer.Write([]byte("bomber-role"))
+		case "/latest/meta-data/iam/security-credentials/bomber-role":
+			writer.Write([]byte(`{"AccessKeyId": "ROLEKEY", "SecretAccessKey": "secret", "Token": "role-token", "Expiration": "` +
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestS3Upload(t *testing.T) {
+	metadata := instanceMetadata(t)
+	cases := []struct {
+		name       string
+		accessKey  string
+		secretKey  string
+		credential string
+		token      string
+	}{
+		{"static keys", "KEY", "secret", "Credential=KEY/", ""},
+		{"instance role without keys", S3Disabled, S3Disabled, "Credential=ROLEKEY/", "role-token"},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			server, requests := storage(t)
+			sink := NewS3Sink(S3Options{
+				Endpoint:    server.URL + "/",
+				Region:      "eu-west-1",
+				Bucket:      "results",
+				AccessKey:   testCase.accessKey,
+				SecretKey:   testCase.secretKey,
+				Prefix:      "/attacks/",
+				MetadataURL: metadata.URL,
+			})
+			started := time.Unix(1600000000, 0)
+			report, err := sink.Upload(&rest_contracts.BomberResult{FormId: "form 1", BomberId: "bomber"}, started)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Key != "attacks/form 1-bomber-1600000000.pb.gz" || report.URL != server.URL+"/results/attacks/form%201-bomber-1600000000.pb.gz" {
+				t.Fatalf("unexpected report %+v", report)
+			}
+			if len(*requests) != 1 {
+				t.Fatalf("got %d requests", len(*requests))
+			}
+			request := (*requests)[0]
+			authorization := request.Header.Get("Authorization")
+			if !strings.Contains(authorization, testCase.credential) || !strings.Contains(authorization, "/eu-west-1/s3/aws4_request") {
+				t.Fatalf("unexpected authorization %s", authorization)
+			}
+			if request.Header.Get("X-Amz-Security-Token") != testCase.token {
+				t.Fatalf("got token %q", request.Header.Get("X-Amz-Security-Token"))
+			}
+		})
+	}
+}
+
+func TestS3UploadWithoutCredentials(t *testing.T) {
+	server, requests := storage(t)
+	sink := NewS3Sink(S3Options{Endpoint: server.URL, Region: "eu-west-1", Bucket: "results",
+		AccessKey: S3Disabled, SecretKey: S3Disabled, MetadataURL: S3Disabled})
+	if _, err := sink.Upload(&rest_contracts.BomberResult{FormId: "form"}, time.Now()); err == nil {
+		t.Fatal("expected error of missing credentials")
+	}
+	if len(*requests) != 0 {
+		t.Fatal("unsigned result is uploaded")
+	}
+}
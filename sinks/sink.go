@@ -0,0 +1,119 @@
+package sinks
+
+import (
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/nats_listener"
+	"github.com/sirupsen/logrus"
+)
+
+// FinalResult - everything known about completed attack
+type FinalResult struct {
+	Task    rest_contracts.Task
+	Started time.Time
+	Result  *rest_contracts.BomberResult
+	Report  *core.AttackReport
+}
+
+/*
+ResultSink - destination of results of attacks. Interim results come periodically during
+the attack if task asks for them, final result once after it
+*/
+type ResultSink interface {
+	PublishInterim(result *core.InterimResult) error
+	PublishFinal(result *FinalResult) error
+}
+
+/*
+Factory - creates sink from configuration of the bomber, nil if the sink is disabled.
+Publisher is connection of the bomber to NATS
+*/
+type Factory func(config *config.Configuration, publisher *nats_listener.Publisher) ResultSink
+
+type registered struct {
+	name    string
+	factory Factory
+	// failure of required sink fails the task, failures of others are only logged
+	required bool
+}
+
+var registry []registered
+
+func init() {
+	registerRequired("nats", func(config *config.Configuration, publisher *nats_listener.Publisher) ResultSink {
+		return NewNatsSink(config, publisher)
+	})
+	Register("file", func(config *config.Configuration, _ *nats_listener.Publisher) ResultSink {
+		if sink := NewFileSink(config.TenantReportDir()); sink != nil {
+			return sink
+		}
+		return nil
+	})
+	Register("influx", func(config *config.Configuration, _ *nats_listener.Publisher) ResultSink {
+		if sink := NewInfluxSink(config.InfluxURL, config.InfluxToken); sink != nil {
+			return sink
+		}
+		return nil
+	})
+}
+
+// Register - adds sink, which results are published into after sinks registered before it
+func Register(name string, factory Factory) {
+	registry = append(registry, registered{name: name, factory: factory})
+}
+
+func registerRequired(name string, factory Factory) {
+	registry = append(registry, registered{name: name, factory: factory, required: true})
+}
+
+type openedSink struct {
+	name     string
+	sink     ResultSink
+	required bool
+}
+
+// Sinks - enabled sinks of the bomber, results are published into each of them in order of registration
+type Sinks struct {
+	sinks []openedSink
+}
+
+func Open(config *config.Configuration, publisher *nats_listener.Publisher) *Sinks {
+	opened := &Sinks{}
+	for _, entry := range registry {
+		sink := entry.factory(config, publisher)
+		if sink == nil {
+			continue
+		}
+		logrus.Info("Publishing results into ", entry.name, " sink")
+		opened.sinks = append(opened.sinks, openedSink{name: entry.name, sink: sink, required: entry.required})
+	}
+	return opened
+}
+
+func (sinks *Sinks) PublishInterim(result *core.InterimResult) error {
+	for _, opened := range sinks.sinks {
+		if err := opened.sink.PublishInterim(result); err != nil {
+			logrus.Error("Can not publish interim result into ", opened.name, " sink: ", err)
+		}
+	}
+	return nil
+}
+
+// PublishFinal - publishes into all sinks, error is the first failure of required sinks
+func (sinks *Sinks) PublishFinal(result *FinalResult) error {
+	var failure error
+	for _, opened := range sinks.sinks {
+		err := opened.sink.PublishFinal(result)
+		if err == nil {
+			continue
+		}
+		logrus.Error("Can not publish result into ", opened.name, " sink: ", err)
+		if opened.required && failure == nil {
+			failure = err
+		}
+	}
+	return failure
+}
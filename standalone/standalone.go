@@ -0,0 +1,134 @@
+package standalone
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/bomber-team/rest-bomber/config"
+	"github.com/bomber-team/rest-bomber/core"
+	"github.com/bomber-team/rest-bomber/sinks"
+	"github.com/sirupsen/logrus"
+)
+
+// exit codes of standalone mode
+const (
+	ExitCompleted        = 0
+	ExitFailed           = 1
+	ExitThresholdsFailed = 2
+)
+
+/*
+Options - standalone mode runs one task without any broker, task is read from json or yaml file
+or built by flags. Report is printed into stdout if its file is not set
+*/
+type Options struct {
+	TaskFile  string
+	Report    string
+	ReportDir string
+	URL       string
+	Method    string
+	Rps       int64
+	Time      int64
+	Headers   map[string]string
+}
+
+// RegisterFlags - options of standalone mode are set by flags of the bomber
+func RegisterFlags(flags *flag.FlagSet) *Options {
+	options := &Options{Headers: map[string]string{}}
+	flags.StringVar(&options.TaskFile, "task", "", "file of the task, json or yaml (.yaml, .yml)")
+	flags.StringVar(&options.Report, "report", "", "file of json report, report is printed into stdout if empty")
+	flags.StringVar(&options.ReportDir, "report-dir", "", "directory of json, html and junit reports")
+	flags.StringVar(&options.URL, "url", "", "address of the attack")
+	flags.StringVar(&options.Method, "method", "", "method of requests, GET if empty")
+	flags.Int64Var(&options.Rps, "rps", 0, "requests per second")
+	flags.Int64Var(&options.Time, "time", 0, "duration of the attack in seconds")
+	flags.Var(headerFlags(options.Headers), "header", "header of requests \"Name: value\", can be repeated")
+	return options
+}
+
+// Enabled - false if neither task nor url is set, bomber works as service then
+func (options *Options) Enabled() bool {
+	return options.TaskFile != "" || options.URL != ""
+}
+
+// Run - exit code of the attack, thresholds of the task decide about it if they are set
+func Run(options *Options, configuration *config.Configuration) int {
+	// stdout is left for the report
+	logrus.SetOutput(os.Stderr)
+	task, err := LoadTask(options)
+	if err != nil {
+		logrus.Error("Can not load task: ", err)
+		return ExitFailed
+	}
+	if fieldErrors := core.ValidateTask(task); len(fieldErrors) > 0 {
+		for _, fieldError := range fieldErrors {
+			logrus.Error("Invalid task field ", fieldError.Field, ": ", fieldError.Reason)
+		}
+		return ExitFailed
+	}
+	bomber := core.NewLocalCore(configuration)
+	attack, err := bomber.PreparingData(task)
+	if err != nil {
+		return ExitFailed
+	}
+	sigOs := make(chan os.Signal, 1)
+	signal.Notify(sigOs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigOs)
+	go func() {
+		if osSig, ok := <-sigOs; ok {
+			logrus.Info("Attack is cancelled by signal: ", osSig)
+			bomber.Cancel(task.FormId)
+		}
+	}()
+	logrus.Info("Starting attack ", task.FormId, " on ", task.Script.Address)
+	attack.WarmUp()
+	started := time.Now()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	attack.Start(task, &wg)
+	wg.Wait()
+	result := attack.FormResultAttack()
+	result.ElapsedTimeAttack = time.Since(started).Nanoseconds()
+	attack.Teardown()
+	result.BomberId = bomber.GetConfig().CurrentServiceID
+	report := attack.FormReportAttack()
+	record := sinks.NewRunRecord(task, started, result, report)
+	if err := writeReport(options, record); err != nil {
+		logrus.Error("Can not write report: ", err)
+		return ExitFailed
+	}
+	if err := attack.Failure(); err != nil {
+		logrus.Error("Attack of task ", task.FormId, " failed: ", err)
+		return ExitFailed
+	}
+	if report.ThresholdsPassed != nil && !*report.ThresholdsPassed {
+		logrus.Error("Thresholds of attack ", task.FormId, " failed")
+		return ExitThresholdsFailed
+	}
+	return ExitCompleted
+}
+
+func writeReport(options *Options, record *sinks.RunRecord) error {
+	if sink := sinks.NewFileSink(options.ReportDir); sink != nil {
+		path, err := sink.Write(record)
+		if err != nil {
+			return err
+		}
+		logrus.Info("Reports of attack were written to ", path)
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	if options.Report == "" {
+		_, err = os.Stdout.Write(append(data, '\n'))
+		return err
+	}
+	return ioutil.WriteFile(options.Report, data, 0644)
+}
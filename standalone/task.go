@@ -0,0 +1,119 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bomber-team/bomber-proto-contracts/golang/rest_contracts"
+	"github.com/gogo/protobuf/jsonpb"
+	"gopkg.in/yaml.v2"
+)
+
+// LoadTask - task of the file, fields given by flags replace fields of the file
+func LoadTask(options *Options) (rest_contracts.Task, error) {
+	var task rest_contracts.Task
+	if options.TaskFile != "" {
+		data, err := ioutil.ReadFile(options.TaskFile)
+		if err != nil {
+			return task, err
+		}
+		if task, err = parseTask(data, filepath.Ext(options.TaskFile)); err != nil {
+			return task, fmt.Errorf("can not parse task %s: %v", options.TaskFile, err)
+		}
+	}
+	if task.Script == nil {
+		task.Script = &rest_contracts.RestScript{}
+	}
+	if task.Script.Config == nil {
+		task.Script.Config = &rest_contracts.ConfigurationScript{}
+	}
+	if task.Schema == nil {
+		task.Schema = &rest_contracts.RestSchema{}
+	}
+	if options.URL != "" {
+		task.Script.Address = options.URL
+	}
+	if options.Method != "" {
+		task.Script.RequestMethod = options.Method
+	}
+	if task.Script.RequestMethod == "" {
+		task.Script.RequestMethod = "GET"
+	}
+	if options.Rps > 0 {
+		task.Script.Config.Rps = options.Rps
+	}
+	if options.Time > 0 {
+		task.Script.Config.Time = options.Time
+	}
+	if len(options.Headers) > 0 && task.Schema.Headers == nil {
+		task.Schema.Headers = map[string]string{}
+	}
+	for name, value := range options.Headers {
+		task.Schema.Headers[name] = value
+	}
+	if task.FormId == "" {
+		task.FormId = "standalone-" + strconv.FormatInt(time.Now().Unix(), 10)
+	}
+	return task, nil
+}
+
+// parseTask - json of protobuf contracts, yaml with the same fields is converted to json
+func parseTask(data []byte, extension string) (rest_contracts.Task, error) {
+	var task rest_contracts.Task
+	if extension == ".yaml" || extension == ".yml" {
+		var document interface{}
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return task, err
+		}
+		converted, err := json.Marshal(jsonValue(document))
+		if err != nil {
+			return task, err
+		}
+		data = converted
+	}
+	err := jsonpb.Unmarshal(bytes.NewReader(data), &task)
+	return task, err
+}
+
+// jsonValue - yaml maps have keys of any type, json objects have string keys only
+func jsonValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		object := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			object[fmt.Sprint(key)] = jsonValue(item)
+		}
+		return object
+	case []interface{}:
+		for index, item := range typed {
+			typed[index] = jsonValue(item)
+		}
+	}
+	return value
+}
+
+// headerFlags - repeated flag of header "Name: value"
+type headerFlags map[string]string
+
+func (headers headerFlags) String() string {
+	var pairs []string
+	for name, value := range headers {
+		pairs = append(pairs, name+": "+value)
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (headers headerFlags) Set(raw string) error {
+	parts := strings.SplitN(raw, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return fmt.Errorf("header %q is not in form Name: value", raw)
+	}
+	headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	return nil
+}
@@ -0,0 +1,430 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultDialTimeout = 5 * time.Second
+	dualStackFallback  = 300 * time.Millisecond
+	dnsPort            = "53"
+)
+
+const (
+	FamilyIPv4      = "ipv4"
+	FamilyIPv6      = "ipv6"
+	FamilyDualStack = "dual"
+)
+
+var (
+	ErrUnknownFamily = errors.New("unknown address family")
+	ErrLocalAddr     = errors.New("local address is not ip")
+	ErrNoAddresses   = errors.New("no addresses of requested family")
+)
+
+type DialerConfig struct {
+	Hosts    map[string]string // static host -> ip mapping, like /etc/hosts
+	Resolver string            // address of dns server, system resolver is used if empty
+	Family   string            // ipv4, ipv6 or dual (happy eyeballs), ipv4 if empty
+	// local ips for outgoing connections, used by round-robin
+	LocalAddrs []string
+	// limit of outgoing traffic of all connections, unlimited if zero
+	MaxBytesPerSecond int64
+	TCP               TCPOptions
+	TLS               TLSOptions
+}
+
+type TLSOptions struct {
+	// keep sessions of handshakes and resume them in new connections
+	SessionResumption  bool
+	InsecureSkipVerify bool
+}
+
+type DialStats struct {
+	IPv4       int64
+	IPv6       int64
+	PerSource  map[string]int64
+	TLSFull    int64
+	TLSResumed int64
+	// bytes of all connections including tls records
+	BytesRead    int64
+	BytesWritten int64
+}
+
+type sourceDialer struct {
+	dialer *net.Dialer
+	source string
+	dials  int64
+}
+
+/*
+Dialer - dial func for fasthttp clients, which resolves hosts by static mapping
+or custom dns server before connecting
+*/
+type Dialer struct {
+	hosts    map[string]string
+	family   string
+	resolver *net.Resolver
+	dialers  []*sourceDialer
+	next     uint64
+	tcp      TCPOptions
+	tls      TLSOptions
+	sessions tls.ClientSessionCache
+	bucket   *tokenBucket
+	warm     warmPool
+	ipv4     int64
+	ipv6     int64
+	full     int64
+	resumed  int64
+	io       ioCounters
+}
+
+func NewDialer(config DialerConfig) (*Dialer, error) {
+	switch config.Family {
+	case "":
+		config.Family = FamilyIPv4
+	case FamilyIPv4, FamilyIPv6, FamilyDualStack:
+	default:
+		return nil, ErrUnknownFamily
+	}
+	dialer := &Dialer{
+		hosts:    map[string]string{},
+		family:   config.Family,
+		resolver: net.DefaultResolver,
+		tcp:      config.TCP,
+		tls:      config.TLS,
+	}
+	if config.TLS.SessionResumption {
+		dialer.sessions = tls.NewLRUClientSessionCache(0)
+	}
+	for host, ip := range config.Hosts {
+		dialer.hosts[host] = ip
+	}
+	if config.MaxBytesPerSecond > 0 {
+		dialer.bucket = newTokenBucket(config.MaxBytesPerSecond)
+	}
+	if config.Resolver != "" {
+		dialer.resolver = newResolver(config.Resolver)
+	}
+	sources := config.LocalAddrs
+	if len(sources) == 0 {
+		sources = []string{""}
+	}
+	for _, source := range sources {
+		netDialer := &net.Dialer{
+			Timeout: defaultDialTimeout,
+			Control: config.TCP.control(),
+		}
+		if source != "" {
+			ip := net.ParseIP(source)
+			if ip == nil {
+				return nil, ErrLocalAddr
+			}
+			netDialer.LocalAddr = &net.TCPAddr{IP: ip}
+		}
+		dialer.dialers = append(dialer.dialers, &sourceDialer{
+			dialer: netDialer,
+			source: source,
+		})
+	}
+	return dialer, nil
+}
+
+func newResolver(address string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, dnsPort)
+	}
+	dialer := &net.Dialer{Timeout: defaultDialTimeout}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, address)
+		},
+	}
+}
+
+func (dialer *Dialer) Dial(addr string) (net.Conn, error) {
+	if conn := dialer.warm.take(addr, nil); conn != nil {
+		return conn, nil
+	}
+	return dialer.dial(addr, nil)
+}
+
+/*
+DialUDP - connects udp socket to addr, resolved the same way as tcp connections,
+outgoing datagrams are throttled by the limit of the dialer
+*/
+func (dialer *Dialer) DialUDP(addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	primaries, _, errResolve := dialer.resolve(host)
+	if errResolve != nil {
+		return nil, errResolve
+	}
+	source := dialer.nextSource()
+	netDialer := &net.Dialer{Timeout: defaultDialTimeout}
+	if source.source != "" {
+		netDialer.LocalAddr = &net.UDPAddr{IP: net.ParseIP(source.source)}
+	}
+	conn, errDial := netDialer.Dial("udp", net.JoinHostPort(primaries[0].String(), port))
+	if errDial != nil {
+		return nil, errDial
+	}
+	atomic.AddInt64(&source.dials, 1)
+	dialer.countFamily(conn.RemoteAddr())
+	if dialer.bucket != nil {
+		conn = &throttledConn{Conn: conn, bucket: dialer.bucket}
+	}
+	return &tracedConn{Conn: conn, io: &dialer.io}, nil
+}
+
+/*
+DialTraced - dials addr with tls handshake if needed and records timings
+of dns, connect and tls phases into trace
+*/
+func (dialer *Dialer) DialTraced(addr string, isTLS bool, trace *Trace) (net.Conn, error) {
+	if conn := dialer.warm.take(addr, trace); conn != nil {
+		return conn, nil
+	}
+	conn, err := dialer.dial(addr, trace)
+	if err != nil || !isTLS {
+		return conn, err
+	}
+	conn, err = dialer.handshake(conn, addr, trace)
+	if trace.recording() {
+		// bytes of handshake are not the request
+		trace.resetIO()
+	}
+	return conn, err
+}
+
+func (dialer *Dialer) handshake(conn net.Conn, addr string, trace *Trace) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	timeStart := time.Now()
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName:         host,
+		ClientSessionCache: dialer.sessions,
+		InsecureSkipVerify: dialer.tls.InsecureSkipVerify,
+	})
+	tlsConn.SetDeadline(timeStart.Add(defaultDialTimeout))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	resumed := tlsConn.ConnectionState().DidResume
+	if resumed {
+		atomic.AddInt64(&dialer.resumed, 1)
+	} else {
+		atomic.AddInt64(&dialer.full, 1)
+	}
+	if trace.recording() {
+		trace.TLS = time.Since(timeStart)
+		trace.TLSResumed = resumed
+	}
+	return tlsConn, nil
+}
+
+func (dialer *Dialer) dial(addr string, trace *Trace) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	timeStart := time.Now()
+	primaries, fallbacks, errResolve := dialer.resolve(host)
+	if errResolve != nil {
+		return nil, errResolve
+	}
+	timeResolved := time.Now()
+	source := dialer.nextSource()
+	conn, errDial := dialParallel(source.dialer, primaries, fallbacks, port)
+	if errDial != nil {
+		return nil, errDial
+	}
+	if trace.recording() {
+		trace.connected()
+		trace.DNS = timeResolved.Sub(timeStart)
+		trace.Connect = time.Since(timeResolved)
+	}
+	atomic.AddInt64(&source.dials, 1)
+	dialer.tcp.applyConn(conn)
+	dialer.countFamily(conn.RemoteAddr())
+	if dialer.bucket != nil {
+		conn = &throttledConn{Conn: conn, bucket: dialer.bucket}
+	}
+	return &tracedConn{Conn: conn, trace: trace, io: &dialer.io}, nil
+}
+
+/*
+resolve - returns addresses of host splitted by family. Fallbacks are not empty
+only for dual stack, they are dialed if primaries do not answer in time
+*/
+func (dialer *Dialer) resolve(host string) ([]net.IP, []net.IP, error) {
+	if ip, ok := dialer.hosts[host]; ok {
+		host = ip
+	}
+	var addrs []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IP{ip}
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), defaultDialTimeout)
+		defer cancel()
+		resolved, err := dialer.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, addr := range resolved {
+			addrs = append(addrs, addr.IP)
+		}
+	}
+	var primaries, fallbacks []net.IP
+	for _, ip := range addrs {
+		isIPv4 := ip.To4() != nil
+		switch dialer.family {
+		case FamilyIPv4:
+			if isIPv4 {
+				primaries = append(primaries, ip)
+			}
+		case FamilyIPv6:
+			if !isIPv4 {
+				primaries = append(primaries, ip)
+			}
+		default:
+			if len(primaries) == 0 || (primaries[0].To4() != nil) == isIPv4 {
+				primaries = append(primaries, ip)
+			} else {
+				fallbacks = append(fallbacks, ip)
+			}
+		}
+	}
+	if len(primaries) == 0 {
+		return nil, nil, ErrNoAddresses
+	}
+	return primaries, fallbacks, nil
+}
+
+func dialSerial(dialer *net.Dialer, ips []net.IP, port string) (net.Conn, error) {
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.Dial("tcp", net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+type dialResult struct {
+	conn    net.Conn
+	err     error
+	primary bool
+}
+
+/*
+dialParallel - happy eyeballs: fallbacks are dialed if primaries are not connected
+in time, the first established connection wins
+*/
+func dialParallel(dialer *net.Dialer, primaries, fallbacks []net.IP, port string) (net.Conn, error) {
+	if len(fallbacks) == 0 {
+		return dialSerial(dialer, primaries, port)
+	}
+	results := make(chan dialResult)
+	returned := make(chan struct{})
+	defer close(returned)
+	race := func(ips []net.IP, primary bool) {
+		conn, err := dialSerial(dialer, ips, port)
+		select {
+		case results <- dialResult{conn: conn, err: err, primary: primary}:
+		case <-returned:
+			if conn != nil {
+				conn.Close()
+			}
+		}
+	}
+	go race(primaries, true)
+	fallbackTimer := time.NewTimer(dualStackFallback)
+	defer fallbackTimer.Stop()
+	var primaryErr, fallbackErr error
+	fallbackStarted := false
+	for {
+		select {
+		case <-fallbackTimer.C:
+			if !fallbackStarted {
+				fallbackStarted = true
+				go race(fallbacks, false)
+			}
+		case result := <-results:
+			if result.err == nil {
+				return result.conn, nil
+			}
+			if result.primary {
+				primaryErr = result.err
+			} else {
+				fallbackErr = result.err
+			}
+			if primaryErr != nil && fallbackErr != nil {
+				return nil, primaryErr
+			}
+			if !fallbackStarted {
+				fallbackStarted = true
+				go race(fallbacks, false)
+			}
+		}
+	}
+}
+
+func (dialer *Dialer) nextSource() *sourceDialer {
+	if len(dialer.dialers) == 1 {
+		return dialer.dialers[0]
+	}
+	index := atomic.AddUint64(&dialer.next, 1)
+	return dialer.dialers[index%uint64(len(dialer.dialers))]
+}
+
+func (dialer *Dialer) countFamily(addr net.Addr) {
+	var ip net.IP
+	switch x := addr.(type) {
+	case *net.TCPAddr:
+		ip = x.IP
+	case *net.UDPAddr:
+		ip = x.IP
+	default:
+		return
+	}
+	if ip.To4() != nil {
+		atomic.AddInt64(&dialer.ipv4, 1)
+	} else {
+		atomic.AddInt64(&dialer.ipv6, 1)
+	}
+}
+
+func (dialer *Dialer) Stats() DialStats {
+	stats := DialStats{
+		IPv4:         atomic.LoadInt64(&dialer.ipv4),
+		IPv6:         atomic.LoadInt64(&dialer.ipv6),
+		PerSource:    map[string]int64{},
+		TLSFull:      atomic.LoadInt64(&dialer.full),
+		TLSResumed:   atomic.LoadInt64(&dialer.resumed),
+		BytesRead:    atomic.LoadInt64(&dialer.io.read),
+		BytesWritten: atomic.LoadInt64(&dialer.io.written),
+	}
+	for _, source := range dialer.dialers {
+		if source.source == "" {
+			continue
+		}
+		stats.PerSource[source.source] = atomic.LoadInt64(&source.dials)
+	}
+	return stats
+}
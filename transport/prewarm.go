@@ -0,0 +1,85 @@
+package transport
+
+import (
+	"net"
+	"sync"
+)
+
+type warmConn struct {
+	conn   net.Conn
+	traced *tracedConn
+}
+
+/*
+warmPool - connections established before the attack, Dial takes them before
+opening new ones
+*/
+type warmPool struct {
+	mutex sync.Mutex
+	conns map[string][]warmConn // by addr
+}
+
+func (pool *warmPool) put(addr string, conn warmConn) {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if pool.conns == nil {
+		pool.conns = map[string][]warmConn{}
+	}
+	pool.conns[addr] = append(pool.conns[addr], conn)
+}
+
+// take - returns warm connection, which records timings into trace from now on
+func (pool *warmPool) take(addr string, trace *Trace) net.Conn {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	conns := pool.conns[addr]
+	if len(conns) == 0 {
+		return nil
+	}
+	conn := conns[len(conns)-1]
+	pool.conns[addr] = conns[:len(conns)-1]
+	conn.traced.trace = trace
+	return conn.conn
+}
+
+func (pool *warmPool) close() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	for _, conns := range pool.conns {
+		for _, conn := range conns {
+			conn.conn.Close()
+		}
+	}
+	pool.conns = nil
+}
+
+/*
+Prewarm - establishes amount of connections to addr (host:port) including tls handshake,
+they are used by the next dials to this addr. Returns amount of established connections
+*/
+func (dialer *Dialer) Prewarm(addr string, isTLS bool, amount int) (int, error) {
+	var errDial error
+	established := 0
+	for index := 0; index < amount; index++ {
+		conn, err := dialer.dial(addr, nil)
+		if err != nil {
+			errDial = err
+			continue
+		}
+		traced := conn.(*tracedConn)
+		if isTLS {
+			if conn, err = dialer.handshake(conn, addr, nil); err != nil {
+				errDial = err
+				continue
+			}
+		}
+		dialer.warm.put(addr, warmConn{conn: conn, traced: traced})
+		established++
+	}
+	return established, errDial
+}
+
+// CloseWarm - closes connections, which were not taken by the attack
+func (dialer *Dialer) CloseWarm() {
+	dialer.warm.close()
+}
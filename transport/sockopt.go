@@ -0,0 +1,37 @@
+package transport
+
+import (
+	"net"
+
+	"github.com/sirupsen/logrus"
+)
+
+type TCPOptions struct {
+	NoDelay    *bool // go sets TCP_NODELAY by default
+	ReuseAddr  bool
+	ReusePort  bool
+	SendBuffer int // SO_SNDBUF in bytes, system default if zero
+	RecvBuffer int // SO_RCVBUF in bytes, system default if zero
+}
+
+func (options TCPOptions) applyConn(conn net.Conn) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	if options.NoDelay != nil {
+		if err := tcpConn.SetNoDelay(*options.NoDelay); err != nil {
+			logrus.Debug("Can not set TCP_NODELAY: ", err)
+		}
+	}
+	if options.SendBuffer > 0 {
+		if err := tcpConn.SetWriteBuffer(options.SendBuffer); err != nil {
+			logrus.Debug("Can not set SO_SNDBUF: ", err)
+		}
+	}
+	if options.RecvBuffer > 0 {
+		if err := tcpConn.SetReadBuffer(options.RecvBuffer); err != nil {
+			logrus.Debug("Can not set SO_RCVBUF: ", err)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !darwin && !freebsd
+// +build !linux,!darwin,!freebsd
+
+package transport
+
+import (
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+)
+
+func (options TCPOptions) control() func(network, address string, conn syscall.RawConn) error {
+	if options.ReuseAddr || options.ReusePort {
+		logrus.Warn("SO_REUSEADDR and SO_REUSEPORT are not supported on this platform")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build linux || darwin || freebsd
+// +build linux darwin freebsd
+
+package transport
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+func (options TCPOptions) control() func(network, address string, conn syscall.RawConn) error {
+	if !options.ReuseAddr && !options.ReusePort {
+		return nil
+	}
+	return func(network, address string, conn syscall.RawConn) error {
+		var errOption error
+		errControl := conn.Control(func(fd uintptr) {
+			if options.ReuseAddr {
+				errOption = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+			}
+			if errOption == nil && options.ReusePort {
+				errOption = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+			}
+		})
+		if errControl != nil {
+			return errControl
+		}
+		return errOption
+	}
+}
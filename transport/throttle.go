@@ -0,0 +1,77 @@
+package transport
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+/*
+tokenBucket - limits amount of bytes per second, shared by all connections of the dialer
+*/
+type tokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64 // bytes per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(bytesPerSecond int64) *tokenBucket {
+	rate := float64(bytesPerSecond)
+	// burst of 100ms of traffic keeps writes smooth without long pauses
+	burst := rate / 10
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// take - waits until n bytes can be sent, n must not exceed the burst
+func (bucket *tokenBucket) take(n int) {
+	bucket.mutex.Lock()
+	now := time.Now()
+	bucket.tokens += now.Sub(bucket.last).Seconds() * bucket.rate
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+	bucket.last = now
+	bucket.tokens -= float64(n)
+	deficit := -bucket.tokens
+	bucket.mutex.Unlock()
+	if deficit > 0 {
+		time.Sleep(time.Duration(deficit / bucket.rate * float64(time.Second)))
+	}
+}
+
+func (bucket *tokenBucket) chunk() int {
+	return int(bucket.burst)
+}
+
+type throttledConn struct {
+	net.Conn
+	bucket *tokenBucket
+}
+
+func (conn *throttledConn) Write(data []byte) (int, error) {
+	written := 0
+	chunk := conn.bucket.chunk()
+	for written < len(data) {
+		end := written + chunk
+		if end > len(data) {
+			end = len(data)
+		}
+		conn.bucket.take(end - written)
+		n, err := conn.Conn.Write(data[written:end])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
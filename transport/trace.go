@@ -0,0 +1,114 @@
+package transport
+
+import (
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+/*
+Trace - timings of phases of one request. Connections are owned by one virtual user,
+which executes requests one by one, so trace is filled without locks.
+Only the first exchange after Start is recorded, redirect hops and retries are not
+*/
+type Trace struct {
+	Reused  bool // request was sent by already established connection
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// tls handshake resumed session of previous connection
+	TLSResumed bool
+
+	start      time.Time
+	wroteAt    time.Time
+	firstByte  time.Time
+	finishedAt time.Time
+}
+
+func (trace *Trace) Start() {
+	*trace = Trace{
+		Reused: true,
+		start:  time.Now(),
+	}
+}
+
+func (trace *Trace) Finish() {
+	if trace.recording() {
+		trace.finishedAt = time.Now()
+	}
+}
+
+func (trace *Trace) recording() bool {
+	return trace != nil && !trace.start.IsZero() && trace.finishedAt.IsZero()
+}
+
+// Wait - time from sending of request until the first byte of response
+func (trace *Trace) Wait() time.Duration {
+	if trace.wroteAt.IsZero() || trace.firstByte.IsZero() {
+		return 0
+	}
+	return trace.firstByte.Sub(trace.wroteAt)
+}
+
+// TTFB - time from start of request until the first byte of response, including connecting
+func (trace *Trace) TTFB() time.Duration {
+	if trace.firstByte.IsZero() {
+		return 0
+	}
+	return trace.firstByte.Sub(trace.start)
+}
+
+// Total - time from start of request until response is read
+func (trace *Trace) Total() time.Duration {
+	if trace.finishedAt.IsZero() {
+		return 0
+	}
+	return trace.finishedAt.Sub(trace.start)
+}
+
+// Transfer - time of reading response after its first byte
+func (trace *Trace) Transfer() time.Duration {
+	if trace.firstByte.IsZero() || trace.finishedAt.IsZero() {
+		return 0
+	}
+	return trace.finishedAt.Sub(trace.firstByte)
+}
+
+func (trace *Trace) connected() {
+	trace.Reused = false
+}
+
+func (trace *Trace) resetIO() {
+	trace.wroteAt = time.Time{}
+	trace.firstByte = time.Time{}
+}
+
+// ioCounters - bytes of all connections of dialer
+type ioCounters struct {
+	read    int64
+	written int64
+}
+
+type tracedConn struct {
+	net.Conn
+	trace *Trace
+	io    *ioCounters
+}
+
+func (conn *tracedConn) Write(data []byte) (int, error) {
+	if conn.trace.recording() && conn.trace.wroteAt.IsZero() {
+		conn.trace.wroteAt = time.Now()
+	}
+	n, err := conn.Conn.Write(data)
+	atomic.AddInt64(&conn.io.written, int64(n))
+	return n, err
+}
+
+func (conn *tracedConn) Read(data []byte) (int, error) {
+	n, err := conn.Conn.Read(data)
+	atomic.AddInt64(&conn.io.read, int64(n))
+	if n > 0 && conn.trace.recording() && !conn.trace.wroteAt.IsZero() && conn.trace.firstByte.IsZero() {
+		conn.trace.firstByte = time.Now()
+	}
+	return n, err
+}